@@ -68,6 +68,9 @@ type Config struct {
 	Ingress        IngressConfig            `yaml:"ingress,omitempty"`
 	SIP            SIPConfig                `yaml:"sip,omitempty"`
 	WebHook        WebHookConfig            `yaml:"webhook,omitempty"`
+	Analytics      AnalyticsConfig          `yaml:"analytics,omitempty"`
+	EventFile      EventFileConfig          `yaml:"event_file,omitempty"`
+	EventStream    EventStreamConfig        `yaml:"event_stream,omitempty"`
 	NodeSelector   NodeSelectorConfig       `yaml:"node_selector,omitempty"`
 	KeyFile        string                   `yaml:"key_file,omitempty"`
 	Keys           map[string]string        `yaml:"keys,omitempty"`
@@ -184,6 +187,8 @@ type AudioConfig struct {
 	SmoothIntervals uint32 `yaml:"smooth_intervals,omitempty"`
 	// enable red encoding downtrack for opus only audio up track
 	ActiveREDEncoding bool `yaml:"active_red_encoding,omitempty"`
+	// active speaker changes within this window are coalesced before being sent to telemetry
+	ActiveSpeakerDebounce time.Duration `yaml:"active_speaker_debounce,omitempty"`
 }
 
 type StreamTrackerPacketConfig struct {
@@ -255,8 +260,209 @@ type TURNConfig struct {
 
 type WebHookConfig struct {
 	URLs []string `yaml:"urls,omitempty"`
+	// additional endpoints, each with its own key. events are delivered to every URL and endpoint independently
+	Endpoints []WebHookEndpointConfig `yaml:"endpoints,omitempty"`
 	// key to use for webhook
 	APIKey string `yaml:"api_key,omitempty"`
+	// when set, every request includes an HMAC-SHA256 signature of the event id and body
+	SigningKey string `yaml:"signing_key,omitempty"`
+	// number of times a failed delivery is retried before the event is dropped
+	MaxRetries int `yaml:"max_retries,omitempty"`
+	// delay before the first retry, doubled on every subsequent attempt
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay,omitempty"`
+	// maximum time a single delivery attempt may take
+	DeliveryTimeout time.Duration `yaml:"delivery_timeout,omitempty"`
+	// deliveries attempted at once per endpoint, including those waiting out a retry. more workers keep
+	// up with a slow endpoint at the cost of more goroutines and connections to it
+	Workers int `yaml:"workers,omitempty"`
+	// deliveries waiting for a worker per endpoint, beyond which events are dead-lettered instead of queued.
+	// a larger queue rides out longer endpoint slowdowns but holds more events in memory
+	QueueSize int `yaml:"queue_size,omitempty"`
+	// deliver events about the same room to each endpoint one at a time, in the order they happened. a slow
+	// or retried delivery then holds up the room's later events, rooms are still delivered in parallel
+	OrderByRoom bool `yaml:"order_by_room,omitempty"`
+	// events per second delivered again when replaying the dead letter sink
+	DeadLetterReplayRate float64 `yaml:"dead_letter_replay_rate,omitempty"`
+	// when not empty, only these events are sent
+	IncludeEvents []string `yaml:"include_events,omitempty"`
+	// events that are never sent, takes precedence over IncludeEvents
+	ExcludeEvents []string `yaml:"exclude_events,omitempty"`
+	// send active_speaker_changed events. off by default as they are high volume
+	ActiveSpeakerEvents bool `yaml:"active_speaker_events,omitempty"`
+	// send track_stalled and track_resumed events, subscribed_track_stalled and subscribed_track_resumed for
+	// subscribed tracks, see AnalyticsConfig.TrackStallIntervals
+	TrackStallEvents bool `yaml:"track_stall_events,omitempty"`
+	// send the client the participant of participant_joined events joined from. every event is then sent wrapped
+	// in an envelope, with the client info alongside the event, see telemetry.WebhookEnvelope. off by default as it
+	// includes device details and the client's address, see Redactions
+	ParticipantClientInfo bool `yaml:"participant_client_info,omitempty"`
+	// send the state events changed along with them, the room metadata before room_metadata_changed events, the
+	// fields participant_attributes_changed events changed, the permissions participant_updated events changed from,
+	// the speakers of active_speaker_changed events with their levels, the reason participant_left events left
+	// for, and who muted or unmuted the track of track_muted and track_unmuted events. every event is then sent
+	// wrapped in an envelope, as with ParticipantClientInfo
+	EventDetails bool `yaml:"event_details,omitempty"`
+	// lifecycle events repeated for the same subject within this window are not sent again, 0 to disable
+	DedupWindow time.Duration `yaml:"dedup_window,omitempty"`
+	// a track unpublished and published again within this window sends neither event, webhook or analytics.
+	// unpublish events are delayed by the window. 0 to disable
+	TrackChurnWindow time.Duration `yaml:"track_churn_window,omitempty"`
+	// static headers added to every request, values may contain {room_name} and {event}
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// gzip request bodies larger than CompressThreshold bytes
+	Compress          bool `yaml:"compress,omitempty"`
+	CompressThreshold int  `yaml:"compress_threshold,omitempty"`
+	// encoding of request bodies, json, protobuf or form
+	Encoding string `yaml:"encoding,omitempty"`
+	// the form field the event is sent in with the form encoding, defaults to payload
+	FormField string `yaml:"form_field,omitempty"`
+	// version of the payload sent to URLs and endpoints, v1 or v2
+	APIVersion string `yaml:"api_version,omitempty"`
+	// client certificate and CAs used to connect to URLs and endpoints
+	TLS WebHookTLSConfig `yaml:"tls,omitempty"`
+	// fields cleared or hashed before events are delivered, analytics and event listeners are not affected
+	Redactions []WebHookRedactionConfig `yaml:"redactions,omitempty"`
+	// fail to start when no URLs or endpoints are configured, instead of not sending events
+	Required bool `yaml:"required,omitempty"`
+	// stop delivering to an endpoint once this many deliveries in a row failed, after their retries, 0 to disable
+	BreakerFailures int `yaml:"breaker_failures,omitempty"`
+	// how long deliveries to the endpoint are stopped for before one is let through to check it has recovered
+	BreakerCooldown time.Duration `yaml:"breaker_cooldown,omitempty"`
+	// what happens to events while deliveries are stopped: dead_letter or drop
+	BreakerPolicy string `yaml:"breaker_policy,omitempty"`
+	// fraction of events delivered, between 0 (none) and 1 (all), by event, e.g. track_published.
+	// independent of AnalyticsConfig.EventSampleRates, events that aren't listed are all delivered
+	EventSampleRates map[string]float64 `yaml:"event_sample_rates,omitempty"`
+}
+
+type WebHookTLSConfig struct {
+	// PEM encoded client certificate and key, for endpoints that require mutual TLS. reloaded when changed
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// PEM encoded CAs trusted in addition to the system roots
+	CAFile string `yaml:"ca_file,omitempty"`
+}
+
+type WebHookRedactionConfig struct {
+	// room.name, room.metadata, participant.identity, participant.name, participant.metadata,
+	// ingress.participant_identity, ingress.participant_name, or client_info.os, client_info.os_version,
+	// client_info.device_model, client_info.browser, client_info.browser_version, client_info.address or
+	// client_info.network, see ParticipantClientInfo
+	Fields []string `yaml:"fields,omitempty"`
+	// events the fields are redacted from, all events when empty
+	Events []string `yaml:"events,omitempty"`
+	// names of the endpoints the fields are redacted for, all URLs, endpoints and the event stream when empty.
+	// the event stream is named event_stream
+	Endpoints []string `yaml:"endpoints,omitempty"`
+	// replace values with their hex encoded SHA-256 instead of clearing them
+	Hash bool `yaml:"hash,omitempty"`
+}
+
+type WebHookEndpointConfig struct {
+	// identifies the endpoint in metrics and logs, defaults to the host of URL
+	Name string `yaml:"name,omitempty"`
+	URL  string `yaml:"url,omitempty"`
+	// key to use for this endpoint, defaults to the webhook api_key
+	APIKey string `yaml:"api_key,omitempty"`
+	// defaults to the webhook signing_key
+	SigningKey string `yaml:"signing_key,omitempty"`
+	// added to the webhook headers, replacing any with the same name
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// defaults to the webhook api_version
+	APIVersion string `yaml:"api_version,omitempty"`
+	// defaults to the webhook encoding and form_field
+	Encoding  string `yaml:"encoding,omitempty"`
+	FormField string `yaml:"form_field,omitempty"`
+}
+
+type AnalyticsConfig struct {
+	// number of analytics events buffered before they are sent as a batch
+	BatchSize int `yaml:"batch_size,omitempty"`
+	// maximum time an analytics event is buffered for
+	BatchInterval time.Duration `yaml:"batch_interval,omitempty"`
+	// stats for a participant that isn't connected and haven't been updated for this long are dropped, 0 to keep them
+	// until the participant leaves
+	WorkerIdleTimeout time.Duration `yaml:"worker_idle_timeout,omitempty"`
+	// stats for participants that haven't left a room this long after it ended are dropped, 0 to drop them right away
+	RoomEndDrainTimeout time.Duration `yaml:"room_end_drain_timeout,omitempty"`
+	// how often participant stats are sampled and sent
+	StatsInterval time.Duration `yaml:"stats_interval,omitempty"`
+	// how often a rollup of the media of each room is sent as stats of the room, 0 to disable
+	RoomStatsInterval time.Duration `yaml:"room_stats_interval,omitempty"`
+	// number of sends to the analytics sink that can be queued while it's busy
+	QueueSize int `yaml:"queue_size,omitempty"`
+	// what to do when the queue is full: block, drop_oldest or drop_newest
+	QueuePolicy string `yaml:"queue_policy,omitempty"`
+	// fraction of simulcast layer changes logged, between 0 (none) and 1 (all)
+	SimulcastLayerSampleRate float64 `yaml:"simulcast_layer_sample_rate,omitempty"`
+	// a published track that isn't muted and receives no media for this many stats intervals is reported as
+	// stalled, as is a subscribed track that is sent none while its published track receives some. 0 to disable
+	TrackStallIntervals int `yaml:"track_stall_intervals,omitempty"`
+	// a published track that isn't muted and receives no media this long after being published, or unmuted, is
+	// reported as never active, disabled when 0
+	TrackFirstPacketTimeout time.Duration `yaml:"track_first_packet_timeout,omitempty"`
+	// how often a summary of the audio level of each participant publishing a microphone is recorded, 0 to disable
+	AudioLevelInterval time.Duration `yaml:"audio_level_interval,omitempty"`
+	// fraction of audio level summaries logged, between 0 (none) and 1 (all)
+	AudioLevelSampleRate float64 `yaml:"audio_level_sample_rate,omitempty"`
+	// weights of the quality score of each subscribed track
+	QoSScore QoSScoreConfig `yaml:"qos_score,omitempty"`
+	// weight of the latest stats interval in the smoothed bitrate of each track, above 0 and up to 1. 1 turns
+	// smoothing off
+	BitrateSmoothingAlpha float64 `yaml:"bitrate_smoothing_alpha,omitempty"`
+	// livekit_room_bitrate_bps is only exported while there are at most this many rooms on the node, 500 by default, 0 for no limit
+	RoomBitrateMaxRooms int `yaml:"room_bitrate_max_rooms,omitempty"`
+	// how rooms are labelled in metrics labelled by room: none, hashed into RoomLabelBuckets buckets, or full.
+	// with full, rooms beyond the first RoomLabelMaxRooms on the node share the label other
+	RoomLabel         string `yaml:"room_label,omitempty"`
+	RoomLabelBuckets  int    `yaml:"room_label_buckets,omitempty"`
+	RoomLabelMaxRooms int    `yaml:"room_label_max_rooms,omitempty"`
+	// a room where participants join and leave more than JoinLeaveThreshold times within JoinLeaveWindow is
+	// reported as suspicious, 0 to disable
+	JoinLeaveThreshold int           `yaml:"join_leave_threshold,omitempty"`
+	JoinLeaveWindow    time.Duration `yaml:"join_leave_window,omitempty"`
+	// events that fail to send are kept in this directory and sent again once the sink is back, disabled when empty
+	BufferDir string `yaml:"buffer_dir,omitempty"`
+	// the oldest buffered events are dropped once the buffer reaches this many megabytes
+	BufferMaxSizeMB int `yaml:"buffer_max_size_mb,omitempty"`
+	// how long to wait after a failed send before trying to send buffered events again
+	BufferRetryInterval time.Duration `yaml:"buffer_retry_interval,omitempty"`
+	// with several analytics sinks, sends to one that fail this many times in a row fail over to the next
+	FailoverThreshold int `yaml:"failover_threshold,omitempty"`
+	// how often the primary analytics sink is tried again once failed over
+	FailoverRetryInterval time.Duration `yaml:"failover_retry_interval,omitempty"`
+	// optional fields of events larger than this many bytes once serialized are dropped, metadata first, 0 for no limit
+	MaxEventSize int `yaml:"max_event_size,omitempty"`
+	// fraction of events of a type sent, between 0 (none) and 1 (all), by type name, e.g. TRACK_SUBSCRIBED.
+	// types that aren't listed are all sent
+	EventSampleRates map[string]float64 `yaml:"event_sample_rates,omitempty"`
+}
+
+// QoSScoreConfig weighs each impairment in the 0-100 quality score of a subscribed track. weights are relative to
+// each other, a track that is as impaired as can be by all of them scores 0. scoring is disabled when all are 0
+type QoSScoreConfig struct {
+	LossWeight   float64 `yaml:"loss_weight,omitempty"`
+	JitterWeight float64 `yaml:"jitter_weight,omitempty"`
+	RTTWeight    float64 `yaml:"rtt_weight,omitempty"`
+	FreezeWeight float64 `yaml:"freeze_weight,omitempty"`
+}
+
+type EventStreamConfig struct {
+	// stream webhook events to clients connected over WebSocket to /events, with a token that may list rooms
+	Enabled bool `yaml:"enabled,omitempty"`
+	// events buffered for each client, a client that falls further behind is disconnected
+	BufferSize int `yaml:"buffer_size,omitempty"`
+	// maximum time writing an event to a client may take before it is disconnected
+	WriteTimeout time.Duration `yaml:"write_timeout,omitempty"`
+}
+
+type EventFileConfig struct {
+	// webhook and analytics events are appended to this file as newline-delimited JSON, disabled when empty
+	Path string `yaml:"path,omitempty"`
+	// the file is rotated once it reaches this many megabytes, 0 to not rotate by size
+	MaxSizeMB int `yaml:"max_size_mb,omitempty"`
+	// the file is rotated once it has been written to for this long, 0 to not rotate by time
+	RotateInterval time.Duration `yaml:"rotate_interval,omitempty"`
 }
 
 type NodeSelectorConfig struct {
@@ -379,10 +585,11 @@ var DefaultConfig = Config{
 		},
 	},
 	Audio: AudioConfig{
-		ActiveLevel:     35, // -35dBov
-		MinPercentile:   40,
-		UpdateInterval:  400,
-		SmoothIntervals: 2,
+		ActiveLevel:           35, // -35dBov
+		MinPercentile:         40,
+		UpdateInterval:        400,
+		SmoothIntervals:       2,
+		ActiveSpeakerDebounce: 500 * time.Millisecond,
 	},
 	Video: VideoConfig{
 		DynacastPauseDelay: 5 * time.Second,
@@ -480,6 +687,45 @@ var DefaultConfig = Config{
 	TURN: TURNConfig{
 		Enabled: false,
 	},
+	WebHook: WebHookConfig{
+		MaxRetries:        3,
+		RetryBaseDelay:    time.Second,
+		DeliveryTimeout:   10 * time.Second,
+		Workers:           10,
+		QueueSize:         1000,
+		DedupWindow:       10 * time.Second,
+		CompressThreshold: 1024,
+
+		DeadLetterReplayRate: 10,
+
+		BreakerCooldown: 30 * time.Second,
+		BreakerPolicy:   "dead_letter",
+	},
+	Analytics: AnalyticsConfig{
+		BatchSize:     50,
+		BatchInterval: time.Second,
+		StatsInterval: TelemetryStatsUpdateInterval,
+		QueueSize:     10000,
+		QueuePolicy:   "drop_oldest",
+
+		SimulcastLayerSampleRate: 0.01,
+		AudioLevelSampleRate:     0.1,
+
+		BitrateSmoothingAlpha: 0.3,
+
+		RoomEndDrainTimeout: 5 * time.Second,
+
+		RoomBitrateMaxRooms: 500,
+
+		RoomLabel:         "hashed",
+		RoomLabelBuckets:  16,
+		RoomLabelMaxRooms: 500,
+
+		JoinLeaveWindow: time.Minute,
+
+		BufferMaxSizeMB:     100,
+		BufferRetryInterval: 5 * time.Second,
+	},
 	NodeSelector: NodeSelectorConfig{
 		Kind:         "any",
 		SortBy:       "random",
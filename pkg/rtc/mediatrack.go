@@ -41,6 +41,8 @@ type MediaTrack struct {
 	params      MediaTrackParams
 	numUpTracks atomic.Uint32
 	buffer      *buffer.Buffer
+	// whether the first packet of the track has been reported to telemetry
+	firstPacketReported atomic.Bool
 
 	*MediaTrackReceiver
 	*MediaLossProxy
@@ -272,6 +274,7 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 			newWR.OnStatsUpdate(func(_ *sfu.WebRTCReceiver, stat *livekit.AnalyticsStat) {
 				key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, t.PublisherID(), t.ID(), ti.Source, ti.Type)
 				t.params.Telemetry.TrackStats(key, stat)
+				t.maybeReportFirstPacket(newWR)
 			})
 
 			newWR.OnMaxLayerChange(t.onMaxLayerChange)
@@ -362,6 +365,20 @@ func (t *MediaTrack) onMaxLayerChange(maxLayer int32) {
 	t.MediaTrackReceiver.NotifyMaxLayerChange(maxLayer)
 }
 
+// maybeReportFirstPacket reports when the first packet of the track was received, as recorded by the RTP stats of
+// its buffers, once there is one
+func (t *MediaTrack) maybeReportFirstPacket(receiver *sfu.WebRTCReceiver) {
+	if t.firstPacketReported.Load() {
+		return
+	}
+
+	stats := receiver.GetTrackStats()
+	if stats == nil || stats.StartTime == nil || !t.firstPacketReported.CompareAndSwap(false, true) {
+		return
+	}
+	t.params.Telemetry.TrackFirstPacket(context.Background(), t.PublisherID(), t.ID(), stats.StartTime.AsTime())
+}
+
 func (t *MediaTrack) Restart() {
 	t.MediaTrackReceiver.Restart()
 
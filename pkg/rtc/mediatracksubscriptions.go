@@ -15,6 +15,7 @@
 package rtc
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -28,6 +29,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 )
 
@@ -191,6 +193,37 @@ func (t *MediaTrackSubscriptions) AddSubscriber(sub types.LocalParticipant, wr *
 		}
 	})
 
+	// layers held back by the bandwidth available to the subscriber, reported as paused until they are forwarded again
+	var pausedLayersMu sync.Mutex
+	var pausedLayers map[livekit.VideoQuality]struct{}
+	downTrack.OnTargetLayerChanged(func(_ *sfu.DownTrack, allocation sfu.VideoAllocation) {
+		// a deficient allocation is held below the desired layer by the bandwidth available to the subscriber,
+		// otherwise it follows the layers published and requested
+		reason := telemetry.SimulcastLayerChangeReasonAvailability
+		if allocation.IsDeficient {
+			reason = telemetry.SimulcastLayerChangeReasonBandwidth
+		}
+		// the receiver's track info is shared rather than cloned, only its layers are read
+		trackInfo := wr.TrackInfo()
+		quality := buffer.SpatialLayerToVideoQuality(allocation.TargetLayer.Spatial, trackInfo)
+		t.params.Telemetry.SimulcastLayerChanged(context.Background(), subscriberID, trackID, quality, reason)
+
+		pausedLayersMu.Lock()
+		defer pausedLayersMu.Unlock()
+		paused := bandwidthPausedLayers(allocation, trackInfo)
+		for layer := range paused {
+			if _, ok := pausedLayers[layer]; !ok {
+				t.params.Telemetry.TrackLayerPaused(context.Background(), subscriberID, trackID, layer)
+			}
+		}
+		for layer := range pausedLayers {
+			if _, ok := paused[layer]; !ok {
+				t.params.Telemetry.TrackLayerResumed(context.Background(), subscriberID, trackID, layer)
+			}
+		}
+		pausedLayers = paused
+	})
+
 	downTrack.OnRttUpdate(func(_ *sfu.DownTrack, rtt uint32) {
 		go sub.UpdateMediaRTT(rtt)
 	})
@@ -428,3 +461,22 @@ func (t *MediaTrackSubscriptions) downTrackClosed(
 		subTrack.Close(willBeResumed)
 	}
 }
+
+// bandwidthPausedLayers returns the layers between the target and the maximum of a deficient allocation, those the
+// subscriber would be forwarded were it not for the bandwidth available to it
+func bandwidthPausedLayers(allocation sfu.VideoAllocation, trackInfo *livekit.TrackInfo) map[livekit.VideoQuality]struct{} {
+	if !allocation.IsDeficient {
+		return nil
+	}
+
+	paused := make(map[livekit.VideoQuality]struct{})
+	for spatial := allocation.TargetLayer.Spatial + 1; spatial <= allocation.MaxLayer.Spatial; spatial++ {
+		if spatial < 0 {
+			continue
+		}
+		if quality := buffer.SpatialLayerToVideoQuality(spatial, trackInfo); quality != livekit.VideoQuality_OFF {
+			paused[quality] = struct{}{}
+		}
+	}
+	return paused
+}
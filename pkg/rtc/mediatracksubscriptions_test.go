@@ -0,0 +1,60 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+func TestBandwidthPausedLayers(t *testing.T) {
+	trackInfo := &livekit.TrackInfo{Type: livekit.TrackType_VIDEO}
+	high := buffer.VideoLayer{Spatial: 2, Temporal: 3}
+
+	t.Run("not deficient", func(t *testing.T) {
+		require.Empty(t, bandwidthPausedLayers(sfu.VideoAllocation{
+			TargetLayer: buffer.VideoLayer{Spatial: 0},
+			MaxLayer:    high,
+		}, trackInfo))
+	})
+
+	t.Run("held below the maximum", func(t *testing.T) {
+		require.Equal(t, map[livekit.VideoQuality]struct{}{
+			livekit.VideoQuality_MEDIUM: {},
+			livekit.VideoQuality_HIGH:   {},
+		}, bandwidthPausedLayers(sfu.VideoAllocation{
+			IsDeficient: true,
+			TargetLayer: buffer.VideoLayer{Spatial: 0},
+			MaxLayer:    high,
+		}, trackInfo))
+	})
+
+	t.Run("fully paused", func(t *testing.T) {
+		require.Equal(t, map[livekit.VideoQuality]struct{}{
+			livekit.VideoQuality_LOW:    {},
+			livekit.VideoQuality_MEDIUM: {},
+		}, bandwidthPausedLayers(sfu.VideoAllocation{
+			IsDeficient: true,
+			TargetLayer: buffer.InvalidLayer,
+			MaxLayer:    buffer.VideoLayer{Spatial: 1, Temporal: 3},
+		}, trackInfo))
+	})
+}
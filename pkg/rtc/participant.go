@@ -206,8 +206,11 @@ type ParticipantImpl struct {
 
 	migrateState atomic.Value // types.MigrateState
 
+	disconnectReason atomic.Value // livekit.DisconnectReason, set on Close
+
 	onClose            func(types.LocalParticipant)
 	onClaimsChanged    func(participant types.LocalParticipant)
+	onICEConnected     func(participant types.LocalParticipant)
 	onICEConfigChanged func(participant types.LocalParticipant, iceConfig *livekit.ICEConfig)
 
 	cachedDownTracks map[livekit.TrackID]*downTrackState
@@ -576,6 +579,12 @@ func (p *ParticipantImpl) OnClaimsChanged(callback func(types.LocalParticipant))
 	p.lock.Unlock()
 }
 
+func (p *ParticipantImpl) OnICEConnected(callback func(types.LocalParticipant)) {
+	p.lock.Lock()
+	p.onICEConnected = callback
+	p.lock.Unlock()
+}
+
 func (p *ParticipantImpl) HandleSignalSourceClose() {
 	p.TransportManager.SetSignalSourceValid(false)
 
@@ -691,6 +700,7 @@ func (p *ParticipantImpl) removeMutedTrackNotFired(mt *MediaTrack) {
 func (p *ParticipantImpl) AddTrack(req *livekit.AddTrackRequest) {
 	if !p.CanPublishSource(req.Source) {
 		p.pubLogger.Warnw("no permission to publish track", nil)
+		p.params.Telemetry.TrackPublishFailed(context.Background(), p.ID(), req, telemetry.TrackPublishFailReasonPermissionDenied)
 		return
 	}
 
@@ -737,6 +747,7 @@ func (p *ParticipantImpl) Close(sendLeave bool, reason types.ParticipantCloseRea
 		// already closed
 		return nil
 	}
+	p.disconnectReason.Store(reason.ToDisconnectReason())
 
 	p.params.Logger.Infow(
 		"participant closing",
@@ -801,6 +812,11 @@ func (p *ParticipantImpl) IsClosed() bool {
 	return p.isClosed.Load()
 }
 
+func (p *ParticipantImpl) DisconnectReason() livekit.DisconnectReason {
+	reason, _ := p.disconnectReason.Load().(livekit.DisconnectReason)
+	return reason
+}
+
 // Negotiate subscriber SDP with client, if force is true, will cancel pending
 // negotiate task and negotiate immediately
 func (p *ParticipantImpl) Negotiate(force bool) {
@@ -1182,6 +1198,9 @@ func (p *ParticipantImpl) setupTransportManager() error {
 	})
 	tm.OnSubscriberInitialConnected(p.onSubscriberInitialConnected)
 	tm.OnSubscriberStreamStateChange(p.onStreamStateChange)
+	tm.OnSubscriberChannelCapacityChange(func(channelCapacity int64) {
+		p.params.Telemetry.BandwidthEstimate(context.Background(), p.ID(), channelCapacity)
+	})
 
 	tm.OnPrimaryTransportInitialConnected(p.onPrimaryTransportInitialConnected)
 	tm.OnPrimaryTransportFullyEstablished(p.onPrimaryTransportFullyEstablished)
@@ -1418,6 +1437,13 @@ func (p *ParticipantImpl) onPrimaryTransportInitialConnected() {
 	if !p.hasPendingMigratedTrack() && p.MigrateState() == types.MigrateStateSync {
 		p.SetMigrateState(types.MigrateStateComplete)
 	}
+
+	p.lock.RLock()
+	onICEConnected := p.onICEConnected
+	p.lock.RUnlock()
+	if onICEConnected != nil {
+		onICEConnected(p)
+	}
 }
 
 func (p *ParticipantImpl) onPrimaryTransportFullyEstablished() {
@@ -1619,6 +1645,7 @@ func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *l
 		track := p.GetPublishedTrack(livekit.TrackID(req.Sid))
 		if track == nil {
 			p.pubLogger.Infow("could not find existing track for multi-codec simulcast", "trackID", req.Sid)
+			p.params.Telemetry.TrackPublishFailed(context.Background(), p.ID(), req, telemetry.TrackPublishFailReasonTrackNotFound)
 			return nil
 		}
 
@@ -1748,10 +1775,10 @@ func (p *ParticipantImpl) SetTrackMuted(trackID livekit.TrackID, muted bool, fro
 		p.sendTrackMuted(trackID, muted)
 	}
 
-	return p.setTrackMuted(trackID, muted)
+	return p.setTrackMuted(trackID, muted, fromAdmin)
 }
 
-func (p *ParticipantImpl) setTrackMuted(trackID livekit.TrackID, muted bool) *livekit.TrackInfo {
+func (p *ParticipantImpl) setTrackMuted(trackID livekit.TrackID, muted bool, fromAdmin bool) *livekit.TrackInfo {
 	p.dirty.Store(true)
 	if p.supervisor != nil {
 		p.supervisor.SetPublicationMute(trackID, muted)
@@ -1777,10 +1804,14 @@ func (p *ParticipantImpl) setTrackMuted(trackID livekit.TrackID, muted bool) *li
 	p.pendingTracksLock.RUnlock()
 
 	if trackInfo != nil {
+		source := telemetry.TrackMuteSourcePublisher
+		if fromAdmin {
+			source = telemetry.TrackMuteSourceAdmin
+		}
 		if muted {
-			p.params.Telemetry.TrackMuted(context.Background(), p.ID(), trackInfo)
+			p.params.Telemetry.TrackMuted(context.Background(), p.ID(), trackInfo, source)
 		} else {
-			p.params.Telemetry.TrackUnmuted(context.Background(), p.ID(), trackInfo)
+			p.params.Telemetry.TrackUnmuted(context.Background(), p.ID(), trackInfo, source)
 		}
 	}
 
@@ -2323,6 +2354,7 @@ func (p *ParticipantImpl) onSubscriptionError(trackID livekit.TrackID, fatal boo
 	case errors.Is(err, ErrTrackNotFound):
 		signalErr = livekit.SubscriptionError_SE_TRACK_NOTFOUND
 	}
+	p.params.Telemetry.TrackSubscriptionFailed(context.Background(), p.ID(), trackID, signalErr)
 
 	_ = p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_SubscriptionResponse{
@@ -2418,6 +2450,7 @@ func (p *ParticipantImpl) SendDataPacket(dp *livekit.DataPacket, data []byte) er
 		}
 	} else {
 		p.dataChannelStats.AddBytes(uint64(len(data)), true)
+		p.params.Telemetry.DataPacketForwarded(context.Background(), p.ID(), dp.Kind, len(data))
 	}
 	return err
 }
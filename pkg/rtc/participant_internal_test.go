@@ -25,6 +25,7 @@ import (
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
@@ -276,6 +277,22 @@ func TestMuteSetting(t *testing.T) {
 		require.True(t, ti.Muted)
 	})
 
+	t.Run("mute source is reported", func(t *testing.T) {
+		p := newParticipantForTest("test")
+		ti := &livekit.TrackInfo{Sid: "testTrack"}
+		p.pendingTracks["cid"] = &pendingTrackInfo{trackInfos: []*livekit.TrackInfo{ti}}
+		telemetryService := p.params.Telemetry.(*telemetryfakes.FakeTelemetryService)
+
+		p.SetTrackMuted(livekit.TrackID(ti.Sid), true, true)
+		p.SetTrackMuted(livekit.TrackID(ti.Sid), false, false)
+		require.Equal(t, 1, telemetryService.TrackMutedCallCount())
+		_, _, _, source := telemetryService.TrackMutedArgsForCall(0)
+		require.Equal(t, telemetry.TrackMuteSourceAdmin, source)
+		require.Equal(t, 1, telemetryService.TrackUnmutedCallCount())
+		_, _, _, source = telemetryService.TrackUnmutedArgsForCall(0)
+		require.Equal(t, telemetry.TrackMuteSourcePublisher, source)
+	})
+
 	t.Run("can publish a muted track", func(t *testing.T) {
 		p := newParticipantForTest("test")
 		p.AddTrack(&livekit.AddTrackRequest{
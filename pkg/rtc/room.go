@@ -74,12 +74,13 @@ type Room struct {
 	protoProxy *utils.ProtoProxy[*livekit.Room]
 	Logger     logger.Logger
 
-	config         WebRTCConfig
-	audioConfig    *config.AudioConfig
-	serverInfo     *livekit.ServerInfo
-	telemetry      telemetry.TelemetryService
-	egressLauncher EgressLauncher
-	trackManager   *RoomTrackManager
+	config          WebRTCConfig
+	audioConfig     *config.AudioConfig
+	analyticsConfig *config.AnalyticsConfig
+	serverInfo      *livekit.ServerInfo
+	telemetry       telemetry.TelemetryService
+	egressLauncher  EgressLauncher
+	trackManager    *RoomTrackManager
 
 	// agents
 	agentClient            AgentClient
@@ -102,6 +103,8 @@ type Room struct {
 	// time that the last participant left the room
 	leftAt atomic.Int64
 	closed chan struct{}
+	// set once the room has been reported as started, see MarkStarted
+	started atomic.Bool
 
 	trailer []byte
 
@@ -119,6 +122,7 @@ func NewRoom(
 	internal *livekit.RoomInternal,
 	config WebRTCConfig,
 	audioConfig *config.AudioConfig,
+	analyticsConfig *config.AnalyticsConfig,
 	serverInfo *livekit.ServerInfo,
 	telemetry telemetry.TelemetryService,
 	agentClient AgentClient,
@@ -134,6 +138,7 @@ func NewRoom(
 		),
 		config:                    config,
 		audioConfig:               audioConfig,
+		analyticsConfig:           analyticsConfig,
 		telemetry:                 telemetry,
 		egressLauncher:            egressLauncher,
 		agentClient:               agentClient,
@@ -272,6 +277,17 @@ func (r *Room) FirstJoinedAt() int64 {
 	return r.joinedAt.Load()
 }
 
+// MarkStarted returns true the first time it is called once a participant has joined, when the room
+// should be reported as started
+func (r *Room) MarkStarted() bool {
+	return r.FirstJoinedAt() != 0 && r.started.CompareAndSwap(false, true)
+}
+
+// Started returns true once the room has been reported as started, see MarkStarted
+func (r *Room) Started() bool {
+	return r.started.Load()
+}
+
 func (r *Room) LastLeftAt() int64 {
 	return r.leftAt.Load()
 }
@@ -363,6 +379,9 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 			go r.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonStateDisconnected)
 		}
 	})
+	participant.OnICEConnected(func(p types.LocalParticipant) {
+		r.telemetry.ParticipantConnected(context.Background(), r.ToProto(), p.ToProto())
+	})
 	participant.OnTrackUpdated(r.onTrackUpdated)
 	participant.OnTrackUnpublished(r.onTrackUnpublished)
 	participant.OnParticipantUpdate(r.onParticipantUpdate)
@@ -652,6 +671,7 @@ func (r *Room) UpdateSubscriptionPermission(participant types.LocalParticipant,
 	if err := participant.UpdateSubscriptionPermission(subscriptionPermission, utils.TimedVersion{}, r.GetParticipant, r.GetParticipantByID); err != nil {
 		return err
 	}
+	r.telemetry.SubscriptionPermissionChanged(context.Background(), participant.ID(), subscriptionPermission)
 	for _, track := range participant.GetPublishedTracks() {
 		r.trackManager.NotifyTrackChanged(track.ID())
 	}
@@ -775,12 +795,31 @@ func (r *Room) SetMetadata(metadata string) <-chan struct{} {
 }
 
 func (r *Room) UpdateParticipantMetadata(participant types.LocalParticipant, name string, metadata string) {
+	prev := participant.ToProto()
 	if metadata != "" {
 		participant.SetMetadata(metadata)
 	}
 	if name != "" {
 		participant.SetName(name)
 	}
+	r.telemetry.ParticipantAttributesChanged(context.Background(), r.ToProto(), participant.ToProto(), prev)
+}
+
+func (r *Room) UpdateParticipantPermission(participant types.LocalParticipant, permission *livekit.ParticipantPermission) {
+	prev := participant.ToProto().Permission
+	if participant.SetPermission(permission) {
+		room, info := r.ToProto(), participant.ToProto()
+		r.telemetry.ParticipantPermissionsChanged(context.Background(), room, info, prev)
+		r.telemetry.ParticipantRoleChanged(context.Background(), room, info, permissionKind(prev), permissionKind(info.Permission))
+	}
+}
+
+// permissionKind returns the kind of a participant with permission, agents are told apart by their permission
+func permissionKind(permission *livekit.ParticipantPermission) livekit.ParticipantInfo_Kind {
+	if permission.GetAgent() {
+		return livekit.ParticipantInfo_AGENT
+	}
+	return livekit.ParticipantInfo_STANDARD
 }
 
 func (r *Room) sendRoomUpdate() {
@@ -1250,14 +1289,33 @@ func (r *Room) audioUpdateWorker() {
 		if len(changedSpeakers) > 0 {
 			r.sendActiveSpeakers(activeSpeakers)
 			r.sendSpeakerChanges(changedSpeakers)
+			r.telemetry.ActiveSpeakerChanged(context.Background(), r.ToProto(), activeSpeakers)
 		}
 
 		lastActiveMap = nextActiveMap
+		r.reportAudioLevels()
 
 		time.Sleep(time.Duration(r.audioConfig.UpdateInterval) * time.Millisecond)
 	}
 }
 
+// reportAudioLevels hands the audio level of every participant publishing a microphone to telemetry, when it
+// summarizes them
+func (r *Room) reportAudioLevels() {
+	if r.analyticsConfig.AudioLevelInterval <= 0 {
+		return
+	}
+	for _, p := range r.GetParticipants() {
+		for _, track := range p.GetPublishedTracks() {
+			if track.Source() == livekit.TrackSource_MICROPHONE {
+				level, active := p.GetAudioLevel()
+				r.telemetry.ParticipantAudioLevel(p.ID(), level, active)
+				break
+			}
+		}
+	}
+}
+
 func (r *Room) connectionQualityWorker() {
 	ticker := time.NewTicker(connectionquality.UpdateInterval)
 	defer ticker.Stop()
@@ -24,7 +24,6 @@ import (
 
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/livekit"
-	"github.com/livekit/protocol/webhook"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
@@ -83,6 +82,18 @@ func TestJoinedState(t *testing.T) {
 		rm.RemoveParticipant(p0.Identity(), p0.ID(), types.ParticipantCloseReasonClientRequestLeave)
 		require.Greater(t, rm.LastLeftAt(), int64(0))
 	})
+
+	t.Run("should be marked started once, after a participant joins", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 0})
+		require.False(t, rm.MarkStarted())
+
+		require.False(t, rm.Started())
+
+		rm = newRoomWithParticipants(t, testRoomOpts{num: 1})
+		require.True(t, rm.MarkStarted())
+		require.False(t, rm.MarkStarted())
+		require.True(t, rm.Started())
+	})
 }
 
 func TestRoomJoin(t *testing.T) {
@@ -720,6 +731,41 @@ func TestRoomUpdate(t *testing.T) {
 	})
 }
 
+func TestUpdateParticipantPermission(t *testing.T) {
+	t.Run("becoming an agent changes the participant's kind", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		defer rm.Close()
+		telemetryService := &telemetryfakes.FakeTelemetryService{}
+		rm.telemetry = telemetryService
+
+		p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+		p.ToProtoReturnsOnCall(p.ToProtoCallCount(), &livekit.ParticipantInfo{Permission: &livekit.ParticipantPermission{CanSubscribe: true}})
+		p.ToProtoReturns(&livekit.ParticipantInfo{Permission: &livekit.ParticipantPermission{CanSubscribe: true, Agent: true}})
+		p.SetPermissionReturns(true)
+		rm.UpdateParticipantPermission(p, &livekit.ParticipantPermission{CanSubscribe: true, Agent: true})
+
+		require.Equal(t, 1, telemetryService.ParticipantPermissionsChangedCallCount())
+		require.Equal(t, 1, telemetryService.ParticipantRoleChangedCallCount())
+		_, _, _, from, to := telemetryService.ParticipantRoleChangedArgsForCall(0)
+		require.Equal(t, livekit.ParticipantInfo_STANDARD, from)
+		require.Equal(t, livekit.ParticipantInfo_AGENT, to)
+	})
+
+	t.Run("nothing is reported when the permission is unchanged", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		defer rm.Close()
+		telemetryService := &telemetryfakes.FakeTelemetryService{}
+		rm.telemetry = telemetryService
+
+		p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+		p.SetPermissionReturns(false)
+		rm.UpdateParticipantPermission(p, &livekit.ParticipantPermission{Agent: true})
+
+		require.Zero(t, telemetryService.ParticipantPermissionsChangedCallCount())
+		require.Zero(t, telemetryService.ParticipantRoleChangedCallCount())
+	})
+}
+
 type testRoomOpts struct {
 	num                  int
 	numHidden            int
@@ -736,6 +782,7 @@ func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
 			UpdateInterval:  audioUpdateInterval,
 			SmoothIntervals: opts.audioSmoothIntervals,
 		},
+		&config.DefaultConfig.Analytics,
 		&livekit.ServerInfo{
 			Edition:  livekit.ServerInfo_Standard,
 			Version:  version.Version,
@@ -743,7 +790,7 @@ func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
 			NodeId:   "testnode",
 			Region:   "testregion",
 		},
-		telemetry.NewTelemetryService(webhook.NewDefaultNotifier("", "", nil), &telemetryfakes.FakeAnalyticsService{}),
+		telemetry.NewTelemetryService(&config.DefaultConfig, nil, &telemetryfakes.FakeAnalyticsService{}),
 		nil, nil,
 	)
 	for i := 0; i < opts.num+opts.numHidden; i++ {
@@ -1165,6 +1165,14 @@ func (t *PCTransport) OnStreamStateChange(f func(update *streamallocator.StreamS
 	t.streamAllocator.OnStreamStateChange(f)
 }
 
+func (t *PCTransport) OnChannelCapacityChange(f func(channelCapacity int64)) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.OnChannelCapacityChange(f)
+}
+
 func (t *PCTransport) AddTrackToStreamAllocator(subTrack types.SubscribedTrack) {
 	if t.streamAllocator == nil {
 		return
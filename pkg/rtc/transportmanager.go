@@ -255,6 +255,10 @@ func (t *TransportManager) OnSubscriberStreamStateChange(f func(update *streamal
 	t.subscriber.OnStreamStateChange(f)
 }
 
+func (t *TransportManager) OnSubscriberChannelCapacityChange(f func(channelCapacity int64)) {
+	t.subscriber.OnChannelCapacityChange(f)
+}
+
 func (t *TransportManager) HasSubscriberEverConnected() bool {
 	return t.subscriber.HasEverConnected()
 }
@@ -305,6 +305,8 @@ type LocalParticipant interface {
 	SupportsTransceiverReuse() bool
 	ConnectedAt() time.Time
 	IsClosed() bool
+	// DisconnectReason - the reason the participant was closed for, UNKNOWN_REASON until it is closed
+	DisconnectReason() livekit.DisconnectReason
 	IsReady() bool
 	IsDisconnected() bool
 	IsIdle() bool
@@ -386,6 +388,8 @@ type LocalParticipant interface {
 	OnSubscribeStatusChanged(fn func(publisherID livekit.ParticipantID, subscribed bool))
 	OnClose(callback func(LocalParticipant))
 	OnClaimsChanged(callback func(LocalParticipant))
+	// OnICEConnected - the primary transport connected for the first time
+	OnICEConnected(callback func(LocalParticipant))
 	OnReceiverReport(dt *sfu.DownTrack, report *rtcp.ReceiverReport)
 	OnTrafficLoad(callback func(trafficLoad *TrafficLoad))
 
@@ -158,6 +158,16 @@ type FakeLocalParticipant struct {
 	debugInfoReturnsOnCall map[int]struct {
 		result1 map[string]interface{}
 	}
+	DisconnectReasonStub        func() livekit.DisconnectReason
+	disconnectReasonMutex       sync.RWMutex
+	disconnectReasonArgsForCall []struct {
+	}
+	disconnectReasonReturns struct {
+		result1 livekit.DisconnectReason
+	}
+	disconnectReasonReturnsOnCall map[int]struct {
+		result1 livekit.DisconnectReason
+	}
 	GetAdaptiveStreamStub        func() bool
 	getAdaptiveStreamMutex       sync.RWMutex
 	getAdaptiveStreamArgsForCall []struct {
@@ -561,6 +571,11 @@ type FakeLocalParticipant struct {
 	onICEConfigChangedArgsForCall []struct {
 		arg1 func(participant types.LocalParticipant, iceConfig *livekit.ICEConfig)
 	}
+	OnICEConnectedStub        func(func(types.LocalParticipant))
+	onICEConnectedMutex       sync.RWMutex
+	onICEConnectedArgsForCall []struct {
+		arg1 func(types.LocalParticipant)
+	}
 	OnMigrateStateChangeStub        func(func(p types.LocalParticipant, migrateState types.MigrateState))
 	onMigrateStateChangeMutex       sync.RWMutex
 	onMigrateStateChangeArgsForCall []struct {
@@ -1687,6 +1702,59 @@ func (fake *FakeLocalParticipant) DebugInfoReturnsOnCall(i int, result1 map[stri
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) DisconnectReason() livekit.DisconnectReason {
+	fake.disconnectReasonMutex.Lock()
+	ret, specificReturn := fake.disconnectReasonReturnsOnCall[len(fake.disconnectReasonArgsForCall)]
+	fake.disconnectReasonArgsForCall = append(fake.disconnectReasonArgsForCall, struct {
+	}{})
+	stub := fake.DisconnectReasonStub
+	fakeReturns := fake.disconnectReasonReturns
+	fake.recordInvocation("DisconnectReason", []interface{}{})
+	fake.disconnectReasonMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) DisconnectReasonCallCount() int {
+	fake.disconnectReasonMutex.RLock()
+	defer fake.disconnectReasonMutex.RUnlock()
+	return len(fake.disconnectReasonArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) DisconnectReasonCalls(stub func() livekit.DisconnectReason) {
+	fake.disconnectReasonMutex.Lock()
+	defer fake.disconnectReasonMutex.Unlock()
+	fake.DisconnectReasonStub = stub
+}
+
+func (fake *FakeLocalParticipant) DisconnectReasonReturns(result1 livekit.DisconnectReason) {
+	fake.disconnectReasonMutex.Lock()
+	defer fake.disconnectReasonMutex.Unlock()
+	fake.DisconnectReasonStub = nil
+	fake.disconnectReasonReturns = struct {
+		result1 livekit.DisconnectReason
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) DisconnectReasonReturnsOnCall(i int, result1 livekit.DisconnectReason) {
+	fake.disconnectReasonMutex.Lock()
+	defer fake.disconnectReasonMutex.Unlock()
+	fake.DisconnectReasonStub = nil
+	if fake.disconnectReasonReturnsOnCall == nil {
+		fake.disconnectReasonReturnsOnCall = make(map[int]struct {
+			result1 livekit.DisconnectReason
+		})
+	}
+	fake.disconnectReasonReturnsOnCall[i] = struct {
+		result1 livekit.DisconnectReason
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetAdaptiveStream() bool {
 	fake.getAdaptiveStreamMutex.Lock()
 	ret, specificReturn := fake.getAdaptiveStreamReturnsOnCall[len(fake.getAdaptiveStreamArgsForCall)]
@@ -3866,6 +3934,38 @@ func (fake *FakeLocalParticipant) OnICEConfigChangedArgsForCall(i int) func(part
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) OnICEConnected(arg1 func(types.LocalParticipant)) {
+	fake.onICEConnectedMutex.Lock()
+	fake.onICEConnectedArgsForCall = append(fake.onICEConnectedArgsForCall, struct {
+		arg1 func(types.LocalParticipant)
+	}{arg1})
+	stub := fake.OnICEConnectedStub
+	fake.recordInvocation("OnICEConnected", []interface{}{arg1})
+	fake.onICEConnectedMutex.Unlock()
+	if stub != nil {
+		fake.OnICEConnectedStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) OnICEConnectedCallCount() int {
+	fake.onICEConnectedMutex.RLock()
+	defer fake.onICEConnectedMutex.RUnlock()
+	return len(fake.onICEConnectedArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) OnICEConnectedCalls(stub func(func(types.LocalParticipant))) {
+	fake.onICEConnectedMutex.Lock()
+	defer fake.onICEConnectedMutex.Unlock()
+	fake.OnICEConnectedStub = stub
+}
+
+func (fake *FakeLocalParticipant) OnICEConnectedArgsForCall(i int) func(types.LocalParticipant) {
+	fake.onICEConnectedMutex.RLock()
+	defer fake.onICEConnectedMutex.RUnlock()
+	argsForCall := fake.onICEConnectedArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) OnMigrateStateChange(arg1 func(p types.LocalParticipant, migrateState types.MigrateState)) {
 	fake.onMigrateStateChangeMutex.Lock()
 	fake.onMigrateStateChangeArgsForCall = append(fake.onMigrateStateChangeArgsForCall, struct {
@@ -6205,6 +6305,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.connectedAtMutex.RUnlock()
 	fake.debugInfoMutex.RLock()
 	defer fake.debugInfoMutex.RUnlock()
+	fake.disconnectReasonMutex.RLock()
+	defer fake.disconnectReasonMutex.RUnlock()
 	fake.getAdaptiveStreamMutex.RLock()
 	defer fake.getAdaptiveStreamMutex.RUnlock()
 	fake.getAudioLevelMutex.RLock()
@@ -6293,6 +6395,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.onDataPacketMutex.RUnlock()
 	fake.onICEConfigChangedMutex.RLock()
 	defer fake.onICEConfigChangedMutex.RUnlock()
+	fake.onICEConnectedMutex.RLock()
+	defer fake.onICEConnectedMutex.RUnlock()
 	fake.onMigrateStateChangeMutex.RLock()
 	defer fake.onMigrateStateChangeMutex.RUnlock()
 	fake.onParticipantUpdateMutex.RLock()
@@ -0,0 +1,60 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"net/http"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// EventStreamService streams webhook events to clients allowed to list rooms, see config.EventStreamConfig
+type EventStreamService struct {
+	// nil when the event stream is disabled
+	sink *telemetry.EventStreamSink
+}
+
+func NewEventStreamService(conf *config.Config) *EventStreamService {
+	s := &EventStreamService{}
+	if conf.EventStream.Enabled {
+		s.sink = telemetry.NewEventStreamSink(telemetry.EventStreamSinkParams{
+			BufferSize:   conf.EventStream.BufferSize,
+			WriteTimeout: conf.EventStream.WriteTimeout,
+			Envelope:     conf.WebHook.ParticipantClientInfo || conf.WebHook.EventDetails,
+		})
+	}
+	return s
+}
+
+// Sink returns the sink events are published to, nil when the event stream is disabled
+func (s *EventStreamService) Sink() *telemetry.EventStreamSink {
+	return s.sink
+}
+
+func (s *EventStreamService) Enabled() bool {
+	return s.sink != nil
+}
+
+// ServeHTTP streams events to the request, only served when the event stream is enabled
+func (s *EventStreamService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// events of every room are streamed
+	if err := EnsureListPermission(r.Context()); err != nil {
+		handleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	s.sink.ServeHTTP(w, r)
+}
@@ -104,10 +104,12 @@ func (s *IOInfoService) UpdateEgress(ctx context.Context, info *livekit.EgressIn
 		s.telemetry.EgressUpdated(ctx, info)
 
 	case livekit.EgressStatus_EGRESS_COMPLETE,
-		livekit.EgressStatus_EGRESS_FAILED,
 		livekit.EgressStatus_EGRESS_ABORTED,
 		livekit.EgressStatus_EGRESS_LIMIT_REACHED:
 		s.telemetry.EgressEnded(ctx, info)
+
+	case livekit.EgressStatus_EGRESS_FAILED:
+		s.telemetry.EgressFailed(ctx, info)
 	}
 
 	if err != nil {
@@ -195,11 +197,11 @@ func (s *IOInfoService) UpdateIngressState(ctx context.Context, req *rpc.UpdateI
 		case livekit.IngressState_ENDPOINT_ERROR,
 			livekit.IngressState_ENDPOINT_INACTIVE,
 			livekit.IngressState_ENDPOINT_COMPLETE:
-			s.telemetry.IngressEnded(ctx, info)
-
 			if req.State.Error != "" {
+				s.telemetry.IngressEnded(ctx, info, errors.New(req.State.Error))
 				logger.Infow("ingress failed", "error", req.State.Error, "ingressID", req.IngressId)
 			} else {
+				s.telemetry.IngressEnded(ctx, info, nil)
 				logger.Infow("ingress ended", "ingressID", req.IngressId)
 			}
 
@@ -33,6 +33,7 @@ import (
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
 	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
 
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
@@ -245,7 +246,7 @@ func (r *RoomManager) StartSession(
 	requestSource routing.MessageSource,
 	responseSink routing.MessageSink,
 ) error {
-	room, err := r.getOrCreateRoom(ctx, roomName)
+	room, created, err := r.getOrCreateRoom(ctx, roomName)
 	if err != nil {
 		return err
 	}
@@ -255,6 +256,10 @@ func (r *RoomManager) StartSession(
 
 	// only create the room, but don't start a participant session
 	if pi.Identity == "" {
+		// reported as started once someone joins
+		if created {
+			r.telemetry.RoomReserved(ctx, protoRoom)
+		}
 		return nil
 	}
 
@@ -262,6 +267,9 @@ func (r *RoomManager) StartSession(
 	// since this is used for TURN server credentials, we don't want to fail the request even if there's no TURN for the session
 	apiKey, _, _ := r.getFirstKeyPair()
 
+	// generated ahead of joining, so that a participant evicted by this one can be reported with it
+	sid := livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix))
+
 	participant := room.GetParticipant(pi.Identity)
 	if participant != nil {
 		// When reconnecting, it means WS has interrupted but underlying peer connection is still ok in this state,
@@ -319,7 +327,11 @@ func (r *RoomManager) StartSession(
 		}
 
 		// we need to clean up the existing participant, so a new one can join
-		participant.GetLogger().Infow("removing duplicate participant")
+		participant.GetLogger().Infow("removing duplicate participant", "replacedBy", sid)
+		r.telemetry.ParticipantDuplicateIdentity(ctx, protoRoom, &livekit.ParticipantInfo{
+			Sid:      string(sid),
+			Identity: string(pi.Identity),
+		}, participant.ToProto())
 		room.RemoveParticipant(participant.Identity(), participant.ID(), types.ParticipantCloseReasonDuplicateIdentity)
 	} else if pi.Reconnect {
 		// send leave request if participant is trying to reconnect without keep subscribe state
@@ -351,7 +363,6 @@ func (r *RoomManager) StartSession(
 	pv := types.ProtocolVersion(pi.Client.Protocol)
 	rtcConf := *r.rtcConfig
 	rtcConf.SetBufferFactory(room.GetBufferFactory())
-	sid := livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix))
 	pLogger := rtc.LoggerWithParticipant(
 		rtc.LoggerWithRoom(logger.GetLogger(), room.Name(), room.ID()),
 		pi.Identity,
@@ -437,6 +448,10 @@ func (r *RoomManager) StartSession(
 		_ = participant.Close(true, types.ParticipantCloseReasonJoinFailed, false)
 		return err
 	}
+	if room.MarkStarted() {
+		r.telemetry.RoomStarted(ctx, protoRoom)
+		prometheus.RoomStarted()
+	}
 
 	participantTopic := rpc.FormatParticipantTopic(roomName, participant.Identity())
 	participantServer := utils.Must(rpc.NewTypedParticipantServer(r, r.bus))
@@ -476,7 +491,7 @@ func (r *RoomManager) StartSession(
 		// update room store with new numParticipants
 		proto := room.ToProto()
 		persistRoomForParticipantCount(proto)
-		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), true)
+		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), p.DisconnectReason(), true)
 	})
 	participant.OnClaimsChanged(func(participant types.LocalParticipant) {
 		pLogger.Debugw("refreshing client token after claims change")
@@ -497,20 +512,20 @@ func (r *RoomManager) StartSession(
 	return nil
 }
 
-// create the actual room object, to be used on RTC node
-func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.RoomName) (*rtc.Room, error) {
+// create the actual room object, to be used on RTC node. returns true when the room was created by this call
+func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.RoomName) (*rtc.Room, bool, error) {
 	r.lock.RLock()
 	lastSeenRoom := r.rooms[roomName]
 	r.lock.RUnlock()
 
 	if lastSeenRoom != nil && lastSeenRoom.Hold() {
-		return lastSeenRoom, nil
+		return lastSeenRoom, false, nil
 	}
 
 	// create new room, get details first
 	ri, internal, err := r.roomStore.LoadRoom(ctx, roomName, true)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	r.lock.Lock()
@@ -519,7 +534,7 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	for currentRoom != lastSeenRoom {
 		r.lock.Unlock()
 		if currentRoom != nil && currentRoom.Hold() {
-			return currentRoom, nil
+			return currentRoom, false, nil
 		}
 
 		lastSeenRoom = currentRoom
@@ -528,7 +543,7 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	}
 
 	// construct ice servers
-	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, &r.config.Audio, r.serverInfo, r.telemetry, r.agentClient, r.egressLauncher)
+	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, &r.config.Audio, &r.config.Analytics, r.serverInfo, r.telemetry, r.agentClient, r.egressLauncher)
 
 	roomTopic := rpc.FormatRoomTopic(roomName)
 	roomServer := utils.Must(rpc.NewTypedRoomServer(r, r.bus))
@@ -536,7 +551,7 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	if err := roomServer.RegisterAllRoomTopics(roomTopic); err != nil {
 		killRoomServer()
 		r.lock.Unlock()
-		return nil, err
+		return nil, false, err
 	}
 
 	newRoom.OnClose(func() {
@@ -544,7 +559,10 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 
 		roomInfo := newRoom.ToProto()
 		r.telemetry.RoomEnded(ctx, roomInfo)
-		prometheus.RoomEnded(time.Unix(roomInfo.CreationTime, 0))
+		// rooms nobody joined were never counted
+		if newRoom.Started() {
+			prometheus.RoomEnded(time.Unix(roomInfo.CreationTime, 0))
+		}
 		if err := r.deleteRoom(ctx, roomName); err != nil {
 			newRoom.Logger.Errorw("could not delete room", err)
 		}
@@ -572,10 +590,7 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 
 	newRoom.Hold()
 
-	r.telemetry.RoomStarted(ctx, newRoom.ToProto())
-	prometheus.RoomStarted()
-
-	return newRoom, nil
+	return newRoom, true, nil
 }
 
 // manages an RTC session for a participant, runs on the RTC node
@@ -711,26 +726,38 @@ func (r *RoomManager) UpdateParticipant(ctx context.Context, req *livekit.Update
 		"metadata", req.Metadata, "permission", req.Permission)
 	room.UpdateParticipantMetadata(participant, req.Name, req.Metadata)
 	if req.Permission != nil {
-		participant.SetPermission(req.Permission)
+		room.UpdateParticipantPermission(participant, req.Permission)
 	}
 	return participant.ToProto(), nil
 }
 
 func (r *RoomManager) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomRequest) (*livekit.DeleteRoomResponse, error) {
+	// only known when the request came through psrpc
+	var deletedBy string
+	if head := metadata.IncomingHeader(ctx); head != nil {
+		deletedBy = head.Metadata[deletedByMetadataKey]
+	}
+
 	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
 	if room == nil {
 		// special case of a non-RTC room e.g. room created but no participants joined
 		logger.Debugw("Deleting non-rtc room, loading from roomstore")
+		protoRoom, _, loadErr := r.roomStore.LoadRoom(ctx, livekit.RoomName(req.Room), false)
 		err := r.roomStore.DeleteRoom(ctx, livekit.RoomName(req.Room))
 		if err != nil {
 			logger.Debugw("Error deleting non-rtc room", "err", err)
 			return nil, err
 		}
+		if loadErr == nil {
+			r.telemetry.RoomDeleted(ctx, protoRoom, deletedBy)
+		}
 	} else {
-		room.Logger.Infow("deleting room")
+		room.Logger.Infow("deleting room", "deletedBy", deletedBy)
 		for _, p := range room.GetParticipants() {
 			_ = p.Close(true, types.ParticipantCloseReasonServiceRequestDeleteRoom, false)
 		}
+		// after the participants have left, so that only the ones that didn't are cleaned up
+		r.telemetry.RoomDeleted(ctx, room.ToProto(), deletedBy)
 		room.Close()
 	}
 	return &livekit.DeleteRoomResponse{}, nil
@@ -776,10 +803,13 @@ func (r *RoomManager) UpdateRoomMetadata(ctx context.Context, req *livekit.Updat
 	}
 
 	room.Logger.Debugw("updating room")
+	prevMetadata := room.ToProto().Metadata
 	done := room.SetMetadata(req.Metadata)
 	// wait till the update is applied
 	<-done
-	return room.ToProto(), nil
+	protoRoom := room.ToProto()
+	r.telemetry.RoomMetadataChanged(ctx, protoRoom, prevMetadata)
+	return protoRoom, nil
 }
 
 func (r *RoomManager) iceServersForParticipant(apiKey string, participant types.LocalParticipant, tlsOnly bool) []*livekit.ICEServer {
@@ -32,8 +32,12 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
+	"github.com/livekit/psrpc/pkg/metadata"
 )
 
+// psrpc metadata carrying the identity of who deleted a room to the node hosting it, see RoomManager.DeleteRoom
+const deletedByMetadataKey = "deleted_by"
+
 // A rooms service that supports a single node
 type RoomService struct {
 	roomConf          config.RoomConfig
@@ -171,6 +175,9 @@ func (s *RoomService) DeleteRoom(ctx context.Context, req *livekit.DeleteRoomReq
 	}
 
 	if s.psrpcConf.Enabled {
+		if claims := GetGrants(ctx); claims != nil && claims.Identity != "" {
+			ctx = metadata.AppendMetadataToOutgoingContext(ctx, deletedByMetadataKey, claims.Identity)
+		}
 		return s.roomClient.DeleteRoom(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
 	}
 
@@ -37,12 +37,16 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 )
 
+// time allowed for telemetry to deliver pending webhooks and analytics when the server stops
+const telemetryShutdownTimeout = 10 * time.Second
+
 type LivekitServer struct {
 	config       *config.Config
 	ioService    *IOInfoService
@@ -52,6 +56,7 @@ type LivekitServer struct {
 	promServer   *http.Server
 	router       routing.Router
 	roomManager  *RoomManager
+	telemetry    telemetry.TelemetryService
 	signalServer *SignalServer
 	turnServer   *turn.Server
 	currentNode  routing.LocalNode
@@ -68,9 +73,11 @@ func NewLivekitServer(conf *config.Config,
 	ioService *IOInfoService,
 	rtcService *RTCService,
 	agentService *AgentService,
+	eventStreamService *EventStreamService,
 	keyProvider auth.KeyProvider,
 	router routing.Router,
 	roomManager *RoomManager,
+	telemetryService telemetry.TelemetryService,
 	signalServer *SignalServer,
 	turnServer *turn.Server,
 	currentNode routing.LocalNode,
@@ -82,6 +89,7 @@ func NewLivekitServer(conf *config.Config,
 		agentService: agentService,
 		router:       router,
 		roomManager:  roomManager,
+		telemetry:    telemetryService,
 		signalServer: signalServer,
 		// turn server starts automatically
 		turnServer:  turnServer,
@@ -131,6 +139,9 @@ func NewLivekitServer(conf *config.Config,
 	mux.Handle(sipServer.PathPrefix(), sipServer)
 	mux.Handle("/rtc", rtcService)
 	mux.Handle("/agent", agentService)
+	if eventStreamService.Enabled() {
+		mux.Handle("/events", eventStreamService)
+	}
 	mux.HandleFunc("/rtc/validate", rtcService.Validate)
 	mux.HandleFunc("/", s.defaultHandler)
 
@@ -288,6 +299,11 @@ func (s *LivekitServer) Start() error {
 	s.signalServer.Stop()
 	s.ioService.Stop()
 
+	// stopped last, to deliver the events of the rooms and participants closed above
+	telemetryCtx, telemetryCancel := context.WithTimeout(context.Background(), telemetryShutdownTimeout)
+	defer telemetryCancel()
+	_ = s.telemetry.Shutdown(telemetryCtx)
+
 	close(s.closedChan)
 	return nil
 }
@@ -19,6 +19,7 @@ package service
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/google/wire"
@@ -39,7 +40,6 @@ import (
 	redisLiveKit "github.com/livekit/protocol/redis"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
-	"github.com/livekit/protocol/webhook"
 	"github.com/livekit/psrpc"
 )
 
@@ -50,7 +50,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		createStore,
 		wire.Bind(new(ServiceStore), new(ObjectStore)),
 		createKeyProvider,
-		createWebhookNotifier,
+		createWebhookNotifiers,
 		createClientConfiguration,
 		routing.CreateRouter,
 		getRoomConf,
@@ -58,6 +58,8 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		wire.Bind(new(routing.MessageRouter), new(routing.Router)),
 		wire.Bind(new(livekit.RoomService), new(*RoomService)),
 		telemetry.NewAnalyticsService,
+		NewEventStreamService,
+		getTelemetryServiceOpts,
 		telemetry.NewTelemetryService,
 		getMessageBus,
 		NewIOInfoService,
@@ -143,17 +145,133 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
+func createWebhookNotifiers(conf *config.Config, provider auth.KeyProvider) ([]telemetry.WebhookNotifier, error) {
 	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
+	if len(wc.URLs) == 0 && len(wc.Endpoints) == 0 {
+		if wc.Required {
+			return nil, ErrWebHookNotConfigured
+		}
 		return nil, nil
 	}
-	secret := provider.GetSecret(wc.APIKey)
-	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
+
+	var webhookTLS *telemetry.WebhookTLS
+	if wc.TLS.CertFile != "" || wc.TLS.KeyFile != "" || wc.TLS.CAFile != "" {
+		var err error
+		webhookTLS, err = telemetry.NewWebhookTLS(telemetry.WebhookTLSParams{
+			CertFile: wc.TLS.CertFile,
+			KeyFile:  wc.TLS.KeyFile,
+			CAFile:   wc.TLS.CAFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notifiers := make([]telemetry.WebhookNotifier, 0, len(wc.URLs)+len(wc.Endpoints))
+	if len(wc.URLs) > 0 {
+		secret := provider.GetSecret(wc.APIKey)
+		if secret == "" {
+			return nil, ErrWebHookMissingAPIKey
+		}
+		for _, url := range wc.URLs {
+			notifiers = append(notifiers, telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:               url,
+				APIKey:            wc.APIKey,
+				APISecret:         secret,
+				SigningKey:        wc.SigningKey,
+				Headers:           wc.Headers,
+				Compress:          wc.Compress,
+				CompressThreshold: wc.CompressThreshold,
+				Encoding:          wc.Encoding,
+				FormField:         wc.FormField,
+				APIVersion:        wc.APIVersion,
+				Envelope:          wc.ParticipantClientInfo || wc.EventDetails,
+				TLS:               webhookTLS,
+			}))
+		}
+	}
+
+	for _, endpoint := range wc.Endpoints {
+		apiKey := endpoint.APIKey
+		if apiKey == "" {
+			apiKey = wc.APIKey
+		}
+		secret := provider.GetSecret(apiKey)
+		if secret == "" {
+			return nil, ErrWebHookMissingAPIKey
+		}
+		signingKey := endpoint.SigningKey
+		if signingKey == "" {
+			signingKey = wc.SigningKey
+		}
+		apiVersion := endpoint.APIVersion
+		if apiVersion == "" {
+			apiVersion = wc.APIVersion
+		}
+		encoding := endpoint.Encoding
+		if encoding == "" {
+			encoding = wc.Encoding
+		}
+		formField := endpoint.FormField
+		if formField == "" {
+			formField = wc.FormField
+		}
+		headers := wc.Headers
+		if len(endpoint.Headers) > 0 {
+			headers = make(map[string]string, len(wc.Headers)+len(endpoint.Headers))
+			for k, v := range wc.Headers {
+				headers[http.CanonicalHeaderKey(k)] = v
+			}
+			for k, v := range endpoint.Headers {
+				headers[http.CanonicalHeaderKey(k)] = v
+			}
+		}
+		notifiers = append(notifiers, telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+			Name:              endpoint.Name,
+			URL:               endpoint.URL,
+			APIKey:            apiKey,
+			APISecret:         secret,
+			SigningKey:        signingKey,
+			Headers:           headers,
+			Compress:          wc.Compress,
+			CompressThreshold: wc.CompressThreshold,
+			Encoding:          encoding,
+			FormField:         formField,
+			APIVersion:        apiVersion,
+			Envelope:          wc.ParticipantClientInfo || wc.EventDetails,
+			TLS:               webhookTLS,
+		}))
 	}
+	return notifiers, nil
+}
 
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+func getTelemetryServiceOpts(conf *config.Config, nodeID livekit.NodeID, eventStream *EventStreamService) ([]telemetry.TelemetryServiceOpts, error) {
+	opts := []telemetry.TelemetryServiceOpts{telemetry.WithNodeID(nodeID)}
+	if eventStream.Enabled() {
+		opts = append(opts, telemetry.WithEventStreamSink(eventStream.Sink()))
+	}
+	if conf.EventFile.Path != "" {
+		sink, err := telemetry.NewFileEventSink(telemetry.FileEventSinkParams{
+			Path:           conf.EventFile.Path,
+			MaxSize:        int64(conf.EventFile.MaxSizeMB) << 20,
+			RotateInterval: conf.EventFile.RotateInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, telemetry.WithFileEventSink(sink))
+	}
+	if conf.Analytics.BufferDir != "" {
+		buffer, err := telemetry.NewAnalyticsBuffer(telemetry.AnalyticsBufferParams{
+			Dir:     conf.Analytics.BufferDir,
+			MaxSize: int64(conf.Analytics.BufferMaxSizeMB) << 20,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, telemetry.WithAnalyticsBuffer(buffer))
+	}
+	return opts, nil
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
@@ -20,12 +20,12 @@ import (
 	redis2 "github.com/livekit/protocol/redis"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
-	"github.com/livekit/protocol/webhook"
 	"github.com/livekit/psrpc"
 	"github.com/pion/turn/v2"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
 	"gopkg.in/yaml.v3"
+	"net/http"
 	"os"
 )
 
@@ -72,12 +72,17 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	queuedNotifier, err := createWebhookNotifier(conf, keyProvider)
+	v, err := createWebhookNotifiers(conf, keyProvider)
 	if err != nil {
 		return nil, err
 	}
 	analyticsService := telemetry.NewAnalyticsService(conf, currentNode)
-	telemetryService := telemetry.NewTelemetryService(queuedNotifier, analyticsService)
+	eventStreamService := NewEventStreamService(conf)
+	v2, err := getTelemetryServiceOpts(conf, nodeID, eventStreamService)
+	if err != nil {
+		return nil, err
+	}
+	telemetryService := telemetry.NewTelemetryService(conf, v, analyticsService, v2...)
 	ioInfoService, err := NewIOInfoService(messageBus, egressStore, ingressStore, sipStore, telemetryService)
 	if err != nil {
 		return nil, err
@@ -130,7 +135,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	livekitServer, err := NewLivekitServer(conf, roomService, egressService, ingressService, sipService, ioInfoService, rtcService, agentService, keyProvider, router, roomManager, signalServer, server, currentNode)
+	livekitServer, err := NewLivekitServer(conf, roomService, egressService, ingressService, sipService, ioInfoService, rtcService, agentService, eventStreamService, keyProvider, router, roomManager, telemetryService, signalServer, server, currentNode)
 	if err != nil {
 		return nil, err
 	}
@@ -188,17 +193,133 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
+func createWebhookNotifiers(conf *config.Config, provider auth.KeyProvider) ([]telemetry.WebhookNotifier, error) {
 	wc := conf.WebHook
-	if len(wc.URLs) == 0 {
+	if len(wc.URLs) == 0 && len(wc.Endpoints) == 0 {
+		if wc.Required {
+			return nil, ErrWebHookNotConfigured
+		}
 		return nil, nil
 	}
-	secret := provider.GetSecret(wc.APIKey)
-	if secret == "" {
-		return nil, ErrWebHookMissingAPIKey
+
+	var webhookTLS *telemetry.WebhookTLS
+	if wc.TLS.CertFile != "" || wc.TLS.KeyFile != "" || wc.TLS.CAFile != "" {
+		var err error
+		webhookTLS, err = telemetry.NewWebhookTLS(telemetry.WebhookTLSParams{
+			CertFile: wc.TLS.CertFile,
+			KeyFile:  wc.TLS.KeyFile,
+			CAFile:   wc.TLS.CAFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notifiers := make([]telemetry.WebhookNotifier, 0, len(wc.URLs)+len(wc.Endpoints))
+	if len(wc.URLs) > 0 {
+		secret := provider.GetSecret(wc.APIKey)
+		if secret == "" {
+			return nil, ErrWebHookMissingAPIKey
+		}
+		for _, url := range wc.URLs {
+			notifiers = append(notifiers, telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:               url,
+				APIKey:            wc.APIKey,
+				APISecret:         secret,
+				SigningKey:        wc.SigningKey,
+				Headers:           wc.Headers,
+				Compress:          wc.Compress,
+				CompressThreshold: wc.CompressThreshold,
+				Encoding:          wc.Encoding,
+				FormField:         wc.FormField,
+				APIVersion:        wc.APIVersion,
+				Envelope:          wc.ParticipantClientInfo || wc.EventDetails,
+				TLS:               webhookTLS,
+			}))
+		}
 	}
 
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+	for _, endpoint := range wc.Endpoints {
+		apiKey := endpoint.APIKey
+		if apiKey == "" {
+			apiKey = wc.APIKey
+		}
+		secret := provider.GetSecret(apiKey)
+		if secret == "" {
+			return nil, ErrWebHookMissingAPIKey
+		}
+		signingKey := endpoint.SigningKey
+		if signingKey == "" {
+			signingKey = wc.SigningKey
+		}
+		apiVersion := endpoint.APIVersion
+		if apiVersion == "" {
+			apiVersion = wc.APIVersion
+		}
+		encoding := endpoint.Encoding
+		if encoding == "" {
+			encoding = wc.Encoding
+		}
+		formField := endpoint.FormField
+		if formField == "" {
+			formField = wc.FormField
+		}
+		headers := wc.Headers
+		if len(endpoint.Headers) > 0 {
+			headers = make(map[string]string, len(wc.Headers)+len(endpoint.Headers))
+			for k, v := range wc.Headers {
+				headers[http.CanonicalHeaderKey(k)] = v
+			}
+			for k, v := range endpoint.Headers {
+				headers[http.CanonicalHeaderKey(k)] = v
+			}
+		}
+		notifiers = append(notifiers, telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+			Name:              endpoint.Name,
+			URL:               endpoint.URL,
+			APIKey:            apiKey,
+			APISecret:         secret,
+			SigningKey:        signingKey,
+			Headers:           headers,
+			Compress:          wc.Compress,
+			CompressThreshold: wc.CompressThreshold,
+			Encoding:          encoding,
+			FormField:         formField,
+			APIVersion:        apiVersion,
+			Envelope:          wc.ParticipantClientInfo || wc.EventDetails,
+			TLS:               webhookTLS,
+		}))
+	}
+	return notifiers, nil
+}
+
+func getTelemetryServiceOpts(conf *config.Config, nodeID livekit.NodeID, eventStream *EventStreamService) ([]telemetry.TelemetryServiceOpts, error) {
+	opts := []telemetry.TelemetryServiceOpts{telemetry.WithNodeID(nodeID)}
+	if eventStream.Enabled() {
+		opts = append(opts, telemetry.WithEventStreamSink(eventStream.Sink()))
+	}
+	if conf.EventFile.Path != "" {
+		sink, err := telemetry.NewFileEventSink(telemetry.FileEventSinkParams{
+			Path:           conf.EventFile.Path,
+			MaxSize:        int64(conf.EventFile.MaxSizeMB) << 20,
+			RotateInterval: conf.EventFile.RotateInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, telemetry.WithFileEventSink(sink))
+	}
+	if conf.Analytics.BufferDir != "" {
+		buffer, err := telemetry.NewAnalyticsBuffer(telemetry.AnalyticsBufferParams{
+			Dir:     conf.Analytics.BufferDir,
+			MaxSize: int64(conf.Analytics.BufferMaxSizeMB) << 20,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, telemetry.WithAnalyticsBuffer(buffer))
+	}
+	return opts, nil
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
@@ -265,6 +265,9 @@ type DownTrack struct {
 	playoutDelayBytes atomic.Value //bytes of marshalled playout delay
 	playoudDelayAcked atomic.Bool
 
+	// spatial layer of the last allocation with a valid target, to report changes of it
+	targetLayerSpatial atomic.Int32
+
 	pacer pacer.Pacer
 
 	maxLayerNotifierChMu     sync.RWMutex
@@ -278,6 +281,7 @@ type DownTrack struct {
 	cbMu                        sync.RWMutex
 	onStatsUpdate               func(dt *DownTrack, stat *livekit.AnalyticsStat)
 	onMaxSubscribedLayerChanged func(dt *DownTrack, layer int32)
+	onTargetLayerChanged        func(dt *DownTrack, allocation VideoAllocation)
 	onRttUpdate                 func(dt *DownTrack, rtt uint32)
 	onCloseHandler              func(willBeResumed bool)
 }
@@ -305,6 +309,7 @@ func NewDownTrack(params DowntrackParams) (*DownTrack, error) {
 		maxLayerNotifierCh:  make(chan struct{}, 1),
 		keyFrameRequesterCh: make(chan struct{}, 1),
 	}
+	d.targetLayerSpatial.Store(buffer.InvalidLayerSpatial)
 	d.forwarder = NewForwarder(
 		d.kind,
 		params.Logger,
@@ -1173,6 +1178,36 @@ func (d *DownTrack) getOnMaxLayerChanged() func(dt *DownTrack, layer int32) {
 	return d.onMaxSubscribedLayerChanged
 }
 
+// OnTargetLayerChanged is called with the allocation that changed the spatial layer being forwarded,
+// allocations that pause the track are not reported
+func (d *DownTrack) OnTargetLayerChanged(fn func(dt *DownTrack, allocation VideoAllocation)) {
+	d.cbMu.Lock()
+	defer d.cbMu.Unlock()
+
+	d.onTargetLayerChanged = fn
+}
+
+func (d *DownTrack) getOnTargetLayerChanged() func(dt *DownTrack, allocation VideoAllocation) {
+	d.cbMu.RLock()
+	defer d.cbMu.RUnlock()
+
+	return d.onTargetLayerChanged
+}
+
+func (d *DownTrack) maybeNotifyTargetLayer(allocation VideoAllocation) {
+	if d.kind == webrtc.RTPCodecTypeAudio || !allocation.TargetLayer.IsValid() {
+		return
+	}
+
+	if d.targetLayerSpatial.Swap(allocation.TargetLayer.Spatial) == allocation.TargetLayer.Spatial {
+		return
+	}
+
+	if onTargetLayerChanged := d.getOnTargetLayerChanged(); onTargetLayerChanged != nil {
+		onTargetLayerChanged(d, allocation)
+	}
+}
+
 func (d *DownTrack) IsDeficient() bool {
 	return d.forwarder.IsDeficient()
 }
@@ -1192,6 +1227,7 @@ func (d *DownTrack) AllocateOptimal(allowOvershoot bool) VideoAllocation {
 	allocation := d.forwarder.AllocateOptimal(al, brs, allowOvershoot)
 	d.postKeyFrameRequestEvent()
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired, allocation.PauseReason)
+	d.maybeNotifyTargetLayer(allocation)
 	return allocation
 }
 
@@ -1234,6 +1270,7 @@ func (d *DownTrack) ProvisionalAllocateCommit() VideoAllocation {
 	allocation := d.forwarder.ProvisionalAllocateCommit()
 	d.postKeyFrameRequestEvent()
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired, allocation.PauseReason)
+	d.maybeNotifyTargetLayer(allocation)
 	return allocation
 }
 
@@ -1242,6 +1279,7 @@ func (d *DownTrack) AllocateNextHigher(availableChannelCapacity int64, allowOver
 	allocation, available := d.forwarder.AllocateNextHigher(availableChannelCapacity, al, brs, allowOvershoot)
 	d.postKeyFrameRequestEvent()
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired, allocation.PauseReason)
+	d.maybeNotifyTargetLayer(allocation)
 	return allocation, available
 }
 
@@ -1262,6 +1300,7 @@ func (d *DownTrack) Pause() VideoAllocation {
 	al, brs := d.params.Receiver.GetLayeredBitrate()
 	allocation := d.forwarder.Pause(al, brs)
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired, allocation.PauseReason)
+	d.maybeNotifyTargetLayer(allocation)
 	return allocation
 }
 
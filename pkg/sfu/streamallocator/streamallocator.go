@@ -141,7 +141,8 @@ type StreamAllocatorParams struct {
 type StreamAllocator struct {
 	params StreamAllocatorParams
 
-	onStreamStateChange func(update *StreamStateUpdate) error
+	onStreamStateChange     func(update *StreamStateUpdate) error
+	onChannelCapacityChange func(channelCapacity int64)
 
 	bwe cc.BandwidthEstimator
 
@@ -216,6 +217,11 @@ func (s *StreamAllocator) OnStreamStateChange(f func(update *StreamStateUpdate)
 	s.onStreamStateChange = f
 }
 
+// OnChannelCapacityChange is called with the estimated channel capacity, in bps, every time a new estimate is committed
+func (s *StreamAllocator) OnChannelCapacityChange(f func(channelCapacity int64)) {
+	s.onChannelCapacityChange = f
+}
+
 func (s *StreamAllocator) SetBandwidthEstimator(bwe cc.BandwidthEstimator) {
 	if bwe != nil {
 		bwe.OnTargetBitrateChange(s.onTargetBitrateChange)
@@ -721,6 +727,13 @@ func (s *StreamAllocator) handleSignalSetAllowPause(event *Event) {
 	s.allowPause = event.Data.(bool)
 }
 
+func (s *StreamAllocator) commitChannelCapacity(channelCapacity int64) {
+	s.committedChannelCapacity = channelCapacity
+	if s.onChannelCapacityChange != nil {
+		s.onChannelCapacityChange(channelCapacity)
+	}
+}
+
 func (s *StreamAllocator) handleSignalSetChannelCapacity(event *Event) {
 	s.overriddenChannelCapacity = event.Data.(int64)
 	if s.overriddenChannelCapacity > 0 {
@@ -847,7 +860,7 @@ func (s *StreamAllocator) handleNewEstimateInNonProbe() {
 		return
 	}
 
-	s.committedChannelCapacity = estimateToCommit
+	s.commitChannelCapacity(estimateToCommit)
 
 	// reset to get new set of samples for next trend
 	s.channelObserver = s.newChannelObserverNonProbe()
@@ -1042,7 +1055,7 @@ func (s *StreamAllocator) onProbeDone(isNotFailing bool, isGoalReached bool) {
 	}
 
 	if highestEstimateInProbe > s.committedChannelCapacity {
-		s.committedChannelCapacity = highestEstimateInProbe
+		s.commitChannelCapacity(highestEstimateInProbe)
 	}
 
 	s.maybeBoostDeficientTracks()
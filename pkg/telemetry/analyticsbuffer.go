@@ -0,0 +1,393 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	analyticsBufferSuffix = ".jsonl"
+	// events are sent again in batches of up to this many
+	analyticsReplayBatchSize = 100
+)
+
+var errAnalyticsBufferClosed = errors.New("analytics buffer is closed")
+
+type AnalyticsBufferParams struct {
+	// directory the buffered events are kept in, created if it doesn't exist
+	Dir string
+	// the oldest events are evicted once the buffer takes up more than this many bytes
+	MaxSize int64
+}
+
+// AnalyticsBuffer keeps analytics events that failed to send on disk until they can be sent again.
+// events are appended to segment files in Dir as newline-delimited JSON, oldest segment first, and a segment
+// is removed once all its events have been sent. segments left over from a previous run are sent again,
+// events that were sent from a segment before the process stopped may then be sent twice
+type AnalyticsBuffer struct {
+	params      AnalyticsBufferParams
+	segmentSize int64
+
+	lock sync.Mutex
+	// oldest first
+	segments []*analyticsBufferSegment
+	size     int64
+	nextID   uint64
+	// the newest segment, open for appending
+	file   *os.File
+	closed bool
+}
+
+type analyticsBufferSegment struct {
+	path string
+	size int64
+	// bytes at the start of the segment that have been sent
+	sent int64
+	// events in the segment that have not been sent
+	events int
+}
+
+func NewAnalyticsBuffer(params AnalyticsBufferParams) (*AnalyticsBuffer, error) {
+	if params.MaxSize <= 0 {
+		return nil, fmt.Errorf("invalid analytics buffer max size %d", params.MaxSize)
+	}
+	if err := os.MkdirAll(params.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	b := &AnalyticsBuffer{
+		params:      params,
+		segmentSize: params.MaxSize / 4,
+	}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	if len(b.segments) != 0 {
+		logger.Infow("sending analytics events buffered by a previous run", "dir", params.Dir, "size", b.size)
+	}
+	return b, nil
+}
+
+// Close syncs the newest segment to disk and closes it. buffered events are kept for the next run,
+// events written after are dropped
+func (b *AnalyticsBuffer) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.closeFileLocked()
+}
+
+// pending returns true when there are buffered events waiting to be sent
+func (b *AnalyticsBuffer) pending() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return !b.closed && len(b.segments) != 0
+}
+
+// write appends events to the buffer, evicting the oldest segments if it grows over the maximum size
+func (b *AnalyticsBuffer) write(events []*livekit.AnalyticsEvent) error {
+	var buf bytes.Buffer
+	for _, event := range events {
+		encoded, err := protojson.Marshal(event)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+		buf.WriteByte('\n')
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return errAnalyticsBufferClosed
+	}
+	if b.file == nil || b.segments[len(b.segments)-1].size >= b.segmentSize {
+		if err := b.openSegmentLocked(); err != nil {
+			return err
+		}
+	}
+
+	segment := b.segments[len(b.segments)-1]
+	n, err := b.file.Write(buf.Bytes())
+	segment.size += int64(n)
+	segment.events += len(events)
+	b.size += int64(n)
+	prometheus.AddAnalyticsBuffered(int64(n))
+
+	for b.size > b.params.MaxSize && len(b.segments) > 1 {
+		prometheus.RecordAnalyticsEvicted(b.segments[0].events)
+		b.removeOldestLocked()
+	}
+	return err
+}
+
+// replay sends buffered events oldest first, removing segments once they have been sent.
+// it stops at the first failed send and returns its error, the events that failed stay buffered
+func (b *AnalyticsBuffer) replay(send func(events []*livekit.AnalyticsEvent) error) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for !b.closed && len(b.segments) != 0 {
+		if err := b.replaySegmentLocked(b.segments[0], send); err != nil {
+			return err
+		}
+		b.removeOldestLocked()
+	}
+	return nil
+}
+
+func (b *AnalyticsBuffer) replaySegmentLocked(segment *analyticsBufferSegment, send func(events []*livekit.AnalyticsEvent) error) error {
+	file, err := os.Open(segment.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Seek(segment.sent, io.SeekStart); err != nil {
+		return err
+	}
+
+	var (
+		events    []*livekit.AnalyticsEvent
+		batchSize int64
+	)
+	flush := func() error {
+		if len(events) != 0 {
+			if err := send(events); err != nil {
+				return err
+			}
+		}
+		segment.sent += batchSize
+		segment.events -= len(events)
+		events, batchSize = nil, 0
+		return nil
+	}
+
+	reader := bufio.NewReader(file)
+	for segment.sent+batchSize < segment.size {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		batchSize += int64(len(line))
+
+		event := &livekit.AnalyticsEvent{}
+		if unmarshalErr := protojson.Unmarshal(bytes.TrimSpace(line), event); unmarshalErr != nil {
+			// a partial line left by a crash while writing, or a corrupted file
+			logger.Warnw("skipping unreadable buffered analytics event", unmarshalErr, "path", segment.path)
+		} else {
+			events = append(events, event)
+		}
+
+		if len(events) >= analyticsReplayBatchSize || err == io.EOF {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+	return flush()
+}
+
+func (b *AnalyticsBuffer) removeOldestLocked() {
+	segment := b.segments[0]
+	if len(b.segments) == 1 && b.file != nil {
+		if err := b.closeFileLocked(); err != nil {
+			logger.Warnw("failed to close analytics buffer segment", err, "path", segment.path)
+		}
+	}
+	if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warnw("failed to remove analytics buffer segment", err, "path", segment.path)
+	}
+
+	b.segments[0] = nil
+	b.segments = b.segments[1:]
+	b.size -= segment.size
+	prometheus.SubAnalyticsBuffered(segment.size)
+}
+
+func (b *AnalyticsBuffer) openSegmentLocked() error {
+	if b.file != nil {
+		if err := b.closeFileLocked(); err != nil {
+			logger.Warnw("failed to close analytics buffer segment", err, "dir", b.params.Dir)
+		}
+	}
+
+	path := filepath.Join(b.params.Dir, fmt.Sprintf("%020d%s", b.nextID, analyticsBufferSuffix))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	b.nextID++
+	b.file = file
+	b.segments = append(b.segments, &analyticsBufferSegment{path: path})
+	return nil
+}
+
+func (b *AnalyticsBuffer) closeFileLocked() error {
+	if b.file == nil {
+		return nil
+	}
+	file := b.file
+	b.file = nil
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// load picks up the segments left in Dir by a previous run
+func (b *AnalyticsBuffer) load() error {
+	entries, err := os.ReadDir(b.params.Dir)
+	if err != nil {
+		return err
+	}
+
+	// entries are sorted by name, which sorts the segments oldest first
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, analyticsBufferSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, analyticsBufferSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(b.params.Dir, name)
+		size, events, err := countAnalyticsBufferSegment(path)
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			_ = os.Remove(path)
+			continue
+		}
+		b.segments = append(b.segments, &analyticsBufferSegment{path: path, size: size, events: events})
+		b.size += size
+		b.nextID = id + 1
+	}
+	prometheus.AddAnalyticsBuffered(b.size)
+	return nil
+}
+
+func countAnalyticsBufferSegment(path string) (int64, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	var (
+		size   int64
+		events int
+	)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		size += int64(len(line))
+		if len(line) != 0 {
+			events++
+		}
+		if err == io.EOF {
+			return size, events, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// sendBuffered sends events to the analytics sink, buffering them if the send fails or earlier events are
+// still waiting to be sent, so events reach the sink in order. buffered events are sent first once the retry
+// interval has passed. returns false when there is no buffer, or it has been closed, and the events should be
+// sent as usual
+func (t *telemetryService) sendBuffered(ctx context.Context, events []*livekit.AnalyticsEvent) bool {
+	if t.analyticsRetrier == nil {
+		return false
+	}
+
+	t.analyticsRetryLock.Lock()
+	defer t.analyticsRetryLock.Unlock()
+
+	if t.analyticsBuffer.pending() && !t.replayAnalyticsLocked(ctx) {
+		return t.bufferAnalytics(events)
+	}
+	if len(events) == 0 {
+		return true
+	}
+	if err := t.analyticsRetrier.TrySendEvents(ctx, events); err != nil {
+		logger.Warnw("failed to send analytics events, buffering them", err, "count", len(events))
+		t.analyticsRetryAt = t.clock.Now().Add(t.analyticsRetryInterval)
+		return t.bufferAnalytics(events)
+	}
+	return true
+}
+
+// replayAnalyticsLocked sends the buffered events if the retry interval has passed since the last failure,
+// returns true once they have all been sent
+func (t *telemetryService) replayAnalyticsLocked(ctx context.Context) bool {
+	if t.clock.Now().Before(t.analyticsRetryAt) {
+		return false
+	}
+	err := t.analyticsBuffer.replay(func(events []*livekit.AnalyticsEvent) error {
+		return t.analyticsRetrier.TrySendEvents(ctx, events)
+	})
+	if err != nil {
+		logger.Warnw("failed to send buffered analytics events", err)
+		t.analyticsRetryAt = t.clock.Now().Add(t.analyticsRetryInterval)
+		return false
+	}
+	return true
+}
+
+func (t *telemetryService) bufferAnalytics(events []*livekit.AnalyticsEvent) bool {
+	if len(events) == 0 {
+		return true
+	}
+	if err := t.analyticsBuffer.write(events); err != nil {
+		if errors.Is(err, errAnalyticsBufferClosed) {
+			return false
+		}
+		logger.Errorw("failed to buffer analytics events", err, "count", len(events))
+	}
+	return true
+}
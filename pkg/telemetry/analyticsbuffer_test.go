@@ -0,0 +1,165 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+// retrySink is an analytics sink that fails every send while down
+type retrySink struct {
+	lock   sync.Mutex
+	down   bool
+	events []string
+}
+
+func (s *retrySink) SendEvent(ctx context.Context, event *livekit.AnalyticsEvent) {
+	_ = s.TrySendEvents(ctx, []*livekit.AnalyticsEvent{event})
+}
+
+func (s *retrySink) SendStats(_ context.Context, _ []*livekit.AnalyticsStat) {}
+
+func (s *retrySink) TrySendEvents(_ context.Context, events []*livekit.AnalyticsEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.down {
+		return errors.New("analytics backend is down")
+	}
+	for _, event := range events {
+		s.events = append(s.events, event.RoomId)
+	}
+	return nil
+}
+
+func (s *retrySink) setDown(down bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.down = down
+}
+
+func (s *retrySink) received() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.events...)
+}
+
+// bufferRetryInterval is how long buffered events wait after a failure before they are sent again
+const bufferRetryInterval = time.Minute
+
+func createBufferedService(t *testing.T, dir string, maxSize int64, sink *retrySink, clock telemetry.Clock) telemetry.TelemetryService {
+	buffer, err := telemetry.NewAnalyticsBuffer(telemetry.AnalyticsBufferParams{Dir: dir, MaxSize: maxSize})
+	require.NoError(t, err)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.BufferRetryInterval = bufferRetryInterval
+	return telemetry.NewTelemetryService(
+		conf,
+		nil,
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithAnalyticsSink(sink),
+		telemetry.WithAnalyticsBuffer(buffer),
+		telemetry.WithClock(clock),
+	)
+}
+
+func sendRoomEvents(sut telemetry.TelemetryService, from, to int) {
+	for i := from; i < to; i++ {
+		sut.SendEvent(context.Background(), &livekit.AnalyticsEvent{
+			Type:   livekit.AnalyticsEventType_ROOM_CREATED,
+			RoomId: fmt.Sprintf("RM_%d", i),
+		})
+	}
+	flushEvents(sut)
+}
+
+func roomIDs(from, to int) []string {
+	var ids []string
+	for i := from; i < to; i++ {
+		ids = append(ids, fmt.Sprintf("RM_%d", i))
+	}
+	return ids
+}
+
+func Test_AnalyticsBuffer_ResendsInOrder(t *testing.T) {
+	sink := &retrySink{down: true}
+	clock := newFakeClock()
+	sut := createBufferedService(t, t.TempDir(), 1<<20, sink, clock)
+
+	sendRoomEvents(sut, 0, 3)
+	require.Empty(t, sink.received())
+
+	sink.setDown(false)
+	clock.Advance(bufferRetryInterval)
+	sendRoomEvents(sut, 3, 5)
+
+	require.Equal(t, roomIDs(0, 5), sink.received())
+}
+
+func Test_AnalyticsBuffer_EvictsOldest(t *testing.T) {
+	sink := &retrySink{down: true}
+	clock := newFakeClock()
+	sut := createBufferedService(t, t.TempDir(), 1000, sink, clock)
+
+	before := findMetric(t, "livekit_telemetry_analytics_buffer_evicted_total", nil).GetCounter().GetValue()
+	sendRoomEvents(sut, 0, 100)
+	evicted := findMetric(t, "livekit_telemetry_analytics_buffer_evicted_total", nil).GetCounter().GetValue() - before
+	require.Greater(t, evicted, float64(0))
+
+	sink.setDown(false)
+	clock.Advance(bufferRetryInterval)
+	require.Eventually(t, func() bool {
+		return len(sink.received()) == 100-int(evicted)
+	}, time.Second, time.Millisecond)
+	// the newest events are kept
+	require.Equal(t, roomIDs(int(evicted), 100), sink.received())
+}
+
+func Test_AnalyticsBuffer_KeepsEventsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	down := &retrySink{down: true}
+	sut := createBufferedService(t, dir, 1<<20, down, newFakeClock())
+	sendRoomEvents(sut, 0, 3)
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Empty(t, down.received())
+
+	sink := &retrySink{}
+	clock := newFakeClock()
+	sut = createBufferedService(t, dir, 1<<20, sink, clock)
+	defer func() {
+		_ = sut.Shutdown(context.Background())
+	}()
+
+	// sent once the first retry comes around
+	flushEvents(sut)
+	clock.Advance(bufferRetryInterval)
+	require.Eventually(t, func() bool {
+		return len(sink.received()) == 3
+	}, time.Second, time.Millisecond)
+	require.Equal(t, roomIDs(0, 3), sink.received())
+}
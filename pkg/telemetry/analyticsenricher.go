@@ -0,0 +1,52 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"github.com/livekit/protocol/livekit"
+)
+
+// EventEnricher adds application specific attributes to analytics events, such as a tenant derived from
+// room metadata. enrichers run on every event once the standard fields are set, in the order they were added
+// with WithEventEnrichers. messages the event refers to, such as its Room, may be shared with the caller
+// and must be copied before they are changed
+type EventEnricher interface {
+	Enrich(event *livekit.AnalyticsEvent)
+}
+
+// EventEnricherFunc adapts a function to an EventEnricher
+type EventEnricherFunc func(event *livekit.AnalyticsEvent)
+
+func (f EventEnricherFunc) Enrich(event *livekit.AnalyticsEvent) {
+	f(event)
+}
+
+// withEnrichers runs the enrichers on event. the type, time and ids of the event identify it to the analytics
+// backend, they are put back if an enricher changes them
+func (t *telemetryService) withEnrichers(event *livekit.AnalyticsEvent) {
+	if len(t.eventEnrichers) == 0 {
+		return
+	}
+
+	eventType, timestamp := event.Type, event.Timestamp
+	roomID, participantID, trackID := event.RoomId, event.ParticipantId, event.TrackId
+	egressID, ingressID := event.EgressId, event.IngressId
+	for _, enricher := range t.eventEnrichers {
+		enricher.Enrich(event)
+	}
+	event.Type, event.Timestamp = eventType, timestamp
+	event.RoomId, event.ParticipantId, event.TrackId = roomID, participantID, trackID
+	event.EgressId, event.IngressId = egressID, ingressID
+}
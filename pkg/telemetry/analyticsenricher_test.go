@@ -0,0 +1,56 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func Test_SendEvent_Enriched(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	analytics := &telemetryfakes.FakeAnalyticsService{}
+	var order []string
+	sut := telemetry.NewTelemetryService(conf, nil, analytics, telemetry.WithEventEnrichers(
+		telemetry.EventEnricherFunc(func(event *livekit.AnalyticsEvent) {
+			order = append(order, "tenant")
+			event.ProjectId = "tenant-" + event.Room.GetMetadata()
+		}),
+		telemetry.EventEnricherFunc(func(event *livekit.AnalyticsEvent) {
+			order = append(order, "careless")
+			event.RoomId = ""
+			event.Type = livekit.AnalyticsEventType_ROOM_CREATED
+		}),
+	))
+
+	sut.RoomEnded(context.Background(), &livekit.Room{Sid: "RoomSid", Metadata: "acme"})
+	flushEvents(sut)
+
+	require.Equal(t, 1, analytics.SendEventCallCount())
+	_, event := analytics.SendEventArgsForCall(0)
+	require.Equal(t, []string{"tenant", "careless"}, order)
+	require.Equal(t, "tenant-acme", event.ProjectId)
+	// identifying fields are kept
+	require.Equal(t, livekit.AnalyticsEventType_ROOM_ENDED, event.Type)
+	require.Equal(t, "RoomSid", event.RoomId)
+}
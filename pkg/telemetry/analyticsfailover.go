@@ -0,0 +1,183 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	defaultAnalyticsFailoverThreshold     = 3
+	defaultAnalyticsFailoverRetryInterval = time.Minute
+)
+
+// analyticsFailover sends analytics to the first of an ordered list of sinks, failing over to the next once a send
+// has failed threshold times in a row. the primary is tried again every retryInterval, and used again as soon as a
+// send to it succeeds. each send goes to a single sink, the one it failed on doesn't get it again from another.
+// failures are only noticed on sinks that implement AnalyticsRetryService, stats follow the events
+type analyticsFailover struct {
+	// the telemetry service's, set once its options are applied
+	clock         Clock
+	sinks         []AnalyticsSink
+	threshold     int
+	retryInterval time.Duration
+
+	lock     sync.Mutex
+	active   int
+	failures int
+	// when the primary is next tried while failed over
+	retryAt time.Time
+}
+
+func newAnalyticsFailover(sinks []AnalyticsSink, threshold int, retryInterval time.Duration) *analyticsFailover {
+	if threshold <= 0 {
+		threshold = defaultAnalyticsFailoverThreshold
+	}
+	if retryInterval <= 0 {
+		retryInterval = defaultAnalyticsFailoverRetryInterval
+	}
+	prometheus.SetAnalyticsActiveSink(0)
+	return &analyticsFailover{
+		clock:         realClock{},
+		sinks:         sinks,
+		threshold:     threshold,
+		retryInterval: retryInterval,
+	}
+}
+
+func (f *analyticsFailover) SendStats(ctx context.Context, stats []*livekit.AnalyticsStat) {
+	f.lock.Lock()
+	sink := f.sinks[f.active]
+	f.lock.Unlock()
+
+	sink.SendStats(ctx, stats)
+}
+
+func (f *analyticsFailover) SendEvent(ctx context.Context, event *livekit.AnalyticsEvent) {
+	if err := f.TrySendEvents(ctx, []*livekit.AnalyticsEvent{event}); err != nil {
+		logger.Errorw("failed to send event", err, "eventType", event.Type.String())
+	}
+}
+
+func (f *analyticsFailover) SendEvents(ctx context.Context, events []*livekit.AnalyticsEvent) {
+	if err := f.TrySendEvents(ctx, events); err != nil {
+		logger.Errorw("failed to send events", err, "count", len(events))
+	}
+}
+
+// TrySendEvents sends events to the active sink, failing over as needed, and returns the error of the last sink
+// tried when none would take them
+func (f *analyticsFailover) TrySendEvents(ctx context.Context, events []*livekit.AnalyticsEvent) error {
+	index, retryPrimary := f.current()
+	if retryPrimary {
+		err := sendAnalyticsEvents(ctx, f.sinks[0], events)
+		if err == nil {
+			f.recovered()
+			return nil
+		}
+		logger.Debugw("analytics primary sink still failing", "error", err)
+	}
+
+	err := sendAnalyticsEvents(ctx, f.sinks[index], events)
+	for err != nil {
+		next, ok := f.failed(index, err)
+		if !ok {
+			return err
+		}
+		index = next
+		err = sendAnalyticsEvents(ctx, f.sinks[index], events)
+	}
+	f.succeeded(index)
+	return nil
+}
+
+// current returns the active sink, and whether the primary is due to be tried again first
+func (f *analyticsFailover) current() (int, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := f.clock.Now()
+	if f.active == 0 || now.Before(f.retryAt) {
+		return f.active, false
+	}
+	f.retryAt = now.Add(f.retryInterval)
+	return f.active, true
+}
+
+// failed records a failed send to index, returning the sink to try next, false when the send has failed for good
+func (f *analyticsFailover) failed(index int, err error) (int, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if index != f.active {
+		// another send already failed over
+		return f.active, true
+	}
+	f.failures++
+	if f.failures < f.threshold || f.active == len(f.sinks)-1 {
+		return 0, false
+	}
+	logger.Warnw("analytics sink failing, failing over to the next", err, "failed", f.active, "next", f.active+1)
+	f.setActiveLocked(f.active + 1)
+	return f.active, true
+}
+
+func (f *analyticsFailover) succeeded(index int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if index == f.active {
+		f.failures = 0
+	}
+}
+
+func (f *analyticsFailover) recovered() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.active != 0 {
+		logger.Infow("analytics primary sink is back", "failedOver", f.active)
+		f.setActiveLocked(0)
+	}
+}
+
+func (f *analyticsFailover) setActiveLocked(index int) {
+	f.active = index
+	f.failures = 0
+	f.retryAt = f.clock.Now().Add(f.retryInterval)
+	prometheus.SetAnalyticsActiveSink(index)
+}
+
+// sendAnalyticsEvents sends events to sink, returning the error when it reports failed sends
+func sendAnalyticsEvents(ctx context.Context, sink AnalyticsSink, events []*livekit.AnalyticsEvent) error {
+	switch s := sink.(type) {
+	case AnalyticsRetryService:
+		return s.TrySendEvents(ctx, events)
+	case AnalyticsBatchService:
+		s.SendEvents(ctx, events)
+	default:
+		for _, event := range events {
+			sink.SendEvent(ctx, event)
+		}
+	}
+	return nil
+}
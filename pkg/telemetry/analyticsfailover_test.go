@@ -0,0 +1,100 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func analyticsActiveSink(t *testing.T) float64 {
+	return findMetric(t, "livekit_telemetry_analytics_active_sink", nil).GetGauge().GetValue()
+}
+
+func Test_AnalyticsFailover(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	// every event is a send of its own
+	conf.Analytics.BatchSize = 1
+	conf.Analytics.FailoverThreshold = 2
+	conf.Analytics.FailoverRetryInterval = time.Minute
+
+	clock := newFakeClock()
+	primary, secondary := &retrySink{}, &retrySink{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		nil,
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithAnalyticsSinks(primary, secondary),
+		telemetry.WithClock(clock),
+	)
+	require.Zero(t, analyticsActiveSink(t))
+
+	sendRoomEvents(sut, 0, 1)
+	require.Equal(t, roomIDs(0, 1), primary.received())
+
+	// a single failure isn't enough to fail over, and the event isn't sent anywhere else
+	primary.setDown(true)
+	sendRoomEvents(sut, 1, 2)
+	require.Empty(t, secondary.received())
+	require.Zero(t, analyticsActiveSink(t))
+
+	// a persistent one is, the failing send goes to the secondary along with the ones after it
+	sendRoomEvents(sut, 2, 4)
+	require.Equal(t, roomIDs(2, 4), secondary.received())
+	require.Equal(t, float64(1), analyticsActiveSink(t))
+
+	// the primary is tried again once the retry interval has passed
+	primary.setDown(false)
+	sendRoomEvents(sut, 4, 5)
+	require.Equal(t, roomIDs(4, 5), secondary.received()[2:])
+	clock.Advance(conf.Analytics.FailoverRetryInterval)
+	sendRoomEvents(sut, 5, 7)
+	require.Equal(t, append(roomIDs(0, 1), roomIDs(5, 7)...), primary.received())
+	require.Equal(t, roomIDs(2, 5), secondary.received())
+	require.Zero(t, analyticsActiveSink(t))
+}
+
+func Test_AnalyticsFailover_StaysOnLastSink(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.BatchSize = 1
+	conf.Analytics.FailoverThreshold = 1
+	conf.Analytics.FailoverRetryInterval = time.Hour
+
+	primary, secondary := &retrySink{}, &retrySink{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		nil,
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithAnalyticsSinks(primary, secondary),
+	)
+
+	primary.setDown(true)
+	secondary.setDown(true)
+	sendRoomEvents(sut, 0, 3)
+	require.Equal(t, float64(1), analyticsActiveSink(t))
+
+	// events are only sent to the sink that is active
+	primary.setDown(false)
+	secondary.setDown(false)
+	sendRoomEvents(sut, 3, 4)
+	require.Empty(t, primary.received())
+	require.Equal(t, roomIDs(3, 4), secondary.received())
+}
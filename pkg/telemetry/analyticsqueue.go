@@ -0,0 +1,199 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// what happens to analytics sent while the queue in front of the analytics sink is full
+const (
+	// wait for the sink to catch up, holding up the sender
+	AnalyticsQueueBlock = "block"
+	// drop the oldest queued analytics to make room
+	AnalyticsQueueDropOldest = "drop_oldest"
+	// drop the analytics being sent
+	AnalyticsQueueDropNewest = "drop_newest"
+)
+
+const defaultAnalyticsQueueSize = 10000
+
+// analyticsItem is a single send to the analytics sink
+type analyticsItem struct {
+	ctx    context.Context
+	event  *livekit.AnalyticsEvent
+	events []*livekit.AnalyticsEvent
+	stats  []*livekit.AnalyticsStat
+	// set to send the events kept in the analytics buffer
+	replay bool
+	// set on flush markers, closed once everything queued before it has been sent
+	done chan struct{}
+}
+
+func (i *analyticsItem) kind() string {
+	if i.stats != nil {
+		return "stats"
+	}
+	return "event"
+}
+
+func (i *analyticsItem) count() int {
+	switch {
+	case i.event != nil:
+		return 1
+	case i.stats != nil:
+		return len(i.stats)
+	default:
+		return len(i.events)
+	}
+}
+
+// analyticsQueue decouples senders from the analytics sink, so a stalled sink can't hold up the telemetry
+// service or grow memory without bound. items are sent to the sink in order by a single goroutine
+type analyticsQueue struct {
+	size   int
+	policy string
+	send   func(item *analyticsItem)
+
+	lock     sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	items    []*analyticsItem
+	// number of queued items that are not flush markers
+	queued  int
+	closed  bool
+	stopped chan struct{}
+}
+
+func newAnalyticsQueue(size int, policy string, send func(item *analyticsItem)) *analyticsQueue {
+	q := &analyticsQueue{
+		size:    size,
+		policy:  policy,
+		send:    send,
+		stopped: make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.lock)
+	q.notFull = sync.NewCond(&q.lock)
+	go q.run()
+	return q
+}
+
+// push queues item, applying the policy when the queue is full. returns false when the queue has been
+// closed, the caller should then send item itself
+func (q *analyticsQueue) push(item *analyticsItem) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for !q.closed && q.queued >= q.size {
+		switch q.policy {
+		case AnalyticsQueueBlock:
+			q.notFull.Wait()
+			continue
+		case AnalyticsQueueDropNewest:
+			prometheus.RecordAnalyticsDropped(item.kind(), item.count())
+			return true
+		default:
+			q.dropOldestLocked()
+		}
+	}
+	if q.closed {
+		return false
+	}
+
+	q.items = append(q.items, item)
+	q.queued++
+	q.notEmpty.Signal()
+	return true
+}
+
+func (q *analyticsQueue) dropOldestLocked() {
+	for i, item := range q.items {
+		if item.done != nil {
+			continue
+		}
+		q.items = append(q.items[:i], q.items[i+1:]...)
+		q.queued--
+		prometheus.RecordAnalyticsDropped(item.kind(), item.count())
+		return
+	}
+}
+
+// flush waits until everything queued before it has been sent, returning the ctx error when ctx is done first
+func (q *analyticsQueue) flush(ctx context.Context) error {
+	done := make(chan struct{})
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return nil
+	}
+	q.items = append(q.items, &analyticsItem{done: done})
+	q.notEmpty.Signal()
+	q.lock.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting items and waits until the queued ones have been sent, or ctx is done
+func (q *analyticsQueue) close(ctx context.Context) {
+	q.lock.Lock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+	q.lock.Unlock()
+
+	select {
+	case <-q.stopped:
+	case <-ctx.Done():
+	}
+}
+
+func (q *analyticsQueue) run() {
+	defer close(q.stopped)
+
+	for {
+		q.lock.Lock()
+		for len(q.items) == 0 && !q.closed {
+			q.notEmpty.Wait()
+		}
+		if len(q.items) == 0 {
+			q.lock.Unlock()
+			return
+		}
+		item := q.items[0]
+		q.items[0] = nil
+		q.items = q.items[1:]
+		if item.done == nil {
+			q.queued--
+			q.notFull.Signal()
+		}
+		q.lock.Unlock()
+
+		if item.done != nil {
+			close(item.done)
+			continue
+		}
+		q.send(item)
+	}
+}
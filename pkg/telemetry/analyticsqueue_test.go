@@ -0,0 +1,204 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+	"github.com/livekit/protocol/livekit"
+)
+
+// stalledSink holds up the first event it is sent until released
+type stalledSink struct {
+	stalled  chan struct{}
+	released chan struct{}
+
+	lock   sync.Mutex
+	events []string
+}
+
+func newStalledSink() *stalledSink {
+	return &stalledSink{
+		stalled:  make(chan struct{}),
+		released: make(chan struct{}),
+	}
+}
+
+func (s *stalledSink) SendEvent(_ context.Context, event *livekit.AnalyticsEvent) {
+	s.lock.Lock()
+	s.events = append(s.events, event.ParticipantId)
+	first := len(s.events) == 1
+	s.lock.Unlock()
+
+	if first {
+		close(s.stalled)
+		<-s.released
+	}
+}
+
+func (s *stalledSink) SendStats(_ context.Context, _ []*livekit.AnalyticsStat) {}
+
+func (s *stalledSink) received() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.events...)
+}
+
+func sendQueueEvent(sut telemetry.TelemetryService, id string) {
+	sut.SendEvent(context.Background(), &livekit.AnalyticsEvent{
+		Type:          livekit.AnalyticsEventType_PARTICIPANT_JOINED,
+		ParticipantId: id,
+	})
+}
+
+func droppedEvents(t *testing.T) float64 {
+	if metric := findMetric(t, "livekit_telemetry_analytics_dropped_total", map[string]string{"kind": "event"}); metric != nil {
+		return metric.GetCounter().GetValue()
+	}
+	return 0
+}
+
+func Test_AnalyticsQueue_DropOldest(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.QueueSize = 2
+	conf.Analytics.QueuePolicy = telemetry.AnalyticsQueueDropOldest
+	sink := newStalledSink()
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithAnalyticsSink(sink), telemetry.WithClock(newFakeClock()))
+	droppedBefore := droppedEvents(t)
+
+	sendQueueEvent(sut, "e1")
+	<-sink.stalled
+	sendQueueEvent(sut, "e2")
+	sendQueueEvent(sut, "e3")
+	sendQueueEvent(sut, "e4")
+
+	close(sink.released)
+	flushEvents(sut)
+
+	require.Equal(t, []string{"e1", "e3", "e4"}, sink.received())
+	require.Equal(t, droppedBefore+1, droppedEvents(t))
+}
+
+func Test_AnalyticsQueue_DropNewest(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.QueueSize = 2
+	conf.Analytics.QueuePolicy = telemetry.AnalyticsQueueDropNewest
+	sink := newStalledSink()
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithAnalyticsSink(sink), telemetry.WithClock(newFakeClock()))
+	droppedBefore := droppedEvents(t)
+
+	sendQueueEvent(sut, "e1")
+	<-sink.stalled
+	sendQueueEvent(sut, "e2")
+	sendQueueEvent(sut, "e3")
+	sendQueueEvent(sut, "e4")
+
+	close(sink.released)
+	flushEvents(sut)
+
+	require.Equal(t, []string{"e1", "e2", "e3"}, sink.received())
+	require.Equal(t, droppedBefore+1, droppedEvents(t))
+}
+
+func Test_AnalyticsQueue_Block(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.QueueSize = 2
+	conf.Analytics.QueuePolicy = telemetry.AnalyticsQueueBlock
+	sink := newStalledSink()
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithAnalyticsSink(sink), telemetry.WithClock(newFakeClock()))
+	droppedBefore := droppedEvents(t)
+
+	sendQueueEvent(sut, "e1")
+	<-sink.stalled
+	sendQueueEvent(sut, "e2")
+	sendQueueEvent(sut, "e3")
+
+	sent := make(chan struct{})
+	go func() {
+		sendQueueEvent(sut, "e4")
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		require.Fail(t, "send should block while the queue is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(sink.released)
+	<-sent
+	flushEvents(sut)
+
+	require.Equal(t, []string{"e1", "e2", "e3", "e4"}, sink.received())
+	require.Equal(t, droppedBefore, droppedEvents(t))
+}
+
+func Test_AnalyticsQueue_SendsQueuedOnShutdown(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.QueueSize = 2
+	conf.Analytics.QueuePolicy = telemetry.AnalyticsQueueDropOldest
+	sink := newStalledSink()
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithAnalyticsSink(sink), telemetry.WithClock(newFakeClock()))
+
+	sendQueueEvent(sut, "e1")
+	<-sink.stalled
+	sendQueueEvent(sut, "e2")
+
+	close(sink.released)
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, []string{"e1", "e2"}, sink.received())
+
+	// sent straight to the sink once the queue is closed
+	sendQueueEvent(sut, "e3")
+	require.Equal(t, []string{"e1", "e2", "e3"}, sink.received())
+}
+
+func Test_AnalyticsQueue_StalledSinkDoesNotHoldUpStats(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomEndDrainTimeout = 0
+	sink := newStalledSink()
+	clock := newFakeClock()
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithAnalyticsSink(sink), telemetry.WithClock(clock))
+
+	room := &livekit.Room{Sid: "RM_stalled", Name: "stalled"}
+	partSID := livekit.ParticipantID("PA_stalled")
+	sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+	sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_UPSTREAM, partSID, "trackID"), &livekit.AnalyticsStat{
+		Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 10, PrimaryPackets: 1}},
+	})
+	// held up by the join
+	<-sink.stalled
+
+	// the stats are queued behind the stalled event, the run goroutine goes on with other jobs
+	clock.Advance(conf.Analytics.StatsInterval)
+	require.Eventually(t, func() bool {
+		stats, ok := sut.GetParticipantStats(partSID)
+		return ok && !stats.SampledAt.IsZero()
+	}, time.Second, time.Millisecond)
+	sut.RoomEnded(context.Background(), room)
+	require.Eventually(t, func() bool {
+		_, ok := sut.GetParticipantStats(partSID)
+		return !ok
+	}, time.Second, time.Millisecond)
+
+	close(sink.released)
+}
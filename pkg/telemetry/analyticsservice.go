@@ -34,6 +34,28 @@ type AnalyticsService interface {
 	SendNodeRoomStates(ctx context.Context, nodeRooms *livekit.AnalyticsNodeRooms)
 }
 
+// AnalyticsSink receives the analytics events and stats produced by the telemetry service.
+// the AnalyticsService given to the telemetry service is used unless another sink is set with WithAnalyticsSink
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . AnalyticsSink
+type AnalyticsSink interface {
+	SendEvent(ctx context.Context, event *livekit.AnalyticsEvent)
+	SendStats(ctx context.Context, stats []*livekit.AnalyticsStat)
+}
+
+// AnalyticsBatchService is implemented by analytics services that can send several events at once.
+// when the AnalyticsSink used by the telemetry service implements it, events are buffered and sent in batches
+type AnalyticsBatchService interface {
+	SendEvents(ctx context.Context, events []*livekit.AnalyticsEvent)
+}
+
+// AnalyticsRetryService is implemented by analytics sinks that report failed sends. when the AnalyticsSink used by
+// the telemetry service implements it and an AnalyticsBuffer is set with WithAnalyticsBuffer, events that fail to
+// send are kept on disk and sent again once the sink is back. stats are never buffered
+type AnalyticsRetryService interface {
+	TrySendEvents(ctx context.Context, events []*livekit.AnalyticsEvent) error
+}
+
 type analyticsService struct {
 	analyticsKey   string
 	nodeID         string
@@ -78,6 +100,25 @@ func (a *analyticsService) SendEvent(_ context.Context, event *livekit.Analytics
 	}
 }
 
+func (a *analyticsService) SendEvents(ctx context.Context, events []*livekit.AnalyticsEvent) {
+	if err := a.TrySendEvents(ctx, events); err != nil {
+		logger.Errorw("failed to send events", err, "count", len(events))
+	}
+}
+
+func (a *analyticsService) TrySendEvents(_ context.Context, events []*livekit.AnalyticsEvent) error {
+	if a.events == nil {
+		return nil
+	}
+
+	for _, event := range events {
+		event.AnalyticsKey = a.analyticsKey
+	}
+	return a.events.Send(&livekit.AnalyticsEvents{
+		Events: events,
+	})
+}
+
 func (a *analyticsService) SendNodeRoomStates(_ context.Context, nodeRooms *livekit.AnalyticsNodeRooms) {
 	if a.nodeRooms == nil {
 		return
@@ -0,0 +1,97 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// optional fields of analytics events, in the order they are dropped from events that are too large.
+// metadata goes first as it's set by users and unbounded, the ids of the room, participant and track are kept
+var truncatableAnalyticsFields = []func(event *livekit.AnalyticsEvent){
+	func(event *livekit.AnalyticsEvent) {
+		if event.Room != nil {
+			event.Room.Metadata = ""
+		}
+	},
+	func(event *livekit.AnalyticsEvent) {
+		if event.Participant != nil {
+			event.Participant.Metadata = ""
+		}
+	},
+	func(event *livekit.AnalyticsEvent) {
+		if event.Publisher != nil {
+			event.Publisher.Metadata = ""
+		}
+	},
+	func(event *livekit.AnalyticsEvent) {
+		event.RtpStats = nil
+	},
+	func(event *livekit.AnalyticsEvent) {
+		event.ClientInfo = nil
+	},
+	func(event *livekit.AnalyticsEvent) {
+		event.Egress = nil
+		event.Ingress = nil
+	},
+	func(event *livekit.AnalyticsEvent) {
+		event.Publisher = nil
+		event.Track = nil
+	},
+	func(event *livekit.AnalyticsEvent) {
+		if event.ParticipantId == "" {
+			event.ParticipantId = event.Participant.GetSid()
+		}
+		event.Participant = nil
+	},
+	func(event *livekit.AnalyticsEvent) {
+		if event.RoomId == "" {
+			event.RoomId = event.Room.GetSid()
+		}
+		event.Room = nil
+	},
+}
+
+// withMaxSize returns event with its optional fields dropped until it fits the maximum event size, or event itself
+// when it already fits. fields are dropped from a copy, the room and participant may be shared with the caller.
+// an event still too large once all of them are dropped is sent as it is. AnalyticsEvent has no field to mark
+// truncated events, they are counted and logged instead
+func (t *telemetryService) withMaxSize(event *livekit.AnalyticsEvent) *livekit.AnalyticsEvent {
+	if t.maxEventSize <= 0 || proto.Size(event) <= t.maxEventSize {
+		return event
+	}
+
+	truncated := proto.Clone(event).(*livekit.AnalyticsEvent)
+	for _, truncateField := range truncatableAnalyticsFields {
+		truncateField(truncated)
+		if proto.Size(truncated) <= t.maxEventSize {
+			break
+		}
+	}
+	prometheus.RecordAnalyticsTruncated(analyticsEventLabel(event.Type))
+	logger.Debugw("truncated analytics event",
+		"type", analyticsEventLabel(event.Type),
+		"roomID", event.RoomId,
+		"participantID", event.ParticipantId,
+		"size", proto.Size(event),
+		"truncatedSize", proto.Size(truncated),
+	)
+	return truncated
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func truncatedEvents(t *testing.T, eventType string) float64 {
+	return findMetric(t, "livekit_telemetry_analytics_truncated_total", map[string]string{"type": eventType}).GetCounter().GetValue()
+}
+
+func Test_SendEvent_TruncatedToMaxSize(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.MaxEventSize = 1024
+	fixture := createFixtureWithConfig(conf)
+	truncated := truncatedEvents(t, "ROOM_CREATED")
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName", Metadata: strings.Repeat("m", 4096)}
+	fixture.sut.RoomStarted(context.Background(), room)
+	flushEvents(fixture.sut)
+
+	events := findAnalyticsEvents(fixture, livekit.AnalyticsEventType_ROOM_CREATED)
+	require.Len(t, events, 1)
+	require.LessOrEqual(t, proto.Size(events[0]), 1024)
+	require.Empty(t, events[0].Error)
+	// only the metadata had to go
+	require.Empty(t, events[0].Room.Metadata)
+	require.Equal(t, room.Sid, events[0].Room.Sid)
+	require.Equal(t, room.Name, events[0].Room.Name)
+	// the caller's room is left alone
+	require.Len(t, room.Metadata, 4096)
+	require.Equal(t, truncated+1, truncatedEvents(t, "ROOM_CREATED"))
+
+	// events that fit are sent as they are
+	fixture.sut.RoomEnded(context.Background(), &livekit.Room{Sid: "RoomSid", Metadata: "small"})
+	flushEvents(fixture.sut)
+
+	events = findAnalyticsEvents(fixture, livekit.AnalyticsEventType_ROOM_ENDED)
+	require.Len(t, events, 1)
+	require.Empty(t, events[0].Error)
+	require.Equal(t, "small", events[0].Room.Metadata)
+}
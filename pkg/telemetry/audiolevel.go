@@ -0,0 +1,117 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// audioActivity summarizes the audio levels observed for a participant since the last summary was sent
+type audioActivity struct {
+	startedAt time.Time
+	samples   int
+	levelSum  float64
+	// samples in which the participant was speaking
+	activeSamples int
+
+	// latest sample, counted in livekit_participant_speaking while speaking
+	speaking bool
+}
+
+func (t *telemetryService) ParticipantAudioLevel(participantID livekit.ParticipantID, level float64, active bool) {
+	// called for every participant a few times a second, so recorded here rather than queuing a job for each
+	if t.audioLevelInterval <= 0 {
+		return
+	}
+	worker, ok := t.getWorker(participantID)
+	if !ok {
+		return
+	}
+	worker.observeAudioLevel(level, active, t.clock.Now())
+}
+
+// observeAudioLevel adds a sample to the current summary
+func (s *StatsWorker) observeAudioLevel(level float64, active bool, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.closedAt.IsZero() {
+		return
+	}
+	a := &s.audio
+	if a.samples == 0 {
+		a.startedAt = now
+	}
+	a.samples++
+	a.levelSum += level
+	if active {
+		a.activeSamples++
+	}
+
+	// recorded with the lock held, so a worker being closed can't leave the participant counted
+	if active != a.speaking {
+		if active {
+			prometheus.AddParticipantSpeaking()
+		} else {
+			prometheus.SubParticipantSpeaking()
+		}
+	}
+	a.speaking = active
+}
+
+// takeAudioActivity returns the summary of the samples observed since it was last called, false when there were none
+func (s *StatsWorker) takeAudioActivity() (audioActivity, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	activity := s.audio
+	s.audio = audioActivity{speaking: activity.speaking}
+	return activity, activity.samples != 0
+}
+
+// flushAudioLevels records the participants' audio level summaries, each classified as speaking when the participant
+// spoke at any point since the last summary, and logs a sample of them. must be called from the run goroutine
+func (t *telemetryService) flushAudioLevels() {
+	now := t.clock.Now()
+	for _, worker := range t.allWorkers() {
+		activity, ok := worker.takeAudioActivity()
+		if !ok {
+			continue
+		}
+
+		speaking := activity.activeSamples > 0
+		level := activity.levelSum / float64(activity.samples)
+		prometheus.RecordParticipantAudioLevel(speaking, level)
+
+		if t.audioLevelSampleRate <= 0 || rand.Float64() >= t.audioLevelSampleRate {
+			continue
+		}
+		logger.Debugw("participant audio level",
+			"room", worker.roomName,
+			"roomID", worker.roomID,
+			"participant", worker.participantIdentity,
+			"pID", worker.participantID,
+			"speaking", speaking,
+			"level", level,
+			"duration", now.Sub(activity.startedAt),
+		)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func Test_ParticipantAudioLevel(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.AudioLevelInterval = 10 * time.Second
+	conf.Analytics.AudioLevelSampleRate = 1
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_speaking", Identity: "speaker"}
+	before := speakingParticipants(t)
+	speaking, silent := audioLevels(t, "speaking"), audioLevels(t, "silent")
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	flushEvents(fixture.sut)
+
+	fixture.sut.ParticipantAudioLevel(livekit.ParticipantID(participant.Sid), 0.4, true)
+	fixture.sut.ParticipantAudioLevel(livekit.ParticipantID(participant.Sid), 0.2, true)
+	require.Equal(t, before+1, speakingParticipants(t))
+
+	clock.Advance(conf.Analytics.AudioLevelInterval)
+	require.Eventually(t, func() bool {
+		return audioLevels(t, "speaking").GetSampleCount() == speaking.GetSampleCount()+1
+	}, time.Second, time.Millisecond)
+	require.InDelta(t, 0.3, audioLevels(t, "speaking").GetSampleSum()-speaking.GetSampleSum(), 0.001)
+
+	fixture.sut.ParticipantAudioLevel(livekit.ParticipantID(participant.Sid), 0, false)
+	require.Equal(t, before, speakingParticipants(t))
+	clock.Advance(conf.Analytics.AudioLevelInterval)
+	require.Eventually(t, func() bool {
+		return audioLevels(t, "silent").GetSampleCount() == silent.GetSampleCount()+1
+	}, time.Second, time.Millisecond)
+
+	// nothing is recorded without new samples, and a participant that leaves while speaking is no longer counted
+	clock.Advance(conf.Analytics.AudioLevelInterval)
+	flushEvents(fixture.sut)
+	require.Equal(t, speaking.GetSampleCount()+1, audioLevels(t, "speaking").GetSampleCount())
+	require.Equal(t, silent.GetSampleCount()+1, audioLevels(t, "silent").GetSampleCount())
+	fixture.sut.ParticipantAudioLevel(livekit.ParticipantID(participant.Sid), 0.4, true)
+	require.Equal(t, before+1, speakingParticipants(t))
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	flushEvents(fixture.sut)
+	require.Equal(t, before, speakingParticipants(t))
+	fixture.sut.ParticipantAudioLevel(livekit.ParticipantID(participant.Sid), 0.4, true)
+	require.Equal(t, before, speakingParticipants(t))
+
+	// summaries are not sent as analytics events
+	for i := 0; i < fixture.analytics.SendEventCallCount(); i++ {
+		_, event := fixture.analytics.SendEventArgsForCall(i)
+		require.Contains(t, []livekit.AnalyticsEventType{
+			livekit.AnalyticsEventType_PARTICIPANT_JOINED,
+			livekit.AnalyticsEventType_PARTICIPANT_LEFT,
+		}, event.Type)
+	}
+}
+
+func Test_ParticipantAudioLevel_Disabled(t *testing.T) {
+	fixture := createFixture()
+	before := speakingParticipants(t)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_disabled"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	fixture.sut.ParticipantAudioLevel(livekit.ParticipantID(participant.Sid), 0.5, true)
+
+	require.Equal(t, before, speakingParticipants(t))
+}
+
+func speakingParticipants(t *testing.T) float64 {
+	if metric := findMetric(t, "livekit_participant_speaking", nil); metric != nil {
+		return metric.GetGauge().GetValue()
+	}
+	return 0
+}
+
+// audioLevels returns the audio level summaries recorded for state, empty when there are none
+func audioLevels(t *testing.T, state string) *dto.Histogram {
+	return findMetric(t, "livekit_participant_audio_level", map[string]string{"state": state}).GetHistogram()
+}
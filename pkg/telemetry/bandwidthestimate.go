@@ -0,0 +1,43 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func (t *telemetryService) BandwidthEstimate(_ context.Context, participantID livekit.ParticipantID, bps int64) {
+	// called from congestion control on every committed estimate, so recorded here rather than queuing a job
+	if worker, ok := t.getWorker(participantID); ok {
+		worker.setBandwidthEstimate(bps)
+	}
+}
+
+// setBandwidthEstimate keeps the latest estimate, ignoring it once the participant has left
+func (s *StatsWorker) setBandwidthEstimate(bps int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// recorded with the lock held, so a worker being closed can't have its gauge recreated
+	if !s.closedAt.IsZero() {
+		return
+	}
+	s.hasBandwidthEstimate = true
+	prometheus.RecordBandwidthEstimate(string(s.participantID), bps)
+}
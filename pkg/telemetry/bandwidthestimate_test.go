@@ -0,0 +1,51 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func Test_BandwidthEstimate(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_bwe", Identity: "subscriber"}
+	participantID := livekit.ParticipantID(participant.Sid)
+	labels := map[string]string{"participant_id": participant.Sid}
+
+	// not recorded for participants that aren't on this node
+	fixture.sut.BandwidthEstimate(context.Background(), participantID, 1_000_000)
+	require.Nil(t, findMetric(t, "livekit_participant_bandwidth_estimate_bps", labels))
+
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	flushEvents(fixture.sut)
+
+	fixture.sut.BandwidthEstimate(context.Background(), participantID, 2_000_000)
+	fixture.sut.BandwidthEstimate(context.Background(), participantID, 1_500_000)
+	require.Equal(t, float64(1_500_000), findMetric(t, "livekit_participant_bandwidth_estimate_bps", labels).GetGauge().GetValue())
+
+	// the gauge goes once the participant leaves, and isn't brought back by a late estimate
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	flushEvents(fixture.sut)
+	require.Nil(t, findMetric(t, "livekit_participant_bandwidth_estimate_bps", labels))
+	fixture.sut.BandwidthEstimate(context.Background(), participantID, 3_000_000)
+	require.Nil(t, findMetric(t, "livekit_participant_bandwidth_estimate_bps", labels))
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"github.com/livekit/protocol/livekit"
+)
+
+// a track as published or subscribed by a participant
+type trackDirection struct {
+	trackID   livekit.TrackID
+	direction livekit.StreamType
+}
+
+// SmoothBitrate returns the exponentially weighted moving average of bitrate after sample, alpha being the weight
+// of sample. an alpha outside of (0, 1] does no smoothing
+func SmoothBitrate(alpha float64, smoothed float64, sample float64) float64 {
+	if alpha <= 0 || alpha >= 1 {
+		return sample
+	}
+	return alpha*sample + (1-alpha)*smoothed
+}
+
+// smoothBitratesLocked sets the smoothed bitrate of each track in the latest sample. a track starts from its first
+// bitrate, and starts over once it has been missing from a sample
+func (s *StatsWorker) smoothBitratesLocked(tracks []ParticipantTrackStats) {
+	smoothed := make(map[trackDirection]float64, len(tracks))
+	for i := range tracks {
+		track := &tracks[i]
+		key := trackDirection{trackID: track.TrackID, direction: track.Direction}
+		if prev, ok := s.smoothedBitrates[key]; ok {
+			track.SmoothedBitrate = SmoothBitrate(s.bitrateAlpha, prev, track.Bitrate)
+		} else {
+			track.SmoothedBitrate = track.Bitrate
+		}
+		smoothed[key] = track.SmoothedBitrate
+	}
+	s.smoothedBitrates = smoothed
+}
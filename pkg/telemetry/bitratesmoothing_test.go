@@ -0,0 +1,102 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func Test_SmoothBitrate(t *testing.T) {
+	t.Run("constant input", func(t *testing.T) {
+		smoothed := 5000.0
+		for i := 0; i < 10; i++ {
+			smoothed = telemetry.SmoothBitrate(0.3, smoothed, 5000)
+			require.InDelta(t, 5000, smoothed, 1e-9)
+		}
+	})
+
+	t.Run("step input", func(t *testing.T) {
+		const alpha = 0.3
+		smoothed := 0.0
+		for i := 1; i <= 20; i++ {
+			prev := smoothed
+			smoothed = telemetry.SmoothBitrate(alpha, smoothed, 1000)
+			// closes the same fraction of the gap every step, never overshooting
+			require.InDelta(t, 1000*(1-math.Pow(1-alpha, float64(i))), smoothed, 1e-9)
+			require.Greater(t, smoothed, prev)
+			require.LessOrEqual(t, smoothed, 1000.0)
+		}
+		require.InDelta(t, 1000, smoothed, 1)
+	})
+
+	t.Run("no smoothing", func(t *testing.T) {
+		require.Equal(t, 1000.0, telemetry.SmoothBitrate(1, 0, 1000))
+		require.Equal(t, 1000.0, telemetry.SmoothBitrate(0, 0, 1000))
+	})
+}
+
+func Test_ParticipantStats_SmoothedBitrate(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	// flushed by the test, on the fake clock
+	conf.Analytics.StatsInterval = time.Hour
+	conf.Analytics.BitrateSmoothingAlpha = 0.5
+	conf.Analytics.QoSScore = config.QoSScoreConfig{LossWeight: 40, JitterWeight: 20, RTTWeight: 20, FreezeWeight: 20}
+
+	clock := newFakeClock()
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithClock(clock))
+
+	room := &livekit.Room{Sid: "RM_smoothing", Name: "smoothing"}
+	partSID := livekit.ParticipantID("PA_smoothing")
+	sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, partSID, "TR_video", livekit.TrackSource_CAMERA, livekit.TrackType_VIDEO)
+
+	// a second of media at bytesPerSecond
+	sample := func(bytesPerSecond uint64) telemetry.ParticipantTrackStats {
+		sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: bytesPerSecond}}})
+		flushEvents(sut)
+		clock.Advance(time.Second)
+		sut.FlushStats()
+
+		stats, ok := sut.GetParticipantStats(partSID)
+		require.True(t, ok)
+		require.Len(t, stats.Tracks, 1)
+		return stats.Tracks[0]
+	}
+
+	// starts from the first bitrate and holds while it is constant
+	for i := 0; i < 3; i++ {
+		track := sample(1000)
+		require.InDelta(t, 8000, track.Bitrate, 1e-6)
+		require.InDelta(t, 8000, track.SmoothedBitrate, 1e-6)
+	}
+
+	// then moves halfway to a new bitrate every interval, the raw bitrate follows right away
+	for _, expected := range []float64{12000, 14000, 15000, 15500} {
+		track := sample(2000)
+		require.InDelta(t, 16000, track.Bitrate, 1e-6)
+		require.InDelta(t, expected, track.SmoothedBitrate, 1e-6)
+	}
+}
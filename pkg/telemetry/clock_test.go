@@ -0,0 +1,218 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+// fakeClock only moves when advanced, firing the timers and tickers that are due
+type fakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	tickers []*fakeTicker
+}
+
+type fakeTimer struct {
+	clock  *fakeClock
+	c      chan time.Time
+	f      func()
+	fireAt time.Time
+	active bool
+}
+
+type fakeTicker struct {
+	clock  *fakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+	active bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) telemetry.Timer {
+	return c.addTimer(d, nil)
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) telemetry.Timer {
+	return c.addTimer(d, f)
+}
+
+func (c *fakeClock) addTimer(d time.Duration, f func()) *fakeTimer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	timer := &fakeTimer{clock: c, c: make(chan time.Time, 1), f: f, fireAt: c.now.Add(d), active: true}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) telemetry.Ticker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ticker := &fakeTicker{clock: c, c: make(chan time.Time, 1), period: d, next: c.now.Add(d), active: true}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// Advance moves the clock forward by d, running the funcs of the timers that fire before returning. like real
+// tickers, a ticker that isn't read from drops ticks
+func (c *fakeClock) Advance(d time.Duration) {
+	var funcs []func()
+	defer func() {
+		for _, f := range funcs {
+			f()
+		}
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	for _, timer := range c.timers {
+		if timer.active && !timer.fireAt.After(c.now) {
+			timer.active = false
+			if timer.f != nil {
+				funcs = append(funcs, timer.f)
+			} else {
+				timer.c <- c.now
+			}
+		}
+	}
+	for _, ticker := range c.tickers {
+		for ticker.active && !ticker.next.After(c.now) {
+			select {
+			case ticker.c <- c.now:
+			default:
+			}
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+}
+
+// pending returns the number of timers that haven't fired or been stopped
+func (c *fakeClock) pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	pending := 0
+	for _, timer := range c.timers {
+		if timer.active {
+			pending++
+		}
+	}
+	return pending
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	t.active = false
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	active := t.active
+	t.active = true
+	t.fireAt = t.clock.now.Add(d)
+	return active
+}
+
+func Test_WithClock_WebhookRetriesWaitOnClock(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.MaxRetries = 1
+	conf.WebHook.RetryBaseDelay = time.Minute
+
+	clock := newFakeClock()
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyReturnsOnCall(0, errors.New("failed"))
+	sut := telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{notifier}, &telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithClock(clock),
+	)
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	require.Eventually(t, func() bool {
+		return notifier.NotifyCallCount() == 1 && clock.pending() == 1
+	}, time.Second, time.Millisecond)
+
+	// the backoff is at least the base delay
+	clock.Advance(59 * time.Second)
+	require.Equal(t, 1, clock.pending())
+	require.Equal(t, 1, notifier.NotifyCallCount())
+
+	// and at most a quarter more
+	clock.Advance(time.Minute)
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, 2, notifier.NotifyCallCount())
+}
+
+func Test_WithClock_IdleWorkerIsReapedByClock(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.WorkerIdleTimeout = 20 * time.Millisecond
+
+	clock := newFakeClock()
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithClock(clock))
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+	flushEvents(sut)
+	_, ok := sut.GetParticipantStats(partSID)
+	require.True(t, ok)
+
+	clock.Advance(conf.Analytics.WorkerIdleTimeout + time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, ok := sut.GetParticipantStats(partSID)
+		return !ok
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// media of tracks, or streams of a track, whose codec isn't known is attributed to this codec
+const unknownCodec = "unknown"
+
+// trackCodecs are the codecs of a track, from its TrackInfo. streams of simulcast codecs are told apart by their
+// SSRC, anything else is attributed to the primary codec
+type trackCodecs struct {
+	mime   string
+	bySSRC map[uint32]string
+}
+
+func newTrackCodecs(track *livekit.TrackInfo) *trackCodecs {
+	codecs := &trackCodecs{
+		mime:   trackMime(track),
+		bySSRC: make(map[uint32]string),
+	}
+	if codecs.mime == "" {
+		codecs.mime = unknownCodec
+	}
+	for _, simulcastCodec := range track.Codecs {
+		if simulcastCodec.MimeType == "" {
+			continue
+		}
+		for _, layer := range simulcastCodec.Layers {
+			if layer.Ssrc != 0 {
+				codecs.bySSRC[layer.Ssrc] = simulcastCodec.MimeType
+			}
+		}
+	}
+	return codecs
+}
+
+func (c *trackCodecs) streamMime(ssrc uint32) string {
+	if c == nil {
+		return unknownCodec
+	}
+	if mime, ok := c.bySSRC[ssrc]; ok {
+		return mime
+	}
+	return c.mime
+}
+
+// trackMime returns the mime type of the track, for simulcast codecs the primary one
+func trackMime(track *livekit.TrackInfo) string {
+	if len(track.Codecs) > 0 && track.Codecs[0].MimeType != "" {
+		return track.Codecs[0].MimeType
+	}
+	return track.MimeType
+}
+
+// mimeCodec returns the codec of a mime type, e.g. vp8 for video/VP8
+func mimeCodec(mime string) string {
+	if i := strings.IndexByte(mime, '/'); i >= 0 {
+		mime = mime[i+1:]
+	}
+	return strings.ToLower(mime)
+}
+
+// SetTrackCodecs records the codecs of a track the participant publishes or subscribes to, so that the stats of its
+// media are broken down by codec. called before the track's stats are received, as they are attributed to a codec
+// when they are
+func (s *StatsWorker) SetTrackCodecs(track *livekit.TrackInfo, direction livekit.StreamType) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.closedAt.IsZero() {
+		return
+	}
+	s.trackCodecs[trackDirection{trackID: livekit.TrackID(track.Sid), direction: direction}] = newTrackCodecs(track)
+}
+
+// ClearTrackCodecs forgets the codecs of a track the participant no longer publishes or subscribes to
+func (s *StatsWorker) ClearTrackCodecs(trackID livekit.TrackID, direction livekit.StreamType) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.trackCodecs, trackDirection{trackID: trackID, direction: direction})
+}
+
+// splitByCodec splits the streams of a track's stat by the codec they carry, with Mime set to the codec of each
+func (s *StatsWorker) splitByCodec(key StatsKey, stat *livekit.AnalyticsStat) []*livekit.AnalyticsStat {
+	s.lock.RLock()
+	codecs := s.trackCodecs[trackDirection{trackID: key.trackID, direction: key.streamType}]
+	s.lock.RUnlock()
+
+	var split []*livekit.AnalyticsStat
+	for _, stream := range stat.Streams {
+		mime := codecs.streamMime(stream.Ssrc)
+		var codecStat *livekit.AnalyticsStat
+		for _, cs := range split {
+			if cs.Mime == mime {
+				codecStat = cs
+				break
+			}
+		}
+		if codecStat == nil {
+			codecStat = &livekit.AnalyticsStat{Score: stat.Score, Mime: mime}
+			split = append(split, codecStat)
+		}
+		codecStat.Streams = append(codecStat.Streams, stream)
+	}
+	if len(split) == 0 {
+		split = append(split, &livekit.AnalyticsStat{Score: stat.Score, Mime: codecs.streamMime(0)})
+	}
+	return split
+}
+
+// mergeCodecStats returns the stats of each track from the stats of each codec of the tracks, as sent. a track whose
+// media was split across codecs in the interval is coalesced again
+func mergeCodecStats(codecStats []*livekit.AnalyticsStat) []*livekit.AnalyticsStat {
+	type trackKey struct {
+		trackID string
+		kind    livekit.StreamType
+	}
+	perTrack := make(map[trackKey][]*livekit.AnalyticsStat, len(codecStats))
+	stats := make([]*livekit.AnalyticsStat, 0, len(codecStats))
+	for _, stat := range codecStats {
+		key := trackKey{trackID: stat.TrackId, kind: stat.Kind}
+		if len(perTrack[key]) == 0 {
+			stats = append(stats, stat)
+		}
+		perTrack[key] = append(perTrack[key], stat)
+	}
+
+	for i, stat := range stats {
+		split := perTrack[trackKey{trackID: stat.TrackId, kind: stat.Kind}]
+		if len(split) == 1 {
+			continue
+		}
+		merged := coalesce(split)
+		merged.TimeStamp = stat.TimeStamp
+		merged.TrackId = stat.TrackId
+		merged.Kind = stat.Kind
+		merged.RoomId = stat.RoomId
+		merged.ParticipantId = stat.ParticipantId
+		merged.RoomName = stat.RoomName
+		stats[i] = merged
+	}
+	return stats
+}
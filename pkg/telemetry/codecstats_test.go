@@ -0,0 +1,96 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// codecBytes sums the bytes of the stats sent for participantID, by mime type
+func codecBytes(fixture *telemetryServiceFixture, participantID string) map[string]uint64 {
+	bytes := make(map[string]uint64)
+	for i := 0; i < fixture.analytics.SendStatsCallCount(); i++ {
+		_, stats := fixture.analytics.SendStatsArgsForCall(i)
+		for _, stat := range stats {
+			if stat.ParticipantId != participantID {
+				continue
+			}
+			for _, stream := range stat.Streams {
+				bytes[stat.Mime] += stream.PrimaryBytes
+			}
+		}
+	}
+	return bytes
+}
+
+func Test_CodecStats(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RM_codecs", Name: "codecs"}
+	publisher, subscriber := livekit.ParticipantID("PA_codecs_pub"), livekit.ParticipantID("PA_codecs_sub")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(publisher)}, nil, nil, true)
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(subscriber)}, nil, nil, true)
+
+	// published with a backup codec, the streams of each are told apart by their SSRC
+	track := &livekit.TrackInfo{
+		Sid:      "TR_codecs",
+		Type:     livekit.TrackType_VIDEO,
+		MimeType: "video/VP8",
+		Codecs: []*livekit.SimulcastCodecInfo{
+			{MimeType: "video/VP8", Layers: []*livekit.VideoLayer{{Ssrc: 111}}},
+			{MimeType: "video/AV1", Layers: []*livekit.VideoLayer{{Ssrc: 222}}},
+		},
+	}
+	fixture.sut.TrackPublished(context.Background(), publisher, "", track)
+	// subscribed without knowing its codec
+	fixture.sut.TrackSubscribed(context.Background(), subscriber, &livekit.TrackInfo{Sid: track.Sid, Type: track.Type}, nil, false)
+
+	// the codecs are known as soon as the track is published, without waiting for the events to be processed
+	upstream := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, publisher, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	downstream := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, subscriber, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	fixture.sut.TrackStats(upstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{
+		{Ssrc: 111, PrimaryPackets: 10, PrimaryBytes: 1000},
+		{Ssrc: 222, PrimaryPackets: 10, PrimaryBytes: 3000},
+		// not one the track info lists, attributed to the primary codec
+		{Ssrc: 333, PrimaryPackets: 1, PrimaryBytes: 100},
+	}})
+	fixture.sut.TrackStats(downstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{
+		{Ssrc: 444, PrimaryPackets: 5, PrimaryBytes: 500},
+	}})
+	fixture.flush()
+
+	require.Equal(t, map[string]uint64{"video/VP8": 1100, "video/AV1": 3000}, codecBytes(fixture, string(publisher)))
+	require.Equal(t, map[string]uint64{"unknown": 500}, codecBytes(fixture, string(subscriber)))
+	_, stats := fixture.analytics.SendStatsArgsForCall(0)
+	for _, stat := range stats {
+		require.Equal(t, track.Sid, stat.TrackId)
+		require.Equal(t, room.Sid, stat.RoomId)
+		// one coalesced stream per codec
+		require.Len(t, stat.Streams, 1)
+	}
+
+	// sampled once per track, across its codecs
+	sample, ok := fixture.sut.GetParticipantStats(publisher)
+	require.True(t, ok)
+	require.Len(t, sample.Tracks, 1)
+	require.Equal(t, livekit.TrackID(track.Sid), sample.Tracks[0].TrackID)
+}
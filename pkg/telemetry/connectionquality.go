@@ -0,0 +1,100 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// loss is a fraction of packets expected, rtt is in ms and jitter in us, as reported in AnalyticsStream
+	excellentMaxLoss   = 0.02
+	excellentMaxRtt    = 300
+	excellentMaxJitter = 30_000
+	poorMinLoss        = 0.10
+	poorMinRtt         = 700
+	poorMinJitter      = 100_000
+
+	// number of consecutive flushes a new quality has to be seen for before it is reported
+	qualityChangeSamples = 2
+)
+
+// connectionQuality rolls loss, jitter and rtt across all of a participant's streams into a quality bucket,
+// rating by the worst of the three. returns false when there is nothing to rate
+func connectionQuality(stats []*livekit.AnalyticsStat) (livekit.ConnectionQuality, bool) {
+	var packets, lost uint64
+	var maxRtt, maxJitter uint32
+	for _, stat := range stats {
+		for _, stream := range stat.Streams {
+			packets += uint64(stream.PrimaryPackets)
+			lost += uint64(stream.PacketsLost)
+			if stream.Rtt > maxRtt {
+				maxRtt = stream.Rtt
+			}
+			if stream.Jitter > maxJitter {
+				maxJitter = stream.Jitter
+			}
+		}
+	}
+	if packets+lost == 0 {
+		return livekit.ConnectionQuality_EXCELLENT, false
+	}
+
+	loss := float64(lost) / float64(packets+lost)
+	switch {
+	case loss >= poorMinLoss || maxRtt >= poorMinRtt || maxJitter >= poorMinJitter:
+		return livekit.ConnectionQuality_POOR, true
+	case loss <= excellentMaxLoss && maxRtt <= excellentMaxRtt && maxJitter <= excellentMaxJitter:
+		return livekit.ConnectionQuality_EXCELLENT, true
+	default:
+		return livekit.ConnectionQuality_GOOD, true
+	}
+}
+
+// qualityTracker reports a connection quality change only once the new quality has held for
+// qualityChangeSamples samples, so borderline links don't flap between buckets
+type qualityTracker struct {
+	quality    livekit.ConnectionQuality
+	hasQuality bool
+
+	pending      livekit.ConnectionQuality
+	pendingCount int
+}
+
+// update records a sample and returns true when the reported quality changes
+func (q *qualityTracker) update(quality livekit.ConnectionQuality) bool {
+	if !q.hasQuality {
+		q.quality = quality
+		q.hasQuality = true
+		return true
+	}
+	if quality == q.quality {
+		q.pendingCount = 0
+		return false
+	}
+
+	if quality != q.pending {
+		q.pending = quality
+		q.pendingCount = 0
+	}
+	q.pendingCount++
+	if q.pendingCount < qualityChangeSamples {
+		return false
+	}
+
+	q.quality = quality
+	q.pendingCount = 0
+	return true
+}
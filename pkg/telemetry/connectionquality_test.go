@@ -0,0 +1,91 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_ConnectionQualityChanged(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, "trackID", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	sendStat := func(lost uint32) {
+		fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{
+			PrimaryPackets: 100 - lost,
+			PacketsLost:    lost,
+			Rtt:            50,
+		}}})
+		fixture.flush()
+	}
+	excellent := participantsOfQuality(t, livekit.ConnectionQuality_EXCELLENT)
+	poor := participantsOfQuality(t, livekit.ConnectionQuality_POOR)
+
+	// first rating is counted right away
+	sendStat(0)
+	require.Equal(t, excellent+1, participantsOfQuality(t, livekit.ConnectionQuality_EXCELLENT))
+
+	// a single poor sample is not enough to change it
+	sendStat(20)
+	sendStat(0)
+	require.Equal(t, excellent+1, participantsOfQuality(t, livekit.ConnectionQuality_EXCELLENT))
+	require.Equal(t, poor, participantsOfQuality(t, livekit.ConnectionQuality_POOR))
+
+	// but a sustained one is
+	sendStat(20)
+	sendStat(20)
+	require.Equal(t, excellent, participantsOfQuality(t, livekit.ConnectionQuality_EXCELLENT))
+	require.Equal(t, poor+1, participantsOfQuality(t, livekit.ConnectionQuality_POOR))
+}
+
+func participantsOfQuality(t *testing.T, quality livekit.ConnectionQuality) float64 {
+	return findMetric(t, "livekit_quality_participants", map[string]string{"quality": quality.String()}).GetGauge().GetValue()
+}
+
+func Test_ConnectionQualityIsReleasedOnLeave(t *testing.T) {
+	fixture := createFixture()
+	participants := func() float64 {
+		return participantsOfQuality(t, livekit.ConnectionQuality_EXCELLENT)
+	}
+	before := participants()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "PA_quality"}
+	partSID := livekit.ParticipantID(participantInfo.Sid)
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, "trackID", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 100, Rtt: 50}}})
+	flushEvents(fixture.sut)
+	fixture.sut.FlushStats()
+	require.Equal(t, before+1, participants())
+
+	// stats reported after the worker is closed, e.g. racing with the leave, are not counted
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 100, Rtt: 50}}})
+	flushEvents(fixture.sut)
+	fixture.sut.FlushStats()
+	require.Equal(t, before, participants())
+}
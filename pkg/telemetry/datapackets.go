@@ -0,0 +1,58 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// dataPacketCounts are the data packets of a kind forwarded since the stats were last flushed
+type dataPacketCounts struct {
+	packets atomic.Uint64
+	bytes   atomic.Uint64
+}
+
+func (t *telemetryService) DataPacketForwarded(
+	_ context.Context,
+	_ livekit.ParticipantID,
+	kind livekit.DataPacket_Kind,
+	bytes int,
+) {
+	// called for every packet sent, so only counted here and recorded once every stats interval
+	counts := &t.lossyDataPackets
+	if kind == livekit.DataPacket_RELIABLE {
+		counts = &t.reliableDataPackets
+	}
+	counts.packets.Inc()
+	counts.bytes.Add(uint64(bytes))
+}
+
+// flushDataPackets records the data packets forwarded since the last flush
+func (t *telemetryService) flushDataPackets() {
+	for kind, counts := range map[livekit.DataPacket_Kind]*dataPacketCounts{
+		livekit.DataPacket_RELIABLE: &t.reliableDataPackets,
+		livekit.DataPacket_LOSSY:    &t.lossyDataPackets,
+	} {
+		if packets := counts.packets.Swap(0); packets > 0 {
+			prometheus.RecordDataPackets(kind, packets, counts.bytes.Swap(0))
+		}
+	}
+}
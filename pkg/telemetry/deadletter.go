@@ -0,0 +1,140 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const defaultDeadLetterReplayRate = 10
+
+var (
+	errDeadLettersNotReplayable = errors.New("dead letter sink does not support replay")
+	errDeadLetterReplayRunning  = errors.New("dead letter replay already running")
+	errDeadLetterEndpointGone   = errors.New("dead letter endpoint is no longer configured")
+)
+
+// ReplayableDeadLetterSink is implemented by dead letter sinks that can hand stored events back to be delivered
+// again, see TelemetryService.ReplayDeadLetters
+type ReplayableDeadLetterSink interface {
+	DeadLetterSink
+	// List returns the stored dead letters, oldest first
+	List(ctx context.Context) ([]*DeadLetter, error)
+	// Remove deletes a stored dead letter once its event has been delivered to its endpoint
+	Remove(ctx context.Context, letter *DeadLetter) error
+}
+
+type DeadLetterReplayResult struct {
+	// delivered and removed from the sink
+	Replayed int
+	// failed again and left in the sink
+	Failed int
+}
+
+// ReplayDeadLetters delivers the events stored in the dead letter sink again, paced by WebHookConfig.DeadLetterReplayRate
+// so a recovering endpoint isn't flooded. each event goes through the usual delivery path, with retries, to the
+// endpoint it failed on only, keeping its original Id so that an endpoint that received it before can discard it.
+// events are removed from the sink once delivered, those that fail again are left for the next replay.
+// when ctx is done or Shutdown is called, the events counted so far are returned along with the error
+func (t *telemetryService) ReplayDeadLetters(ctx context.Context) (DeadLetterReplayResult, error) {
+	var result DeadLetterReplayResult
+	sink, ok := t.deadLetterSink.(ReplayableDeadLetterSink)
+	if !ok {
+		return result, errDeadLettersNotReplayable
+	}
+	if !t.deadLetterReplayLock.TryLock() {
+		return result, errDeadLetterReplayRunning
+	}
+	defer t.deadLetterReplayLock.Unlock()
+
+	letters, err := sink.List(ctx)
+	if err != nil {
+		return result, err
+	}
+	if len(letters) == 0 {
+		return result, nil
+	}
+
+	ticker := t.clock.NewTicker(t.deadLetterReplayInterval)
+	defer ticker.Stop()
+	for i, letter := range letters {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-ticker.C():
+			}
+		}
+
+		event := letter.Event
+		if err := t.redeliver(ctx, letter); err != nil {
+			if errors.Is(err, errWebhookDropped) {
+				return result, err
+			}
+			result.Failed++
+			prometheus.RecordWebhookDeadLetterReplay(false)
+			logger.Debugw("dead-lettered webhook failed again", "error", err, "endpoint", letter.Endpoint, "event", event.Event, "eventID", event.Id)
+			continue
+		}
+
+		result.Replayed++
+		prometheus.RecordWebhookDeadLetterReplay(true)
+		if err := sink.Remove(ctx, letter); err != nil {
+			logger.Warnw("failed to remove replayed webhook from dead letter sink", err, "endpoint", letter.Endpoint, "event", event.Event, "eventID", event.Id)
+		}
+	}
+
+	logger.Infow("replayed dead-lettered webhooks", "replayed", result.Replayed, "failed", result.Failed)
+	return result, nil
+}
+
+// redeliver queues the event of letter on the endpoint it failed on, redacted for it, and waits for the delivery.
+// the endpoint's circuit breaker stops the delivery while open and counts its result as any other's.
+// unlike NotifyEvent, a failed delivery is not dead-lettered again
+func (t *telemetryService) redeliver(ctx context.Context, letter *DeadLetter) error {
+	endpoint := t.webhookEndpoint(letter.Endpoint)
+	if endpoint == nil {
+		return errDeadLetterEndpointGone
+	}
+
+	done := make(chan error, 1)
+	// replayed events are out of order anyway, so they don't wait for the room's queue
+	event, details := endpoint.redact(letter.Event, letter.Details)
+	if err := t.submitWebhook(endpoint, "", func() {
+		err := t.deliverWebhook(ctx, endpoint, event, details)
+		if errors.Is(err, errWebhookShortCircuited) {
+			prometheus.RecordWebhookShortCircuited(endpoint.name)
+		}
+		done <- err
+	}); err != nil {
+		return err
+	}
+	return <-done
+}
+
+// webhookEndpoint returns the endpoint named name, nil if there is none
+func (t *telemetryService) webhookEndpoint(name string) *webhookEndpoint {
+	for _, endpoint := range t.webhookEndpoints {
+		if endpoint.name == name {
+			return endpoint
+		}
+	}
+	return nil
+}
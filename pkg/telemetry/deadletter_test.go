@@ -0,0 +1,200 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+// memoryDeadLetters keeps dead letters in memory, in the order they were stored
+type memoryDeadLetters struct {
+	lock    sync.Mutex
+	letters []*telemetry.DeadLetter
+}
+
+func (s *memoryDeadLetters) Store(_ context.Context, letter *telemetry.DeadLetter) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.letters = append(s.letters, letter)
+	return nil
+}
+
+func (s *memoryDeadLetters) List(_ context.Context) ([]*telemetry.DeadLetter, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*telemetry.DeadLetter(nil), s.letters...), nil
+}
+
+func (s *memoryDeadLetters) Remove(_ context.Context, letter *telemetry.DeadLetter) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, stored := range s.letters {
+		if stored.Endpoint == letter.Endpoint && stored.Event.Id == letter.Event.Id {
+			s.letters = append(s.letters[:i], s.letters[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryDeadLetters) stored() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var events []string
+	for _, letter := range s.letters {
+		events = append(events, letter.Event.Event)
+	}
+	return events
+}
+
+func Test_ReplayDeadLetters(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 0
+	conf.WebHook.DeadLetterReplayRate = 20
+
+	// every event fails while the endpoint is down, room_finished keeps failing after
+	var down sync.Map
+	down.Store(webhook.EventRoomStarted, true)
+	down.Store(webhook.EventRoomFinished, true)
+	down.Store(webhook.EventParticipantJoined, true)
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyStub = func(_ context.Context, event *livekit.WebhookEvent) error {
+		if _, ok := down.Load(event.Event); ok {
+			return errors.New("bad gateway")
+		}
+		return nil
+	}
+	sink := &memoryDeadLetters{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+	)
+
+	for _, event := range []string{webhook.EventRoomStarted, webhook.EventRoomFinished, webhook.EventParticipantJoined} {
+		sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: event, Room: &livekit.Room{Sid: "RM_1"}})
+	}
+	require.Eventually(t, func() bool {
+		return len(sink.stored()) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	down.Delete(webhook.EventRoomStarted)
+	down.Delete(webhook.EventParticipantJoined)
+	start := time.Now()
+	result, err := sut.ReplayDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, telemetry.DeadLetterReplayResult{Replayed: 2, Failed: 1}, result)
+	// kept, without being stored a second time
+	require.Equal(t, []string{webhook.EventRoomFinished}, sink.stored())
+	// paced at 20 a second
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	down.Delete(webhook.EventRoomFinished)
+	result, err = sut.ReplayDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, telemetry.DeadLetterReplayResult{Replayed: 1}, result)
+	require.Empty(t, sink.stored())
+	require.Equal(t, 7, notifier.NotifyCallCount())
+}
+
+func Test_ReplayDeadLetters_RequiresReplayableSink(t *testing.T) {
+	fixture := createFixture()
+
+	_, err := fixture.sut.ReplayDeadLetters(context.Background())
+	require.Error(t, err)
+}
+
+func Test_ReplayDeadLetters_OnlyToFailedEndpoint(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 0
+
+	healthy := &telemetryfakes.FakeWebhookNotifier{}
+	failing := &telemetryfakes.FakeWebhookNotifier{}
+	failing.NotifyReturnsOnCall(0, errors.New("bad gateway"))
+	sink := &memoryDeadLetters{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{healthy, failing},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+	)
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted, Room: &livekit.Room{Sid: "RM_1"}})
+	require.Eventually(t, func() bool {
+		return len(sink.stored()) == 1
+	}, time.Second, 10*time.Millisecond)
+	letters, err := sink.List(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "notifier_1", letters[0].Endpoint)
+
+	result, err := sut.ReplayDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, telemetry.DeadLetterReplayResult{Replayed: 1}, result)
+	require.Equal(t, 1, healthy.NotifyCallCount())
+	require.Equal(t, 2, failing.NotifyCallCount())
+	_, replayed := failing.NotifyArgsForCall(1)
+	_, original := failing.NotifyArgsForCall(0)
+	require.Equal(t, original.Id, replayed.Id)
+}
+
+func Test_ReplayDeadLetters_Redacts(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 0
+	conf.WebHook.Redactions = []config.WebHookRedactionConfig{
+		{Fields: []string{"participant.metadata"}, Endpoints: []string{"notifier_1"}},
+	}
+
+	open := &telemetryfakes.FakeWebhookNotifier{}
+	open.NotifyReturnsOnCall(0, errors.New("bad gateway"))
+	redacted := &telemetryfakes.FakeWebhookNotifier{}
+	redacted.NotifyReturnsOnCall(0, errors.New("bad gateway"))
+	sink := &memoryDeadLetters{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{open, redacted},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+	)
+
+	participant := &livekit.ParticipantInfo{Sid: "PA_redacted", Metadata: "secret"}
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventParticipantJoined, Participant: participant})
+	require.Eventually(t, func() bool {
+		return len(sink.stored()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	result, err := sut.ReplayDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, telemetry.DeadLetterReplayResult{Replayed: 2}, result)
+
+	// each endpoint gets the event as it would have been delivered to it
+	_, event := open.NotifyArgsForCall(1)
+	require.Equal(t, "secret", event.Participant.Metadata)
+	_, event = redacted.NotifyArgsForCall(1)
+	require.Empty(t, event.Participant.Metadata)
+}
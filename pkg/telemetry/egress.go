@@ -0,0 +1,126 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/psrpc"
+)
+
+const (
+	EgressFailureTimeout  = "timeout"
+	EgressFailureUpload   = "upload"
+	EgressFailurePipeline = "pipeline"
+	EgressFailureSource   = "source"
+	EgressFailureUnknown  = "unknown"
+)
+
+// kinds of egress, by request, labelling the egress usage metrics
+const (
+	EgressTypeRoomComposite  = "room_composite"
+	EgressTypeWeb            = "web"
+	EgressTypeParticipant    = "participant"
+	EgressTypeTrackComposite = "track_composite"
+	EgressTypeTrack          = "track"
+	EgressTypeUnknown        = "unknown"
+)
+
+// ClassifyEgressError buckets an egress failure into a coarse category, by the psrpc code it carries or the
+// network error it wraps. errors only known by their message are unknown
+func ClassifyEgressError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return EgressFailureTimeout
+	}
+	if isNetworkError(err) {
+		return EgressFailureUpload
+	}
+
+	switch errorCode(err) {
+	case psrpc.DeadlineExceeded:
+		return EgressFailureTimeout
+	case psrpc.Unavailable, psrpc.Unauthenticated, psrpc.PermissionDenied:
+		return EgressFailureUpload
+	case psrpc.Internal:
+		return EgressFailurePipeline
+	case psrpc.NotFound, psrpc.FailedPrecondition:
+		return EgressFailureSource
+	default:
+		return EgressFailureUnknown
+	}
+}
+
+// isNetworkError returns whether err is, or wraps, a failure to reach or hear back from a peer
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, os.ErrDeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// errorCode returns the code of the psrpc error err wraps, psrpc.Unknown when there is none
+func errorCode(err error) psrpc.ErrorCode {
+	var psrpcErr psrpc.Error
+	if errors.As(err, &psrpcErr) {
+		return psrpcErr.Code()
+	}
+	return psrpc.Unknown
+}
+
+// EgressType returns the kind of egress info was started with, one of the EgressType values
+func EgressType(info *livekit.EgressInfo) string {
+	switch info.Request.(type) {
+	case *livekit.EgressInfo_RoomComposite:
+		return EgressTypeRoomComposite
+	case *livekit.EgressInfo_Web:
+		return EgressTypeWeb
+	case *livekit.EgressInfo_Participant:
+		return EgressTypeParticipant
+	case *livekit.EgressInfo_TrackComposite:
+		return EgressTypeTrackComposite
+	case *livekit.EgressInfo_Track:
+		return EgressTypeTrack
+	default:
+		return EgressTypeUnknown
+	}
+}
+
+// EgressDuration returns how long the egress ran for, 0 when it never started or hasn't ended
+func EgressDuration(info *livekit.EgressInfo) time.Duration {
+	if info.StartedAt == 0 || info.EndedAt < info.StartedAt {
+		return 0
+	}
+	return time.Duration(info.EndedAt - info.StartedAt)
+}
+
+// EgressOutputBytes returns the size of the files and segments written by the egress. streams have no size,
+// and an egress that failed before uploading anything has none either
+func EgressOutputBytes(info *livekit.EgressInfo) int64 {
+	var size int64
+	for _, file := range info.FileResults {
+		size += file.Size
+	}
+	for _, segments := range info.SegmentResults {
+		size += segments.Size
+	}
+	return size
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_ClassifyEgressError(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		reason string
+	}{
+		{context.DeadlineExceeded, telemetry.EgressFailureTimeout},
+		{psrpc.NewErrorf(psrpc.DeadlineExceeded, "start signal not received"), telemetry.EgressFailureTimeout},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, telemetry.EgressFailureUpload},
+		{psrpc.NewErrorf(psrpc.PermissionDenied, "access denied"), telemetry.EgressFailureUpload},
+		{fmt.Errorf("pipeline frozen: %w", psrpc.NewErrorf(psrpc.Internal, "gst error")), telemetry.EgressFailurePipeline},
+		{psrpc.NewErrorf(psrpc.NotFound, "track not found"), telemetry.EgressFailureSource},
+		{errors.New("track not found"), telemetry.EgressFailureUnknown},
+	} {
+		require.Equal(t, tc.reason, telemetry.ClassifyEgressError(tc.err), fmt.Sprint(tc.err))
+	}
+}
+
+func Test_EgressLifecycle(t *testing.T) {
+	fixture := createFixture()
+	info := &livekit.EgressInfo{
+		EgressId: "EG_1",
+		RoomId:   "RoomSid",
+		RoomName: "RoomName",
+		Status:   livekit.EgressStatus_EGRESS_STARTING,
+	}
+
+	fixture.sut.EgressStarted(context.Background(), info)
+	fixture.sut.EgressEnded(context.Background(), &livekit.EgressInfo{
+		EgressId: "EG_1",
+		RoomId:   "RoomSid",
+		RoomName: "RoomName",
+		Status:   livekit.EgressStatus_EGRESS_COMPLETE,
+	})
+
+	fixture.drain(t)
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+	require.Equal(t, 2, fixture.analytics.SendEventCallCount())
+
+	// webhooks are delivered concurrently, so they may reach the notifier in either order
+	var events []string
+	for i := 0; i < fixture.notifier.NotifyCallCount(); i++ {
+		_, event := fixture.notifier.NotifyArgsForCall(i)
+		events = append(events, event.Event)
+	}
+	require.ElementsMatch(t, []string{webhook.EventEgressStarted, webhook.EventEgressEnded}, events)
+
+	_, ev := fixture.analytics.SendEventArgsForCall(1)
+	require.Equal(t, livekit.AnalyticsEventType_EGRESS_ENDED, ev.Type)
+	require.Equal(t, "EG_1", ev.EgressId)
+	require.Equal(t, "RoomName", ev.Room.Name)
+	require.Equal(t, livekit.EgressStatus_EGRESS_COMPLETE, ev.Egress.Status)
+}
+
+func Test_EgressFailed(t *testing.T) {
+	fixture := createFixture()
+	info := &livekit.EgressInfo{
+		EgressId: "EG_1",
+		RoomId:   "RoomSid",
+		RoomName: "RoomName",
+		Status:   livekit.EgressStatus_EGRESS_FAILED,
+		Error:    "failed to upload file to s3",
+	}
+
+	fixture.sut.EgressFailed(context.Background(), info)
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventEgressFailed, event.Event)
+	require.Equal(t, "EG_1", event.EgressInfo.EgressId)
+
+	_, ev := fixture.analytics.SendEventArgsForCall(0)
+	require.Equal(t, livekit.AnalyticsEventType_EGRESS_ENDED, ev.Type)
+	require.Equal(t, livekit.EgressStatus_EGRESS_FAILED, ev.Egress.Status)
+	require.Equal(t, info.Error, ev.Error)
+}
+
+func egressUsage(t *testing.T, name string, egressType string) (count uint64, sum float64) {
+	histogram := findMetric(t, name, map[string]string{"type": egressType}).GetHistogram()
+	return histogram.GetSampleCount(), histogram.GetSampleSum()
+}
+
+func Test_EgressUsage(t *testing.T) {
+	fixture := createFixture()
+	durations, _ := egressUsage(t, "livekit_egress_duration_seconds", telemetry.EgressTypeRoomComposite)
+	outputs, outputBytes := egressUsage(t, "livekit_egress_output_bytes", telemetry.EgressTypeRoomComposite)
+
+	startedAt := time.Now().Add(-time.Minute)
+	fixture.sut.EgressEnded(context.Background(), &livekit.EgressInfo{
+		EgressId:  "EG_1",
+		Status:    livekit.EgressStatus_EGRESS_COMPLETE,
+		StartedAt: startedAt.UnixNano(),
+		EndedAt:   startedAt.Add(time.Minute).UnixNano(),
+		Request:   &livekit.EgressInfo_RoomComposite{RoomComposite: &livekit.RoomCompositeEgressRequest{}},
+		FileResults: []*livekit.FileInfo{
+			{Filename: "recording.mp4", Size: 1000},
+		},
+		SegmentResults: []*livekit.SegmentsInfo{
+			{PlaylistName: "playlist.m3u8", Size: 500},
+		},
+	})
+	// failed before writing anything, only its duration is recorded
+	fixture.sut.EgressFailed(context.Background(), &livekit.EgressInfo{
+		EgressId:  "EG_2",
+		Status:    livekit.EgressStatus_EGRESS_FAILED,
+		StartedAt: startedAt.UnixNano(),
+		EndedAt:   startedAt.Add(time.Second).UnixNano(),
+		Request:   &livekit.EgressInfo_RoomComposite{RoomComposite: &livekit.RoomCompositeEgressRequest{}},
+		Error:     "failed to upload file to s3",
+	})
+
+	flushEvents(fixture.sut)
+	count, _ := egressUsage(t, "livekit_egress_duration_seconds", telemetry.EgressTypeRoomComposite)
+	require.Equal(t, durations+2, count)
+	count, sum := egressUsage(t, "livekit_egress_output_bytes", telemetry.EgressTypeRoomComposite)
+	require.Equal(t, outputs+1, count)
+	require.Equal(t, outputBytes+1500, sum)
+
+	ended := findAnalyticsEvents(fixture, livekit.AnalyticsEventType_EGRESS_ENDED)
+	require.Len(t, ended, 2)
+	// the egress info the usage is computed from is sent as is
+	require.Equal(t, "EG_1", ended[0].Egress.EgressId)
+	require.Len(t, ended[0].Egress.FileResults, 1)
+	require.Equal(t, "EG_2", ended[1].Egress.EgressId)
+	require.Nil(t, ended[0].RtpStats)
+	require.Nil(t, ended[1].RtpStats)
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/protocol/livekit"
+)
+
+// what caused a batch of analytics events to be sent, labels the batch metrics
+const (
+	analyticsFlushSize     = "size"
+	analyticsFlushInterval = "interval"
+	analyticsFlushExplicit = "flush"
+	analyticsFlushShutdown = "shutdown"
+)
+
+// SendEvent buffers the event when the analytics sink supports batching, sending the batch once it is full.
+// events are kept in the order they were sent in, across all rooms. events sampled out by their type's
+// sample rate are counted, but not sent
+func (t *telemetryService) SendEvent(ctx context.Context, event *livekit.AnalyticsEvent) {
+	ctx, span := t.startAnalyticsSpan(ctx, event)
+	defer span.End()
+	eventType := analyticsEventLabel(event.Type)
+	prometheus.RecordAnalyticsEvent(eventType)
+	if t.analyticsSampleRates.sampledOut(eventType) {
+		prometheus.RecordAnalyticsSampledOut(eventType)
+		return
+	}
+	t.withRegion(event)
+	t.withNode(event)
+	t.withParticipantOrder(event)
+	t.withEnrichers(event)
+	event = t.withMaxSize(event)
+
+	if t.eventBatcher == nil {
+		t.queueAnalytics(&analyticsItem{ctx: ctx, event: event})
+		return
+	}
+
+	t.eventLock.Lock()
+	t.pendingEvents = append(t.pendingEvents, event)
+	full := len(t.pendingEvents) >= t.eventBatchSize
+	t.eventLock.Unlock()
+
+	if full {
+		t.flushEvents(analyticsFlushSize)
+	}
+}
+
+// withRegion sets the region of participant events that don't have one to the region of this node.
+// the client's own region isn't known, ClientInfo doesn't carry it, so nothing is set when the node has no region.
+// ClientMeta is copied before it's changed, as it may be shared with the caller
+func (t *telemetryService) withRegion(event *livekit.AnalyticsEvent) {
+	if t.region == "" || event.ParticipantId == "" || event.ClientMeta.GetRegion() != "" {
+		return
+	}
+
+	meta := &livekit.AnalyticsClientMeta{}
+	if event.ClientMeta != nil {
+		meta = proto.Clone(event.ClientMeta).(*livekit.AnalyticsClientMeta)
+	}
+	meta.Region = t.region
+	event.ClientMeta = meta
+}
+
+// withNode sets the node of events that don't have one to this node. the node that a participant event is
+// about is set by its caller, e.g. to the node the participant moved to, and is kept
+func (t *telemetryService) withNode(event *livekit.AnalyticsEvent) {
+	if t.nodeID == "" || event.ClientMeta.GetNode() != "" {
+		return
+	}
+
+	meta := &livekit.AnalyticsClientMeta{}
+	if event.ClientMeta != nil {
+		meta = proto.Clone(event.ClientMeta).(*livekit.AnalyticsClientMeta)
+	}
+	meta.Node = string(t.nodeID)
+	event.ClientMeta = meta
+}
+
+// withParticipantOrder keeps the timestamps of a participant's events increasing in the order they are sent.
+// events are timestamped when they are created, which doesn't always match the order they are sent in,
+// so an event that is not later than the participant's previous one is moved to just after it
+func (t *telemetryService) withParticipantOrder(event *livekit.AnalyticsEvent) {
+	if event.ParticipantId == "" || event.Timestamp == nil {
+		return
+	}
+	worker, ok := t.getWorker(livekit.ParticipantID(event.ParticipantId))
+	if !ok {
+		return
+	}
+	ts := event.Timestamp.AsTime()
+	if ordered := worker.orderEventTime(ts); !ordered.Equal(ts) {
+		event.Timestamp = timestamppb.New(ordered)
+	}
+}
+
+func (t *telemetryService) SendStats(ctx context.Context, stats []*livekit.AnalyticsStat) {
+	t.queueAnalytics(&analyticsItem{ctx: ctx, stats: stats})
+}
+
+// queueAnalytics hands item to the analytics queue, sending it directly once the queue has been closed on shutdown.
+// the span in ctx is kept, but not its deadline, the caller may be done by the time item is sent
+func (t *telemetryService) queueAnalytics(item *analyticsItem) {
+	item.ctx = trace.ContextWithSpan(context.Background(), trace.SpanFromContext(item.ctx))
+	if !t.analyticsQueue.push(item) {
+		t.sendAnalytics(item)
+	}
+}
+
+func (t *telemetryService) sendAnalytics(item *analyticsItem) {
+	switch {
+	case item.replay:
+		t.sendBuffered(item.ctx, nil)
+	case item.event != nil:
+		if !t.sendBuffered(item.ctx, []*livekit.AnalyticsEvent{item.event}) {
+			t.analyticsSink.SendEvent(item.ctx, item.event)
+		}
+		if t.fileSink != nil {
+			t.writeFileEvent(t.fileSink.WriteAnalyticsEvent(item.event))
+		}
+	case item.stats != nil:
+		t.analyticsSink.SendStats(item.ctx, item.stats)
+	default:
+		if !t.sendBuffered(item.ctx, item.events) {
+			t.eventBatcher.SendEvents(item.ctx, item.events)
+		}
+		if t.fileSink != nil {
+			for _, event := range item.events {
+				t.writeFileEvent(t.fileSink.WriteAnalyticsEvent(event))
+			}
+		}
+	}
+}
+
+// FlushEvents sends buffered analytics events once the telemetry jobs queued before it have run. it doesn't wait
+// for them, so it may be called from the telemetry service's own goroutines, e.g. by an EventEnricher
+func (t *telemetryService) FlushEvents() {
+	select {
+	case t.jobsChan <- func() {
+		t.flushEvents(analyticsFlushExplicit)
+	}:
+	default:
+		// queue is full, don't wait on it
+		t.flushEvents(analyticsFlushExplicit)
+	}
+}
+
+// FlushAnalytics sends buffered analytics events once the telemetry jobs queued before it have run, and returns
+// once they, and the analytics queued before them, have been handed to the analytics sink, or ctx is done.
+// called from the telemetry service's own goroutines it would wait on itself until ctx is done, see FlushEvents
+func (t *telemetryService) FlushAnalytics(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case t.jobsChan <- func() {
+		t.flushEvents(analyticsFlushExplicit)
+		close(done)
+	}:
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		// queue is full, don't wait on it
+		t.flushEvents(analyticsFlushExplicit)
+	}
+	return t.analyticsQueue.flush(ctx)
+}
+
+// flushEvents queues the buffered events as a batch, trigger is one of the analyticsFlush values
+func (t *telemetryService) flushEvents(trigger string) {
+	if t.eventBatcher == nil {
+		return
+	}
+
+	// held while queueing so batches can't overtake each other
+	t.eventFlushLock.Lock()
+	defer t.eventFlushLock.Unlock()
+
+	t.eventLock.Lock()
+	events := t.pendingEvents
+	t.pendingEvents = nil
+	t.eventLock.Unlock()
+
+	if len(events) > 0 {
+		prometheus.RecordAnalyticsBatch(trigger, len(events))
+		t.queueAnalytics(&analyticsItem{ctx: context.Background(), events: events})
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+type batchingAnalytics struct {
+	telemetryfakes.FakeAnalyticsService
+
+	lock    sync.Mutex
+	batches [][]*livekit.AnalyticsEvent
+}
+
+func (b *batchingAnalytics) SendEvents(_ context.Context, events []*livekit.AnalyticsEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.batches = append(b.batches, events)
+}
+
+func (b *batchingAnalytics) getBatches() [][]*livekit.AnalyticsEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.batches
+}
+
+// stalledAnalytics holds up sending batches until released
+type stalledAnalytics struct {
+	telemetryfakes.FakeAnalyticsService
+
+	release chan struct{}
+	sent    atomic.Int32
+}
+
+func (s *stalledAnalytics) SendEvents(_ context.Context, _ []*livekit.AnalyticsEvent) {
+	s.sent.Inc()
+	<-s.release
+}
+
+func batchFlushes(t *testing.T, trigger string) float64 {
+	return findMetric(t, "livekit_telemetry_analytics_batch_flushes_total", map[string]string{"trigger": trigger}).GetCounter().GetValue()
+}
+
+func batchedEvents(t *testing.T) float64 {
+	return findMetric(t, "livekit_telemetry_analytics_batch_size", nil).GetHistogram().GetSampleSum()
+}
+
+func Test_SendEvent_BatchedBySize(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.BatchSize = 3
+	conf.Analytics.BatchInterval = time.Hour
+	analytics := &batchingAnalytics{}
+	sut := telemetry.NewTelemetryService(conf, nil, analytics, telemetry.WithClock(newFakeClock()))
+	bySize, byFlush, events := batchFlushes(t, "size"), batchFlushes(t, "flush"), batchedEvents(t)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	for i := 0; i < 7; i++ {
+		sut.RoomStarted(context.Background(), room)
+	}
+
+	// the tail is sent on flush
+	flushEvents(sut)
+	require.Zero(t, analytics.SendEventCallCount())
+	batches := analytics.getBatches()
+	require.Len(t, batches, 3)
+	require.Len(t, batches[0], 3)
+	require.Len(t, batches[1], 3)
+	require.Len(t, batches[2], 1)
+
+	require.Equal(t, bySize+2, batchFlushes(t, "size"))
+	require.Equal(t, byFlush+1, batchFlushes(t, "flush"))
+	require.Equal(t, events+7, batchedEvents(t))
+}
+
+func Test_SendEvent_BatchedByInterval(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.BatchSize = 50
+	conf.Analytics.BatchInterval = time.Minute
+	clock := newFakeClock()
+	analytics := &batchingAnalytics{}
+	sut := telemetry.NewTelemetryService(conf, nil, analytics, telemetry.WithClock(clock))
+	byInterval := batchFlushes(t, "interval")
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	sut.RoomStarted(context.Background(), room)
+	sut.RoomEnded(context.Background(), room)
+
+	// ticks that come before the events are queued send nothing
+	require.Eventually(t, func() bool {
+		clock.Advance(conf.Analytics.BatchInterval)
+		return len(analytics.getBatches()) == 1
+	}, time.Second, time.Millisecond)
+
+	// order is kept within the batch
+	batch := analytics.getBatches()[0]
+	require.Len(t, batch, 2)
+	require.Equal(t, livekit.AnalyticsEventType_ROOM_CREATED, batch[0].Type)
+	require.Equal(t, livekit.AnalyticsEventType_ROOM_ENDED, batch[1].Type)
+	require.Equal(t, byInterval+1, batchFlushes(t, "interval"))
+}
+
+func Test_SendEvent_NotBatchedWithoutBatchService(t *testing.T) {
+	fixture := createFixture()
+
+	fixture.sut.RoomStarted(context.Background(), &livekit.Room{Sid: "RoomSid"})
+	flushEvents(fixture.sut)
+
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+}
+
+func Test_SendEvent_ParticipantTimestampsIncrease(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: "part1"}, nil, nil, true)
+
+	later := time.Now().Add(time.Hour)
+	for _, ts := range []time.Time{later, later, later.Add(-time.Minute), later.Add(time.Second)} {
+		fixture.sut.SendEvent(context.Background(), &livekit.AnalyticsEvent{
+			Type:          livekit.AnalyticsEventType_PARTICIPANT_ACTIVE,
+			ParticipantId: "part1",
+			Timestamp:     timestamppb.New(ts),
+		})
+	}
+
+	flushEvents(fixture.sut)
+	require.Equal(t, 5, fixture.analytics.SendEventCallCount())
+	var timestamps []time.Time
+	for _, event := range findAnalyticsEvents(fixture, livekit.AnalyticsEventType_PARTICIPANT_ACTIVE) {
+		timestamps = append(timestamps, event.Timestamp.AsTime())
+	}
+	require.Len(t, timestamps, 4)
+	require.True(t, timestamps[0].Equal(later))
+	require.True(t, timestamps[1].Equal(later.Add(time.Microsecond)))
+	require.True(t, timestamps[2].Equal(later.Add(2*time.Microsecond)))
+	require.True(t, timestamps[3].Equal(later.Add(time.Second)))
+}
+
+func Test_FlushAnalytics(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.BatchSize = 50
+	conf.Analytics.BatchInterval = time.Hour
+	analytics := &batchingAnalytics{}
+	sut := telemetry.NewTelemetryService(conf, nil, analytics, telemetry.WithClock(newFakeClock()))
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				sut.RoomStarted(context.Background(), room)
+				require.NoError(t, sut.FlushAnalytics(context.Background()))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every event is sent by the time the last flush returns
+	require.NoError(t, sut.FlushAnalytics(context.Background()))
+	sent := 0
+	for _, batch := range analytics.getBatches() {
+		sent += len(batch)
+	}
+	require.Equal(t, 40, sent)
+}
+
+func Test_FlushAnalytics_ReturnsWhenContextDone(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.BatchSize = 50
+	conf.Analytics.BatchInterval = time.Hour
+	release := make(chan struct{})
+	defer close(release)
+	analytics := &stalledAnalytics{release: release}
+	sut := telemetry.NewTelemetryService(conf, nil, analytics)
+
+	sut.RoomStarted(context.Background(), &livekit.Room{Sid: "RoomSid", Name: "RoomName"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, sut.FlushAnalytics(ctx), context.DeadlineExceeded)
+	require.Eventually(t, func() bool {
+		return analytics.sent.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_FlushEvents_FromEnricher(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	analytics := &telemetryfakes.FakeAnalyticsService{}
+	var sut telemetry.TelemetryService
+	// enrichers run on the telemetry run goroutine
+	sut = telemetry.NewTelemetryService(conf, nil, analytics, telemetry.WithEventEnrichers(
+		telemetry.EventEnricherFunc(func(event *livekit.AnalyticsEvent) {
+			sut.FlushEvents()
+		}),
+	))
+
+	sut.RoomEnded(context.Background(), &livekit.Room{Sid: "RoomSid"})
+	flushEvents(sut)
+
+	require.Equal(t, 1, analytics.SendEventCallCount())
+}
@@ -16,8 +16,14 @@ package telemetry
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
 	"time"
 
+	"github.com/gammazero/workerpool"
+	"go.uber.org/atomic"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
@@ -27,17 +33,299 @@ import (
 	"github.com/livekit/protocol/webhook"
 )
 
+// webhook events this server sends, anything else is counted as other to bound metric cardinality
+var webhookEventNames = map[string]struct{}{
+	EventRoomReserved:                 {},
+	webhook.EventRoomStarted:          {},
+	webhook.EventRoomFinished:         {},
+	webhook.EventParticipantJoined:    {},
+	webhook.EventParticipantLeft:      {},
+	webhook.EventTrackPublished:       {},
+	webhook.EventTrackUnpublished:     {},
+	webhook.EventEgressStarted:        {},
+	webhook.EventEgressUpdated:        {},
+	webhook.EventEgressEnded:          {},
+	webhook.EventIngressStarted:       {},
+	webhook.EventIngressEnded:         {},
+	EventTrackMuted:                   {},
+	EventTrackUnmuted:                 {},
+	EventActiveSpeakerChanged:         {},
+	EventRoomMetadataChanged:          {},
+	EventParticipantAttributesChanged: {},
+	EventEgressFailed:                 {},
+	EventTranscriptionStarted:         {},
+	EventTranscriptionEnded:           {},
+	EventParticipantMediaActive:       {},
+	EventTrackStalled:                 {},
+	EventTrackResumed:                 {},
+	EventSubscribedTrackStalled:       {},
+	EventSubscribedTrackResumed:       {},
+	EventParticipantUpdated:           {},
+	EventRoomDeleted:                  {},
+	EventParticipantRoleChanged:       {},
+}
+
+const otherEventLabel = "other"
+
+func webhookEventLabel(event string) string {
+	if _, ok := webhookEventNames[event]; ok {
+		return event
+	}
+	return otherEventLabel
+}
+
+func analyticsEventLabel(eventType livekit.AnalyticsEventType) string {
+	if _, ok := livekit.AnalyticsEventType_name[int32(eventType)]; ok {
+		return eventType.String()
+	}
+	return otherEventLabel
+}
+
 func (t *telemetryService) NotifyEvent(ctx context.Context, event *livekit.WebhookEvent) {
-	if t.notifier == nil {
+	t.notifyEvent(ctx, event, nil)
+}
+
+// notifyEvent sends event to webhooks, along with the details it has no field for, see WebhookDetails
+func (t *telemetryService) notifyEvent(ctx context.Context, event *livekit.WebhookEvent, details *WebhookDetails) {
+	prometheus.RecordWebhookEvent(webhookEventLabel(event.Event))
+	if t.isWebhookFiltered(event.Event) {
+		prometheus.RecordWebhookFiltered(event.Event)
+		return
+	}
+	if t.webhookSampleRates.sampledOut(event.Event) {
+		prometheus.RecordWebhookSampledOut(webhookEventLabel(event.Event))
+		return
+	}
+	if len(t.webhookEndpoints) == 0 {
+		// counted so a missing webhook config shows up rather than events silently going nowhere
+		prometheus.RecordWebhookUnconfigured(webhookEventLabel(event.Event))
+		if !t.hasEventListeners() {
+			return
+		}
+	}
+	now := t.clock.Now()
+	if t.webhookDedup != nil && t.webhookDedup.isDuplicate(event, now) {
+		prometheus.RecordWebhookDuplicate(event.Event)
+		logger.Debugw("suppressing duplicate webhook", "event", event.Event)
 		return
 	}
 
-	event.CreatedAt = time.Now().Unix()
+	event.CreatedAt = now.Unix()
 	event.Id = utils.NewGuid("EV_")
 
-	if err := t.notifier.QueueNotify(ctx, event); err != nil {
-		logger.Warnw("failed to notify webhook", err, "event", event.Event)
+	t.notifyListeners(event, details)
+	endpoints := t.routeWebhook(event)
+	if len(endpoints) == 0 && len(t.webhookEndpoints) != 0 {
+		prometheus.RecordWebhookFiltered(event.Event)
+	}
+	for _, endpoint := range endpoints {
+		endpoint := endpoint
+		// retries go on after the caller's context is done, only the trace span is kept
+		spanCtx, span := t.startWebhookSpan(t.detachContext(ctx), endpoint.name, event)
+		queuedAt := t.clock.Now()
+		delivered, deliveredDetails := endpoint.redact(event, details)
+		err := t.submitWebhook(endpoint, webhookRoomID(event), func() {
+			err := t.deliverWebhook(spanCtx, endpoint, delivered, deliveredDetails)
+			if errors.Is(err, errWebhookShortCircuited) {
+				t.shortCircuit(endpoint, event, details)
+				endSpan(span, err)
+				return
+			}
+			prometheus.RecordWebhookLatency(webhookEventLabel(event.Event), webhookOutcome(err), t.clock.Now().Sub(queuedAt))
+			if err != nil {
+				t.deadLetter(endpoint, event, details)
+			}
+			endSpan(span, err)
+		})
+		if err != nil {
+			if errors.Is(err, errWebhookQueueFull) {
+				t.deadLetter(endpoint, event, details)
+			}
+			endSpan(span, err)
+		}
+	}
+}
+
+// webhookEndpoint delivers events to one notifier on its own pool, so a slow or failing endpoint
+// does not hold up deliveries to the others
+type webhookEndpoint struct {
+	// the configured notifier wrapped in the delivery middlewares
+	notifier WebhookNotifier
+	// the configured notifier, as returned by a WebhookRouter
+	target WebhookNotifier
+	// labels metrics and logs for the endpoint
+	name string
+	pool *workerpool.WorkerPool
+	// deliveries waiting for a worker, bounded by the webhook queue size
+	queued atomic.Int32
+	// set when deliveries are ordered by room
+	rooms *roomQueues
+	// applied to events before they are delivered, see WebHookConfig.Redactions
+	redactions []*webhookRedaction
+	// nil unless WebHookConfig.BreakerFailures is set
+	breaker *webhookBreaker
+}
+
+// newWebhookEndpoints wraps each notifier so that a delivery is retried as configured, each attempt passing through
+// the middlewares added with WithNotifierMiddleware before being recorded, audited and bounded by the webhook timeout
+func (t *telemetryService) newWebhookEndpoints(notifiers []WebhookNotifier) []*webhookEndpoint {
+	endpoints := make([]*webhookEndpoint, 0, len(notifiers))
+	for i, notifier := range notifiers {
+		if urlNotifier, ok := notifier.(*URLNotifier); ok {
+			urlNotifier.useClock(t.clock)
+			if t.webhookTransport != nil {
+				urlNotifier.useTransport(t.webhookTransport)
+			}
+		}
+		name := fmt.Sprintf("notifier_%d", i)
+		if named, ok := notifier.(NamedWebhookNotifier); ok && named.Name() != "" {
+			name = named.Name()
+		}
+
+		middlewares := []NotifierMiddleware{retryMiddleware(t.clock, name, t.webhookMaxRetries, t.webhookRetryBaseDelay)}
+		middlewares = append(middlewares, t.notifierMiddlewares...)
+		middlewares = append(middlewares, MetricsMiddleware(name))
+		if t.webhookAuditor != nil {
+			middlewares = append(middlewares, auditMiddleware(t.clock, name, t.webhookAuditor))
+		}
+		middlewares = append(middlewares, TimeoutMiddleware(name, t.webhookTimeout))
+		endpoint := &webhookEndpoint{
+			notifier: ChainNotifier(notifier, middlewares...),
+			target:   notifier,
+			name:     name,
+			pool:     workerpool.New(t.webhookWorkers),
+		}
+		if t.webhookOrderByRoom {
+			endpoint.rooms = newRoomQueues()
+		}
+		endpoint.redactions = t.webhookRedactionsFor(name)
+		if t.webhookBreakerFailures > 0 {
+			endpoint.breaker = newWebhookBreaker(name, t.webhookBreakerFailures, t.webhookBreakerCooldown)
+		}
+		endpoints = append(endpoints, endpoint)
+		prometheus.RecordWebhookQueueCapacity(name, t.webhookQueueSize)
+	}
+	return endpoints
+}
+
+// submitWebhook queues a delivery on the endpoint's pool, tracking how many are waiting and in flight.
+// when deliveries are ordered by room, it waits for those submitted before it for roomID, if not empty.
+// deliveries are dropped once Shutdown has been called or when the endpoint's queue is full,
+// returns an error when deliver was not queued
+func (t *telemetryService) submitWebhook(endpoint *webhookEndpoint, roomID livekit.RoomID, deliver func()) error {
+	t.webhookLock.RLock()
+	defer t.webhookLock.RUnlock()
+	if t.webhookClosed {
+		logger.Warnw("dropping webhook, telemetry is shutting down", nil, "endpoint", endpoint.name)
+		return errWebhookDropped
+	}
+	if endpoint.queued.Inc() > int32(t.webhookQueueSize) {
+		endpoint.queued.Dec()
+		prometheus.RecordWebhookQueueFull(endpoint.name)
+		logger.Warnw("dropping webhook, endpoint queue is full", nil, "endpoint", endpoint.name, "queueSize", t.webhookQueueSize)
+		return errWebhookQueueFull
+	}
+
+	prometheus.AddWebhookQueued(endpoint.name)
+	t.webhookPending.Inc()
+	task := func() {
+		endpoint.queued.Dec()
+		prometheus.SubWebhookQueued(endpoint.name)
+		prometheus.AddWebhookInFlight(endpoint.name)
+		defer prometheus.SubWebhookInFlight(endpoint.name)
+		defer t.webhookPending.Dec()
+
+		deliver()
+	}
+	if endpoint.rooms != nil && roomID != "" {
+		endpoint.rooms.submit(endpoint.pool, roomID, task)
+	} else {
+		endpoint.pool.Submit(task)
+	}
+	return nil
+}
+
+// deliverWebhook delivers event to the endpoint, passing the result to its circuit breaker. returns
+// errWebhookShortCircuited without delivering while the breaker is open. must be called from the endpoint's pool
+func (t *telemetryService) deliverWebhook(
+	ctx context.Context,
+	endpoint *webhookEndpoint,
+	event *livekit.WebhookEvent,
+	details *WebhookDetails,
+) error {
+	// checked once a worker picks the delivery up, so deliveries queued before the breaker opened are stopped too
+	if endpoint.breaker != nil && !endpoint.breaker.allow(t.clock.Now()) {
+		return errWebhookShortCircuited
+	}
+	err := endpoint.notifier.Notify(withWebhookDetails(ctx, details), event)
+	if endpoint.breaker != nil {
+		endpoint.breaker.record(err, t.clock.Now())
+	}
+	return err
+}
+
+// isWebhookFiltered returns true if the event is excluded, or if an include list is configured and the event isn't on it
+func (t *telemetryService) isWebhookFiltered(event string) bool {
+	if _, ok := t.webhookExcludeEvents[event]; ok {
+		return true
+	}
+	if t.webhookIncludeEvents == nil {
+		return false
 	}
+	_, ok := t.webhookIncludeEvents[event]
+	return !ok
+}
+
+func webhookOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "failure"
+	}
+}
+
+func (t *telemetryService) deadLetter(endpoint *webhookEndpoint, event *livekit.WebhookEvent, details *WebhookDetails) {
+	prometheus.RecordWebhookDeadLettered(endpoint.name)
+
+	// the delivery context may have been what stopped delivery, don't let it fail the store as well
+	if err := t.deadLetterSink.Store(context.Background(), &DeadLetter{Endpoint: endpoint.name, Event: event, Details: details}); err != nil {
+		logger.Errorw("failed to store dead-lettered webhook", err, "endpoint", endpoint.name, "event", event.Event, "eventID", event.Id)
+	}
+}
+
+// shortCircuit handles an event that was not delivered since the endpoint's circuit breaker is open
+func (t *telemetryService) shortCircuit(endpoint *webhookEndpoint, event *livekit.WebhookEvent, details *WebhookDetails) {
+	prometheus.RecordWebhookShortCircuited(endpoint.name)
+	if t.webhookBreakerPolicy == WebhookBreakerDeadLetter {
+		t.deadLetter(endpoint, event, details)
+	}
+}
+
+// webhookRetryDelay returns base * 2^attempt capped at webhookMaxRetryDelay,
+// with up to 25% jitter added to avoid synchronized retries
+func webhookRetryDelay(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	delay := base << attempt
+	if delay <= 0 || delay > webhookMaxRetryDelay {
+		delay = webhookMaxRetryDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/4+1))
+}
+
+func (t *telemetryService) RoomReserved(ctx context.Context, room *livekit.Room) {
+	t.enqueue(func() {
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event: EventRoomReserved,
+			Room:  room,
+		})
+
+		logger.Infow("room reserved", "room", room.Name, "roomID", room.Sid)
+	})
 }
 
 func (t *telemetryService) RoomStarted(ctx context.Context, room *livekit.Room) {
@@ -56,7 +344,30 @@ func (t *telemetryService) RoomStarted(ctx context.Context, room *livekit.Room)
 }
 
 func (t *telemetryService) RoomEnded(ctx context.Context, room *livekit.Room) {
+	t.clearActiveSpeakers(livekit.RoomID(room.Sid))
+
 	t.enqueue(func() {
+		if label, ok := t.roomLabels.release(livekit.RoomName(room.Name)); ok {
+			t.deleteParticipantSessionsLater(label)
+			t.deleteRoomBitrate(label)
+		}
+		if t.joinLeaveRates != nil {
+			t.joinLeaveRates.remove(livekit.RoomID(room.Sid))
+		}
+		// participants leaving with the room may be reported just after it ends, their workers are closed then
+		if t.roomEndDrainTimeout > 0 {
+			t.clock.AfterFunc(t.roomEndDrainTimeout, func() {
+				t.enqueue(func() {
+					t.reapRoomWorkers(room)
+				})
+			})
+		} else {
+			t.reapRoomWorkers(room)
+		}
+		if t.roomStatsInterval > 0 {
+			t.flushRoomStats(ctx, livekit.RoomID(room.Sid))
+		}
+
 		t.NotifyEvent(ctx, &livekit.WebhookEvent{
 			Event: webhook.EventRoomFinished,
 			Room:  room,
@@ -71,6 +382,76 @@ func (t *telemetryService) RoomEnded(ctx context.Context, room *livekit.Room) {
 	})
 }
 
+// deleteParticipantSessionsLater deletes the session durations of a room label once participantSessionRetention
+// has passed, unless a room is labelled with it again before then
+func (t *telemetryService) deleteParticipantSessionsLater(label string) {
+	t.sessionDeleteLock.Lock()
+	defer t.sessionDeleteLock.Unlock()
+
+	if pending, ok := t.sessionDeletes[label]; ok {
+		pending.timer.Stop()
+	}
+	pending := &pendingSessionDelete{}
+	pending.timer = t.clock.AfterFunc(participantSessionRetention, func() {
+		t.sessionDeleteLock.Lock()
+		defer t.sessionDeleteLock.Unlock()
+
+		// cancelled or replaced while waiting for the lock
+		if t.sessionDeletes[label] != pending {
+			return
+		}
+		delete(t.sessionDeletes, label)
+		prometheus.DeleteParticipantSessions(label)
+	})
+	t.sessionDeletes[label] = pending
+}
+
+// cancelParticipantSessionsDelete keeps the session durations of a room label that is in use again
+func (t *telemetryService) cancelParticipantSessionsDelete(label string) {
+	t.sessionDeleteLock.Lock()
+	defer t.sessionDeleteLock.Unlock()
+
+	if pending, ok := t.sessionDeletes[label]; ok {
+		pending.timer.Stop()
+		delete(t.sessionDeletes, label)
+	}
+}
+
+func (t *telemetryService) RoomDeleted(ctx context.Context, room *livekit.Room, deletedBy string) {
+	t.enqueue(func() {
+		prometheus.RecordRoomDeleted()
+		// participants that left with the room have been accounted for already, jobs run in order
+		t.reapRoomWorkers(room)
+
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event: EventRoomDeleted,
+			Room:  room,
+		})
+
+		logger.Infow("room deleted", "room", room.Name, "roomID", room.Sid, "deletedBy", deletedBy)
+	})
+}
+
+func (t *telemetryService) RoomMetadataChanged(ctx context.Context, room *livekit.Room, prevMetadata string) {
+	if room.Metadata == prevMetadata {
+		return
+	}
+
+	t.enqueue(func() {
+		var details *WebhookDetails
+		if t.webhookEventDetails {
+			details = &WebhookDetails{PrevRoomMetadata: prevMetadata}
+		}
+		t.notifyEvent(ctx, &livekit.WebhookEvent{
+			Event: EventRoomMetadataChanged,
+			Room:  room,
+		}, details)
+
+		// analytics has no event type for it
+		logger.Debugw("room metadata changed", "room", room.Name, "roomID", room.Sid)
+	})
+}
+
 func (t *telemetryService) ParticipantJoined(
 	ctx context.Context,
 	room *livekit.Room,
@@ -79,17 +460,22 @@ func (t *telemetryService) ParticipantJoined(
 	clientMeta *livekit.AnalyticsClientMeta,
 	shouldSendEvent bool,
 ) {
-	t.enqueue(func() {
-		prometheus.IncrementParticipantRtcConnected(1)
-		prometheus.AddParticipant()
+	prometheus.IncrementParticipantRtcConnected(1)
+	prometheus.AddParticipant()
+	prometheus.RecordParticipantJoined()
+
+	// created before returning so that events for the participant that follow can always resolve the room
+	worker := t.createWorker(
+		ctx,
+		livekit.RoomID(room.Sid),
+		livekit.RoomName(room.Name),
+		livekit.ParticipantID(participant.Sid),
+		livekit.ParticipantIdentity(participant.Identity),
+	)
+	worker.setJoined(clientInfo)
 
-		t.createWorker(
-			ctx,
-			livekit.RoomID(room.Sid),
-			livekit.RoomName(room.Name),
-			livekit.ParticipantID(participant.Sid),
-			livekit.ParticipantIdentity(participant.Identity),
-		)
+	t.enqueue(func() {
+		t.recordJoinLeave(room)
 
 		if shouldSendEvent {
 			ev := newParticipantEvent(livekit.AnalyticsEventType_PARTICIPANT_JOINED, room, participant)
@@ -100,6 +486,163 @@ func (t *telemetryService) ParticipantJoined(
 	})
 }
 
+// recordJoinLeave counts a join or leave in room, reporting the room once they exceed the threshold.
+// only called on the run goroutine
+func (t *telemetryService) recordJoinLeave(room *livekit.Room) {
+	if t.joinLeaveRates == nil {
+		return
+	}
+
+	count, exceeded := t.joinLeaveRates.record(livekit.RoomID(room.Sid), t.clock.Now())
+	if !exceeded {
+		return
+	}
+
+	prometheus.RecordRoomJoinLeaveStorm()
+	logger.Infow("participants joining and leaving room at a high rate",
+		"room", room.Name,
+		"roomID", room.Sid,
+		"count", count,
+		"window", t.joinLeaveRates.window,
+	)
+}
+
+func (t *telemetryService) ParticipantAttributesChanged(
+	ctx context.Context,
+	room *livekit.Room,
+	participant *livekit.ParticipantInfo,
+	prev *livekit.ParticipantInfo,
+) {
+	changed := attributeChanges(prev, participant)
+	if prev != nil && len(changed) == 0 {
+		return
+	}
+
+	t.enqueue(func() {
+		var details *WebhookDetails
+		if t.webhookEventDetails {
+			details = &WebhookDetails{ChangedFields: changed}
+		}
+		t.notifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantAttributesChanged,
+			Room:        room,
+			Participant: participant,
+		}, details)
+
+		// analytics has no event type for it
+		logger.Debugw("participant attributes changed",
+			"room", room.Name,
+			"roomID", room.Sid,
+			"participant", participant.Identity,
+			"pID", participant.Sid,
+			"changed", changed,
+		)
+	})
+}
+
+// attributeChanges returns the names of the attributes of participant that differ from prev, as in
+// livekit.ParticipantInfo. nil when prev isn't known
+func attributeChanges(prev *livekit.ParticipantInfo, participant *livekit.ParticipantInfo) []string {
+	if prev == nil {
+		return nil
+	}
+	var changed []string
+	if participant.Name != prev.Name {
+		changed = append(changed, "name")
+	}
+	if participant.Metadata != prev.Metadata {
+		changed = append(changed, "metadata")
+	}
+	return changed
+}
+
+func (t *telemetryService) ParticipantPermissionsChanged(
+	ctx context.Context,
+	room *livekit.Room,
+	participant *livekit.ParticipantInfo,
+	prev *livekit.ParticipantPermission,
+) {
+	changed := permissionChanges(prev, participant.Permission)
+	if len(changed) == 0 {
+		return
+	}
+
+	t.enqueue(func() {
+		for _, permission := range changed {
+			prometheus.RecordPermissionChanged(permission)
+		}
+
+		var details *WebhookDetails
+		if t.webhookEventDetails {
+			details = &WebhookDetails{ChangedFields: changed, PrevPermission: prev}
+		}
+		t.notifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantUpdated,
+			Room:        room,
+			Participant: participant,
+		}, details)
+
+		logger.Debugw("participant permissions changed",
+			"room", room.Name,
+			"roomID", room.Sid,
+			"participant", participant.Identity,
+			"pID", participant.Sid,
+			"changed", changed,
+		)
+	})
+}
+
+func (t *telemetryService) ParticipantRoleChanged(
+	ctx context.Context,
+	room *livekit.Room,
+	participant *livekit.ParticipantInfo,
+	from livekit.ParticipantInfo_Kind,
+	to livekit.ParticipantInfo_Kind,
+) {
+	if from == to {
+		return
+	}
+
+	t.enqueue(func() {
+		// the session carries on, so the participant's worker and the stats it has accumulated are kept as they are
+		prometheus.RecordParticipantRoleChanged(participantKindLabel(from), participantKindLabel(to))
+
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantRoleChanged,
+			Room:        room,
+			Participant: participant,
+		})
+
+		logger.Infow("participant role changed",
+			"room", room.Name,
+			"roomID", room.Sid,
+			"participant", participant.Identity,
+			"pID", participant.Sid,
+			"from", from,
+			"to", to,
+		)
+	})
+}
+
+// participantKindLabel returns kind as it labels metrics, e.g. agent
+func participantKindLabel(kind livekit.ParticipantInfo_Kind) string {
+	return strings.ToLower(kind.String())
+}
+
+// permissionChanges returns the names of the fields that differ between prev and permission, in field order
+func permissionChanges(prev *livekit.ParticipantPermission, permission *livekit.ParticipantPermission) []string {
+	from, to := prev.ProtoReflect(), permission.ProtoReflect()
+	fields := from.Descriptor().Fields()
+	var changed []string
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !from.Get(field).Equal(to.Get(field)) {
+			changed = append(changed, string(field.Name()))
+		}
+	}
+	return changed
+}
+
 func (t *telemetryService) ParticipantActive(
 	ctx context.Context,
 	room *livekit.Room,
@@ -110,11 +653,18 @@ func (t *telemetryService) ParticipantActive(
 	t.enqueue(func() {
 		if !isMigration {
 			// consider participant joined only when they became active
-			t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			event := &livekit.WebhookEvent{
 				Event:       webhook.EventParticipantJoined,
 				Room:        room,
 				Participant: participant,
-			})
+			}
+			var details *WebhookDetails
+			if t.webhookClientInfo {
+				if worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid)); ok {
+					details = &WebhookDetails{ClientInfo: worker.getClientInfo()}
+				}
+			}
+			t.notifyEvent(ctx, event, details)
 		}
 
 		worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid))
@@ -140,6 +690,159 @@ func (t *telemetryService) ParticipantActive(
 	})
 }
 
+func (t *telemetryService) ParticipantConnected(
+	ctx context.Context,
+	room *livekit.Room,
+	participant *livekit.ParticipantInfo,
+) {
+	connectedAt := t.clock.Now()
+
+	t.enqueue(func() {
+		worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid))
+		if !ok {
+			return
+		}
+		latency, ok := worker.takeConnectLatency(connectedAt)
+		if !ok {
+			// participants migrating to this node can connect without having joined here
+			return
+		}
+		prometheus.RecordParticipantConnectLatency(latency)
+
+		logger.Infow("participant connected",
+			"room", room.Name,
+			"roomID", room.Sid,
+			"participant", participant.Identity,
+			"pID", participant.Sid,
+			"latency", latency,
+		)
+	})
+}
+
+// participantMediaActive is called by the worker of a participant the first time it sees media flowing
+func (t *telemetryService) participantMediaActive(worker *StatsWorker, activeAt time.Time) {
+	latency := activeAt.Sub(worker.JoinedAt())
+	prometheus.RecordParticipantJoinToMedia(latency)
+
+	t.enqueue(func() {
+		room := &livekit.Room{
+			Sid:  string(worker.roomID),
+			Name: string(worker.roomName),
+		}
+		participant := &livekit.ParticipantInfo{
+			Sid:      string(worker.participantID),
+			Identity: string(worker.participantIdentity),
+		}
+		t.NotifyEvent(worker.ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantMediaActive,
+			Room:        room,
+			Participant: participant,
+		})
+
+		logger.Infow("participant media active",
+			"room", room.Name,
+			"roomID", room.Sid,
+			"participant", participant.Identity,
+			"pID", participant.Sid,
+			"latency", latency,
+		)
+	})
+}
+
+// trackStallChanged is called by a worker when media stops flowing on one of its published or subscribed tracks,
+// or starts again
+func (t *telemetryService) trackStallChanged(worker *StatsWorker, stall trackStall) {
+	direction := "publish"
+	if stall.direction == livekit.StreamType_DOWNSTREAM {
+		direction = "subscribe"
+	}
+	if stall.stalled {
+		prometheus.RecordTrackStall(stall.trackType.String(), direction)
+	}
+
+	t.enqueue(func() {
+		// analytics has no event type for it
+		logger.Infow("track stall changed",
+			"room", worker.roomName,
+			"roomID", worker.roomID,
+			"participant", worker.participantIdentity,
+			"pID", worker.participantID,
+			"trackID", stall.trackID,
+			"kind", stall.trackType,
+			"direction", direction,
+			"stalled", stall.stalled,
+		)
+		if !t.trackStallWebhook {
+			return
+		}
+
+		var event string
+		switch {
+		case stall.direction == livekit.StreamType_DOWNSTREAM && stall.stalled:
+			event = EventSubscribedTrackStalled
+		case stall.direction == livekit.StreamType_DOWNSTREAM:
+			event = EventSubscribedTrackResumed
+		case stall.stalled:
+			event = EventTrackStalled
+		default:
+			event = EventTrackResumed
+		}
+		t.NotifyEvent(worker.ctx, &livekit.WebhookEvent{
+			Event: event,
+			Room: &livekit.Room{
+				Sid:  string(worker.roomID),
+				Name: string(worker.roomName),
+			},
+			Participant: &livekit.ParticipantInfo{
+				Sid:      string(worker.participantID),
+				Identity: string(worker.participantIdentity),
+			},
+			Track: &livekit.TrackInfo{
+				Sid:  string(stall.trackID),
+				Type: stall.trackType,
+			},
+		})
+	})
+}
+
+// isPublishedTrackLive returns true if media flows on a track published by a participant on this node
+func (t *telemetryService) isPublishedTrackLive(publisherID livekit.ParticipantID, trackID livekit.TrackID) bool {
+	worker, ok := t.getWorker(publisherID)
+	return ok && worker.isTrackLive(trackID)
+}
+
+func (t *telemetryService) trackNeverActive(worker *StatsWorker, trackID livekit.TrackID, trackType livekit.TrackType) {
+	prometheus.RecordTrackNeverActive(trackType.String())
+	logger.Infow("published track never active",
+		"room", worker.roomName,
+		"roomID", worker.roomID,
+		"participant", worker.participantIdentity,
+		"pID", worker.participantID,
+		"trackID", trackID,
+		"kind", trackType,
+		"timeout", t.trackFirstPacketTimeout,
+	)
+}
+
+func (t *telemetryService) ParticipantDuplicateIdentity(
+	ctx context.Context,
+	room *livekit.Room,
+	participant *livekit.ParticipantInfo,
+	evicted *livekit.ParticipantInfo,
+) {
+	t.enqueue(func() {
+		prometheus.RecordParticipantDuplicateIdentity()
+
+		logger.Infow("participant joined with a duplicate identity",
+			"room", room.GetName(),
+			"roomID", room.GetSid(),
+			"participant", participant.GetIdentity(),
+			"pID", participant.GetSid(),
+			"evictedID", evicted.GetSid(),
+		)
+	})
+}
+
 func (t *telemetryService) ParticipantResumed(
 	ctx context.Context,
 	room *livekit.Room,
@@ -160,6 +863,7 @@ func (t *telemetryService) ParticipantResumed(
 func (t *telemetryService) ParticipantLeft(ctx context.Context,
 	room *livekit.Room,
 	participant *livekit.ParticipantInfo,
+	reason livekit.DisconnectReason,
 	shouldSendEvent bool,
 ) {
 	t.enqueue(func() {
@@ -168,6 +872,14 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context,
 		if worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid)); ok {
 			hasWorker = true
 			isConnected = worker.IsConnected()
+			// on a repeated leave the worker is already closed and the session has been recorded
+			if worker.ClosedAt().IsZero() {
+				prometheus.RecordParticipantSession(worker.roomLabel, t.clock.Now().Sub(worker.JoinedAt()))
+				prometheus.RecordParticipantLeft(reason.String())
+				t.recordJoinLeave(room)
+			}
+			// the track events go out before the participant leaves
+			sendHeldUnpublishes(worker)
 			worker.Close()
 		}
 
@@ -176,12 +888,21 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context,
 			prometheus.SubParticipant()
 		}
 
+		if !hasWorker {
+			// the worker may have been reaped with the room, while the participant was still connected
+			isConnected = t.takeConnected(livekit.ParticipantID(participant.Sid))
+		}
+
 		if isConnected && shouldSendEvent {
-			t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			var details *WebhookDetails
+			if t.webhookEventDetails {
+				details = &WebhookDetails{DisconnectReason: reason}
+			}
+			t.notifyEvent(ctx, &livekit.WebhookEvent{
 				Event:       webhook.EventParticipantLeft,
 				Room:        room,
 				Participant: participant,
-			})
+			}, details)
 
 			t.SendEvent(ctx, newParticipantEvent(livekit.AnalyticsEventType_PARTICIPANT_LEFT, room, participant))
 		}
@@ -205,15 +926,71 @@ func (t *telemetryService) TrackPublishRequested(
 	})
 }
 
+// reasons for a rejected publication, see TelemetryService.TrackPublishFailed
+const (
+	// the participant may not publish tracks of the requested source
+	TrackPublishFailReasonPermissionDenied = "permission_denied"
+	// a codec was added for a track that is not published
+	TrackPublishFailReasonTrackNotFound = "track_not_found"
+)
+
+func (t *telemetryService) TrackPublishFailed(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	req *livekit.AddTrackRequest,
+	reason string,
+) {
+	t.enqueue(func() {
+		prometheus.RecordTrackPublishFailure(req.Type.String(), reason)
+
+		var mime string
+		if len(req.SimulcastCodecs) != 0 {
+			mime = req.SimulcastCodecs[0].Codec
+		}
+		room := t.getRoomDetails(participantID)
+		logger.Infow("track publication rejected",
+			"room", room.GetName(),
+			"roomID", room.GetSid(),
+			"pID", participantID,
+			"trackID", req.Sid,
+			"kind", req.Type,
+			"source", req.Source,
+			"mime", mime,
+			"reason", reason,
+		)
+	})
+}
+
 func (t *telemetryService) TrackPublished(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
 	identity livekit.ParticipantIdentity,
 	track *livekit.TrackInfo,
 ) {
+	// before the stats of the track are received, they are attributed to its codecs as they are
+	t.setTrackCodecs(participantID, track, livekit.StreamType_UPSTREAM)
+
 	t.enqueue(func() {
 		prometheus.AddPublishedTrack(track.Type.String())
 		prometheus.AddPublishSuccess(track.Type.String())
+		if codec := trackCodec(track); codec != "" {
+			prometheus.AddPublishedCodec(track.Sid, codec)
+		}
+		coalesced := false
+		if worker, ok := t.getWorker(participantID); ok {
+			worker.AddTrack(livekit.TrackID(track.Sid), track.Type)
+			worker.SetTrackMuted(livekit.TrackID(track.Sid), track.Muted)
+
+			// republished within the churn window, neither the unpublish nor this publish is sent
+			if held, ok := worker.takeUnpublish(livekit.TrackID(track.Sid)); ok {
+				held.timer.Stop()
+				prometheus.RecordTrackChurnCoalesced(track.Type.String())
+				coalesced = true
+			}
+		}
+		if coalesced {
+			return
+		}
 
 		room := t.getRoomDetails(participantID)
 		participant := &livekit.ParticipantInfo{
@@ -233,7 +1010,22 @@ func (t *telemetryService) TrackPublished(
 	})
 }
 
+func (t *telemetryService) TrackFirstPacket(
+	_ context.Context,
+	participantID livekit.ParticipantID,
+	trackID livekit.TrackID,
+	firstPacketAt time.Time,
+) {
+	t.enqueue(func() {
+		if worker, ok := t.getWorker(participantID); ok {
+			worker.SetTrackFirstPacket(trackID, firstPacketAt)
+		}
+	})
+}
+
 func (t *telemetryService) TrackPublishedUpdate(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo) {
+	t.setTrackCodecs(participantID, track, livekit.StreamType_UPSTREAM)
+
 	t.enqueue(func() {
 		room := t.getRoomDetails(participantID)
 		t.SendEvent(ctx, newTrackEvent(livekit.AnalyticsEventType_TRACK_PUBLISHED_UPDATE, room, participantID, track))
@@ -256,13 +1048,91 @@ func (t *telemetryService) TrackMaxSubscribedVideoQuality(
 	})
 }
 
+// reasons for a simulcast layer change, see TelemetryService.SimulcastLayerChanged
+const (
+	// available bandwidth to the subscriber changed
+	SimulcastLayerChangeReasonBandwidth = "bandwidth"
+	// layers published by the publisher or requested by the subscriber changed
+	SimulcastLayerChangeReasonAvailability = "availability"
+)
+
+func (t *telemetryService) SimulcastLayerChanged(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	trackID livekit.TrackID,
+	layer livekit.VideoQuality,
+	reason string,
+) {
+	t.enqueue(func() {
+		prometheus.RecordSimulcastLayerSwitch(layer.String(), reason)
+
+		if t.simulcastLayerSampleRate <= 0 || rand.Float64() >= t.simulcastLayerSampleRate {
+			return
+		}
+
+		room := t.getRoomDetails(participantID)
+		logger.Debugw("simulcast layer changed",
+			"room", room.GetName(),
+			"roomID", room.GetSid(),
+			"participantID", participantID,
+			"trackID", trackID,
+			"layer", layer,
+			"reason", reason,
+		)
+	})
+}
+
+func (t *telemetryService) TrackLayerPaused(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	trackID livekit.TrackID,
+	layer livekit.VideoQuality,
+) {
+	t.enqueue(func() {
+		prometheus.RecordTrackLayerPaused(layer.String())
+		t.logTrackLayer("track layer paused", participantID, trackID, layer)
+	})
+}
+
+func (t *telemetryService) TrackLayerResumed(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	trackID livekit.TrackID,
+	layer livekit.VideoQuality,
+) {
+	t.enqueue(func() {
+		t.logTrackLayer("track layer resumed", participantID, trackID, layer)
+	})
+}
+
+func (t *telemetryService) logTrackLayer(
+	msg string,
+	participantID livekit.ParticipantID,
+	trackID livekit.TrackID,
+	layer livekit.VideoQuality,
+) {
+	room := t.getRoomDetails(participantID)
+	logger.Debugw(msg,
+		"room", room.GetName(),
+		"roomID", room.GetSid(),
+		"pID", participantID,
+		"trackID", trackID,
+		"layer", layer,
+	)
+}
+
 func (t *telemetryService) TrackSubscribeRequested(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
 	track *livekit.TrackInfo,
 ) {
+	requestedAt := t.clock.Now()
+
 	t.enqueue(func() {
 		prometheus.RecordTrackSubscribeAttempt()
+		if worker, ok := t.getWorker(participantID); ok {
+			worker.subscribeRequested(livekit.TrackID(track.Sid), requestedAt)
+		}
 
 		room := t.getRoomDetails(participantID)
 		ev := newTrackEvent(livekit.AnalyticsEventType_TRACK_SUBSCRIBE_REQUESTED, room, participantID, track)
@@ -277,8 +1147,17 @@ func (t *telemetryService) TrackSubscribed(
 	publisher *livekit.ParticipantInfo,
 	shouldSendEvent bool,
 ) {
+	subscribedAt := t.clock.Now()
+	t.setTrackCodecs(participantID, track, livekit.StreamType_DOWNSTREAM)
+
 	t.enqueue(func() {
 		prometheus.RecordTrackSubscribeSuccess(track.Type.String())
+		if worker, ok := t.getWorker(participantID); ok {
+			if latency, ok := worker.takeSubscribeLatency(livekit.TrackID(track.Sid), subscribedAt); ok {
+				prometheus.RecordTrackSubscribeLatency(track.Type.String(), latency)
+			}
+			worker.AddSubscribedTrack(livekit.TrackID(track.Sid), track.Type, livekit.ParticipantID(publisher.GetSid()))
+		}
 
 		if !shouldSendEvent {
 			return
@@ -300,6 +1179,9 @@ func (t *telemetryService) TrackSubscribeFailed(
 ) {
 	t.enqueue(func() {
 		prometheus.RecordTrackSubscribeFailure(err, isUserError)
+		if worker, ok := t.getWorker(participantID); ok {
+			worker.clearSubscribeRequest(trackID)
+		}
 
 		room := t.getRoomDetails(participantID)
 		ev := newTrackEvent(livekit.AnalyticsEventType_TRACK_SUBSCRIBE_FAILED, room, participantID, &livekit.TrackInfo{
@@ -310,14 +1192,55 @@ func (t *telemetryService) TrackSubscribeFailed(
 	})
 }
 
+func (t *telemetryService) TrackSubscriptionFailed(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	trackID livekit.TrackID,
+	reason livekit.SubscriptionError,
+) {
+	t.enqueue(func() {
+		prometheus.RecordTrackSubscriptionFailure(reason.String())
+
+		// as a subscription that failed on this side, with the error the subscriber was told
+		room := t.getRoomDetails(participantID)
+		ev := newTrackEvent(livekit.AnalyticsEventType_TRACK_SUBSCRIBE_FAILED, room, participantID, &livekit.TrackInfo{
+			Sid: string(trackID),
+		})
+		ev.Error = reason.String()
+		t.SendEvent(ctx, ev)
+	})
+}
+
+func (t *telemetryService) SubscriptionPermissionChanged(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	permissions *livekit.SubscriptionPermission,
+) {
+	t.enqueue(func() {
+		// AnalyticsEvent has no field for subscription permissions, they're logged for auditing
+		room := t.getRoomDetails(participantID)
+		logger.Infow("subscription permission changed",
+			"room", room.GetName(),
+			"roomID", room.GetSid(),
+			"pID", participantID,
+			"permissions", logger.Proto(permissions),
+		)
+	})
+}
+
 func (t *telemetryService) TrackUnsubscribed(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
 	track *livekit.TrackInfo,
 	shouldSendEvent bool,
 ) {
+	t.clearTrackCodecs(participantID, livekit.TrackID(track.Sid), livekit.StreamType_DOWNSTREAM)
+
 	t.enqueue(func() {
 		prometheus.RecordTrackUnsubscribed(track.Type.String())
+		if worker, ok := t.getWorker(participantID); ok {
+			worker.RemoveSubscribedTrack(livekit.TrackID(track.Sid))
+		}
 
 		if shouldSendEvent {
 			room := t.getRoomDetails(participantID)
@@ -333,8 +1256,15 @@ func (t *telemetryService) TrackUnpublished(
 	track *livekit.TrackInfo,
 	shouldSendEvent bool,
 ) {
+	t.clearTrackCodecs(participantID, livekit.TrackID(track.Sid), livekit.StreamType_UPSTREAM)
+
 	t.enqueue(func() {
 		prometheus.SubPublishedTrack(track.Type.String())
+		prometheus.SubPublishedCodec(track.Sid)
+		worker, hasWorker := t.getWorker(participantID)
+		if hasWorker {
+			worker.RemoveTrack(livekit.TrackID(track.Sid))
+		}
 		if !shouldSendEvent {
 			return
 		}
@@ -344,24 +1274,79 @@ func (t *telemetryService) TrackUnpublished(
 			Sid:      string(participantID),
 			Identity: string(identity),
 		}
-		t.NotifyEvent(ctx, &livekit.WebhookEvent{
-			Event:       webhook.EventTrackUnpublished,
-			Room:        room,
-			Participant: participant,
-			Track:       track,
-		})
+		send := func(ctx context.Context) {
+			t.NotifyEvent(ctx, &livekit.WebhookEvent{
+				Event:       webhook.EventTrackUnpublished,
+				Room:        room,
+				Participant: participant,
+				Track:       track,
+			})
 
-		t.SendEvent(ctx, newTrackEvent(livekit.AnalyticsEventType_TRACK_UNPUBLISHED, room, participantID, track))
+			t.SendEvent(ctx, newTrackEvent(livekit.AnalyticsEventType_TRACK_UNPUBLISHED, room, participantID, track))
+		}
+		if t.trackChurnWindow <= 0 || !hasWorker || !worker.ClosedAt().IsZero() {
+			send(ctx)
+			return
+		}
+
+		// held back in case the track is published again within the window, see TrackPublished. the caller's
+		// context may be done by the time it is sent
+		detached := t.detachContext(ctx)
+		held := &heldUnpublish{trackID: livekit.TrackID(track.Sid), send: func() { send(detached) }}
+		held.timer = t.clock.AfterFunc(t.trackChurnWindow, func() {
+			t.enqueue(func() {
+				if worker.releaseUnpublish(held) {
+					held.send()
+				}
+			})
+		})
+		worker.holdUnpublish(held)
 	})
 }
 
+// sendHeldUnpublishes sends the events of unpublishes held back by worker right away
+func sendHeldUnpublishes(worker *StatsWorker) {
+	for _, held := range worker.takeUnpublishes() {
+		held.timer.Stop()
+		held.send()
+	}
+}
+
+// sources of a track being muted or unmuted, see TelemetryService.TrackMuted
+const (
+	// the publisher muted or unmuted the track
+	TrackMuteSourcePublisher = "publisher"
+	// the track was muted or unmuted through the API, e.g. by a moderator
+	TrackMuteSourceAdmin = "admin"
+)
+
 func (t *telemetryService) TrackMuted(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
 	track *livekit.TrackInfo,
+	source string,
 ) {
 	t.enqueue(func() {
+		prometheus.RecordTrackMute(track.Type.String(), true, source)
+		logger.Debugw("track muted", "pID", participantID, "trackID", track.Sid, "source", source)
+		participant := &livekit.ParticipantInfo{Sid: string(participantID)}
+		if worker, ok := t.getWorker(participantID); ok {
+			worker.SetTrackMuted(livekit.TrackID(track.Sid), true)
+			participant.Identity = string(worker.participantIdentity)
+		}
+
 		room := t.getRoomDetails(participantID)
+		var details *WebhookDetails
+		if t.webhookEventDetails {
+			details = &WebhookDetails{MuteSource: source}
+		}
+		t.notifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventTrackMuted,
+			Room:        room,
+			Participant: participant,
+			Track:       track,
+		}, details)
+
 		t.SendEvent(ctx, newTrackEvent(livekit.AnalyticsEventType_TRACK_MUTED, room, participantID, track))
 	})
 }
@@ -370,13 +1355,50 @@ func (t *telemetryService) TrackUnmuted(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
 	track *livekit.TrackInfo,
+	source string,
 ) {
 	t.enqueue(func() {
+		prometheus.RecordTrackMute(track.Type.String(), false, source)
+		logger.Debugw("track unmuted", "pID", participantID, "trackID", track.Sid, "source", source)
+		participant := &livekit.ParticipantInfo{Sid: string(participantID)}
+		if worker, ok := t.getWorker(participantID); ok {
+			worker.SetTrackMuted(livekit.TrackID(track.Sid), false)
+			participant.Identity = string(worker.participantIdentity)
+		}
+
 		room := t.getRoomDetails(participantID)
+		var details *WebhookDetails
+		if t.webhookEventDetails {
+			details = &WebhookDetails{MuteSource: source}
+		}
+		t.notifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventTrackUnmuted,
+			Room:        room,
+			Participant: participant,
+			Track:       track,
+		}, details)
+
 		t.SendEvent(ctx, newTrackEvent(livekit.AnalyticsEventType_TRACK_UNMUTED, room, participantID, track))
 	})
 }
 
+func (t *telemetryService) ConnectionQualityChanged(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	quality livekit.ConnectionQuality,
+) {
+	t.enqueue(func() {
+		// the participants of each quality are counted by the workers, see prometheus.AddConnectionQuality
+		room := t.getRoomDetails(participantID)
+		logger.Infow("connection quality changed",
+			"room", room.GetName(),
+			"roomID", room.GetSid(),
+			"participantID", participantID,
+			"quality", quality,
+		)
+	})
+}
+
 func (t *telemetryService) TrackPublishRTPStats(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
@@ -417,6 +1439,8 @@ func (t *telemetryService) TrackSubscribeRTPStats(
 
 func (t *telemetryService) EgressStarted(ctx context.Context, info *livekit.EgressInfo) {
 	t.enqueue(func() {
+		prometheus.AddEgress()
+
 		t.NotifyEvent(ctx, &livekit.WebhookEvent{
 			Event:      webhook.EventEgressStarted,
 			EgressInfo: info,
@@ -438,6 +1462,9 @@ func (t *telemetryService) EgressUpdated(ctx context.Context, info *livekit.Egre
 
 func (t *telemetryService) EgressEnded(ctx context.Context, info *livekit.EgressInfo) {
 	t.enqueue(func() {
+		prometheus.SubEgress()
+		prometheus.RecordEgressUsage(EgressType(info), EgressDuration(info), EgressOutputBytes(info))
+
 		t.NotifyEvent(ctx, &livekit.WebhookEvent{
 			Event:      webhook.EventEgressEnded,
 			EgressInfo: info,
@@ -447,6 +1474,67 @@ func (t *telemetryService) EgressEnded(ctx context.Context, info *livekit.Egress
 	})
 }
 
+func (t *telemetryService) EgressFailed(ctx context.Context, info *livekit.EgressInfo) {
+	t.enqueue(func() {
+		prometheus.SubEgress()
+		prometheus.RecordEgressFailure(ClassifyEgressError(errors.New(info.Error)))
+		// a failed egress may still have run for a while and uploaded part of its output
+		prometheus.RecordEgressUsage(EgressType(info), EgressDuration(info), EgressOutputBytes(info))
+
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:      EventEgressFailed,
+			EgressInfo: info,
+		})
+
+		// EGRESS_ENDED as for egress that completed, the egress carries the failed status
+		ev := newEgressEvent(livekit.AnalyticsEventType_EGRESS_ENDED, info)
+		ev.Error = info.Error
+		t.SendEvent(ctx, ev)
+	})
+}
+
+func (t *telemetryService) TranscriptionStarted(ctx context.Context, room *livekit.Room, trackID livekit.TrackID) {
+	t.enqueue(func() {
+		// a session started twice is only counted once
+		if _, ok := t.transcriptions[trackID]; !ok {
+			t.transcriptions[trackID] = struct{}{}
+			prometheus.AddTranscription()
+		}
+
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event: EventTranscriptionStarted,
+			Room:  room,
+			Track: &livekit.TrackInfo{Sid: string(trackID)},
+		})
+
+		logger.Infow("transcription started", "room", room.GetName(), "roomID", room.GetSid(), "trackID", trackID)
+	})
+}
+
+func (t *telemetryService) TranscriptionEnded(ctx context.Context, room *livekit.Room, trackID livekit.TrackID, duration time.Duration) {
+	t.enqueue(func() {
+		// a session ended without having started, or ended twice, isn't subtracted from the active sessions
+		if _, ok := t.transcriptions[trackID]; ok {
+			delete(t.transcriptions, trackID)
+			prometheus.SubTranscription()
+		}
+		prometheus.RecordTranscriptionDuration(duration)
+
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event: EventTranscriptionEnded,
+			Room:  room,
+			Track: &livekit.TrackInfo{Sid: string(trackID)},
+		})
+
+		logger.Infow("transcription ended",
+			"room", room.GetName(),
+			"roomID", room.GetSid(),
+			"trackID", trackID,
+			"duration", duration,
+		)
+	})
+}
+
 func (t *telemetryService) IngressCreated(ctx context.Context, info *livekit.IngressInfo) {
 	t.enqueue(func() {
 		t.SendEvent(ctx, newIngressEvent(livekit.AnalyticsEventType_INGRESS_CREATED, info))
@@ -476,19 +1564,24 @@ func (t *telemetryService) IngressUpdated(ctx context.Context, info *livekit.Ing
 	})
 }
 
-func (t *telemetryService) IngressEnded(ctx context.Context, info *livekit.IngressInfo) {
+func (t *telemetryService) IngressEnded(ctx context.Context, info *livekit.IngressInfo, err error) {
 	t.enqueue(func() {
 		t.NotifyEvent(ctx, &livekit.WebhookEvent{
 			Event:       webhook.EventIngressEnded,
 			IngressInfo: info,
 		})
 
-		t.SendEvent(ctx, newIngressEvent(livekit.AnalyticsEventType_INGRESS_ENDED, info))
+		ev := newIngressEvent(livekit.AnalyticsEventType_INGRESS_ENDED, info)
+		if err != nil {
+			prometheus.RecordIngressFailure(info.InputType.String(), ClassifyIngressError(err))
+			ev.Error = err.Error()
+		}
+		t.SendEvent(ctx, ev)
 	})
 }
 
-// returns a livekit.Room with only name and sid filled out
-// returns nil if room is not found
+// returns a livekit.Room with only name and sid filled out, falling back to the rooms of recently
+// removed workers. returns nil if room is not found
 func (t *telemetryService) getRoomDetails(participantID livekit.ParticipantID) *livekit.Room {
 	if worker, ok := t.getWorker(participantID); ok {
 		return &livekit.Room{
@@ -497,9 +1590,35 @@ func (t *telemetryService) getRoomDetails(participantID livekit.ParticipantID) *
 		}
 	}
 
+	if cached, ok := t.getParticipantRoom(participantID); ok {
+		return &livekit.Room{
+			Sid:  string(cached.roomID),
+			Name: string(cached.roomName),
+		}
+	}
+
 	return nil
 }
 
+// setTrackCodecs records the codecs of a track on the participant's worker, see StatsWorker.SetTrackCodecs. called
+// on the caller's goroutine, in the order of the stats of the track
+func (t *telemetryService) setTrackCodecs(participantID livekit.ParticipantID, track *livekit.TrackInfo, direction livekit.StreamType) {
+	if worker, ok := t.getWorker(participantID); ok {
+		worker.SetTrackCodecs(track, direction)
+	}
+}
+
+func (t *telemetryService) clearTrackCodecs(participantID livekit.ParticipantID, trackID livekit.TrackID, direction livekit.StreamType) {
+	if worker, ok := t.getWorker(participantID); ok {
+		worker.ClearTrackCodecs(trackID, direction)
+	}
+}
+
+// trackCodec returns the codec of the track, for simulcast codecs the primary one, e.g. vp8 for video/VP8
+func trackCodec(track *livekit.TrackInfo) string {
+	return mimeCodec(trackMime(track))
+}
+
 func newRoomEvent(event livekit.AnalyticsEventType, room *livekit.Room) *livekit.AnalyticsEvent {
 	ev := &livekit.AnalyticsEvent{
 		Type:      event,
@@ -538,15 +1657,29 @@ func newEgressEvent(event livekit.AnalyticsEventType, egress *livekit.EgressInfo
 		Timestamp: timestamppb.Now(),
 		EgressId:  egress.EgressId,
 		RoomId:    egress.RoomId,
-		Egress:    egress,
+		Room: &livekit.Room{
+			Sid:  egress.RoomId,
+			Name: egress.RoomName,
+		},
+		Egress: egress,
 	}
 }
 
 func newIngressEvent(event livekit.AnalyticsEventType, ingress *livekit.IngressInfo) *livekit.AnalyticsEvent {
-	return &livekit.AnalyticsEvent{
+	ev := &livekit.AnalyticsEvent{
 		Type:      event,
 		Timestamp: timestamppb.Now(),
 		IngressId: ingress.IngressId,
 		Ingress:   ingress,
 	}
+	if ingress.RoomName != "" {
+		ev.Room = &livekit.Room{
+			Name: ingress.RoomName,
+		}
+		if ingress.State != nil {
+			ev.RoomId = ingress.State.RoomId
+			ev.Room.Sid = ingress.State.RoomId
+		}
+	}
+	return ev
 }
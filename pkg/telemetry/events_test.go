@@ -16,12 +16,19 @@ package telemetry_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
 )
 
 func Test_OnParticipantJoin_EventIsSent(t *testing.T) {
@@ -81,10 +88,18 @@ func Test_OnParticipantLeft_EventIsSent(t *testing.T) {
 	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
 	partSID := "part1"
 	participantInfo := &livekit.ParticipantInfo{Sid: partSID}
+	left := func() float64 {
+		labels := map[string]string{"reason": livekit.DisconnectReason_DUPLICATE_IDENTITY.String()}
+		if metric := findMetric(t, "livekit_participant_left_total", labels); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	before := left()
 
 	// do
 	fixture.sut.ParticipantActive(context.Background(), room, participantInfo, &livekit.AnalyticsClientMeta{}, false)
-	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_DUPLICATE_IDENTITY, true)
 	time.Sleep(time.Millisecond * 500)
 
 	// test
@@ -94,6 +109,8 @@ func Test_OnParticipantLeft_EventIsSent(t *testing.T) {
 	require.Equal(t, partSID, event.ParticipantId)
 	require.Equal(t, room.Sid, event.RoomId)
 	require.Equal(t, room, event.Room)
+	require.Empty(t, event.Error)
+	require.Equal(t, before+1, left())
 }
 
 func Test_OnTrackUpdate_EventIsSent(t *testing.T) {
@@ -136,6 +153,54 @@ func Test_OnTrackUpdate_EventIsSent(t *testing.T) {
 
 }
 
+func Test_RoomReserved_WebhookIsSent(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RM_reserved", Name: "reserved", CreationTime: time.Now().Unix()}
+	fixture.sut.RoomReserved(context.Background(), room)
+	flushEvents(fixture.sut)
+
+	// not counted as started until someone joins
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventRoomReserved, event.Event)
+	require.Equal(t, room.Sid, event.Room.Sid)
+}
+
+func Test_RoomDeleted_ReapsLingeringParticipants(t *testing.T) {
+	fixture := createFixture()
+	deleted := func() float64 {
+		return findMetric(t, "livekit_room_deleted_total", nil).GetCounter().GetValue()
+	}
+	before := deleted()
+
+	room := &livekit.Room{Sid: "RM_deleted", Name: "deleted"}
+	gone := &livekit.ParticipantInfo{Sid: "PA_gone"}
+	lingering := &livekit.ParticipantInfo{Sid: "PA_lingering"}
+	for _, participant := range []*livekit.ParticipantInfo{gone, lingering} {
+		fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+		fixture.sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+	}
+	fixture.sut.ParticipantLeft(context.Background(), room, gone, livekit.DisconnectReason_SERVER_SHUTDOWN, true)
+	fixture.sut.RoomDeleted(context.Background(), room, "admin")
+	flushEvents(fixture.sut)
+
+	require.Equal(t, before+1, deleted())
+
+	// the participant that left is reported as usual, the lingering one is no longer tracked
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_PARTICIPANT_LEFT), 1)
+	_, ok := fixture.sut.GetParticipantStats(livekit.ParticipantID(lingering.Sid))
+	require.False(t, ok)
+
+	fixture.drain(t)
+	roomDeleted := findWebhookEvents(fixture, telemetry.EventRoomDeleted)
+	require.Len(t, roomDeleted, 1)
+	require.Equal(t, room.Sid, roomDeleted[0].Room.Sid)
+}
+
 func Test_OnParticipantActive_EventIsSent(t *testing.T) {
 	fixture := createFixture()
 
@@ -186,6 +251,85 @@ func Test_OnParticipantActive_EventIsSent(t *testing.T) {
 	require.Equal(t, clientMetaConnect.ClientConnectTime, eventActive.ClientMeta.ClientConnectTime)
 }
 
+func Test_ParticipantConnected_RecordsLatency(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "PA_connected"}
+	before := participantConnects(t)
+
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, false)
+	flushEvents(fixture.sut)
+	clock.Advance(50 * time.Millisecond)
+	fixture.sut.ParticipantConnected(context.Background(), room, participantInfo)
+	// only the first connection counts
+	fixture.sut.ParticipantConnected(context.Background(), room, participantInfo)
+	flushEvents(fixture.sut)
+
+	after := participantConnects(t)
+	require.Equal(t, before.GetSampleCount()+1, after.GetSampleCount())
+	require.InDelta(t, 0.05, after.GetSampleSum()-before.GetSampleSum(), 1e-6)
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+}
+
+func Test_ParticipantConnected_SkippedWithoutJoin(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "PA_migrated"}
+	before := participantConnects(t).GetSampleCount()
+
+	// migrated in, the worker is created without a join
+	fixture.sut.ParticipantActive(context.Background(), room, participantInfo, &livekit.AnalyticsClientMeta{}, true)
+	fixture.sut.ParticipantConnected(context.Background(), room, participantInfo)
+	// never seen at all
+	fixture.sut.ParticipantConnected(context.Background(), room, &livekit.ParticipantInfo{Sid: "PA_unknown"})
+	flushEvents(fixture.sut)
+
+	require.Equal(t, before, participantConnects(t).GetSampleCount())
+}
+
+// participantConnects returns the join to connected latencies recorded, empty when there are none
+func participantConnects(t *testing.T) *dto.Histogram {
+	return findMetric(t, "livekit_participant_join_to_connected_seconds", nil).GetHistogram()
+}
+
+func Test_TrackSubscribed_RecordsLatency(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "PA_subscriber"}
+	subscribes := func() (uint64, float64) {
+		histogram := findMetric(t, "livekit_track_subscribe_latency_seconds", map[string]string{"kind": "VIDEO"}).GetHistogram()
+		return histogram.GetSampleCount(), histogram.GetSampleSum()
+	}
+	beforeCount, beforeSum := subscribes()
+
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, false)
+	track := &livekit.TrackInfo{Sid: "TR_video", Type: livekit.TrackType_VIDEO}
+	fixture.sut.TrackSubscribeRequested(context.Background(), livekit.ParticipantID(participantInfo.Sid), &livekit.TrackInfo{Sid: track.Sid})
+	flushEvents(fixture.sut)
+	clock.Advance(50 * time.Millisecond)
+	fixture.sut.TrackSubscribed(context.Background(), livekit.ParticipantID(participantInfo.Sid), track, nil, false)
+	// only the first subscription after the request counts
+	fixture.sut.TrackSubscribed(context.Background(), livekit.ParticipantID(participantInfo.Sid), track, nil, false)
+
+	// a failed subscription is not counted once the track is later subscribed without a new request
+	failed := &livekit.TrackInfo{Sid: "TR_failed", Type: livekit.TrackType_VIDEO}
+	fixture.sut.TrackSubscribeRequested(context.Background(), livekit.ParticipantID(participantInfo.Sid), &livekit.TrackInfo{Sid: failed.Sid})
+	fixture.sut.TrackSubscribeFailed(context.Background(), livekit.ParticipantID(participantInfo.Sid), livekit.TrackID(failed.Sid), errors.New("no permission"), true)
+	fixture.sut.TrackSubscribed(context.Background(), livekit.ParticipantID(participantInfo.Sid), failed, nil, false)
+	flushEvents(fixture.sut)
+
+	count, sum := subscribes()
+	require.Equal(t, beforeCount+1, count)
+	require.InDelta(t, 0.05, sum-beforeSum, 1e-6)
+}
+
 func Test_OnTrackSubscribed_EventIsSent(t *testing.T) {
 	fixture := createFixture()
 
@@ -236,3 +380,520 @@ func Test_OnTrackSubscribed_EventIsSent(t *testing.T) {
 	require.Equal(t, publisherInfo.Identity, eventTrackSubscribed.Publisher.Identity)
 
 }
+
+func Test_OnTrackPublished_ImmediatelyAfterJoin_HasRoom(t *testing.T) {
+	fixture := createFixture()
+
+	// prepare
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID), Identity: "part1Identity"}
+
+	// do
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, false)
+	fixture.sut.TrackPublished(context.Background(), partSID, "part1Identity", &livekit.TrackInfo{Sid: "trackID"})
+	flushEvents(fixture.sut)
+
+	// test
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+	_, event := fixture.analytics.SendEventArgsForCall(0)
+	require.Equal(t, livekit.AnalyticsEventType_TRACK_PUBLISHED, event.Type)
+	require.Equal(t, room.Sid, event.RoomId)
+	require.Equal(t, room.Name, event.Room.Name)
+}
+
+func Test_OnTrackPublished_AfterWorkerRemoved_HasRoom(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.WorkerIdleTimeout = time.Minute
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	// prepare, a participant that never connects
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, false)
+	flushEvents(fixture.sut)
+
+	// do, the idle worker is removed before the track is published
+	clock.Advance(conf.Analytics.WorkerIdleTimeout + time.Second)
+	require.Eventually(t, func() bool {
+		_, ok := fixture.sut.GetParticipantStats(partSID)
+		return !ok
+	}, time.Second, time.Millisecond)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", &livekit.TrackInfo{Sid: "trackID"})
+	flushEvents(fixture.sut)
+
+	// test
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+	_, event := fixture.analytics.SendEventArgsForCall(0)
+	require.Equal(t, room.Sid, event.RoomId)
+}
+
+func Test_TrackChurnIsCoalesced(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.TrackChurnWindow = 200 * time.Millisecond
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	track := &livekit.TrackInfo{Sid: "TR_churn", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, false)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+	before := findMetric(t, "livekit_track_churn_coalesced_total", map[string]string{"kind": "VIDEO"}).GetCounter().GetValue()
+
+	// republished within the window, nothing is sent
+	fixture.sut.TrackUnpublished(context.Background(), partSID, "", track, true)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+	clock.Advance(conf.WebHook.TrackChurnWindow)
+	flushEvents(fixture.sut)
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_TRACK_PUBLISHED), 1)
+	require.Empty(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_TRACK_UNPUBLISHED))
+	after := findMetric(t, "livekit_track_churn_coalesced_total", map[string]string{"kind": "VIDEO"}).GetCounter().GetValue()
+	require.Equal(t, before+1, after)
+
+	// not republished, the unpublish is sent once the window has passed, even though the caller is done by then
+	ctx, cancel := context.WithCancel(context.Background())
+	fixture.sut.TrackUnpublished(ctx, partSID, "", track, true)
+	cancel()
+	flushEvents(fixture.sut)
+	require.Empty(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_TRACK_UNPUBLISHED))
+
+	clock.Advance(conf.WebHook.TrackChurnWindow)
+	flushEvents(fixture.sut)
+	unpublished := findAnalyticsEvents(fixture, livekit.AnalyticsEventType_TRACK_UNPUBLISHED)
+	require.Len(t, unpublished, 1)
+	for i := 0; i < fixture.analytics.SendEventCallCount(); i++ {
+		sendCtx, event := fixture.analytics.SendEventArgsForCall(i)
+		if event.Type == livekit.AnalyticsEventType_TRACK_UNPUBLISHED {
+			require.NoError(t, sendCtx.Err())
+		}
+	}
+}
+
+func Test_HeldUnpublishIsSentBeforeLeaving(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.TrackChurnWindow = time.Minute
+	fixture := createFixtureWithClock(conf, newFakeClock())
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "part1"}
+	track := &livekit.TrackInfo{Sid: "TR_held", Type: livekit.TrackType_AUDIO}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, false)
+	fixture.sut.TrackPublished(context.Background(), livekit.ParticipantID(participantInfo.Sid), "", track)
+	fixture.sut.TrackUnpublished(context.Background(), livekit.ParticipantID(participantInfo.Sid), "", track, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	flushEvents(fixture.sut)
+
+	// the window never passes on the clock
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_TRACK_UNPUBLISHED), 1)
+}
+
+func Test_OnTrackSubscriptionFailed_EventIsSent(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := "part1"
+	failures := func() float64 {
+		labels := map[string]string{"reason": livekit.SubscriptionError_SE_CODEC_UNSUPPORTED.String()}
+		if metric := findMetric(t, "livekit_track_subscription_failures_total", labels); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	before := failures()
+
+	// do
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: partSID}, nil, nil, true)
+	fixture.sut.TrackSubscriptionFailed(context.Background(), livekit.ParticipantID(partSID), "tr1", livekit.SubscriptionError_SE_CODEC_UNSUPPORTED)
+	flushEvents(fixture.sut)
+
+	// test
+	require.Equal(t, 2, fixture.analytics.SendEventCallCount())
+	_, event := fixture.analytics.SendEventArgsForCall(1)
+	require.Equal(t, livekit.AnalyticsEventType_TRACK_SUBSCRIBE_FAILED, event.Type)
+	require.Equal(t, partSID, event.ParticipantId)
+	require.Equal(t, "tr1", event.TrackId)
+	require.Equal(t, room.Sid, event.RoomId)
+	require.Equal(t, room, event.Room)
+	require.Equal(t, livekit.SubscriptionError_SE_CODEC_UNSUPPORTED.String(), event.Error)
+	require.Equal(t, before+1, failures())
+}
+
+func Test_TrackPublishFailed_IsCounted(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := "part1"
+	labels := map[string]string{"kind": "VIDEO", "reason": telemetry.TrackPublishFailReasonPermissionDenied}
+	before := findMetric(t, "livekit_track_publish_failures_total", labels).GetCounter().GetValue()
+
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: partSID}, nil, nil, true)
+	fixture.sut.TrackPublishFailed(context.Background(), livekit.ParticipantID(partSID), &livekit.AddTrackRequest{
+		Cid:             "cid",
+		Name:            "camera",
+		Type:            livekit.TrackType_VIDEO,
+		Source:          livekit.TrackSource_CAMERA,
+		SimulcastCodecs: []*livekit.SimulcastCodec{{Codec: "video/vp9", Cid: "cid"}},
+	}, telemetry.TrackPublishFailReasonPermissionDenied)
+	flushEvents(fixture.sut)
+
+	require.Equal(t, before+1, findMetric(t, "livekit_track_publish_failures_total", labels).GetCounter().GetValue())
+	// only the join is sent
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+}
+
+func Test_ParticipantRoleChanged_KeepsStats(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	labels := map[string]string{"from": "agent", "to": "standard"}
+	roleChanges := func() float64 {
+		if metric := findMetric(t, "livekit_participant_role_changes_total", labels); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	before := roleChanges()
+
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID), Kind: livekit.ParticipantInfo_AGENT}, nil, nil, true)
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, "TR_1", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}})
+	fixture.flush()
+	sampled, ok := fixture.sut.GetParticipantStats(partSID)
+	require.True(t, ok)
+	require.Len(t, sampled.Tracks, 1)
+
+	// do
+	promoted := &livekit.ParticipantInfo{Sid: string(partSID), Kind: livekit.ParticipantInfo_STANDARD}
+	fixture.sut.ParticipantRoleChanged(context.Background(), room, promoted, livekit.ParticipantInfo_AGENT, livekit.ParticipantInfo_AGENT)
+	fixture.sut.ParticipantRoleChanged(context.Background(), room, promoted, livekit.ParticipantInfo_AGENT, livekit.ParticipantInfo_STANDARD)
+
+	// test
+	flushEvents(fixture.sut)
+	// only the change of kind is counted, and it isn't sent as an analytics event
+	require.Equal(t, before+1, roleChanges())
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+
+	// stats accumulated before the change carry on
+	stats, ok := fixture.sut.GetParticipantStats(partSID)
+	require.True(t, ok)
+	require.Equal(t, sampled.SampledAt, stats.SampledAt)
+	require.Equal(t, sampled.Tracks, stats.Tracks)
+
+	fixture.drain(t)
+	require.Len(t, findWebhookEvents(fixture, telemetry.EventParticipantRoleChanged), 1)
+}
+
+func Test_ParticipantDuplicateIdentity(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	joining := &livekit.ParticipantInfo{Sid: "PA_joining", Identity: "shared"}
+	evicted := &livekit.ParticipantInfo{Sid: "PA_evicted", Identity: "shared"}
+	duplicates := func() float64 {
+		return findMetric(t, "livekit_participant_duplicate_identity_total", nil).GetCounter().GetValue()
+	}
+	before := duplicates()
+
+	fixture.sut.ParticipantDuplicateIdentity(context.Background(), room, joining, evicted)
+	flushEvents(fixture.sut)
+
+	require.Equal(t, before+1, duplicates())
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+}
+
+func simulcastLayerSwitches(t *testing.T, layer livekit.VideoQuality, reason string) float64 {
+	labels := map[string]string{"layer": layer.String(), "reason": reason}
+	return findMetric(t, "livekit_simulcast_layer_switches_total", labels).GetCounter().GetValue()
+}
+
+func Test_SimulcastLayerChanged_CountedByLayerAndReason(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.SimulcastLayerSampleRate = 1
+	fixture := createFixtureWithConfig(conf)
+
+	bandwidth := simulcastLayerSwitches(t, livekit.VideoQuality_MEDIUM, telemetry.SimulcastLayerChangeReasonBandwidth)
+	availability := simulcastLayerSwitches(t, livekit.VideoQuality_MEDIUM, telemetry.SimulcastLayerChangeReasonAvailability)
+
+	// do
+	fixture.sut.SimulcastLayerChanged(
+		context.Background(),
+		"part1",
+		"tr1",
+		livekit.VideoQuality_MEDIUM,
+		telemetry.SimulcastLayerChangeReasonBandwidth,
+	)
+	flushEvents(fixture.sut)
+
+	// test
+	require.Equal(t, bandwidth+1, simulcastLayerSwitches(t, livekit.VideoQuality_MEDIUM, telemetry.SimulcastLayerChangeReasonBandwidth))
+	require.Equal(t, availability, simulcastLayerSwitches(t, livekit.VideoQuality_MEDIUM, telemetry.SimulcastLayerChangeReasonAvailability))
+	require.Equal(t, 0, fixture.analytics.SendEventCallCount())
+}
+
+func Test_SimulcastLayerChanged_CountedWhenNotSampled(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.SimulcastLayerSampleRate = 0
+	fixture := createFixtureWithConfig(conf)
+
+	before := simulcastLayerSwitches(t, livekit.VideoQuality_HIGH, "")
+
+	// do
+	for i := 0; i < 10; i++ {
+		fixture.sut.SimulcastLayerChanged(context.Background(), "part1", "tr1", livekit.VideoQuality_HIGH, "")
+	}
+
+	flushEvents(fixture.sut)
+
+	// test
+	require.Equal(t, before+10, simulcastLayerSwitches(t, livekit.VideoQuality_HIGH, ""))
+}
+
+func Test_TrackLayerPausedAndResumed(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := "part1"
+	pauses := func() float64 {
+		if metric := findMetric(t, "livekit_track_layer_paused_total", map[string]string{"layer": "HIGH"}); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	before := pauses()
+
+	// do
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: partSID}, nil, nil, true)
+	fixture.sut.TrackLayerPaused(context.Background(), livekit.ParticipantID(partSID), "tr1", livekit.VideoQuality_HIGH)
+	fixture.sut.TrackLayerResumed(context.Background(), livekit.ParticipantID(partSID), "tr1", livekit.VideoQuality_HIGH)
+
+	// test
+	flushEvents(fixture.sut)
+	// only pauses are counted, and neither is sent as an analytics event
+	require.Equal(t, before+1, pauses())
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+}
+
+func Test_DataPacketForwarded(t *testing.T) {
+	fixture := createFixture()
+
+	counter := func(name string) float64 {
+		return findMetric(t, name, map[string]string{"kind": "reliable"}).GetCounter().GetValue()
+	}
+	packetsBefore, bytesBefore := counter("livekit_data_packet_total"), counter("livekit_data_packet_bytes")
+
+	// do
+	fixture.sut.DataPacketForwarded(context.Background(), "part1", livekit.DataPacket_RELIABLE, 100)
+	fixture.sut.DataPacketForwarded(context.Background(), "part1", livekit.DataPacket_RELIABLE, 50)
+
+	// test
+	// only recorded once the stats are flushed
+	require.Equal(t, packetsBefore, counter("livekit_data_packet_total"))
+	fixture.sut.FlushStats()
+	require.Equal(t, packetsBefore+2, counter("livekit_data_packet_total"))
+	require.Equal(t, bytesBefore+150, counter("livekit_data_packet_bytes"))
+	require.Equal(t, 0, fixture.analytics.SendEventCallCount())
+}
+
+func Test_PublishedTracksAreCountedByCodec(t *testing.T) {
+	fixture := createFixture()
+
+	published := func(codec string) float64 {
+		if metric := findMetric(t, "livekit_track_published_codec", map[string]string{"codec": codec}); metric != nil {
+			return metric.GetGauge().GetValue()
+		}
+		return 0
+	}
+	vp8Before, opusBefore := published("vp8"), published("opus")
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+
+	// the backup codec isn't counted
+	video := &livekit.TrackInfo{
+		Sid:      "TR_codec_video",
+		Type:     livekit.TrackType_VIDEO,
+		MimeType: "video/VP8",
+		Codecs:   []*livekit.SimulcastCodecInfo{{MimeType: "video/VP8"}, {MimeType: "video/VP9"}},
+	}
+	audio := &livekit.TrackInfo{Sid: "TR_codec_audio", Type: livekit.TrackType_AUDIO, MimeType: "audio/opus"}
+	fixture.sut.TrackPublished(context.Background(), partSID, "", video)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", audio)
+	flushEvents(fixture.sut)
+	require.Equal(t, vp8Before+1, published("vp8"))
+	require.Equal(t, opusBefore+1, published("opus"))
+	require.Zero(t, published("vp9"))
+
+	// unpublishing twice, or a track that was never published, doesn't take the count below zero
+	fixture.sut.TrackUnpublished(context.Background(), partSID, "", video, false)
+	fixture.sut.TrackUnpublished(context.Background(), partSID, "", video, false)
+	fixture.sut.TrackUnpublished(context.Background(), partSID, "", &livekit.TrackInfo{Sid: "TR_codec_unknown", MimeType: "video/VP8"}, false)
+	flushEvents(fixture.sut)
+	require.Equal(t, vp8Before, published("vp8"))
+	require.Equal(t, opusBefore+1, published("opus"))
+}
+
+func Test_EventsAreCountedByType(t *testing.T) {
+	fixture := createFixture()
+
+	counter := func(name string, labels map[string]string) float64 {
+		if metric := findMetric(t, name, labels); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	webhookEvents := func(event string) float64 {
+		return counter("livekit_telemetry_webhook_events_total", map[string]string{"event": event})
+	}
+	analyticsEvents := func(eventType string) float64 {
+		return counter("livekit_telemetry_analytics_events_total", map[string]string{"type": eventType})
+	}
+	joinedBefore, otherBefore := webhookEvents(webhook.EventParticipantJoined), webhookEvents("other")
+	protocolBefore := analyticsEvents(livekit.AnalyticsEventType_PARTICIPANT_JOINED.String())
+	otherTypeBefore := analyticsEvents("other")
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventParticipantJoined})
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: "not_an_event"})
+	fixture.sut.SendEvent(context.Background(), &livekit.AnalyticsEvent{Type: livekit.AnalyticsEventType_PARTICIPANT_JOINED})
+	fixture.sut.SendEvent(context.Background(), &livekit.AnalyticsEvent{Type: livekit.AnalyticsEventType(5000)})
+
+	// unknown events are counted as other rather than getting a series of their own
+	require.Equal(t, joinedBefore+1, webhookEvents(webhook.EventParticipantJoined))
+	require.Equal(t, otherBefore+1, webhookEvents("other"))
+	require.Nil(t, findMetric(t, "livekit_telemetry_webhook_events_total", map[string]string{"event": "not_an_event"}))
+	require.Equal(t, protocolBefore+1, analyticsEvents(livekit.AnalyticsEventType_PARTICIPANT_JOINED.String()))
+	require.Equal(t, otherTypeBefore+1, analyticsEvents("other"))
+}
+
+func Test_ParticipantEventsCarryNodeRegion(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Region = "us-west"
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "part1"}
+	clientMeta := &livekit.AnalyticsClientMeta{Node: "node1"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, clientMeta, true)
+	fixture.sut.TrackPublished(context.Background(), livekit.ParticipantID(participantInfo.Sid), "", &livekit.TrackInfo{Sid: "TR_1"})
+	fixture.sut.ParticipantActive(context.Background(), room, participantInfo, &livekit.AnalyticsClientMeta{Region: "eu-central"}, false)
+	fixture.sut.RoomStarted(context.Background(), room)
+	flushEvents(fixture.sut)
+
+	require.Equal(t, 4, fixture.analytics.SendEventCallCount())
+
+	_, joined := fixture.analytics.SendEventArgsForCall(0)
+	require.Equal(t, "us-west", joined.ClientMeta.Region)
+	require.Equal(t, "node1", joined.ClientMeta.Node)
+	// the caller's meta is left alone
+	require.Empty(t, clientMeta.Region)
+
+	_, published := fixture.analytics.SendEventArgsForCall(1)
+	require.Equal(t, "us-west", published.ClientMeta.Region)
+
+	// a region that is already set is kept
+	_, active := fixture.analytics.SendEventArgsForCall(2)
+	require.Equal(t, "eu-central", active.ClientMeta.Region)
+
+	// room events are not participant scoped
+	_, started := fixture.analytics.SendEventArgsForCall(3)
+	require.Nil(t, started.ClientMeta)
+}
+
+func Test_EventsCarryNodeID(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	analytics := &telemetryfakes.FakeAnalyticsService{}
+	sut := telemetry.NewTelemetryService(conf, nil, analytics, telemetry.WithNodeID("ND_local"))
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "part1"}
+	sut.RoomStarted(context.Background(), room)
+	sut.ParticipantJoined(context.Background(), room, participantInfo, nil, &livekit.AnalyticsClientMeta{Node: "ND_other"}, true)
+	flushEvents(sut)
+
+	require.Equal(t, 2, analytics.SendEventCallCount())
+
+	_, started := analytics.SendEventArgsForCall(0)
+	require.Equal(t, "ND_local", started.ClientMeta.GetNode())
+
+	// a node set by the caller is kept
+	_, joined := analytics.SendEventArgsForCall(1)
+	require.Equal(t, "ND_other", joined.ClientMeta.GetNode())
+}
+
+func Test_ParticipantEventsWithoutNodeRegion(t *testing.T) {
+	fixture := createFixture()
+
+	fixture.sut.TrackPublished(context.Background(), "part1", "", &livekit.TrackInfo{Sid: "TR_1"})
+	flushEvents(fixture.sut)
+
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+	_, published := fixture.analytics.SendEventArgsForCall(0)
+	require.Nil(t, published.ClientMeta)
+}
+
+func Test_ParticipantMediaActive_SentOnceWhenMediaFlows(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID), Identity: "ident"}, nil, nil, false)
+
+	latencies := func() uint64 {
+		if metric := findMetric(t, "livekit_participant_join_to_media_seconds", nil); metric != nil {
+			return metric.GetHistogram().GetSampleCount()
+		}
+		return 0
+	}
+	latenciesBefore := latencies()
+
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, "TR_1", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	// connected, but no media yet
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PaddingBytes: 100}}})
+	fixture.flush()
+	require.Equal(t, latenciesBefore, latencies())
+
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 1000}}})
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 1000}}})
+	fixture.flush()
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventParticipantMediaActive, event.Event)
+	require.Equal(t, room.Sid, event.Room.Sid)
+	require.Equal(t, string(partSID), event.Participant.Sid)
+	require.Equal(t, "ident", event.Participant.Identity)
+
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+	require.Equal(t, latenciesBefore+1, latencies())
+}
+
+func Test_ParticipantMediaActive_ResetsWithSession(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+	key := telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, "")
+	stat := &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 33}}}
+
+	// stats arriving after leaving don't start the session
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, false)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, false)
+	fixture.flush()
+	fixture.sut.TrackStats(key, stat)
+	fixture.flush()
+
+	// joining again starts a new session, which becomes active once
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, false)
+	fixture.sut.TrackStats(key, stat)
+	fixture.flush()
+	fixture.sut.TrackStats(key, stat)
+	fixture.flush()
+
+	fixture.drain(t)
+	require.Len(t, findWebhookEvents(fixture, telemetry.EventParticipantMediaActive), 1)
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"math/rand"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// eventSampleRates is the fraction of each kind of event that is sent, events that aren't in it are all sent
+type eventSampleRates map[string]float64
+
+// newEventSampleRates returns the configured rates that sample anything, nil when none do.
+// rates outside 0 to 1 are ignored, so those events are all sent
+func newEventSampleRates(kind string, rates map[string]float64) eventSampleRates {
+	var sampled eventSampleRates
+	for event, rate := range rates {
+		if rate < 0 || rate > 1 {
+			logger.Warnw("invalid event sample rate, sending all events", nil,
+				"kind", kind,
+				"event", event,
+				"sampleRate", rate,
+			)
+			continue
+		}
+		if rate == 1 {
+			continue
+		}
+		if sampled == nil {
+			sampled = make(eventSampleRates)
+		}
+		sampled[event] = rate
+	}
+	return sampled
+}
+
+// sampledOut returns true when the event should not be sent
+func (r eventSampleRates) sampledOut(event string) bool {
+	rate, ok := r[event]
+	return ok && (rate <= 0 || rand.Float64() >= rate)
+}
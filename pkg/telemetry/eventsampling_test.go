@@ -0,0 +1,78 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func Test_SendEvent_SampledByType(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.EventSampleRates = map[string]float64{
+		"ROOM_CREATED": 0,
+		"ROOM_ENDED":   1,
+	}
+	fixture := createFixtureWithConfig(conf)
+	sampledOut := func(eventType string) float64 {
+		return findMetric(t, "livekit_telemetry_analytics_sampled_out_total", map[string]string{"type": eventType}).GetCounter().GetValue()
+	}
+	before := sampledOut("ROOM_CREATED")
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	fixture.sut.RoomStarted(context.Background(), room)
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+
+	require.Empty(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_ROOM_CREATED))
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_ROOM_ENDED), 1)
+	require.Equal(t, before+1, sampledOut("ROOM_CREATED"))
+}
+
+func Test_NotifyEvent_SampledByEvent(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.EventSampleRates = map[string]float64{
+		webhook.EventRoomStarted: 0,
+		// out of range, all delivered
+		webhook.EventRoomFinished: 2,
+	}
+	// analytics sampling doesn't apply to webhooks
+	conf.Analytics.EventSampleRates = map[string]float64{"ROOM_ENDED": 0}
+
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	sut := telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{notifier}, &telemetryfakes.FakeAnalyticsService{})
+	sampledOut := func() float64 {
+		return findMetric(t, "livekit_webhook_sampled_out_total", map[string]string{"event": webhook.EventRoomStarted}).GetCounter().GetValue()
+	}
+	before := sampledOut()
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, 1, notifier.NotifyCallCount())
+	_, event := notifier.NotifyArgsForCall(0)
+	require.Equal(t, webhook.EventRoomFinished, event.Event)
+	require.Equal(t, before+1, sampledOut())
+}
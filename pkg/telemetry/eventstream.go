@@ -0,0 +1,204 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// why a client of an event stream was disconnected, labels livekit_event_stream_disconnects_total
+const (
+	eventStreamDisconnectSlow   = "slow"
+	eventStreamDisconnectClosed = "closed"
+	eventStreamDisconnectError  = "error"
+)
+
+// the endpoint name WebHookConfig.Redactions apply to the event stream by
+const EventStreamEndpoint = "event_stream"
+
+const (
+	defaultEventStreamBufferSize   = 100
+	defaultEventStreamWriteTimeout = 10 * time.Second
+)
+
+type EventStreamSinkParams struct {
+	// events buffered for each client, a client that falls further behind is disconnected
+	BufferSize int
+	// maximum time writing an event to a client may take
+	WriteTimeout time.Duration
+	// send events in a WebhookEnvelope, along with the details they have no field for, as URLNotifierParams.Envelope
+	Envelope bool
+}
+
+// EventStreamSink pushes webhook events to clients connected over WebSocket as they are sent, alongside webhook
+// delivery. each event is a text message with the event encoded as JSON, as delivered to webhooks, redacted as for
+// an endpoint named EventStreamEndpoint. clients that don't keep up are disconnected rather than holding up the others
+type EventStreamSink struct {
+	params   EventStreamSinkParams
+	upgrader websocket.Upgrader
+
+	lock    sync.Mutex
+	closed  bool
+	clients map[*eventStreamClient]struct{}
+}
+
+type eventStreamClient struct {
+	conn   *websocket.Conn
+	events chan []byte
+	// closed once the client is removed, the writer then closes the connection
+	done chan struct{}
+}
+
+func NewEventStreamSink(params EventStreamSinkParams) *EventStreamSink {
+	if params.BufferSize <= 0 {
+		params.BufferSize = defaultEventStreamBufferSize
+	}
+	if params.WriteTimeout <= 0 {
+		params.WriteTimeout = defaultEventStreamWriteTimeout
+	}
+	return &EventStreamSink{
+		params: params,
+		upgrader: websocket.Upgrader{
+			// clients are authenticated by access token, not origin
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		clients: make(map[*eventStreamClient]struct{}),
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket and streams events to it until either side closes it
+func (s *EventStreamSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		http.Error(w, "requires websocket", http.StatusBadRequest)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Warnw("could not upgrade event stream connection", err)
+		return
+	}
+
+	c := &eventStreamClient{
+		conn:   conn,
+		events: make(chan []byte, s.params.BufferSize),
+		done:   make(chan struct{}),
+	}
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		_ = conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.lock.Unlock()
+	prometheus.AddEventStreamClient()
+
+	// nothing is expected from clients, reading notices when they go away
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				s.remove(c, eventStreamDisconnectClosed)
+				return
+			}
+		}
+	}()
+
+	s.write(c)
+}
+
+func (s *EventStreamSink) write(c *eventStreamClient) {
+	defer c.conn.Close()
+	for {
+		select {
+		case <-c.done:
+			deadline := time.Now().Add(s.params.WriteTimeout)
+			_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+			return
+		case event := <-c.events:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(s.params.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, event); err != nil {
+				s.remove(c, eventStreamDisconnectError)
+				return
+			}
+		}
+	}
+}
+
+// Publish queues event for every connected client, disconnecting those whose buffer is full. details are sent
+// along with it when the sink sends envelopes. it never blocks
+func (s *EventStreamSink) Publish(event *livekit.WebhookEvent, details *WebhookDetails) {
+	encoded, err := protojson.Marshal(event)
+	if err == nil && s.params.Envelope {
+		encoded, err = encodeWebhookEnvelope(encoded, details, WebhookEncodingJSON)
+	}
+	if err != nil {
+		logger.Errorw("could not encode event for event stream", err, "event", event.Event)
+		return
+	}
+
+	var slow []*eventStreamClient
+	s.lock.Lock()
+	for c := range s.clients {
+		select {
+		case c.events <- encoded:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, c := range slow {
+		logger.Infow("disconnecting slow event stream client", "remoteAddr", c.conn.RemoteAddr().String(), "bufferSize", s.params.BufferSize)
+		s.remove(c, eventStreamDisconnectSlow)
+	}
+}
+
+// Close disconnects every client, connections made after are closed right away
+func (s *EventStreamSink) Close() {
+	s.lock.Lock()
+	s.closed = true
+	clients := make([]*eventStreamClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.lock.Unlock()
+
+	for _, c := range clients {
+		s.remove(c, eventStreamDisconnectClosed)
+	}
+}
+
+func (s *EventStreamSink) remove(c *eventStreamClient, reason string) {
+	s.lock.Lock()
+	_, ok := s.clients[c]
+	delete(s.clients, c)
+	s.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	close(c.done)
+	prometheus.SubEventStreamClient(reason)
+}
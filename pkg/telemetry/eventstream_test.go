@@ -0,0 +1,165 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func dialEventStream(t *testing.T, server *httptest.Server) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func eventStreamClients(t *testing.T) float64 {
+	return findMetric(t, "livekit_event_stream_clients", nil).GetGauge().GetValue()
+}
+
+func Test_EventStreamSink_StreamsWebhookEvents(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	sink := telemetry.NewEventStreamSink(telemetry.EventStreamSinkParams{})
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithEventStreamSink(sink),
+	)
+
+	clients := eventStreamClients(t)
+	conn := dialEventStream(t, server)
+	require.Eventually(t, func() bool {
+		return eventStreamClients(t) == clients+1
+	}, time.Second, 10*time.Millisecond)
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Room:  &livekit.Room{Sid: "RoomSid", Name: "RoomName"},
+	})
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	messageType, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, websocket.TextMessage, messageType)
+	event := &livekit.WebhookEvent{}
+	require.NoError(t, protojson.Unmarshal(message, event))
+	require.Equal(t, webhook.EventRoomStarted, event.Event)
+	require.Equal(t, "RoomSid", event.Room.Sid)
+	require.NotEmpty(t, event.Id)
+
+	// webhooks are still delivered, and clients are disconnected on shutdown
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, 1, notifier.NotifyCallCount())
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+	require.Equal(t, clients, eventStreamClients(t))
+}
+
+func Test_EventStreamSink_DisconnectsSlowClients(t *testing.T) {
+	sink := telemetry.NewEventStreamSink(telemetry.EventStreamSinkParams{BufferSize: 1})
+	server := httptest.NewServer(sink)
+	defer server.Close()
+	defer sink.Close()
+
+	slow := func() float64 {
+		return findMetric(t, "livekit_event_stream_disconnects_total", map[string]string{"reason": "slow"}).GetCounter().GetValue()
+	}
+	before := slow()
+	clients := eventStreamClients(t)
+
+	// never reads, so once the socket's buffers are full its events pile up
+	dialEventStream(t, server)
+	require.Eventually(t, func() bool {
+		return eventStreamClients(t) == clients+1
+	}, time.Second, 10*time.Millisecond)
+
+	event := &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Room:  &livekit.Room{Sid: "RoomSid", Metadata: strings.Repeat("m", 64<<10)},
+	}
+	for i := 0; i < 1000 && eventStreamClients(t) > clients; i++ {
+		sink.Publish(event, nil)
+	}
+	require.Equal(t, clients, eventStreamClients(t))
+	require.Equal(t, before+1, slow())
+}
+
+func Test_EventStreamSink_RedactsEvents(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.ParticipantClientInfo = true
+	conf.WebHook.Redactions = []config.WebHookRedactionConfig{
+		{Fields: []string{"participant.identity"}, Endpoints: []string{telemetry.EventStreamEndpoint}},
+		{Fields: []string{"client_info.address"}},
+	}
+	sink := telemetry.NewEventStreamSink(telemetry.EventStreamSinkParams{Envelope: true})
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithEventStreamSink(sink),
+	)
+
+	clients := eventStreamClients(t)
+	conn := dialEventStream(t, server)
+	require.Eventually(t, func() bool {
+		return eventStreamClients(t) == clients+1
+	}, time.Second, 10*time.Millisecond)
+
+	joinWithClientInfo(sut, &livekit.ClientInfo{Sdk: livekit.ClientInfo_JS, Os: "macOS", Address: "203.0.113.7"})
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	envelope, err := telemetry.ParseWebhookEnvelope(message, telemetry.WebhookContentTypeJSON)
+	require.NoError(t, err)
+	require.Equal(t, webhook.EventParticipantJoined, envelope.Event.Event)
+	require.Equal(t, "PA_client", envelope.Event.Participant.GetSid())
+	require.Empty(t, envelope.Event.Participant.GetIdentity())
+	require.NotNil(t, envelope.ClientInfo)
+	require.Equal(t, "macOS", envelope.ClientInfo.Os)
+	require.Empty(t, envelope.ClientInfo.Address)
+
+	// the identity is only redacted for the event stream
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, 1, notifier.NotifyCallCount())
+	_, delivered := notifier.NotifyArgsForCall(0)
+	require.Equal(t, "client", delivered.Participant.GetIdentity())
+}
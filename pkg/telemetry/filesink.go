@@ -0,0 +1,181 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+// values of the type field of records written by FileEventSink
+const (
+	FileEventTypeWebhook   = "webhook"
+	FileEventTypeAnalytics = "analytics"
+)
+
+var errFileEventSinkClosed = errors.New("file event sink is closed")
+
+type FileEventSinkParams struct {
+	Path string
+	// the file is rotated once it reaches this many bytes, 0 to not rotate by size
+	MaxSize int64
+	// the file is rotated once it has been written to for this long, 0 to not rotate by time
+	RotateInterval time.Duration
+	// rotation goes by this clock, the real one when nil
+	Clock Clock
+}
+
+// FileEventSink appends webhook and analytics events to a file as newline-delimited JSON, one record per line
+// with the event under "event" and its kind under "type". rotated files are renamed with the time of rotation
+// appended to the path
+type FileEventSink struct {
+	params FileEventSinkParams
+
+	lock     sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+type fileEventRecord struct {
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+func NewFileEventSink(params FileEventSinkParams) (*FileEventSink, error) {
+	if params.Clock == nil {
+		params.Clock = realClock{}
+	}
+	s := &FileEventSink{params: params}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileEventSink) WriteWebhookEvent(event *livekit.WebhookEvent) error {
+	return s.write(FileEventTypeWebhook, event)
+}
+
+func (s *FileEventSink) WriteAnalyticsEvent(event *livekit.AnalyticsEvent) error {
+	return s.write(FileEventTypeAnalytics, event)
+}
+
+// Close syncs the file to disk and closes it, events written after are dropped
+func (s *FileEventSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.closeLocked()
+	s.file = nil
+	return err
+}
+
+func (s *FileEventSink) write(eventType string, event proto.Message) error {
+	encoded, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(fileEventRecord{Type: eventType, Event: encoded})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return errFileEventSinkClosed
+	}
+	if s.shouldRotate(int64(len(line))) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileEventSink) shouldRotate(next int64) bool {
+	if s.size == 0 {
+		return false
+	}
+	if s.params.MaxSize > 0 && s.size+next > s.params.MaxSize {
+		return true
+	}
+	return s.params.RotateInterval > 0 && s.params.Clock.Now().Sub(s.openedAt) >= s.params.RotateInterval
+}
+
+func (s *FileEventSink) rotate() error {
+	if err := s.closeLocked(); err != nil {
+		logger.Warnw("failed to close event file", err, "path", s.params.Path)
+	}
+	s.file = nil
+
+	rotated := fmt.Sprintf("%s.%s", s.params.Path, s.params.Clock.Now().UTC().Format("20060102T150405.000"))
+	if err := os.Rename(s.params.Path, rotated); err != nil {
+		logger.Warnw("failed to rotate event file", err, "path", s.params.Path, "rotated", rotated)
+	}
+	return s.open()
+}
+
+func (s *FileEventSink) open() error {
+	file, err := os.OpenFile(s.params.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	s.openedAt = s.params.Clock.Now()
+	return nil
+}
+
+func (s *FileEventSink) closeLocked() error {
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// writeFileEvent logs a failure to write an event to the file sink. events sent once the sink has been closed on
+// shutdown are dropped quietly
+func (t *telemetryService) writeFileEvent(err error) {
+	if err != nil && !errors.Is(err, errFileEventSinkClosed) {
+		logger.Warnw("failed to write event to file", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+type fileEventRecord struct {
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+func readFileEvents(t *testing.T, path string) []fileEventRecord {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []fileEventRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record fileEventRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func Test_FileEventSink_WritesTypedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := telemetry.NewFileEventSink(telemetry.FileEventSinkParams{Path: path})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.WriteWebhookEvent(&livekit.WebhookEvent{Event: webhook.EventRoomStarted, Id: "EV_1"}))
+	require.NoError(t, sink.WriteAnalyticsEvent(&livekit.AnalyticsEvent{Type: livekit.AnalyticsEventType_ROOM_CREATED, RoomId: "RoomSid"}))
+	require.NoError(t, sink.Close())
+	require.Error(t, sink.WriteWebhookEvent(&livekit.WebhookEvent{Event: webhook.EventRoomFinished}))
+
+	records := readFileEvents(t, path)
+	require.Len(t, records, 2)
+
+	require.Equal(t, telemetry.FileEventTypeWebhook, records[0].Type)
+	webhookEvent := &livekit.WebhookEvent{}
+	require.NoError(t, protojson.Unmarshal(records[0].Event, webhookEvent))
+	require.Equal(t, "EV_1", webhookEvent.Id)
+
+	require.Equal(t, telemetry.FileEventTypeAnalytics, records[1].Type)
+	analyticsEvent := &livekit.AnalyticsEvent{}
+	require.NoError(t, protojson.Unmarshal(records[1].Event, analyticsEvent))
+	require.Equal(t, "RoomSid", analyticsEvent.RoomId)
+}
+
+func Test_FileEventSink_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+	clock := newFakeClock()
+	sink, err := telemetry.NewFileEventSink(telemetry.FileEventSinkParams{Path: path, MaxSize: 100, Clock: clock})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, sink.WriteWebhookEvent(&livekit.WebhookEvent{Event: webhook.EventParticipantJoined}))
+		// rotated files are named after the time of rotation
+		clock.Advance(time.Second)
+	}
+	require.NoError(t, sink.Close())
+
+	files, err := filepath.Glob(path + "*")
+	require.NoError(t, err)
+	require.Greater(t, len(files), 1)
+	total := 0
+	for _, file := range files {
+		records := readFileEvents(t, file)
+		require.NotEmpty(t, records)
+		total += len(records)
+	}
+	require.Equal(t, 4, total)
+}
+
+func Test_FileEventSink_RotatesByTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	clock := newFakeClock()
+	sink, err := telemetry.NewFileEventSink(telemetry.FileEventSinkParams{Path: path, RotateInterval: time.Hour, Clock: clock})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.WriteWebhookEvent(&livekit.WebhookEvent{Event: webhook.EventParticipantJoined}))
+	clock.Advance(time.Hour - time.Millisecond)
+	require.NoError(t, sink.WriteWebhookEvent(&livekit.WebhookEvent{Event: webhook.EventParticipantJoined}))
+	clock.Advance(time.Millisecond)
+	require.NoError(t, sink.WriteWebhookEvent(&livekit.WebhookEvent{Event: webhook.EventParticipantLeft}))
+	require.NoError(t, sink.Close())
+
+	files, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Len(t, readFileEvents(t, files[0]), 2)
+	require.Len(t, readFileEvents(t, path), 1)
+}
+
+func Test_FileEventSink_ReceivesTelemetryEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := telemetry.NewFileEventSink(telemetry.FileEventSinkParams{Path: path})
+	require.NoError(t, err)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.ExcludeEvents = []string{webhook.EventRoomFinished}
+	// no webhook endpoints, events still reach the file
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithFileEventSink(sink))
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	sut.RoomStarted(context.Background(), room)
+	sut.RoomEnded(context.Background(), room)
+	require.NoError(t, sut.Shutdown(context.Background()))
+
+	var types []string
+	for _, record := range readFileEvents(t, path) {
+		types = append(types, record.Type)
+	}
+	// room_finished is filtered out of webhooks, its analytics event is still written
+	require.ElementsMatch(t, []string{
+		telemetry.FileEventTypeWebhook,
+		telemetry.FileEventTypeAnalytics,
+		telemetry.FileEventTypeAnalytics,
+	}, types)
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"github.com/livekit/psrpc"
+)
+
+const (
+	IngressFailureNetwork = "network"
+	IngressFailureAuth    = "auth"
+	IngressFailureInput   = "input"
+	IngressFailureUnknown = "unknown"
+)
+
+// ClassifyIngressError buckets an ingress failure into a coarse reason, by the psrpc code it carries or the
+// network error it wraps. errors only known by their message are unknown
+func ClassifyIngressError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if isNetworkError(err) {
+		return IngressFailureNetwork
+	}
+
+	switch errorCode(err) {
+	case psrpc.DeadlineExceeded, psrpc.Unavailable:
+		return IngressFailureNetwork
+	case psrpc.Unauthenticated, psrpc.PermissionDenied:
+		return IngressFailureAuth
+	case psrpc.InvalidArgument, psrpc.NotAcceptable, psrpc.Unimplemented:
+		return IngressFailureInput
+	default:
+		return IngressFailureUnknown
+	}
+}
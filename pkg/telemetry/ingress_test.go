@@ -0,0 +1,79 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+	"github.com/livekit/psrpc"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_ClassifyIngressError(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		reason string
+	}{
+		{nil, ""},
+		{context.DeadlineExceeded, telemetry.IngressFailureNetwork},
+		{fmt.Errorf("pull failed: %w", context.DeadlineExceeded), telemetry.IngressFailureNetwork},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, telemetry.IngressFailureNetwork},
+		{psrpc.NewErrorf(psrpc.Unauthenticated, "invalid stream key"), telemetry.IngressFailureAuth},
+		{psrpc.NewErrorf(psrpc.PermissionDenied, "forbidden"), telemetry.IngressFailureAuth},
+		{psrpc.NewErrorf(psrpc.InvalidArgument, "unsupported codec H265"), telemetry.IngressFailureInput},
+		{errors.New("invalid stream key"), telemetry.IngressFailureUnknown},
+	} {
+		require.Equal(t, tc.reason, telemetry.ClassifyIngressError(tc.err), fmt.Sprint(tc.err))
+	}
+}
+
+func Test_IngressEnded(t *testing.T) {
+	fixture := createFixture()
+	info := &livekit.IngressInfo{
+		IngressId: "IN_1",
+		InputType: livekit.IngressInput_RTMP_INPUT,
+		RoomName:  "RoomName",
+		State:     &livekit.IngressState{RoomId: "RoomSid"},
+	}
+
+	fixture.sut.IngressEnded(context.Background(), info, nil)
+	fixture.sut.IngressEnded(context.Background(), info, psrpc.NewErrorf(psrpc.Unavailable, "connection reset by peer"))
+
+	fixture.drain(t)
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+	require.Equal(t, 2, fixture.analytics.SendEventCallCount())
+
+	_, event := fixture.notifier.NotifyArgsForCall(1)
+	require.Equal(t, webhook.EventIngressEnded, event.Event)
+
+	_, ev := fixture.analytics.SendEventArgsForCall(0)
+	require.Equal(t, livekit.AnalyticsEventType_INGRESS_ENDED, ev.Type)
+	require.Equal(t, "IN_1", ev.IngressId)
+	require.Equal(t, "RoomSid", ev.RoomId)
+	require.Equal(t, "RoomName", ev.Room.Name)
+	require.Empty(t, ev.Error)
+
+	_, ev = fixture.analytics.SendEventArgsForCall(1)
+	require.Equal(t, "connection reset by peer", ev.Error)
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const defaultJoinLeaveWindow = time.Minute
+
+// joinLeaveRates counts the joins and leaves of each room over a sliding window, to report rooms where they exceed a
+// threshold. each room takes constant space, approximating the window by weighting the count of the previous window
+// by how much of it still overlaps. rooms are forgotten when they end or have been quiet for two windows.
+// only used on the run goroutine
+type joinLeaveRates struct {
+	window    time.Duration
+	threshold int
+	rooms     map[livekit.RoomID]*joinLeaveRate
+}
+
+type joinLeaveRate struct {
+	windowStart time.Time
+	previous    int
+	current     int
+	// the threshold was exceeded, reported again only once the rate drops back to it
+	exceeded bool
+}
+
+func newJoinLeaveRates(window time.Duration, threshold int) *joinLeaveRates {
+	return &joinLeaveRates{
+		window:    window,
+		threshold: threshold,
+		rooms:     make(map[livekit.RoomID]*joinLeaveRate),
+	}
+}
+
+// record counts a join or leave in roomID at now, returning the joins and leaves within the window and whether that
+// just exceeded the threshold
+func (r *joinLeaveRates) record(roomID livekit.RoomID, now time.Time) (int, bool) {
+	rate, ok := r.rooms[roomID]
+	if !ok {
+		rate = &joinLeaveRate{windowStart: now}
+		r.rooms[roomID] = rate
+	}
+
+	if elapsed := now.Sub(rate.windowStart); elapsed >= 2*r.window {
+		rate.previous, rate.current = 0, 0
+		rate.windowStart = now
+	} else if elapsed >= r.window {
+		rate.previous, rate.current = rate.current, 0
+		rate.windowStart = rate.windowStart.Add(r.window)
+	}
+	rate.current++
+
+	overlap := 1 - float64(now.Sub(rate.windowStart))/float64(r.window)
+	count := rate.current + int(float64(rate.previous)*overlap)
+	if count <= r.threshold {
+		rate.exceeded = false
+		return count, false
+	}
+	if rate.exceeded {
+		return count, false
+	}
+	rate.exceeded = true
+	return count, true
+}
+
+func (r *joinLeaveRates) remove(roomID livekit.RoomID) {
+	delete(r.rooms, roomID)
+}
+
+// prune forgets rooms whose counts would start over from zero on their next join or leave
+func (r *joinLeaveRates) prune(now time.Time) {
+	for roomID, rate := range r.rooms {
+		if now.Sub(rate.windowStart) >= 2*r.window {
+			delete(r.rooms, roomID)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func joinAndLeave(fixture *telemetryServiceFixture, room *livekit.Room, sid string) {
+	participant := &livekit.ParticipantInfo{Sid: sid, Identity: sid}
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_CLIENT_INITIATED, true)
+}
+
+func Test_JoinsAndLeavesAreCounted(t *testing.T) {
+	fixture := createFixture()
+	joined := func() float64 {
+		return findMetric(t, "livekit_participant_joined_total", nil).GetCounter().GetValue()
+	}
+	left := func() float64 {
+		return findMetric(t, "livekit_participant_left_total", map[string]string{"reason": "CLIENT_INITIATED"}).GetCounter().GetValue()
+	}
+	joinedBefore, leftBefore := joined(), left()
+
+	room := &livekit.Room{Sid: "RM_counted", Name: "counted"}
+	joinAndLeave(fixture, room, "PA_counted")
+	flushEvents(fixture.sut)
+
+	require.Equal(t, joinedBefore+1, joined())
+	require.Equal(t, leftBefore+1, left())
+}
+
+func Test_JoinLeaveStormIsReported(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.JoinLeaveThreshold = 3
+	conf.Analytics.JoinLeaveWindow = time.Minute
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RM_storm", Name: "storm"}
+	before := joinLeaveStorms(t)
+	reported := func() float64 {
+		flushEvents(fixture.sut)
+		return joinLeaveStorms(t) - before
+	}
+
+	joinAndLeave(fixture, room, "PA_1")
+	require.Zero(t, reported())
+
+	joinAndLeave(fixture, room, "PA_2")
+	require.Equal(t, float64(1), reported())
+
+	// reported once while the storm lasts
+	joinAndLeave(fixture, room, "PA_3")
+	require.Equal(t, float64(1), reported())
+
+	// other rooms are counted separately
+	joinAndLeave(fixture, &livekit.Room{Sid: "RM_calm", Name: "calm"}, "PA_4")
+	require.Equal(t, float64(1), reported())
+
+	// once it has calmed down, another storm is reported again
+	clock.Advance(3 * time.Minute)
+	joinAndLeave(fixture, room, "PA_5")
+	require.Equal(t, float64(1), reported())
+	joinAndLeave(fixture, room, "PA_6")
+	require.Equal(t, float64(2), reported())
+}
+
+func Test_JoinLeaveRatesAreForgottenWhenRoomEnds(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.JoinLeaveThreshold = 3
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RM_ended", Name: "ended"}
+	before := joinLeaveStorms(t)
+	joinAndLeave(fixture, room, "PA_1")
+	fixture.sut.RoomEnded(context.Background(), room)
+
+	// had the room's joins and leaves been kept, these would exceed the threshold
+	for i := 0; i < 2; i++ {
+		participant := &livekit.ParticipantInfo{Sid: fmt.Sprintf("PA_rejoined_%d", i)}
+		fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	}
+	flushEvents(fixture.sut)
+	require.Equal(t, before, joinLeaveStorms(t))
+}
+
+func joinLeaveStorms(t *testing.T) float64 {
+	return findMetric(t, "livekit_room_join_leave_storms_total", nil).GetCounter().GetValue()
+}
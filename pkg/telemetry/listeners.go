@@ -0,0 +1,118 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gammazero/workerpool"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+// EventListener is called with webhook events from within the process, see TelemetryService.Subscribe
+type EventListener func(event *livekit.WebhookEvent)
+
+type eventListeners struct {
+	lock      sync.RWMutex
+	nextID    uint64
+	listeners map[uint64]EventListener
+	// a single worker, so listeners see events in the order they were sent
+	pool *workerpool.WorkerPool
+}
+
+func newEventListeners() *eventListeners {
+	return &eventListeners{
+		listeners: make(map[uint64]EventListener),
+		pool:      workerpool.New(1),
+	}
+}
+
+func (t *telemetryService) Subscribe(listener EventListener) func() {
+	l := t.eventListeners
+	l.lock.Lock()
+	id := l.nextID
+	l.nextID++
+	l.listeners[id] = listener
+	l.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			delete(l.listeners, id)
+			l.lock.Unlock()
+		})
+	}
+}
+
+func (l *eventListeners) hasListeners() bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return len(l.listeners) != 0
+}
+
+// hasEventListeners returns true if webhook events are published to listeners or the event stream
+func (t *telemetryService) hasEventListeners() bool {
+	return t.eventListeners.hasListeners() || t.eventStream != nil
+}
+
+// notifyListeners hands event to the listeners and the event stream on their own worker, so they never hold up
+// webhook delivery. details are published along with it to the event stream, redacted as event is. like webhooks,
+// events are dropped once Shutdown has been called
+func (t *telemetryService) notifyListeners(event *livekit.WebhookEvent, details *WebhookDetails) {
+	l := t.eventListeners
+	if !t.hasEventListeners() {
+		return
+	}
+	// listeners get their own copy, the event is being delivered to webhooks concurrently
+	event = proto.Clone(event).(*livekit.WebhookEvent)
+
+	t.webhookLock.RLock()
+	defer t.webhookLock.RUnlock()
+	if t.webhookClosed {
+		return
+	}
+	l.pool.Submit(func() {
+		l.lock.RLock()
+		listeners := make([]EventListener, 0, len(l.listeners))
+		for _, listener := range l.listeners {
+			listeners = append(listeners, listener)
+		}
+		l.lock.RUnlock()
+
+		for _, listener := range listeners {
+			callListener(listener, event)
+		}
+		if t.eventStream != nil {
+			redacted, redactedDetails := redactWebhook(t.eventStreamRedactions, event, details)
+			t.eventStream.Publish(redacted, redactedDetails)
+		}
+	})
+}
+
+func callListener(listener EventListener, event *livekit.WebhookEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorw("telemetry event listener panicked", fmt.Errorf("%v", r), "event", event.Event)
+		}
+	}()
+
+	listener(event)
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+type recordingListener struct {
+	lock   sync.Mutex
+	events []*livekit.WebhookEvent
+}
+
+func (r *recordingListener) onEvent(event *livekit.WebhookEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingListener) getEvents() []*livekit.WebhookEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]*livekit.WebhookEvent{}, r.events...)
+}
+
+func Test_Subscribe_ReceivesEventsInOrder(t *testing.T) {
+	// no webhook endpoints configured, listeners still get events
+	conf, _ := config.NewConfig("", true, nil, nil)
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{})
+
+	listener := &recordingListener{}
+	unsubscribe := sut.Subscribe(listener.onEvent)
+	// a panicking listener doesn't affect the others
+	sut.Subscribe(func(_ *livekit.WebhookEvent) {
+		panic("listener failure")
+	})
+
+	rooms := []string{"RM_1", "RM_2", "RM_3"}
+	for _, sid := range rooms {
+		sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Room:  &livekit.Room{Sid: sid},
+		})
+	}
+
+	require.Eventually(t, func() bool {
+		return len(listener.getEvents()) == len(rooms)
+	}, time.Second, 10*time.Millisecond)
+	for i, event := range listener.getEvents() {
+		require.Equal(t, rooms[i], event.Room.Sid)
+		require.NotEmpty(t, event.Id)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Room:  &livekit.Room{Sid: "RM_4"},
+	})
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Len(t, listener.getEvents(), len(rooms))
+}
+
+func Test_Subscribe_DoesNotBlockWebhooks(t *testing.T) {
+	fixture := createFixture()
+
+	release := make(chan struct{})
+	defer close(release)
+	fixture.sut.Subscribe(func(_ *livekit.WebhookEvent) {
+		<-release
+	})
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	require.Eventually(t, func() bool {
+		return fixture.notifier.NotifyCallCount() == 1
+	}, time.Second, 10*time.Millisecond)
+}
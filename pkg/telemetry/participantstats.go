@@ -0,0 +1,134 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"sort"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// ParticipantStats is the latest sample of a participant's media, taken each time its stats are sent
+type ParticipantStats struct {
+	ParticipantID livekit.ParticipantID
+	RoomID        livekit.RoomID
+	RoomName      livekit.RoomName
+	// zero until the first sample has been taken
+	SampledAt time.Time
+	// tracks that had stats over the interval ending at SampledAt
+	Tracks []ParticipantTrackStats
+}
+
+// ParticipantTrackStats is the media of one track over the sampled interval
+type ParticipantTrackStats struct {
+	TrackID   livekit.TrackID
+	TrackType livekit.TrackType
+	// UPSTREAM for tracks the participant publishes, DOWNSTREAM for the ones it subscribes to
+	Direction livekit.StreamType
+	// bits per second over the interval
+	Bitrate float64
+	// Bitrate averaged with the previous intervals', see AnalyticsConfig.BitrateSmoothingAlpha
+	SmoothedBitrate float64
+	// fraction of packets lost, between 0 and 1
+	PacketLoss float64
+	Jitter     time.Duration
+	RTT        time.Duration
+	// latest quality score of a track the participant subscribes to, see QoSScore. 0 for published tracks and
+	// when scoring is disabled
+	QoSScore float64
+}
+
+// GetParticipantStats returns the latest stats sampled for a participant that is in a room on this node
+func (t *telemetryService) GetParticipantStats(participantID livekit.ParticipantID) (*ParticipantStats, bool) {
+	worker, ok := t.getWorker(participantID)
+	if !ok {
+		return nil, false
+	}
+	return worker.ParticipantStats()
+}
+
+// ParticipantStats returns a copy of the latest sample, false once the participant has left
+func (s *StatsWorker) ParticipantStats() (*ParticipantStats, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.closedAt.IsZero() {
+		return nil, false
+	}
+	tracks := append([]ParticipantTrackStats(nil), s.sampledTracks...)
+	for i := range tracks {
+		if qos, ok := s.subscribedQoS[tracks[i].TrackID]; ok && tracks[i].Direction == livekit.StreamType_DOWNSTREAM {
+			tracks[i].QoSScore = qos.score
+		}
+	}
+	return &ParticipantStats{
+		ParticipantID: s.participantID,
+		RoomID:        s.roomID,
+		RoomName:      s.roomName,
+		SampledAt:     s.sampledAt,
+		Tracks:        tracks,
+	}, true
+}
+
+// sampleTracks summarizes the media tracks in stats, which cover interval
+func sampleTracks(
+	stats []*livekit.AnalyticsStat,
+	trackTypes map[livekit.TrackID]livekit.TrackType,
+	interval time.Duration,
+) []ParticipantTrackStats {
+	var tracks []ParticipantTrackStats
+	for _, stat := range stats {
+		trackType, ok := trackTypes[livekit.TrackID(stat.TrackId)]
+		if !ok || trackType == livekit.TrackType_DATA {
+			continue
+		}
+
+		track := ParticipantTrackStats{
+			TrackID:   livekit.TrackID(stat.TrackId),
+			TrackType: trackType,
+			Direction: stat.Kind,
+		}
+		var bytes uint64
+		var packets, lost uint32
+		for _, stream := range stat.Streams {
+			bytes += stream.PrimaryBytes + stream.RetransmitBytes + stream.PaddingBytes
+			packets += stream.PrimaryPackets + stream.PaddingPackets
+			lost += stream.PacketsLost
+			// jitter is reported in microseconds, RTT in milliseconds
+			if jitter := time.Duration(stream.Jitter) * time.Microsecond; jitter > track.Jitter {
+				track.Jitter = jitter
+			}
+			if rtt := time.Duration(stream.Rtt) * time.Millisecond; rtt > track.RTT {
+				track.RTT = rtt
+			}
+		}
+		if interval > 0 {
+			track.Bitrate = float64(bytes*8) / interval.Seconds()
+		}
+		if packets+lost > 0 {
+			track.PacketLoss = float64(lost) / float64(packets+lost)
+		}
+		tracks = append(tracks, track)
+	}
+
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].Direction != tracks[j].Direction {
+			return tracks[i].Direction < tracks[j].Direction
+		}
+		return tracks[i].TrackID < tracks[j].TrackID
+	})
+	return tracks
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promEgressActive   prometheus.Gauge
+	promEgressFailures *prometheus.CounterVec
+	promEgressDuration *prometheus.HistogramVec
+	promEgressBytes    *prometheus.HistogramVec
+)
+
+func initEgressStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promEgressActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "egress",
+		Name:        "active",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Egress sessions started through this node that have not ended yet.",
+	})
+	promEgressFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "egress",
+		Name:        "failures",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"reason"})
+	promEgressDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "egress",
+		Name:        "duration_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "How long each egress ran for, by type. egress that never started is not counted.",
+		Buckets:     []float64{10, 30, 60, 300, 600, 1800, 3600, 7200, 14400, 28800},
+	}, []string{"type"})
+	promEgressBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "egress",
+		Name:        "output_bytes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Size of the files and segments written by each egress, by type. egress with no output is not counted.",
+		Buckets:     prometheus.ExponentialBuckets(1<<20, 4, 10),
+	}, []string{"type"})
+
+	prometheus.MustRegister(promEgressActive)
+	prometheus.MustRegister(promEgressFailures)
+	prometheus.MustRegister(promEgressDuration)
+	prometheus.MustRegister(promEgressBytes)
+}
+
+func AddEgress() {
+	promEgressActive.Inc()
+}
+
+func SubEgress() {
+	promEgressActive.Dec()
+}
+
+func RecordEgressFailure(reason string) {
+	promEgressFailures.WithLabelValues(reason).Inc()
+}
+
+// RecordEgressUsage records the duration and output size of an egress that ended. either is skipped when zero,
+// so egress that failed early or only streamed doesn't pull down the averages
+func RecordEgressUsage(egressType string, duration time.Duration, bytes int64) {
+	if duration > 0 {
+		promEgressDuration.WithLabelValues(egressType).Observe(duration.Seconds())
+	}
+	if bytes > 0 {
+		promEgressBytes.WithLabelValues(egressType).Observe(float64(bytes))
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promWebhookEvents    *prometheus.CounterVec
+	promAnalyticsEvents  *prometheus.CounterVec
+	promAnalyticsDropped *prometheus.CounterVec
+
+	promAnalyticsBuffered prometheus.Gauge
+	promAnalyticsEvicted  prometheus.Counter
+
+	promAnalyticsBatchSize    prometheus.Histogram
+	promAnalyticsBatchFlushes *prometheus.CounterVec
+	promAnalyticsTruncated    *prometheus.CounterVec
+	promAnalyticsSampledOut   *prometheus.CounterVec
+	promAnalyticsActiveSink   prometheus.Gauge
+
+	promEventStreamClients     prometheus.Gauge
+	promEventStreamDisconnects *prometheus.CounterVec
+)
+
+func initEventStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promWebhookEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "webhook_events_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook events sent to the telemetry service, by event, whether or not they were delivered.",
+	}, []string{"event"})
+	promAnalyticsEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_events_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Analytics events sent by the telemetry service, by type.",
+	}, []string{"type"})
+	promAnalyticsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_dropped_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Analytics events and stats dropped because the queue in front of the analytics sink was full, by kind.",
+	}, []string{"kind"})
+	promAnalyticsBuffered = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_buffered_bytes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Size of the analytics events kept on disk after failing to send, waiting to be sent again.",
+	})
+	promAnalyticsEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_buffer_evicted_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Analytics events dropped from the disk buffer, oldest first, to keep it under its maximum size.",
+	})
+	promAnalyticsBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_batch_size",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Analytics events in each batch sent, compare with the configured batch size.",
+		Buckets:     []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000},
+	})
+	promAnalyticsBatchFlushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_batch_flushes_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Analytics batches sent, by what triggered the send: size, interval, flush or shutdown.",
+	}, []string{"trigger"})
+	promAnalyticsTruncated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_truncated_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Analytics events that had optional fields dropped to fit the configured maximum size, by type.",
+	}, []string{"type"})
+	promAnalyticsSampledOut = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_sampled_out_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Analytics events not sent as they were sampled out by the event's sample rate, by type.",
+	}, []string{"type"})
+	promAnalyticsActiveSink = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "telemetry",
+		Name:        "analytics_active_sink",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Position in the failover order of the analytics sink events are sent to, 0 for the primary.",
+	})
+	promEventStreamClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "event_stream",
+		Name:        "clients",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Clients connected to the event stream.",
+	})
+	promEventStreamDisconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "event_stream",
+		Name:        "disconnects_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Clients disconnected from the event stream, by reason: slow when they fell behind, closed or error.",
+	}, []string{"reason"})
+
+	prometheus.MustRegister(promWebhookEvents)
+	prometheus.MustRegister(promAnalyticsEvents)
+	prometheus.MustRegister(promAnalyticsDropped)
+	prometheus.MustRegister(promAnalyticsBuffered)
+	prometheus.MustRegister(promAnalyticsEvicted)
+	prometheus.MustRegister(promAnalyticsBatchSize)
+	prometheus.MustRegister(promAnalyticsBatchFlushes)
+	prometheus.MustRegister(promAnalyticsTruncated)
+	prometheus.MustRegister(promAnalyticsSampledOut)
+	prometheus.MustRegister(promAnalyticsActiveSink)
+	prometheus.MustRegister(promEventStreamClients)
+	prometheus.MustRegister(promEventStreamDisconnects)
+}
+
+func RecordWebhookEvent(event string) {
+	promWebhookEvents.WithLabelValues(event).Inc()
+}
+
+func RecordAnalyticsEvent(eventType string) {
+	promAnalyticsEvents.WithLabelValues(eventType).Inc()
+}
+
+func RecordAnalyticsDropped(kind string, count int) {
+	promAnalyticsDropped.WithLabelValues(kind).Add(float64(count))
+}
+
+func AddAnalyticsBuffered(bytes int64) {
+	promAnalyticsBuffered.Add(float64(bytes))
+}
+
+func SubAnalyticsBuffered(bytes int64) {
+	promAnalyticsBuffered.Sub(float64(bytes))
+}
+
+func RecordAnalyticsEvicted(count int) {
+	promAnalyticsEvicted.Add(float64(count))
+}
+
+func RecordAnalyticsBatch(trigger string, size int) {
+	promAnalyticsBatchSize.Observe(float64(size))
+	promAnalyticsBatchFlushes.WithLabelValues(trigger).Inc()
+}
+
+func RecordAnalyticsTruncated(eventType string) {
+	promAnalyticsTruncated.WithLabelValues(eventType).Inc()
+}
+
+func RecordAnalyticsSampledOut(eventType string) {
+	promAnalyticsSampledOut.WithLabelValues(eventType).Inc()
+}
+
+func SetAnalyticsActiveSink(index int) {
+	promAnalyticsActiveSink.Set(float64(index))
+}
+
+func AddEventStreamClient() {
+	promEventStreamClients.Inc()
+}
+
+func SubEventStreamClient(reason string) {
+	promEventStreamClients.Dec()
+	promEventStreamDisconnects.WithLabelValues(reason).Inc()
+}
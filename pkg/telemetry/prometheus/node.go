@@ -110,6 +110,12 @@ func Init(nodeID string, nodeType livekit.NodeType, env string) {
 	initRoomStats(nodeID, nodeType, env)
 	initPSRPCStats(nodeID, nodeType, env)
 	initQualityStats(nodeID, nodeType, env)
+	initWebhookStats(nodeID, nodeType, env)
+	initEventStats(nodeID, nodeType, env)
+	initEgressStats(nodeID, nodeType, env)
+	initTranscriptionStats(nodeID, nodeType, env)
+	initIngressStats(nodeID, nodeType, env)
+	initTelemetryStats(nodeID, nodeType, env)
 }
 
 func GetUpdatedNodeStats(prev *livekit.NodeStats, prevAverage *livekit.NodeStats) (*livekit.NodeStats, bool, error) {
@@ -65,6 +65,13 @@ var (
 	promPacketBytesIncomingRetransmit prometheus.Counter
 	promPacketBytesOutgoingInitial    prometheus.Counter
 	promPacketBytesOutgoingRetransmit prometheus.Counter
+
+	promDataPacketTotal         *prometheus.CounterVec
+	promDataPacketBytes         *prometheus.CounterVec
+	promDataPacketTotalReliable prometheus.Counter
+	promDataPacketTotalLossy    prometheus.Counter
+	promDataPacketBytesReliable prometheus.Counter
+	promDataPacketBytesLossy    prometheus.Counter
 )
 
 func initPacketStats(nodeID string, nodeType livekit.NodeType, env string) {
@@ -137,6 +144,20 @@ func initPacketStats(nodeID string, nodeType livekit.NodeType, env string) {
 		Name:        "total",
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
 	}, []string{"kind"})
+	promDataPacketTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "data_packet",
+		Name:        "total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Data packets forwarded to participants, by kind.",
+	}, []string{"kind"})
+	promDataPacketBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "data_packet",
+		Name:        "bytes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Bytes of data packets forwarded to participants, by kind.",
+	}, []string{"kind"})
 
 	prometheus.MustRegister(promPacketTotal)
 	prometheus.MustRegister(promPacketBytes)
@@ -149,6 +170,8 @@ func initPacketStats(nodeID string, nodeType livekit.NodeType, env string) {
 	prometheus.MustRegister(promRTT)
 	prometheus.MustRegister(promParticipantJoin)
 	prometheus.MustRegister(promConnections)
+	prometheus.MustRegister(promDataPacketTotal)
+	prometheus.MustRegister(promDataPacketBytes)
 
 	promPacketTotalIncomingInitial = promPacketTotal.WithLabelValues(string(Incoming), transmissionInitial)
 	promPacketTotalIncomingRetransmit = promPacketTotal.WithLabelValues(string(Incoming), transmissionRetransmit)
@@ -158,6 +181,10 @@ func initPacketStats(nodeID string, nodeType livekit.NodeType, env string) {
 	promPacketBytesIncomingRetransmit = promPacketBytes.WithLabelValues(string(Incoming), transmissionRetransmit)
 	promPacketBytesOutgoingInitial = promPacketBytes.WithLabelValues(string(Outgoing), transmissionInitial)
 	promPacketBytesOutgoingRetransmit = promPacketBytes.WithLabelValues(string(Outgoing), transmissionRetransmit)
+	promDataPacketTotalReliable = promDataPacketTotal.WithLabelValues("reliable")
+	promDataPacketTotalLossy = promDataPacketTotal.WithLabelValues("lossy")
+	promDataPacketBytesReliable = promDataPacketBytes.WithLabelValues("reliable")
+	promDataPacketBytesLossy = promDataPacketBytes.WithLabelValues("lossy")
 }
 
 func IncrementPackets(direction Direction, count uint64, retransmit bool) {
@@ -210,6 +237,16 @@ func IncrementBytes(direction Direction, count uint64, retransmit bool) {
 	}
 }
 
+func RecordDataPackets(kind livekit.DataPacket_Kind, packets uint64, bytes uint64) {
+	if kind == livekit.DataPacket_RELIABLE {
+		promDataPacketTotalReliable.Add(float64(packets))
+		promDataPacketBytesReliable.Add(float64(bytes))
+	} else {
+		promDataPacketTotalLossy.Add(float64(packets))
+		promDataPacketBytesLossy.Add(float64(bytes))
+	}
+}
+
 func IncrementRTCP(direction Direction, nack, pli, fir uint32) {
 	if nack > 0 {
 		promNackTotal.WithLabelValues(string(direction)).Add(float64(nack))
@@ -24,6 +24,10 @@ var (
 	qualityRating prometheus.Histogram
 	qualityScore  prometheus.Histogram
 	qualityDrop   *prometheus.CounterVec
+
+	promParticipantQuality *prometheus.GaugeVec
+	promIntervalJitter     *prometheus.HistogramVec
+	promIntervalRTT        *prometheus.HistogramVec
 )
 
 func initQualityStats(nodeID string, nodeType livekit.NodeType, env string) {
@@ -47,10 +51,36 @@ func initQualityStats(nodeID string, nodeType livekit.NodeType, env string) {
 		Name:        "drop",
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
 	}, []string{"direction"})
+	promParticipantQuality = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "participants",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Participants by their current connection quality, as computed from telemetry stats.",
+	}, []string{"quality"})
+	promIntervalJitter = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "jitter_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Highest jitter of a track over a stats interval.",
+		Buckets:     []float64{1, 5, 10, 20, 50, 100, 200, 500},
+	}, []string{"kind", "direction"})
+	promIntervalRTT = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "quality",
+		Name:        "rtt_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Highest round-trip time of a track over a stats interval.",
+		Buckets:     []float64{5, 10, 20, 50, 100, 200, 500, 1000},
+	}, []string{"kind", "direction"})
 
 	prometheus.MustRegister(qualityRating)
 	prometheus.MustRegister(qualityScore)
 	prometheus.MustRegister(qualityDrop)
+	prometheus.MustRegister(promParticipantQuality)
+	prometheus.MustRegister(promIntervalJitter)
+	prometheus.MustRegister(promIntervalRTT)
 }
 
 func RecordQuality(rating livekit.ConnectionQuality, score float32, numUpDrops int, numDownDrops int) {
@@ -59,3 +89,22 @@ func RecordQuality(rating livekit.ConnectionQuality, score float32, numUpDrops i
 	qualityDrop.WithLabelValues("up").Add(float64(numUpDrops))
 	qualityDrop.WithLabelValues("down").Add(float64(numDownDrops))
 }
+
+func AddConnectionQuality(quality string) {
+	promParticipantQuality.WithLabelValues(quality).Inc()
+}
+
+func SubConnectionQuality(quality string) {
+	promParticipantQuality.WithLabelValues(quality).Dec()
+}
+
+// RecordIntervalNetworkStats records the jitter and RTT of a track over a stats interval, skipping values that weren't reported.
+// direction is either "publish" or "subscribe"
+func RecordIntervalNetworkStats(kind string, direction string, jitterMs float64, rttMs float64) {
+	if jitterMs > 0 {
+		promIntervalJitter.WithLabelValues(kind, direction).Observe(jitterMs)
+	}
+	if rttMs > 0 {
+		promIntervalRTT.WithLabelValues(kind, direction).Observe(rttMs)
+	}
+}
@@ -15,6 +15,8 @@
 package prometheus
 
 import (
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -43,6 +45,60 @@ var (
 	promTrackSubscribedCurrent *prometheus.GaugeVec
 	promTrackPublishCounter    *prometheus.CounterVec
 	promTrackSubscribeCounter  *prometheus.CounterVec
+	promTrackMuteCounter       *prometheus.CounterVec
+	promTrackSubscriptionError *prometheus.CounterVec
+	promTrackPublishFailures   *prometheus.CounterVec
+	promTrackPacketsLost       *prometheus.CounterVec
+	promTrackPackets           *prometheus.CounterVec
+	promTrackPublishedBytes    *prometheus.CounterVec
+	promTrackSubscribedBytes   *prometheus.CounterVec
+	promParticipantSession     *prometheus.HistogramVec
+	promParticipantJoinToMedia prometheus.Histogram
+	promParticipantConnect     prometheus.Histogram
+	promTrackSubscribeLatency  *prometheus.HistogramVec
+	promTrackNacks             *prometheus.CounterVec
+	promTrackPlis              *prometheus.CounterVec
+	promTrackFirs              *prometheus.CounterVec
+	promSimulcastLayerSwitches *prometheus.CounterVec
+	promTrackLayerPauses       *prometheus.CounterVec
+	promParticipantDuplicates  prometheus.Counter
+	promTrackPublishedCodec    *prometheus.GaugeVec
+	promParticipantJoined      prometheus.Counter
+	promParticipantLeft        *prometheus.CounterVec
+	promTrackActiveLayers      *prometheus.GaugeVec
+	promTrackChurnCoalesced    *prometheus.CounterVec
+	promTrackStalls            *prometheus.CounterVec
+	promParticipantSpeaking    prometheus.Gauge
+	promParticipantAudioLevel  *prometheus.HistogramVec
+	promBandwidthEstimate      *prometheus.GaugeVec
+	promRoomBitrate            *prometheus.GaugeVec
+	promNodeBitrate            *prometheus.GaugeVec
+	promTrackQoSScore          *prometheus.GaugeVec
+	promRoomDeleted            prometheus.Counter
+	promRoomJoinLeaveStorms    prometheus.Counter
+	promParticipantRoleChanges *prometheus.CounterVec
+	promPermissionChanges      *prometheus.CounterVec
+	promTrackFirstPacket       *prometheus.HistogramVec
+	promTrackNeverActive       *prometheus.CounterVec
+	promActiveSpeakerChanges   prometheus.Counter
+	promActiveSpeakerLevel     prometheus.Histogram
+
+	// number of published tracks per packet loss label set, the series is deleted once it drops to zero
+	trackLossLock   sync.Mutex
+	trackLossSeries = make(map[[2]string]int)
+
+	// codec each published track is counted under, so an unpublish only undoes a matching publish
+	trackCodecLock sync.Mutex
+	trackCodecs    = make(map[string]string)
+
+	// latest quality scores of the subscribed tracks of each kind, averaged into livekit_track_qos_score. the series
+	// is deleted once no track is left to score
+	trackQoSLock   sync.Mutex
+	trackQoSScores = make(map[string]*qosScores)
+
+	// number of published tracks per kind and active layer count, the series is deleted once it drops to zero
+	trackLayersLock   sync.Mutex
+	trackLayersSeries = make(map[[2]string]int)
 )
 
 func initRoomStats(nodeID string, nodeType livekit.NodeType, env string) {
@@ -57,6 +113,7 @@ func initRoomStats(nodeID string, nodeType livekit.NodeType, env string) {
 		Subsystem:   "room",
 		Name:        "duration_seconds",
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Duration of rooms that ended, from their creation time.",
 		Buckets: []float64{
 			5, 10, 60, 5 * 60, 10 * 60, 30 * 60, 60 * 60, 2 * 60 * 60, 5 * 60 * 60, 10 * 60 * 60,
 		},
@@ -91,6 +148,274 @@ func initRoomStats(nodeID string, nodeType livekit.NodeType, env string) {
 		Name:        "subscribe_counter",
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
 	}, []string{"state", "error"})
+	promTrackSubscriptionError = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "subscription_failures_total",
+		Help:        "Subscription failures reported to subscribers, by reason.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"reason"})
+	promTrackPublishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "publish_failures_total",
+		Help:        "Track publications rejected by the server, by kind and reason.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"kind", "reason"})
+	promTrackMuteCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "mute_counter",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"kind", "state", "source"})
+	promTrackPacketsLost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "packets_lost_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Packets lost on published tracks, by track type and room.",
+	}, []string{"kind", "room"})
+	promTrackPackets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "packets_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Packets received on published tracks, by track type and room, to compute loss rate against.",
+	}, []string{"kind", "room"})
+	promTrackPublishedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "published_bytes_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Bytes received on published tracks, including retransmissions and padding, by track type.",
+	}, []string{"kind"})
+	promTrackSubscribedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "subscribed_bytes_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Bytes sent on subscribed tracks, including retransmissions and padding, by track type.",
+	}, []string{"kind"})
+
+	promParticipantSession = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "session_duration_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Time participants stayed in a room, from joining to leaving.",
+		Buckets: []float64{
+			10, 30, 60, 5 * 60, 10 * 60, 30 * 60, 60 * 60, 2 * 60 * 60, 5 * 60 * 60,
+		},
+	}, []string{"room"})
+	promParticipantJoinToMedia = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "join_to_media_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Time from a participant joining to first sending or receiving media.",
+		Buckets:     []float64{0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+	})
+	promParticipantConnect = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "join_to_connected_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Time from a participant joining to its peer connection being established.",
+		Buckets:     []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30},
+	})
+	promTrackSubscribeLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "subscribe_latency_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Time from a participant asking to subscribe to a track to the track being bound and forwarded, by kind.",
+		Buckets:     []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 30},
+	}, []string{"kind"})
+	promTrackNacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "nacks_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "NACKs on tracks, incoming from their subscribers or outgoing to their publishers, by kind and direction.",
+	}, []string{"kind", "direction"})
+	promTrackPlis = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "plis_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "PLIs on tracks, by kind and direction.",
+	}, []string{"kind", "direction"})
+	promTrackFirs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "firs_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "FIRs on tracks, by kind and direction.",
+	}, []string{"kind", "direction"})
+	promSimulcastLayerSwitches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "simulcast",
+		Name:        "layer_switches_total",
+		Help:        "Changes of the simulcast layer forwarded to subscribers, by the layer switched to and the reason.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"layer", "reason"})
+	promTrackLayerPauses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "layer_paused_total",
+		Help:        "Layers of subscribed tracks paused because of the bandwidth available to the subscriber, by layer.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"layer"})
+	promTrackPublishedCodec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "published_codec",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Published tracks by their primary codec.",
+	}, []string{"codec"})
+	promTrackActiveLayers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "active_layers",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Published tracks by the number of spatial layers received for them in the last stats interval.",
+	}, []string{"kind", "layers"})
+	promTrackChurnCoalesced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "churn_coalesced_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Tracks republished soon after being unpublished, whose unpublish and publish events were not sent.",
+	}, []string{"kind"})
+	promTrackStalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "stalls_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Times media stopped flowing on a published track that wasn't muted, or to a subscriber while it flowed on the published track, by kind and direction.",
+	}, []string{"kind", "direction"})
+	promParticipantSpeaking = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "speaking",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Participants publishing a microphone that are speaking. only kept when analytics.audio_level_interval is set.",
+	})
+	promParticipantAudioLevel = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "audio_level",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Average audio level of participants publishing a microphone over each analytics.audio_level_interval, between 0 and 1, by whether they spoke during it.",
+		Buckets:     []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1},
+	}, []string{"state"})
+	promBandwidthEstimate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "bandwidth_estimate_bps",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Latest estimate of a subscriber's available downlink bandwidth, in bits per second.",
+	}, []string{"participant_id"})
+	promRoomBitrate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "room",
+		Name:        "bitrate_bps",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Bitrate of the media published to and subscribed from a room on this node over the last stats interval, by direction.",
+	}, []string{"room", "direction"})
+	promNodeBitrate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "node",
+		Name:        "bitrate_bps",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Bitrate of the media published to and subscribed from every room on this node over the last stats interval, by direction.",
+	}, []string{"direction"})
+	promTrackQoSScore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "qos_score",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Average of the latest quality scores of subscribed tracks, between 0 and 100, by track type.",
+	}, []string{"kind"})
+	promParticipantJoined = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "joined_total",
+		Help:        "Participants that joined.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+	promParticipantLeft = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "left_total",
+		Help:        "Participants that left, by disconnect reason.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"reason"})
+	promParticipantDuplicates = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "duplicate_identity_total",
+		Help:        "Participants evicted since another joined the same room with the same identity.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+	promRoomDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "room",
+		Name:        "deleted_total",
+		Help:        "Rooms deleted through the API, rather than ending once empty.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+	promRoomJoinLeaveStorms = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "room",
+		Name:        "join_leave_storms_total",
+		Help:        "Times participants joined and left a room more than analytics.join_leave_threshold times within analytics.join_leave_window.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+	promParticipantRoleChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "role_changes_total",
+		Help:        "Participants whose kind changed during their session, by the kind changed from and to.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"from", "to"})
+	promPermissionChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "participant",
+		Name:        "permission_changes_total",
+		Help:        "Changes to participant permissions, by the livekit.ParticipantPermission field changed.",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"permission"})
+	promTrackFirstPacket = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "first_packet_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Time from a track being published to its first packet being received, by kind.",
+		Buckets:     []float64{0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+	}, []string{"kind"})
+	promTrackNeverActive = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "track",
+		Name:        "never_active_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Published tracks that received no media before the first packet timeout, by kind.",
+	}, []string{"kind"})
+	promActiveSpeakerChanges = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "room",
+		Name:        "active_speaker_changes_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Changes to the order of the active speakers of rooms, once debounced.",
+	})
+	promActiveSpeakerLevel = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "room",
+		Name:        "active_speaker_level",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Audio levels of the active speakers when their order changed, between 0 and 1.",
+		Buckets:     []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1},
+	})
 
 	prometheus.MustRegister(promRoomCurrent)
 	prometheus.MustRegister(promRoomDuration)
@@ -99,6 +424,43 @@ func initRoomStats(nodeID string, nodeType livekit.NodeType, env string) {
 	prometheus.MustRegister(promTrackSubscribedCurrent)
 	prometheus.MustRegister(promTrackPublishCounter)
 	prometheus.MustRegister(promTrackSubscribeCounter)
+	prometheus.MustRegister(promTrackSubscriptionError)
+	prometheus.MustRegister(promTrackPublishFailures)
+	prometheus.MustRegister(promTrackMuteCounter)
+	prometheus.MustRegister(promTrackPacketsLost)
+	prometheus.MustRegister(promTrackPackets)
+	prometheus.MustRegister(promTrackPublishedBytes)
+	prometheus.MustRegister(promTrackSubscribedBytes)
+	prometheus.MustRegister(promParticipantSession)
+	prometheus.MustRegister(promParticipantJoinToMedia)
+	prometheus.MustRegister(promParticipantConnect)
+	prometheus.MustRegister(promTrackSubscribeLatency)
+	prometheus.MustRegister(promTrackNacks)
+	prometheus.MustRegister(promTrackPlis)
+	prometheus.MustRegister(promTrackFirs)
+	prometheus.MustRegister(promSimulcastLayerSwitches)
+	prometheus.MustRegister(promTrackLayerPauses)
+	prometheus.MustRegister(promParticipantDuplicates)
+	prometheus.MustRegister(promParticipantJoined)
+	prometheus.MustRegister(promParticipantLeft)
+	prometheus.MustRegister(promTrackPublishedCodec)
+	prometheus.MustRegister(promTrackActiveLayers)
+	prometheus.MustRegister(promTrackChurnCoalesced)
+	prometheus.MustRegister(promTrackStalls)
+	prometheus.MustRegister(promParticipantSpeaking)
+	prometheus.MustRegister(promParticipantAudioLevel)
+	prometheus.MustRegister(promBandwidthEstimate)
+	prometheus.MustRegister(promRoomBitrate)
+	prometheus.MustRegister(promNodeBitrate)
+	prometheus.MustRegister(promTrackQoSScore)
+	prometheus.MustRegister(promRoomDeleted)
+	prometheus.MustRegister(promRoomJoinLeaveStorms)
+	prometheus.MustRegister(promParticipantRoleChanges)
+	prometheus.MustRegister(promPermissionChanges)
+	prometheus.MustRegister(promTrackFirstPacket)
+	prometheus.MustRegister(promTrackNeverActive)
+	prometheus.MustRegister(promActiveSpeakerChanges)
+	prometheus.MustRegister(promActiveSpeakerLevel)
 }
 
 func RoomStarted() {
@@ -107,13 +469,22 @@ func RoomStarted() {
 }
 
 func RoomEnded(startedAt time.Time) {
-	if !startedAt.IsZero() {
-		promRoomDuration.Observe(float64(time.Since(startedAt)) / float64(time.Second))
+	if duration, ok := roomDuration(startedAt, time.Now()); ok {
+		promRoomDuration.Observe(duration.Seconds())
 	}
 	promRoomCurrent.Sub(1)
 	roomCurrent.Dec()
 }
 
+// roomDuration returns how long a room created at startedAt lasted when ending at now.
+// rooms without a creation time are reported as time.Unix(0, 0) and have no duration
+func roomDuration(startedAt time.Time, now time.Time) (time.Duration, bool) {
+	if startedAt.IsZero() || startedAt.Unix() <= 0 || startedAt.After(now) {
+		return 0, false
+	}
+	return now.Sub(startedAt), true
+}
+
 func AddParticipant() {
 	promParticipantCurrent.Add(1)
 	participantCurrent.Inc()
@@ -134,6 +505,31 @@ func SubPublishedTrack(kind string) {
 	trackPublishedCurrent.Dec()
 }
 
+// AddPublishedCodec counts a published track under codec, a track that is already counted is not counted again
+func AddPublishedCodec(trackID string, codec string) {
+	trackCodecLock.Lock()
+	defer trackCodecLock.Unlock()
+
+	if _, ok := trackCodecs[trackID]; ok {
+		return
+	}
+	trackCodecs[trackID] = codec
+	promTrackPublishedCodec.WithLabelValues(codec).Inc()
+}
+
+// SubPublishedCodec stops counting a track added with AddPublishedCodec, tracks that weren't added are ignored
+func SubPublishedCodec(trackID string) {
+	trackCodecLock.Lock()
+	defer trackCodecLock.Unlock()
+
+	codec, ok := trackCodecs[trackID]
+	if !ok {
+		return
+	}
+	delete(trackCodecs, trackID)
+	promTrackPublishedCodec.WithLabelValues(codec).Dec()
+}
+
 func AddPublishAttempt(kind string) {
 	trackPublishAttempts.Inc()
 	promTrackPublishCounter.WithLabelValues(kind, "attempt").Inc()
@@ -153,6 +549,10 @@ func RecordTrackSubscribeSuccess(kind string) {
 	trackSubscribeSuccess.Inc()
 }
 
+func RecordTrackSubscribeLatency(kind string, latency time.Duration) {
+	promTrackSubscribeLatency.WithLabelValues(kind).Observe(latency.Seconds())
+}
+
 func RecordTrackUnsubscribed(kind string) {
 	// unsubscribed modifies current counter, but we leave the total values alone since they
 	// are used to compute rate
@@ -172,3 +572,270 @@ func RecordTrackSubscribeFailure(err error, isUserError bool) {
 		trackSubscribeUserError.Inc()
 	}
 }
+
+func RecordTrackSubscriptionFailure(reason string) {
+	promTrackSubscriptionError.WithLabelValues(reason).Inc()
+}
+
+func RecordTrackPublishFailure(kind string, reason string) {
+	promTrackPublishFailures.WithLabelValues(kind, reason).Inc()
+}
+
+func RecordTrackMute(kind string, muted bool, source string) {
+	state := "unmuted"
+	if muted {
+		state = "muted"
+	}
+	promTrackMuteCounter.WithLabelValues(kind, state, source).Inc()
+}
+
+func RecordParticipantSession(room string, duration time.Duration) {
+	promParticipantSession.WithLabelValues(room).Observe(duration.Seconds())
+}
+
+func RecordParticipantJoinToMedia(latency time.Duration) {
+	promParticipantJoinToMedia.Observe(latency.Seconds())
+}
+
+func RecordParticipantConnectLatency(latency time.Duration) {
+	promParticipantConnect.Observe(latency.Seconds())
+}
+
+func RecordParticipantJoined() {
+	promParticipantJoined.Inc()
+}
+
+func RecordParticipantLeft(reason string) {
+	promParticipantLeft.WithLabelValues(reason).Inc()
+}
+
+func RecordRoomDeleted() {
+	promRoomDeleted.Inc()
+}
+
+func RecordRoomJoinLeaveStorm() {
+	promRoomJoinLeaveStorms.Inc()
+}
+
+func RecordParticipantRoleChanged(from string, to string) {
+	promParticipantRoleChanges.WithLabelValues(from, to).Inc()
+}
+
+func RecordPermissionChanged(permission string) {
+	promPermissionChanges.WithLabelValues(permission).Inc()
+}
+
+func RecordParticipantDuplicateIdentity() {
+	promParticipantDuplicates.Inc()
+}
+
+func RecordSimulcastLayerSwitch(layer string, reason string) {
+	promSimulcastLayerSwitches.WithLabelValues(layer, reason).Inc()
+}
+
+func RecordTrackLayerPaused(layer string) {
+	promTrackLayerPauses.WithLabelValues(layer).Inc()
+}
+
+// DeleteParticipantSessions deletes the session duration series of a room
+func DeleteParticipantSessions(room string) {
+	promParticipantSession.DeleteLabelValues(room)
+}
+
+// AddPacketLossTrack accounts for a published track whose packet loss is recorded under kind and room
+func AddPacketLossTrack(kind string, room string) {
+	trackLossLock.Lock()
+	trackLossSeries[[2]string{kind, room}]++
+	trackLossLock.Unlock()
+}
+
+// SubPacketLossTrack removes a track added with AddPacketLossTrack, deleting the series of kind and room
+// when no published track is left to report them
+func SubPacketLossTrack(kind string, room string) {
+	key := [2]string{kind, room}
+
+	trackLossLock.Lock()
+	defer trackLossLock.Unlock()
+
+	trackLossSeries[key]--
+	if trackLossSeries[key] > 0 {
+		return
+	}
+	delete(trackLossSeries, key)
+	promTrackPacketsLost.DeleteLabelValues(kind, room)
+	promTrackPackets.DeleteLabelValues(kind, room)
+}
+
+func RecordTrackFeedback(kind string, direction Direction, nacks, plis, firs uint32) {
+	if nacks > 0 {
+		promTrackNacks.WithLabelValues(kind, string(direction)).Add(float64(nacks))
+	}
+	if plis > 0 {
+		promTrackPlis.WithLabelValues(kind, string(direction)).Add(float64(plis))
+	}
+	if firs > 0 {
+		promTrackFirs.WithLabelValues(kind, string(direction)).Add(float64(firs))
+	}
+}
+
+func RecordTrackChurnCoalesced(kind string) {
+	promTrackChurnCoalesced.WithLabelValues(kind).Inc()
+}
+
+// RecordTrackStall counts a stall of a track of kind, direction is publish or subscribe
+func RecordTrackStall(kind string, direction string) {
+	promTrackStalls.WithLabelValues(kind, direction).Inc()
+}
+
+func RecordTrackFirstPacket(kind string, latency time.Duration) {
+	promTrackFirstPacket.WithLabelValues(kind).Observe(latency.Seconds())
+}
+
+func RecordTrackNeverActive(kind string) {
+	promTrackNeverActive.WithLabelValues(kind).Inc()
+}
+
+// RecordActiveSpeakers counts a change to the order of the active speakers of a room, with their levels
+func RecordActiveSpeakers(levels []float32) {
+	promActiveSpeakerChanges.Inc()
+	for _, level := range levels {
+		promActiveSpeakerLevel.Observe(float64(level))
+	}
+}
+
+func AddParticipantSpeaking() {
+	promParticipantSpeaking.Inc()
+}
+
+func SubParticipantSpeaking() {
+	promParticipantSpeaking.Dec()
+}
+
+func RecordParticipantAudioLevel(speaking bool, level float64) {
+	state := "silent"
+	if speaking {
+		state = "speaking"
+	}
+	promParticipantAudioLevel.WithLabelValues(state).Observe(level)
+}
+
+func RecordBandwidthEstimate(participantID string, bps int64) {
+	promBandwidthEstimate.WithLabelValues(participantID).Set(float64(bps))
+}
+
+// RecordRoomBitrate records the bitrate published to and subscribed from room, in bits per second
+func RecordRoomBitrate(room string, published float64, subscribed float64) {
+	promRoomBitrate.WithLabelValues(room, "publish").Set(published)
+	promRoomBitrate.WithLabelValues(room, "subscribe").Set(subscribed)
+}
+
+// DeleteRoomBitrate removes the bitrate series of a room
+func DeleteRoomBitrate(room string) {
+	promRoomBitrate.DeleteLabelValues(room, "publish")
+	promRoomBitrate.DeleteLabelValues(room, "subscribe")
+}
+
+// RecordNodeBitrate records the bitrate published to and subscribed from every room on the node, in bits per second
+func RecordNodeBitrate(published float64, subscribed float64) {
+	promNodeBitrate.WithLabelValues("publish").Set(published)
+	promNodeBitrate.WithLabelValues("subscribe").Set(subscribed)
+}
+
+type qosScores struct {
+	sum   float64
+	count int
+}
+
+// AddTrackQoSScore adds the score of a subscribed track of kind to the average
+func AddTrackQoSScore(kind string, score float64) {
+	trackQoSLock.Lock()
+	defer trackQoSLock.Unlock()
+
+	scores := trackQoSScores[kind]
+	if scores == nil {
+		scores = &qosScores{}
+		trackQoSScores[kind] = scores
+	}
+	scores.sum += score
+	scores.count++
+	promTrackQoSScore.WithLabelValues(kind).Set(scores.sum / float64(scores.count))
+}
+
+// UpdateTrackQoSScore replaces the score prev of a subscribed track of kind, added with AddTrackQoSScore, by score
+func UpdateTrackQoSScore(kind string, prev float64, score float64) {
+	trackQoSLock.Lock()
+	defer trackQoSLock.Unlock()
+
+	scores := trackQoSScores[kind]
+	if scores == nil {
+		return
+	}
+	scores.sum += score - prev
+	promTrackQoSScore.WithLabelValues(kind).Set(scores.sum / float64(scores.count))
+}
+
+// SubTrackQoSScore removes a score added with AddTrackQoSScore, deleting the series of kind once no score is left
+func SubTrackQoSScore(kind string, score float64) {
+	trackQoSLock.Lock()
+	defer trackQoSLock.Unlock()
+
+	scores := trackQoSScores[kind]
+	if scores == nil {
+		return
+	}
+	scores.sum -= score
+	scores.count--
+	if scores.count > 0 {
+		promTrackQoSScore.WithLabelValues(kind).Set(scores.sum / float64(scores.count))
+		return
+	}
+	delete(trackQoSScores, kind)
+	promTrackQoSScore.DeleteLabelValues(kind)
+}
+
+// DeleteBandwidthEstimate removes the bandwidth estimate series of a participant that left
+func DeleteBandwidthEstimate(participantID string) {
+	promBandwidthEstimate.DeleteLabelValues(participantID)
+}
+
+// RecordTrackActiveLayers moves a published track of kind from being counted under prev active layers to curr.
+// tracks with no active layers are not counted, a series is deleted once no track is counted under it
+func RecordTrackActiveLayers(kind string, prev int, curr int) {
+	if prev == curr {
+		return
+	}
+
+	trackLayersLock.Lock()
+	defer trackLayersLock.Unlock()
+
+	if prev > 0 {
+		key := [2]string{kind, strconv.Itoa(prev)}
+		trackLayersSeries[key]--
+		if trackLayersSeries[key] > 0 {
+			promTrackActiveLayers.WithLabelValues(key[0], key[1]).Dec()
+		} else {
+			delete(trackLayersSeries, key)
+			promTrackActiveLayers.DeleteLabelValues(key[0], key[1])
+		}
+	}
+	if curr > 0 {
+		key := [2]string{kind, strconv.Itoa(curr)}
+		trackLayersSeries[key]++
+		promTrackActiveLayers.WithLabelValues(key[0], key[1]).Inc()
+	}
+}
+
+func RecordTrackPacketLoss(kind string, room string, lost uint32, packets uint32) {
+	promTrackPacketsLost.WithLabelValues(kind, room).Add(float64(lost))
+	promTrackPackets.WithLabelValues(kind, room).Add(float64(packets))
+}
+
+// RecordTrackBytes adds the bytes published and subscribed for tracks of kind since the last call
+func RecordTrackBytes(kind string, published uint64, subscribed uint64) {
+	if published != 0 {
+		promTrackPublishedBytes.WithLabelValues(kind).Add(float64(published))
+	}
+	if subscribed != 0 {
+		promTrackSubscribedBytes.WithLabelValues(kind).Add(float64(subscribed))
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoomDuration(t *testing.T) {
+	creationTime := int64(1700000000)
+	now := time.Unix(creationTime, 0).Add(90 * time.Minute)
+
+	t.Run("from creation time", func(t *testing.T) {
+		duration, ok := roomDuration(time.Unix(creationTime, 0), now)
+		require.True(t, ok)
+		require.Equal(t, 90*time.Minute, duration)
+	})
+
+	t.Run("missing creation time", func(t *testing.T) {
+		_, ok := roomDuration(time.Unix(0, 0), now)
+		require.False(t, ok)
+		_, ok = roomDuration(time.Time{}, now)
+		require.False(t, ok)
+	})
+
+	t.Run("creation time in the future", func(t *testing.T) {
+		_, ok := roomDuration(now.Add(time.Second), now)
+		require.False(t, ok)
+	})
+}
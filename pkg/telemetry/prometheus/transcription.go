@@ -0,0 +1,61 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promTranscriptionActive   prometheus.Gauge
+	promTranscriptionDuration prometheus.Histogram
+)
+
+func initTranscriptionStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promTranscriptionActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "transcription",
+		Name:        "active",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Transcription sessions started through this node that have not ended yet.",
+	})
+	promTranscriptionDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "transcription",
+		Name:        "duration_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "How long each transcription session ran for.",
+		Buckets:     []float64{10, 30, 60, 300, 600, 1800, 3600, 7200, 14400, 28800},
+	})
+
+	prometheus.MustRegister(promTranscriptionActive)
+	prometheus.MustRegister(promTranscriptionDuration)
+}
+
+func AddTranscription() {
+	promTranscriptionActive.Inc()
+}
+
+func SubTranscription() {
+	promTranscriptionActive.Dec()
+}
+
+func RecordTranscriptionDuration(duration time.Duration) {
+	promTranscriptionDuration.Observe(duration.Seconds())
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promWebhookQueued       *prometheus.GaugeVec
+	promWebhookInFlight     *prometheus.GaugeVec
+	promWebhookCapacity     *prometheus.GaugeVec
+	promWebhookQueueFull    *prometheus.CounterVec
+	promWebhookAttempts     *prometheus.CounterVec
+	promWebhookTimeouts     *prometheus.CounterVec
+	promWebhookTLSErrors    *prometheus.CounterVec
+	promWebhookDeadLettered *prometheus.CounterVec
+	promWebhookReplayed     *prometheus.CounterVec
+	promWebhookFiltered     *prometheus.CounterVec
+	promWebhookDuplicates   *prometheus.CounterVec
+	promWebhookLatency      *prometheus.HistogramVec
+	promWebhookUnconfigured *prometheus.CounterVec
+	promWebhookBreakerState *prometheus.GaugeVec
+	promWebhookShortCircuit *prometheus.CounterVec
+	promWebhookSampledOut   *prometheus.CounterVec
+	promWebhookAuditDropped *prometheus.CounterVec
+)
+
+func initWebhookStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promWebhookQueued = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "queued",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook deliveries waiting for a worker.",
+	}, []string{"endpoint"})
+	promWebhookInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "in_flight",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook deliveries currently being attempted, including retry backoff.",
+	}, []string{"endpoint"})
+	promWebhookCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "queue_capacity",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook deliveries that may wait for a worker before new events are dead-lettered, compare with queued.",
+	}, []string{"endpoint"})
+	promWebhookQueueFull = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "queue_full",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook events dead-lettered without an attempt since the endpoint's queue was full.",
+	}, []string{"endpoint"})
+	promWebhookAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "attempts",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook delivery attempts, including retries, by result.",
+	}, []string{"endpoint", "result"})
+	promWebhookDeadLettered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "dead_lettered",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"endpoint"})
+	promWebhookReplayed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "dead_letters_replayed",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Dead-lettered webhook events delivered again, by result.",
+	}, []string{"result"})
+	promWebhookTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "timeouts",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"endpoint"})
+	promWebhookTLSErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "tls_errors",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook delivery attempts that failed in the TLS handshake, before reaching the endpoint.",
+	}, []string{"endpoint"})
+	promWebhookFiltered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "filtered",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"event"})
+	promWebhookDuplicates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "duplicates",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook events that were not sent since the same event was sent within the dedup window.",
+	}, []string{"event"})
+	promWebhookLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "delivery_latency_seconds",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Time from a webhook delivery being queued to it completing, including retries, by event and outcome.",
+		Buckets:     []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"event", "outcome"})
+	promWebhookUnconfigured = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "unconfigured_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook events that were not sent since no webhook endpoint is configured.",
+	}, []string{"event"})
+	promWebhookBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "breaker_state",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "State of the endpoint's circuit breaker: 0 closed, 1 open, 2 half-open while probing the endpoint.",
+	}, []string{"endpoint"})
+	promWebhookShortCircuit = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "short_circuited_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook events not delivered since the endpoint's circuit breaker was open.",
+	}, []string{"endpoint"})
+	promWebhookSampledOut = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "sampled_out_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Webhook events not delivered as they were sampled out by the event's sample rate.",
+	}, []string{"event"})
+	promWebhookAuditDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "webhook",
+		Name:        "audit_dropped_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Help:        "Records of webhook delivery attempts not audited since the audit logger could not keep up.",
+	}, []string{"endpoint"})
+
+	prometheus.MustRegister(promWebhookQueued)
+	prometheus.MustRegister(promWebhookInFlight)
+	prometheus.MustRegister(promWebhookCapacity)
+	prometheus.MustRegister(promWebhookQueueFull)
+	prometheus.MustRegister(promWebhookAttempts)
+	prometheus.MustRegister(promWebhookTimeouts)
+	prometheus.MustRegister(promWebhookTLSErrors)
+	prometheus.MustRegister(promWebhookDeadLettered)
+	prometheus.MustRegister(promWebhookReplayed)
+	prometheus.MustRegister(promWebhookFiltered)
+	prometheus.MustRegister(promWebhookDuplicates)
+	prometheus.MustRegister(promWebhookLatency)
+	prometheus.MustRegister(promWebhookUnconfigured)
+	prometheus.MustRegister(promWebhookBreakerState)
+	prometheus.MustRegister(promWebhookShortCircuit)
+	prometheus.MustRegister(promWebhookSampledOut)
+	prometheus.MustRegister(promWebhookAuditDropped)
+}
+
+func AddWebhookQueued(endpoint string) {
+	promWebhookQueued.WithLabelValues(endpoint).Inc()
+}
+
+func SubWebhookQueued(endpoint string) {
+	promWebhookQueued.WithLabelValues(endpoint).Dec()
+}
+
+func AddWebhookInFlight(endpoint string) {
+	promWebhookInFlight.WithLabelValues(endpoint).Inc()
+}
+
+func SubWebhookInFlight(endpoint string) {
+	promWebhookInFlight.WithLabelValues(endpoint).Dec()
+}
+
+func RecordWebhookQueueCapacity(endpoint string, capacity int) {
+	promWebhookCapacity.WithLabelValues(endpoint).Set(float64(capacity))
+}
+
+func RecordWebhookQueueFull(endpoint string) {
+	promWebhookQueueFull.WithLabelValues(endpoint).Inc()
+}
+
+func RecordWebhookAttempt(endpoint string, success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	promWebhookAttempts.WithLabelValues(endpoint, result).Inc()
+}
+
+func RecordWebhookTimeout(endpoint string) {
+	promWebhookTimeouts.WithLabelValues(endpoint).Inc()
+}
+
+func RecordWebhookTLSError(endpoint string) {
+	promWebhookTLSErrors.WithLabelValues(endpoint).Inc()
+}
+
+func RecordWebhookDeadLettered(endpoint string) {
+	promWebhookDeadLettered.WithLabelValues(endpoint).Inc()
+}
+
+func RecordWebhookDeadLetterReplay(success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	promWebhookReplayed.WithLabelValues(result).Inc()
+}
+
+func RecordWebhookFiltered(event string) {
+	promWebhookFiltered.WithLabelValues(event).Inc()
+}
+
+func RecordWebhookDuplicate(event string) {
+	promWebhookDuplicates.WithLabelValues(event).Inc()
+}
+
+// RecordWebhookLatency records a completed delivery, outcome is one of success, failure or timeout
+func RecordWebhookLatency(event string, outcome string, latency time.Duration) {
+	promWebhookLatency.WithLabelValues(event, outcome).Observe(latency.Seconds())
+}
+
+func RecordWebhookUnconfigured(event string) {
+	promWebhookUnconfigured.WithLabelValues(event).Inc()
+}
+
+func RecordWebhookBreakerState(endpoint string, state int) {
+	promWebhookBreakerState.WithLabelValues(endpoint).Set(float64(state))
+}
+
+func RecordWebhookShortCircuited(endpoint string) {
+	promWebhookShortCircuit.WithLabelValues(endpoint).Inc()
+}
+
+func RecordWebhookSampledOut(event string) {
+	promWebhookSampledOut.WithLabelValues(event).Inc()
+}
+
+func RecordWebhookAuditDropped(endpoint string) {
+	promWebhookAuditDropped.WithLabelValues(endpoint).Inc()
+}
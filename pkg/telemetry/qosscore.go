@@ -0,0 +1,135 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"math"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// QoSSample is the media of a subscribed track over a stats interval, as scored by QoSScore
+type QoSSample struct {
+	// fraction of packets lost, between 0 and 1
+	PacketLoss float64
+	Jitter     time.Duration
+	RTT        time.Duration
+	// a video track that was showing frames received none over the interval
+	Frozen bool
+}
+
+// QoSScore rates sample between 0 and 100. each impairment costs its share of the total weight, scaled by how bad it
+// is up to the point a connection is rated poor, see connectionQuality. a frozen track costs the whole freeze weight
+func QoSScore(weights config.QoSScoreConfig, sample QoSSample) float64 {
+	total := weights.LossWeight + weights.JitterWeight + weights.RTTWeight + weights.FreezeWeight
+	if total <= 0 {
+		return 100
+	}
+
+	penalty := weights.LossWeight*math.Min(sample.PacketLoss/poorMinLoss, 1) +
+		weights.JitterWeight*math.Min(float64(sample.Jitter/time.Microsecond)/poorMinJitter, 1) +
+		weights.RTTWeight*math.Min(float64(sample.RTT/time.Millisecond)/poorMinRtt, 1)
+	if sample.Frozen {
+		penalty += weights.FreezeWeight
+	}
+	return math.Max(0, 100*(1-penalty/total))
+}
+
+func qosScoringEnabled(weights config.QoSScoreConfig) bool {
+	return weights.LossWeight > 0 || weights.JitterWeight > 0 || weights.RTTWeight > 0 || weights.FreezeWeight > 0
+}
+
+// trackQoS is the scoring state of a track the participant subscribes to
+type trackQoS struct {
+	trackType livekit.TrackType
+	// latest score, counted in livekit_track_qos_score
+	score float64
+	// frames were received over the last interval, a video track that then receives none is frozen
+	hadFrames bool
+}
+
+// updateQoS scores the tracks the participant subscribes to that had stats over the interval
+func (s *StatsWorker) updateQoS(stats []*livekit.AnalyticsStat, trackTypes map[livekit.TrackID]livekit.TrackType) {
+	if !qosScoringEnabled(s.qosWeights) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// recorded with the lock held, so a track being removed can't leave a score behind
+	if !s.closedAt.IsZero() {
+		return
+	}
+	for _, stat := range stats {
+		trackType, ok := trackTypes[livekit.TrackID(stat.TrackId)]
+		if stat.Kind != livekit.StreamType_DOWNSTREAM || !ok || trackType == livekit.TrackType_DATA {
+			continue
+		}
+
+		var packets, lost, frames uint32
+		var sample QoSSample
+		for _, stream := range stat.Streams {
+			packets += stream.PrimaryPackets + stream.PaddingPackets
+			lost += stream.PacketsLost
+			frames += stream.Frames
+			// jitter is reported in microseconds, RTT in milliseconds
+			if jitter := time.Duration(stream.Jitter) * time.Microsecond; jitter > sample.Jitter {
+				sample.Jitter = jitter
+			}
+			if rtt := time.Duration(stream.Rtt) * time.Millisecond; rtt > sample.RTT {
+				sample.RTT = rtt
+			}
+		}
+		if packets+lost > 0 {
+			sample.PacketLoss = float64(lost) / float64(packets+lost)
+		}
+
+		trackID := livekit.TrackID(stat.TrackId)
+		track, scored := s.subscribedQoS[trackID]
+		if !scored {
+			track = &trackQoS{trackType: trackType}
+		}
+		sample.Frozen = trackType == livekit.TrackType_VIDEO && track.hadFrames && frames == 0
+		track.hadFrames = frames > 0
+		if packets+lost == 0 && !sample.Frozen {
+			// nothing was sent to rate
+			continue
+		}
+
+		score := QoSScore(s.qosWeights, sample)
+		if scored {
+			prometheus.UpdateTrackQoSScore(trackType.String(), track.score, score)
+		} else {
+			s.subscribedQoS[trackID] = track
+			prometheus.AddTrackQoSScore(trackType.String(), score)
+		}
+		track.score = score
+	}
+}
+
+// removeQoSLocked stops scoring a track the participant no longer subscribes to
+func (s *StatsWorker) removeQoSLocked(trackID livekit.TrackID) {
+	track, ok := s.subscribedQoS[trackID]
+	if !ok {
+		return
+	}
+	delete(s.subscribedQoS, trackID)
+	prometheus.SubTrackQoSScore(track.trackType.String(), track.score)
+}
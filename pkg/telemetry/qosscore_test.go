@@ -0,0 +1,103 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_QoSScore(t *testing.T) {
+	weights := config.QoSScoreConfig{LossWeight: 40, JitterWeight: 20, RTTWeight: 20, FreezeWeight: 20}
+
+	for _, tc := range []struct {
+		name   string
+		sample telemetry.QoSSample
+		score  float64
+	}{
+		{name: "clean", sample: telemetry.QoSSample{}, score: 100},
+		{name: "typical", sample: telemetry.QoSSample{PacketLoss: 0.01, Jitter: 10 * time.Millisecond, RTT: 70 * time.Millisecond}, score: 92},
+		{name: "lossy", sample: telemetry.QoSSample{PacketLoss: 0.05}, score: 80},
+		{name: "loss beyond poor", sample: telemetry.QoSSample{PacketLoss: 0.5}, score: 60},
+		{name: "high latency", sample: telemetry.QoSSample{Jitter: 50 * time.Millisecond, RTT: 350 * time.Millisecond}, score: 80},
+		{name: "frozen", sample: telemetry.QoSSample{Frozen: true}, score: 80},
+		{name: "worst", sample: telemetry.QoSSample{PacketLoss: 1, Jitter: time.Second, RTT: time.Second, Frozen: true}, score: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.InDelta(t, tc.score, telemetry.QoSScore(weights, tc.sample), 0.001)
+		})
+	}
+
+	// weights are relative
+	require.InDelta(t, 80, telemetry.QoSScore(config.QoSScoreConfig{LossWeight: 4, JitterWeight: 2, RTTWeight: 2, FreezeWeight: 2}, telemetry.QoSSample{PacketLoss: 0.05}), 0.001)
+	// impairments without weight don't count
+	require.Equal(t, float64(100), telemetry.QoSScore(config.QoSScoreConfig{LossWeight: 1}, telemetry.QoSSample{RTT: time.Second, Frozen: true}))
+}
+
+func Test_TrackQoSScoreIsReported(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	// flushed by the test, on the fake clock
+	conf.Analytics.StatsInterval = time.Hour
+	conf.Analytics.QoSScore = config.QoSScoreConfig{LossWeight: 40, JitterWeight: 20, RTTWeight: 20, FreezeWeight: 20}
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("PA_qos")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, partSID, "TR_qos", livekit.TrackSource_CAMERA, livekit.TrackType_VIDEO)
+	// the track's stats over a second
+	sample := func(stream *livekit.AnalyticsStream) []telemetry.ParticipantTrackStats {
+		fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{stream}})
+		flushEvents(fixture.sut)
+		clock.Advance(time.Second)
+		fixture.sut.FlushStats()
+
+		stats, ok := fixture.sut.GetParticipantStats(partSID)
+		require.True(t, ok)
+		return stats.Tracks
+	}
+
+	// 5% loss
+	tracks := sample(&livekit.AnalyticsStream{PrimaryPackets: 95, PacketsLost: 5, PrimaryBytes: 10000, Frames: 30})
+	require.Len(t, tracks, 1)
+	require.Equal(t, livekit.TrackID("TR_qos"), tracks[0].TrackID)
+	require.InDelta(t, 80, tracks[0].QoSScore, 0.1)
+	// averaged with the tracks other tests left subscribed
+	require.NotNil(t, findMetric(t, "livekit_track_qos_score", map[string]string{"kind": "VIDEO"}))
+
+	// packets without frames, the video froze
+	tracks = sample(&livekit.AnalyticsStream{PrimaryPackets: 100, PrimaryBytes: 10000})
+	require.Len(t, tracks, 1)
+	require.InDelta(t, 80, tracks[0].QoSScore, 0.1)
+
+	// tracks without media aren't scored, the latest score is kept
+	tracks = sample(&livekit.AnalyticsStream{})
+	require.Len(t, tracks, 1)
+	require.InDelta(t, 80, tracks[0].QoSScore, 0.1)
+
+	// scores are not sent as analytics events
+	flushEvents(fixture.sut)
+	require.Equal(t, 1, fixture.analytics.SendEventCallCount())
+}
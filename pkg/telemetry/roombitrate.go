@@ -0,0 +1,82 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+type roomBitrate struct {
+	published  float64
+	subscribed float64
+}
+
+// updateRoomBitrates sums the latest bitrates sampled by the workers of each room into the room and node gauges,
+// rooms sharing a label are summed together. only the node gauges are kept while there are more room labels than
+// roomBitrateMaxRooms, bounding the series exported. must be called from the run goroutine
+func (t *telemetryService) updateRoomBitrates() {
+	rooms := make(map[string]*roomBitrate)
+	var node roomBitrate
+	for _, worker := range t.allWorkers() {
+		stats, ok := worker.ParticipantStats()
+		if !ok {
+			continue
+		}
+
+		label := worker.roomLabel
+		room := rooms[label]
+		if room == nil {
+			room = &roomBitrate{}
+			rooms[label] = room
+		}
+		for _, track := range stats.Tracks {
+			switch track.Direction {
+			case livekit.StreamType_UPSTREAM:
+				room.published += track.Bitrate
+				node.published += track.Bitrate
+			case livekit.StreamType_DOWNSTREAM:
+				room.subscribed += track.Bitrate
+				node.subscribed += track.Bitrate
+			}
+		}
+	}
+	prometheus.RecordNodeBitrate(node.published, node.subscribed)
+
+	if t.roomBitrateMaxRooms > 0 && len(rooms) > t.roomBitrateMaxRooms {
+		rooms = nil
+	}
+	for label := range t.roomBitrateRooms {
+		if _, ok := rooms[label]; !ok {
+			prometheus.DeleteRoomBitrate(label)
+			delete(t.roomBitrateRooms, label)
+		}
+	}
+	for label, room := range rooms {
+		prometheus.RecordRoomBitrate(label, room.published, room.subscribed)
+		t.roomBitrateRooms[label] = struct{}{}
+	}
+}
+
+// deleteRoomBitrate removes the bitrate series of a room that ended, by the label it released. series of labels
+// other rooms may share are left to the next update. must be called from the run goroutine
+func (t *telemetryService) deleteRoomBitrate(label string) {
+	if _, ok := t.roomBitrateRooms[label]; !ok {
+		return
+	}
+	prometheus.DeleteRoomBitrate(label)
+	delete(t.roomBitrateRooms, label)
+}
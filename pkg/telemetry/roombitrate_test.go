@@ -0,0 +1,99 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func joinWithMedia(fixture *telemetryServiceFixture, room *livekit.Room, participantID livekit.ParticipantID) func() {
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(participantID)}, nil, nil, true)
+	upstream := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, participantID, "TR_up", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	downstream := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, participantID, "TR_down", livekit.TrackSource_CAMERA, livekit.TrackType_VIDEO)
+	return func() {
+		fixture.sut.TrackStats(upstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}})
+		fixture.sut.TrackStats(downstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 5000}}})
+	}
+}
+
+func Test_RoomBitrate(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.StatsInterval = time.Minute
+	conf.Analytics.RoomLabel = telemetry.RoomLabelFull
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RM_bitrate", Name: "bitrate"}
+	publish := map[string]string{"room": room.Name, "direction": "publish"}
+	subscribe := map[string]string{"room": room.Name, "direction": "subscribe"}
+	sendMedia := joinWithMedia(fixture, room, "PA_bitrate")
+
+	sendMedia()
+	flushEvents(fixture.sut)
+	clock.Advance(conf.Analytics.StatsInterval)
+	require.Eventually(t, func() bool {
+		// the node gauge is shared with the services of other tests, which may have just set it
+		return findMetric(t, "livekit_room_bitrate_bps", publish).GetGauge().GetValue() > 0 &&
+			findMetric(t, "livekit_room_bitrate_bps", subscribe).GetGauge().GetValue() > 0 &&
+			findMetric(t, "livekit_node_bitrate_bps", map[string]string{"direction": "subscribe"}).GetGauge().GetValue() > 0
+	}, time.Second, time.Millisecond)
+
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+	require.Nil(t, findMetric(t, "livekit_room_bitrate_bps", publish))
+	require.Nil(t, findMetric(t, "livekit_room_bitrate_bps", subscribe))
+}
+
+func Test_RoomBitrate_AggregatesToNodeAboveMaxRooms(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.StatsInterval = time.Minute
+	conf.Analytics.RoomBitrateMaxRooms = 1
+	conf.Analytics.RoomLabel = telemetry.RoomLabelFull
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	first := &livekit.Room{Sid: "RM_first", Name: "bitrate-first"}
+	second := &livekit.Room{Sid: "RM_second", Name: "bitrate-second"}
+	firstPublish := map[string]string{"room": first.Name, "direction": "publish"}
+
+	sendFirst := joinWithMedia(fixture, first, "PA_first")
+	sendFirst()
+	flushEvents(fixture.sut)
+	clock.Advance(conf.Analytics.StatsInterval)
+	require.Eventually(t, func() bool {
+		return findMetric(t, "livekit_room_bitrate_bps", firstPublish).GetGauge().GetValue() > 0
+	}, time.Second, time.Millisecond)
+
+	// a second room is one too many, only the node keeps being reported
+	sendSecond := joinWithMedia(fixture, second, "PA_second")
+	sendFirst()
+	sendSecond()
+	flushEvents(fixture.sut)
+	clock.Advance(conf.Analytics.StatsInterval)
+	require.Eventually(t, func() bool {
+		return findMetric(t, "livekit_room_bitrate_bps", firstPublish) == nil
+	}, time.Second, time.Millisecond)
+	require.Nil(t, findMetric(t, "livekit_room_bitrate_bps", map[string]string{"room": second.Name, "direction": "publish"}))
+	require.NotNil(t, findMetric(t, "livekit_node_bitrate_bps", map[string]string{"direction": "publish"}))
+}
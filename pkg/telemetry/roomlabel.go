@@ -0,0 +1,135 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+// how rooms are labelled in metrics labelled by room, see AnalyticsConfig.RoomLabel
+const (
+	// every room shares an empty label
+	RoomLabelNone = "none"
+	// rooms are hashed into a fixed number of buckets, labelled by bucket
+	RoomLabelHashed = "hashed"
+	// rooms are labelled by name, one series per room
+	RoomLabelFull = "full"
+)
+
+const (
+	defaultRoomLabelBuckets  = 16
+	defaultRoomLabelMaxRooms = 500
+)
+
+// rooms labelled by name beyond RoomLabelMaxRooms share this label
+const roomLabelOther = "other"
+
+// roomLabeler turns room names into the room label of metrics, bounding their cardinality
+type roomLabeler struct {
+	strategy string
+	buckets  int
+	// with RoomLabelFull, at most maxRooms rooms are labelled by name at a time
+	maxRooms int
+	named    *namedRooms
+}
+
+// namedRooms are the rooms labelled by name, until they end
+type namedRooms struct {
+	lock  sync.Mutex
+	rooms map[livekit.RoomName]struct{}
+}
+
+func newRoomLabeler(strategy string, buckets int, maxRooms int) roomLabeler {
+	switch strategy {
+	case RoomLabelNone:
+	case RoomLabelFull:
+		if maxRooms <= 0 {
+			logger.Warnw("invalid room label max rooms, using default", nil,
+				"roomLabelMaxRooms", maxRooms,
+				"default", defaultRoomLabelMaxRooms,
+			)
+			maxRooms = defaultRoomLabelMaxRooms
+		}
+		return roomLabeler{
+			strategy: strategy,
+			maxRooms: maxRooms,
+			named:    &namedRooms{rooms: make(map[livekit.RoomName]struct{})},
+		}
+	case RoomLabelHashed:
+		if buckets <= 0 {
+			logger.Warnw("invalid room label buckets, using default", nil,
+				"roomLabelBuckets", buckets,
+				"default", defaultRoomLabelBuckets,
+			)
+			buckets = defaultRoomLabelBuckets
+		}
+	default:
+		logger.Warnw("invalid room label strategy, using default", nil,
+			"roomLabel", strategy,
+			"default", RoomLabelHashed,
+		)
+		strategy = RoomLabelHashed
+		if buckets <= 0 {
+			buckets = defaultRoomLabelBuckets
+		}
+	}
+	return roomLabeler{strategy: strategy, buckets: buckets}
+}
+
+// label returns the room label of roomName. with RoomLabelFull the room is labelled by name from then on, until
+// it is released, unless maxRooms rooms already are
+func (l roomLabeler) label(roomName livekit.RoomName) string {
+	switch l.strategy {
+	case RoomLabelFull:
+		l.named.lock.Lock()
+		defer l.named.lock.Unlock()
+
+		if _, ok := l.named.rooms[roomName]; !ok {
+			if len(l.named.rooms) >= l.maxRooms {
+				return roomLabelOther
+			}
+			l.named.rooms[roomName] = struct{}{}
+		}
+		return string(roomName)
+	case RoomLabelHashed:
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(roomName))
+		return strconv.Itoa(int(h.Sum32() % uint32(l.buckets)))
+	default:
+		return ""
+	}
+}
+
+// release frees the label of a room that ended, returning it when it was the room's own. labels other rooms may
+// share are not returned, as their series must be kept
+func (l roomLabeler) release(roomName livekit.RoomName) (string, bool) {
+	if l.strategy != RoomLabelFull {
+		return "", false
+	}
+
+	l.named.lock.Lock()
+	defer l.named.lock.Unlock()
+
+	if _, ok := l.named.rooms[roomName]; !ok {
+		return "", false
+	}
+	delete(l.named.rooms, roomName)
+	return string(roomName), true
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_RoomLabel_HashedRoomsShareBuckets(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomLabel = telemetry.RoomLabelHashed
+	conf.Analytics.RoomLabelBuckets = 1
+	fixture := createFixtureWithConfig(conf)
+
+	before, _ := trackPacketsLost(t, "VIDEO", "0")
+	publishWithLoss := func(room *livekit.Room, partSID livekit.ParticipantID, lost uint32) *livekit.TrackInfo {
+		track := &livekit.TrackInfo{Sid: "TR_" + string(partSID), Type: livekit.TrackType_VIDEO}
+		fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+		fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+		key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+		fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 100, PacketsLost: lost}}})
+		return track
+	}
+	first := &livekit.Room{Sid: "RM_hashed1", Name: "HashedRoom1"}
+	second := &livekit.Room{Sid: "RM_hashed2", Name: "HashedRoom2"}
+	firstTrack := publishWithLoss(first, "PA_hashed1", 5)
+	publishWithLoss(second, "PA_hashed2", 3)
+	fixture.flush()
+
+	// both rooms are in the only bucket, none is labelled by name
+	lost, ok := trackPacketsLost(t, "VIDEO", "0")
+	require.True(t, ok)
+	require.Equal(t, before+8, lost)
+	_, ok = trackPacketsLost(t, "VIDEO", first.Name)
+	require.False(t, ok)
+
+	// the bucket is kept while a room in it still has a published track
+	fixture.sut.TrackUnpublished(context.Background(), "PA_hashed1", "", firstTrack, true)
+	fixture.sut.RoomEnded(context.Background(), first)
+	fixture.flush()
+	_, ok = trackPacketsLost(t, "VIDEO", "0")
+	require.True(t, ok)
+}
+
+func Test_RoomLabel_NoneSumsRooms(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomLabel = telemetry.RoomLabelNone
+	fixture := createFixtureWithConfig(conf)
+
+	sessions := func() uint64 {
+		if metric := findMetric(t, "livekit_participant_session_duration_seconds", map[string]string{"room": ""}); metric != nil {
+			return metric.GetHistogram().GetSampleCount()
+		}
+		return 0
+	}
+	before := sessions()
+
+	for _, room := range []*livekit.Room{
+		{Sid: "RM_unlabelled1", Name: "UnlabelledRoom1"},
+		{Sid: "RM_unlabelled2", Name: "UnlabelledRoom2"},
+	} {
+		participantInfo := &livekit.ParticipantInfo{Sid: "PA_" + room.Sid}
+		fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+		fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+		fixture.sut.RoomEnded(context.Background(), room)
+	}
+	fixture.flush()
+	require.Equal(t, before+2, sessions())
+	require.Nil(t, findMetric(t, "livekit_participant_session_duration_seconds", map[string]string{"room": "UnlabelledRoom1"}))
+}
+
+func Test_RoomLabel_FullIsBounded(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomLabel = telemetry.RoomLabelFull
+	conf.Analytics.RoomLabelMaxRooms = 1
+	fixture := createFixtureWithConfig(conf)
+
+	sessions := func(label string) uint64 {
+		if metric := findMetric(t, "livekit_participant_session_duration_seconds", map[string]string{"room": label}); metric != nil {
+			return metric.GetHistogram().GetSampleCount()
+		}
+		return 0
+	}
+	before := sessions("other")
+	joinAndLeave := func(room *livekit.Room) {
+		participantInfo := &livekit.ParticipantInfo{Sid: "PA_" + room.Sid}
+		fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+		fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	}
+
+	first := &livekit.Room{Sid: "RM_bounded1", Name: "BoundedRoom1"}
+	second := &livekit.Room{Sid: "RM_bounded2", Name: "BoundedRoom2"}
+	joinAndLeave(first)
+	joinAndLeave(second)
+	fixture.flush()
+
+	// only the first room is labelled by name, the second is past the limit
+	require.Equal(t, uint64(1), sessions(first.Name))
+	require.Equal(t, uint64(0), sessions(second.Name))
+	require.Equal(t, before+1, sessions("other"))
+
+	// once the first room ends, a new room takes its place
+	fixture.sut.RoomEnded(context.Background(), first)
+	fixture.flush()
+	third := &livekit.Room{Sid: "RM_bounded3", Name: "BoundedRoom3"}
+	joinAndLeave(third)
+	fixture.flush()
+	require.Equal(t, uint64(1), sessions(third.Name))
+}
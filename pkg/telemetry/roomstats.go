@@ -0,0 +1,118 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"math"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+type roomRollup struct {
+	room       *livekit.Room
+	published  trafficTotals
+	subscribed trafficTotals
+}
+
+// flushRoomStats sends a rollup of the media of every room since the last rollup, or of only roomID when not empty.
+// the media published and subscribed in a room is sent as a stat of the room, without a participant or track, the
+// participants and publishers are logged. rooms without participants or media since the last rollup are skipped.
+// must be called from the run goroutine
+func (t *telemetryService) flushRoomStats(ctx context.Context, roomID livekit.RoomID) {
+	now := t.clock.Now()
+	start := t.roomStatsAt
+	if roomID == "" {
+		t.roomStatsAt = now
+	}
+
+	rollups := make(map[livekit.RoomID]*roomRollup)
+	for _, worker := range t.allWorkers() {
+		if roomID != "" && worker.roomID != roomID {
+			continue
+		}
+		published, subscribed, active, publishing := worker.takeRoomTraffic()
+
+		rollup := rollups[worker.roomID]
+		if rollup == nil {
+			rollup = &roomRollup{
+				room: &livekit.Room{
+					Sid:  string(worker.roomID),
+					Name: string(worker.roomName),
+				},
+			}
+			rollups[worker.roomID] = rollup
+		}
+		if active {
+			rollup.room.NumParticipants++
+		}
+		if publishing {
+			rollup.room.NumPublishers++
+		}
+		rollup.published.bytes += published.bytes
+		rollup.published.packets += published.packets
+		rollup.subscribed.bytes += subscribed.bytes
+		rollup.subscribed.packets += subscribed.packets
+	}
+
+	ts := timestamppb.New(now)
+	duration := now.Sub(start).Seconds()
+	for _, rollup := range rollups {
+		if rollup.room.NumParticipants == 0 && rollup.published.bytes == 0 && rollup.subscribed.bytes == 0 {
+			continue
+		}
+		t.SendStats(ctx, []*livekit.AnalyticsStat{
+			newRoomStat(livekit.StreamType_UPSTREAM, rollup.room, rollup.published, ts),
+			newRoomStat(livekit.StreamType_DOWNSTREAM, rollup.room, rollup.subscribed, ts),
+		})
+
+		var publishedBitrate, subscribedBitrate float64
+		if duration > 0 {
+			publishedBitrate = float64(rollup.published.bytes*8) / duration
+			subscribedBitrate = float64(rollup.subscribed.bytes*8) / duration
+		}
+		logger.Infow("room stats",
+			"room", rollup.room.Name,
+			"roomID", rollup.room.Sid,
+			"participants", rollup.room.NumParticipants,
+			"publishers", rollup.room.NumPublishers,
+			"publishedBitrate", publishedBitrate,
+			"subscribedBitrate", subscribedBitrate,
+		)
+	}
+}
+
+// newRoomStat describes the media in traffic, published to the room for livekit.StreamType_UPSTREAM or subscribed
+// from it for livekit.StreamType_DOWNSTREAM
+func newRoomStat(kind livekit.StreamType, room *livekit.Room, traffic trafficTotals, ts *timestamppb.Timestamp) *livekit.AnalyticsStat {
+	stream := &livekit.AnalyticsStream{
+		PrimaryPackets: math.MaxUint32,
+		PrimaryBytes:   traffic.bytes,
+	}
+	// PrimaryPackets is 32 bits wide, it saturates rather than wraps
+	if traffic.packets < math.MaxUint32 {
+		stream.PrimaryPackets = uint32(traffic.packets)
+	}
+	return &livekit.AnalyticsStat{
+		Kind:      kind,
+		TimeStamp: ts,
+		RoomId:    room.Sid,
+		RoomName:  room.Name,
+		Streams:   []*livekit.AnalyticsStream{stream},
+	}
+}
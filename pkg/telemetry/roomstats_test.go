@@ -0,0 +1,138 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func joinRoomWithMedia(fixture *telemetryServiceFixture, room *livekit.Room) {
+	publisher, subscriber := livekit.ParticipantID("pub"), livekit.ParticipantID("sub")
+	track := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(publisher)}, nil, nil, true)
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(subscriber)}, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), publisher, "", track)
+
+	upstream := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, publisher, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	downstream := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, subscriber, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	fixture.sut.TrackStats(upstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}})
+	fixture.sut.TrackStats(downstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 5, PrimaryBytes: 500}}})
+}
+
+func findAnalyticsEvents(fixture *telemetryServiceFixture, eventType livekit.AnalyticsEventType) []*livekit.AnalyticsEvent {
+	var events []*livekit.AnalyticsEvent
+	for i := 0; i < fixture.analytics.SendEventCallCount(); i++ {
+		if _, ev := fixture.analytics.SendEventArgsForCall(i); ev.Type == eventType {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// findRoomStats returns the rollups of the room sent as stats of kind, those without a participant
+func findRoomStats(fixture *telemetryServiceFixture, kind livekit.StreamType) []*livekit.AnalyticsStat {
+	var stats []*livekit.AnalyticsStat
+	for i := 0; i < fixture.analytics.SendStatsCallCount(); i++ {
+		_, sent := fixture.analytics.SendStatsArgsForCall(i)
+		for _, stat := range sent {
+			if stat.ParticipantId == "" && stat.Kind == kind {
+				stats = append(stats, stat)
+			}
+		}
+	}
+	return stats
+}
+
+func findWebhookEvents(fixture *telemetryServiceFixture, event string) []*livekit.WebhookEvent {
+	var events []*livekit.WebhookEvent
+	for i := 0; i < fixture.notifier.NotifyCallCount(); i++ {
+		if _, ev := fixture.notifier.NotifyArgsForCall(i); ev.Event == event {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func Test_RoomStatsAreSentPeriodically(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomStatsInterval = time.Minute
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	joinRoomWithMedia(fixture, room)
+	flushEvents(fixture.sut)
+
+	clock.Advance(conf.Analytics.RoomStatsInterval)
+	var published, subscribed []*livekit.AnalyticsStat
+	require.Eventually(t, func() bool {
+		published = findRoomStats(fixture, livekit.StreamType_UPSTREAM)
+		subscribed = findRoomStats(fixture, livekit.StreamType_DOWNSTREAM)
+		return len(published) > 0 && len(subscribed) > 0
+	}, time.Second, time.Millisecond)
+
+	stat := published[0]
+	require.Equal(t, room.Sid, stat.RoomId)
+	require.Equal(t, room.Name, stat.RoomName)
+	require.Empty(t, stat.TrackId)
+	require.Equal(t, uint64(1000), stat.Streams[0].PrimaryBytes)
+	require.Equal(t, uint32(10), stat.Streams[0].PrimaryPackets)
+	require.Equal(t, uint64(500), subscribed[0].Streams[0].PrimaryBytes)
+
+	// media is accounted for in one rollup only
+	clock.Advance(conf.Analytics.RoomStatsInterval)
+	require.Eventually(t, func() bool {
+		published = findRoomStats(fixture, livekit.StreamType_UPSTREAM)
+		return len(published) == 2
+	}, time.Second, time.Millisecond)
+	require.Zero(t, published[1].Streams[0].PrimaryBytes)
+}
+
+func Test_RoomStatsAreFlushedOnRoomEnded(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomStatsInterval = time.Hour
+	fixture := createFixtureWithClock(conf, newFakeClock())
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	joinRoomWithMedia(fixture, room)
+
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_ROOM_ENDED), 1)
+	published := findRoomStats(fixture, livekit.StreamType_UPSTREAM)
+	require.Len(t, published, 1)
+	require.Equal(t, uint64(1000), published[0].Streams[0].PrimaryBytes)
+	require.Len(t, findRoomStats(fixture, livekit.StreamType_DOWNSTREAM), 1)
+}
+
+func Test_RoomStatsDisabledByDefault(t *testing.T) {
+	fixture := createFixture()
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	joinRoomWithMedia(fixture, room)
+
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_ROOM_ENDED), 1)
+	require.Empty(t, findRoomStats(fixture, livekit.StreamType_UPSTREAM))
+}
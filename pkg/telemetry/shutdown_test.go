@@ -0,0 +1,87 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func Test_Shutdown_DrainsWebhooks(t *testing.T) {
+	fixture := createFixture()
+
+	fixture.sut.RoomStarted(context.Background(), &livekit.Room{Sid: "RoomSid"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, fixture.sut.Shutdown(ctx))
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+
+	// nothing is delivered after shutdown
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+}
+
+func Test_Shutdown_ReportsAbandonedWebhooks(t *testing.T) {
+	fixture := createFixture()
+	release := make(chan struct{})
+	defer close(release)
+	fixture.notifier.NotifyCalls(func(_ context.Context, _ *livekit.WebhookEvent) error {
+		<-release
+		return nil
+	})
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := fixture.sut.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "abandoned 2 webhook deliveries")
+}
+
+func Test_Shutdown_FlushesStatsAndEvents(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.BatchInterval = time.Hour
+	analytics := &batchingAnalytics{}
+	sut := telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{&telemetryfakes.FakeWebhookNotifier{}}, analytics)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+	sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_UPSTREAM, partSID, "trackID"), &livekit.AnalyticsStat{
+		Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 10, PrimaryPackets: 1}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, sut.Shutdown(ctx))
+
+	require.Equal(t, 1, analytics.SendStatsCallCount())
+	batches := analytics.getBatches()
+	require.Len(t, batches, 1)
+	require.Equal(t, livekit.AnalyticsEventType_PARTICIPANT_JOINED, batches[0][0].Type)
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"sort"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// Snapshot is an overview of the rooms and participants telemetry is tracking on this node, see TelemetryService.Snapshot
+type Snapshot struct {
+	TakenAt time.Time
+	// sorted by RoomID
+	Rooms []RoomSnapshot
+}
+
+// RoomSnapshot counts the participants of a room that are on this node and the tracks they publish
+type RoomSnapshot struct {
+	RoomID       livekit.RoomID
+	RoomName     livekit.RoomName
+	Participants int
+	// participants publishing at least one track
+	Publishers int
+	// tracks published by the participants, by type
+	PublishedTracks map[livekit.TrackType]int
+}
+
+// roomSnapshotEntry is what a worker contributes to its room's snapshot
+type roomSnapshotEntry struct {
+	roomID   livekit.RoomID
+	roomName livekit.RoomName
+	tracks   map[livekit.TrackType]int
+}
+
+// Snapshot returns the rooms with participants on this node. the participants of every room are read at the same
+// point in time, the shards of workers are locked for reading while they are counted, rather than the read going
+// through the run goroutine, so events keep being processed while it is taken
+func (t *telemetryService) Snapshot() Snapshot {
+	snapshot := Snapshot{TakenAt: t.clock.Now()}
+
+	var entries []roomSnapshotEntry
+	for i := range t.workers {
+		t.workers[i].lock.RLock()
+	}
+	for i := range t.workers {
+		for _, worker := range t.workers[i].workers {
+			if entry, ok := worker.snapshot(); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	for i := range t.workers {
+		t.workers[i].lock.RUnlock()
+	}
+
+	rooms := make(map[livekit.RoomID]*RoomSnapshot)
+	for _, entry := range entries {
+		room := rooms[entry.roomID]
+		if room == nil {
+			room = &RoomSnapshot{
+				RoomID:          entry.roomID,
+				RoomName:        entry.roomName,
+				PublishedTracks: make(map[livekit.TrackType]int),
+			}
+			rooms[entry.roomID] = room
+		}
+		room.Participants++
+		if len(entry.tracks) != 0 {
+			room.Publishers++
+		}
+		for trackType, count := range entry.tracks {
+			room.PublishedTracks[trackType] += count
+		}
+	}
+
+	snapshot.Rooms = make([]RoomSnapshot, 0, len(rooms))
+	for _, room := range rooms {
+		snapshot.Rooms = append(snapshot.Rooms, *room)
+	}
+	sort.Slice(snapshot.Rooms, func(i, j int) bool {
+		return snapshot.Rooms[i].RoomID < snapshot.Rooms[j].RoomID
+	})
+	return snapshot
+}
+
+// snapshot returns the worker's room and published tracks, false once the participant has left
+func (s *StatsWorker) snapshot() (roomSnapshotEntry, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.closedAt.IsZero() {
+		return roomSnapshotEntry{}, false
+	}
+	entry := roomSnapshotEntry{
+		roomID:   s.roomID,
+		roomName: s.roomName,
+	}
+	if len(s.publishedTracks) != 0 {
+		entry.tracks = make(map[livekit.TrackType]int)
+		for _, track := range s.publishedTracks {
+			entry.tracks[track.trackType]++
+		}
+	}
+	return entry, true
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_Snapshot(t *testing.T) {
+	fixture := createFixture()
+
+	room1 := &livekit.Room{Sid: "RM_snapshot1", Name: "snapshot1"}
+	room2 := &livekit.Room{Sid: "RM_snapshot2", Name: "snapshot2"}
+	publisher := &livekit.ParticipantInfo{Sid: "PA_publisher"}
+	subscriber := &livekit.ParticipantInfo{Sid: "PA_subscriber"}
+	leaving := &livekit.ParticipantInfo{Sid: "PA_leaving"}
+	fixture.sut.ParticipantJoined(context.Background(), room1, publisher, nil, nil, true)
+	fixture.sut.ParticipantJoined(context.Background(), room1, subscriber, nil, nil, true)
+	fixture.sut.ParticipantJoined(context.Background(), room2, leaving, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), livekit.ParticipantID(publisher.Sid), "", &livekit.TrackInfo{Sid: "TR_audio", Type: livekit.TrackType_AUDIO})
+	fixture.sut.TrackPublished(context.Background(), livekit.ParticipantID(publisher.Sid), "", &livekit.TrackInfo{Sid: "TR_video", Type: livekit.TrackType_VIDEO})
+	fixture.sut.TrackPublished(context.Background(), livekit.ParticipantID(leaving.Sid), "", &livekit.TrackInfo{Sid: "TR_leaving", Type: livekit.TrackType_AUDIO})
+
+	flushEvents(fixture.sut)
+	snapshot := fixture.sut.Snapshot()
+	require.False(t, snapshot.TakenAt.IsZero())
+	require.Equal(t, telemetry.RoomSnapshot{
+		RoomID:       livekit.RoomID(room1.Sid),
+		RoomName:     livekit.RoomName(room1.Name),
+		Participants: 2,
+		Publishers:   1,
+		PublishedTracks: map[livekit.TrackType]int{
+			livekit.TrackType_AUDIO: 1,
+			livekit.TrackType_VIDEO: 1,
+		},
+	}, snapshot.Rooms[0])
+	require.Equal(t, livekit.RoomID(room2.Sid), snapshot.Rooms[1].RoomID)
+	require.Equal(t, 1, snapshot.Rooms[1].Participants)
+
+	// rooms go once their last participant leaves
+	fixture.sut.ParticipantLeft(context.Background(), room2, leaving, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	flushEvents(fixture.sut)
+	snapshot = fixture.sut.Snapshot()
+	require.Len(t, snapshot.Rooms, 1)
+	require.Equal(t, livekit.RoomID(room1.Sid), snapshot.Rooms[0].RoomID)
+}
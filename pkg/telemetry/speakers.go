@@ -0,0 +1,130 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// activeSpeakers holds the latest speaker list reported for a room while a debounce window is pending
+type activeSpeakers struct {
+	room     *livekit.Room
+	speakers []*livekit.SpeakerInfo
+	// sids of the last list that was sent, in order
+	sent  []string
+	timer Timer
+}
+
+// ActiveSpeakerChanged records the ordered list of active speakers in a room. Changes within the debounce
+// window are coalesced, and only the latest list is sent once the window closes, if the order of speakers changed.
+// AnalyticsEvent cannot carry a list of speakers, so the change and the speakers' levels are recorded in prometheus,
+// and the list is delivered along with the webhook, see WebHookConfig.EventDetails
+func (t *telemetryService) ActiveSpeakerChanged(ctx context.Context, room *livekit.Room, speakers []*livekit.SpeakerInfo) {
+	if room == nil {
+		return
+	}
+	roomID := livekit.RoomID(room.Sid)
+
+	t.speakerLock.Lock()
+	defer t.speakerLock.Unlock()
+
+	state := t.activeSpeakers[roomID]
+	if state == nil {
+		state = &activeSpeakers{}
+		t.activeSpeakers[roomID] = state
+	}
+	state.room = room
+	state.speakers = speakers
+	if state.timer == nil {
+		state.timer = t.clock.AfterFunc(t.activeSpeakerDebounce, func() {
+			t.flushActiveSpeakers(ctx, roomID)
+		})
+	}
+}
+
+func (t *telemetryService) flushActiveSpeakers(ctx context.Context, roomID livekit.RoomID) {
+	t.speakerLock.Lock()
+	state := t.activeSpeakers[roomID]
+	if state == nil {
+		// room has ended
+		t.speakerLock.Unlock()
+		return
+	}
+	state.timer = nil
+	room, speakers := state.room, state.speakers
+	if sameSpeakers(state.sent, speakers) {
+		t.speakerLock.Unlock()
+		return
+	}
+	sent := make([]string, 0, len(speakers))
+	levels := make([]float32, 0, len(speakers))
+	for _, speaker := range speakers {
+		sent = append(sent, speaker.Sid)
+		levels = append(levels, speaker.Level)
+	}
+	state.sent = sent
+	t.speakerLock.Unlock()
+
+	prometheus.RecordActiveSpeakers(levels)
+	logger.Debugw("active speakers changed", "room", room.Name, "roomID", room.Sid, "speakers", sent, "levels", levels)
+
+	if !t.activeSpeakerWebhook {
+		return
+	}
+	t.enqueue(func() {
+		event := &livekit.WebhookEvent{
+			Event: EventActiveSpeakerChanged,
+			Room:  room,
+		}
+		if len(speakers) > 0 {
+			event.Participant = &livekit.ParticipantInfo{Sid: speakers[0].Sid}
+		}
+		var details *WebhookDetails
+		if t.webhookEventDetails {
+			details = &WebhookDetails{Speakers: speakers}
+		}
+		t.notifyEvent(ctx, event, details)
+	})
+}
+
+// clearActiveSpeakers drops any pending speaker change for the room
+func (t *telemetryService) clearActiveSpeakers(roomID livekit.RoomID) {
+	t.speakerLock.Lock()
+	defer t.speakerLock.Unlock()
+
+	if state := t.activeSpeakers[roomID]; state != nil {
+		if state.timer != nil {
+			state.timer.Stop()
+		}
+		delete(t.activeSpeakers, roomID)
+	}
+}
+
+func sameSpeakers(sent []string, speakers []*livekit.SpeakerInfo) bool {
+	if sent == nil || len(sent) != len(speakers) {
+		return false
+	}
+	for i, speaker := range speakers {
+		if sent[i] != speaker.Sid {
+			return false
+		}
+	}
+	return true
+}
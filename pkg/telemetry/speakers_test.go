@@ -0,0 +1,144 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+// speakerChanges returns the number of active speaker changes recorded so far
+func speakerChanges(t *testing.T) float64 {
+	if metric := findMetric(t, "livekit_room_active_speaker_changes_total", nil); metric != nil {
+		return metric.GetCounter().GetValue()
+	}
+	return 0
+}
+
+func Test_ActiveSpeakerChanged_Coalesced(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.ActiveSpeakerEvents = true
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	before := speakerChanges(t)
+
+	// flapping between two speakers within the window only sends the latest order
+	fixture.sut.ActiveSpeakerChanged(context.Background(), room, []*livekit.SpeakerInfo{{Sid: "p1"}})
+	fixture.sut.ActiveSpeakerChanged(context.Background(), room, []*livekit.SpeakerInfo{{Sid: "p2"}, {Sid: "p1"}})
+	fixture.sut.ActiveSpeakerChanged(context.Background(), room, []*livekit.SpeakerInfo{{Sid: "p1"}, {Sid: "p2"}})
+	clock.Advance(conf.Audio.ActiveSpeakerDebounce)
+	require.Equal(t, before+1, speakerChanges(t))
+
+	// levels changing without the order changing is not sent again
+	fixture.sut.ActiveSpeakerChanged(context.Background(), room, []*livekit.SpeakerInfo{{Sid: "p1", Level: 0.5}, {Sid: "p2"}})
+	clock.Advance(conf.Audio.ActiveSpeakerDebounce)
+	require.Equal(t, before+1, speakerChanges(t))
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, "p1", event.Participant.Sid)
+	// analytics has no event type for it
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+}
+
+func Test_ActiveSpeakerChanged_Webhook(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.ActiveSpeakerEvents = true
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+
+	fixture.sut.ActiveSpeakerChanged(context.Background(), room, []*livekit.SpeakerInfo{{Sid: "p2"}, {Sid: "p1"}})
+	clock.Advance(conf.Audio.ActiveSpeakerDebounce)
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventActiveSpeakerChanged, event.Event)
+	require.Equal(t, room.Sid, event.Room.Sid)
+	require.Equal(t, "p2", event.Participant.Sid)
+}
+
+func Test_ActiveSpeakerChanged_WebhookCarriesLevels(t *testing.T) {
+	server, requests := newEnvelopeServer(t, true)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.ActiveSpeakerEvents = true
+	conf.WebHook.EventDetails = true
+	clock := newFakeClock()
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Envelope: true}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:       server.URL,
+				APIKey:    "key",
+				APISecret: "secret",
+				Encoding:  telemetry.WebhookEncodingProtobuf,
+				Envelope:  true,
+			}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithClock(clock),
+	)
+
+	room := &livekit.Room{Sid: "RM_speakers", Name: "speakers"}
+	sut.ActiveSpeakerChanged(context.Background(), room, []*livekit.SpeakerInfo{
+		{Sid: "PA_loud", Level: 0.8, Active: true},
+		{Sid: "PA_quiet", Level: 0.2, Active: true},
+	})
+	clock.Advance(conf.Audio.ActiveSpeakerDebounce)
+	flushEvents(sut)
+	require.NoError(t, sut.Shutdown(context.Background()))
+
+	require.Len(t, requests, 2)
+	for i := 0; i < 2; i++ {
+		req := <-requests
+		require.Equal(t, telemetry.EventActiveSpeakerChanged, req.event.Event)
+		require.Len(t, req.details.Speakers, 2)
+		require.Equal(t, "PA_loud", req.details.Speakers[0].Sid)
+		require.InDelta(t, 0.8, req.details.Speakers[0].Level, 0.001)
+		require.Equal(t, "PA_quiet", req.details.Speakers[1].Sid)
+		require.InDelta(t, 0.2, req.details.Speakers[1].Level, 0.001)
+	}
+}
+
+func Test_ActiveSpeakerChanged_DroppedWhenRoomEnds(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.ActiveSpeakerEvents = true
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	before := speakerChanges(t)
+
+	fixture.sut.ActiveSpeakerChanged(context.Background(), room, []*livekit.SpeakerInfo{{Sid: "p1"}})
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+	clock.Advance(conf.Audio.ActiveSpeakerDebounce)
+
+	fixture.drain(t)
+	require.Equal(t, before, speakerChanges(t))
+	require.Empty(t, findWebhookEvents(fixture, telemetry.EventActiveSpeakerChanged))
+}
@@ -48,6 +48,14 @@ func StatsKeyForData(streamType livekit.StreamType, participantID livekit.Partic
 }
 
 func (t *telemetryService) TrackStats(key StatsKey, stat *livekit.AnalyticsStat) {
+	// attributed to codecs in the order the codecs of the track are set, rather than when the stat is processed
+	codecStats := []*livekit.AnalyticsStat{stat}
+	if key.track {
+		if worker, ok := t.getWorker(key.participantID); ok {
+			codecStats = worker.splitByCodec(key, stat)
+		}
+	}
+
 	t.enqueue(func() {
 		direction := prometheus.Incoming
 		if key.streamType == livekit.StreamType_DOWNSTREAM {
@@ -92,7 +100,9 @@ func (t *telemetryService) TrackStats(key StatsKey, stat *livekit.AnalyticsStat)
 		}
 
 		if worker, ok := t.getWorker(key.participantID); ok {
-			worker.OnTrackStat(key.trackID, key.streamType, stat)
+			for _, codecStat := range codecStats {
+				worker.OnTrackStat(key, codecStat)
+			}
 		}
 	})
 }
@@ -19,8 +19,11 @@ import (
 	"testing"
 	"time"
 
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/livekit"
 
@@ -35,12 +38,19 @@ func init() {
 type telemetryServiceFixture struct {
 	sut       telemetry.TelemetryService
 	analytics *telemetryfakes.FakeAnalyticsService
+	notifier  *telemetryfakes.FakeWebhookNotifier
 }
 
 func createFixture() *telemetryServiceFixture {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	return createFixtureWithConfig(conf)
+}
+
+func createFixtureWithConfig(conf *config.Config) *telemetryServiceFixture {
 	fixture := &telemetryServiceFixture{}
 	fixture.analytics = &telemetryfakes.FakeAnalyticsService{}
-	fixture.sut = telemetry.NewTelemetryService(nil, fixture.analytics)
+	fixture.notifier = &telemetryfakes.FakeWebhookNotifier{}
+	fixture.sut = telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{fixture.notifier}, fixture.analytics)
 	return fixture
 }
 
@@ -489,7 +499,7 @@ func Test_AnalyticsSentWhenParticipantLeaves(t *testing.T) {
 	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
 
 	// do
-	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
 
 	// should not be called if there are no track stats
 	time.Sleep(time.Millisecond * 500)
@@ -614,7 +624,683 @@ func Test_BothDownstreamAndUpstreamStatsAreSentTogether(t *testing.T) {
 	require.Equal(t, livekit.StreamType_DOWNSTREAM, stats[1].Kind)
 }
 
+// flushEvents waits until the analytics events of the telemetry calls made so far have been sent
+func flushEvents(sut telemetry.TelemetryService) {
+	_ = sut.FlushAnalytics(context.Background())
+}
+
+// drain shuts the service down once the telemetry calls made so far have been handled, so every webhook
+// they queued has been delivered
+func (f *telemetryServiceFixture) drain(t *testing.T) {
+	flushEvents(f.sut)
+	require.NoError(t, f.sut.Shutdown(context.Background()))
+}
+
+// flush sends the stats of the telemetry calls made so far
 func (f *telemetryServiceFixture) flush() {
-	time.Sleep(time.Millisecond * 500)
+	flushEvents(f.sut)
 	f.sut.FlushStats()
 }
+
+func Test_IdleWorkerIsReaped(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.WorkerIdleTimeout = time.Minute
+	conf.Analytics.StatsInterval = time.Hour
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+
+	// stats keep the worker alive
+	key := telemetry.StatsKeyForData(livekit.StreamType_UPSTREAM, partSID, "trackID")
+	stat := func() *livekit.AnalyticsStat {
+		return &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 10, PrimaryPackets: 1}}}
+	}
+	for i := 0; i < 4; i++ {
+		fixture.sut.TrackStats(key, stat())
+		flushEvents(fixture.sut)
+		clock.Advance(conf.Analytics.WorkerIdleTimeout / 2)
+	}
+	fixture.flush()
+	require.Equal(t, 1, fixture.analytics.SendStatsCallCount())
+
+	// once idle, the worker is closed and stats for the participant are no longer collected
+	clock.Advance(conf.Analytics.WorkerIdleTimeout + time.Second)
+	require.Eventually(t, func() bool {
+		_, ok := fixture.sut.GetParticipantStats(partSID)
+		return !ok
+	}, time.Second, time.Millisecond)
+	fixture.sut.TrackStats(key, stat())
+	fixture.flush()
+	require.Equal(t, 1, fixture.analytics.SendStatsCallCount())
+}
+
+func Test_AnalyticsSinkReplacesAnalyticsService(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	analytics := &telemetryfakes.FakeAnalyticsService{}
+	sink := &telemetryfakes.FakeAnalyticsSink{}
+	sut := telemetry.NewTelemetryService(conf, nil, analytics, telemetry.WithAnalyticsSink(sink))
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+	sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	stat := &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 33}}}
+	sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, ""), stat)
+
+	flushEvents(sut)
+	sut.FlushStats()
+
+	require.Equal(t, 1, sink.SendEventCallCount())
+	_, event := sink.SendEventArgsForCall(0)
+	require.Equal(t, livekit.AnalyticsEventType_PARTICIPANT_JOINED, event.Type)
+	require.Equal(t, 1, sink.SendStatsCallCount())
+	require.Zero(t, analytics.SendEventCallCount())
+	require.Zero(t, analytics.SendStatsCallCount())
+}
+
+// findMetric returns the series of the named metric with the given labels, or nil if it doesn't exist
+func findMetric(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	families, err := promclient.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			values := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				values[label.GetName()] = label.GetValue()
+			}
+			for label, value := range labels {
+				if values[label] != value {
+					continue metrics
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+// trackPacketsLost returns the value of the track packet loss counter for kind and room, if the series exists
+func trackPacketsLost(t *testing.T, kind string, room string) (float64, bool) {
+	metric := findMetric(t, "livekit_track_packets_lost_total", map[string]string{"kind": kind, "room": room})
+	if metric == nil {
+		return 0, false
+	}
+	return metric.GetCounter().GetValue(), true
+}
+
+func Test_TrackPacketLossIsRecordedUntilUnpublished(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomLabel = telemetry.RoomLabelFull
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "PacketLossRoom"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+	track := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 100, PacketsLost: 5}}})
+	fixture.flush()
+	lost, ok := trackPacketsLost(t, "VIDEO", room.Name)
+	require.True(t, ok)
+	require.Equal(t, float64(5), lost)
+
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 100, PacketsLost: 2}}})
+	fixture.flush()
+	lost, _ = trackPacketsLost(t, "VIDEO", room.Name)
+	require.Equal(t, float64(7), lost)
+
+	// unpublishing the only track in the room removes the series
+	fixture.sut.TrackUnpublished(context.Background(), partSID, "", track, true)
+	fixture.flush()
+	_, ok = trackPacketsLost(t, "VIDEO", room.Name)
+	require.False(t, ok)
+}
+
+// trackFeedback returns the value of the track feedback counter name for kind and direction, 0 if the series
+// doesn't exist
+func trackFeedback(t *testing.T, name string, kind string, direction string) float64 {
+	return findMetric(t, name, map[string]string{"kind": kind, "direction": direction}).GetCounter().GetValue()
+}
+
+func Test_TrackFeedbackIsRecordedByKind(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "FeedbackRoom"}
+	publisher := livekit.ParticipantID("publisher")
+	subscriber := livekit.ParticipantID("subscriber")
+	track := &livekit.TrackInfo{Sid: "TR_feedback", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(publisher)}, nil, nil, true)
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(subscriber)}, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), publisher, "", track)
+
+	incomingNacks := trackFeedback(t, "livekit_track_nacks_total", "VIDEO", "incoming")
+	outgoingNacks := trackFeedback(t, "livekit_track_nacks_total", "VIDEO", "outgoing")
+	incomingPlis := trackFeedback(t, "livekit_track_plis_total", "VIDEO", "incoming")
+	outgoingFirs := trackFeedback(t, "livekit_track_firs_total", "VIDEO", "outgoing")
+
+	upstream := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, publisher, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	downstream := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, subscriber, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	fixture.sut.TrackStats(upstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 100, Nacks: 3, Plis: 1}}})
+	fixture.sut.TrackStats(downstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 100, Nacks: 4, Firs: 1}}})
+	fixture.flush()
+
+	require.Equal(t, incomingNacks+3, trackFeedback(t, "livekit_track_nacks_total", "VIDEO", "incoming"))
+	require.Equal(t, outgoingNacks+4, trackFeedback(t, "livekit_track_nacks_total", "VIDEO", "outgoing"))
+	require.Equal(t, incomingPlis+1, trackFeedback(t, "livekit_track_plis_total", "VIDEO", "incoming"))
+	require.Equal(t, outgoingFirs+1, trackFeedback(t, "livekit_track_firs_total", "VIDEO", "outgoing"))
+
+	// the series aren't labelled by track
+	require.Nil(t, findMetric(t, "livekit_track_nacks_total", map[string]string{"track": track.Sid}))
+}
+
+// trackActiveLayers returns the number of published tracks of kind counted under layers, 0 if the series doesn't exist
+func trackActiveLayers(t *testing.T, kind string, layers string) float64 {
+	return findMetric(t, "livekit_track_active_layers", map[string]string{"kind": kind, "layers": layers}).GetGauge().GetValue()
+}
+
+func Test_TrackActiveLayersFollowStats(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "LayersRoom"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+	track := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+	beforeOne := trackActiveLayers(t, "VIDEO", "1")
+	beforeThree := trackActiveLayers(t, "VIDEO", "3")
+
+	// a simulcast layer per ssrc
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{
+		{Ssrc: 1, PrimaryPackets: 100},
+		{Ssrc: 2, PrimaryPackets: 100},
+		{Ssrc: 3, PrimaryPackets: 100},
+	}})
+	fixture.flush()
+	require.Equal(t, beforeThree+1, trackActiveLayers(t, "VIDEO", "3"))
+
+	// the higher layers stop
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{
+		{Ssrc: 1, PrimaryPackets: 100},
+		{Ssrc: 2},
+		{Ssrc: 3},
+	}})
+	fixture.flush()
+	require.Equal(t, beforeThree, trackActiveLayers(t, "VIDEO", "3"))
+	require.Equal(t, beforeOne+1, trackActiveLayers(t, "VIDEO", "1"))
+
+	fixture.sut.TrackUnpublished(context.Background(), partSID, "", track, true)
+	fixture.flush()
+	require.Equal(t, beforeOne, trackActiveLayers(t, "VIDEO", "1"))
+}
+
+func Test_IntervalJitterAndRTTAreRecorded(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+
+	labels := map[string]string{"kind": livekit.TrackType_AUDIO.String(), "direction": "subscribe"}
+	sampleCount := func(name string) uint64 {
+		if metric := findMetric(t, name, labels); metric != nil {
+			return metric.GetHistogram().GetSampleCount()
+		}
+		return 0
+	}
+	jitterBefore, rttBefore := sampleCount("livekit_quality_jitter_ms"), sampleCount("livekit_quality_rtt_ms")
+
+	// several samples in an interval are recorded once, with the highest values
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, partSID, "TR_1", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 1, Jitter: 8000, Rtt: 30}}})
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 1, Jitter: 15000, Rtt: 40}}})
+	fixture.flush()
+
+	require.Equal(t, jitterBefore+1, sampleCount("livekit_quality_jitter_ms"))
+	require.Equal(t, rttBefore+1, sampleCount("livekit_quality_rtt_ms"))
+	jitter := findMetric(t, "livekit_quality_jitter_ms", labels).GetHistogram()
+	require.GreaterOrEqual(t, jitter.GetSampleSum(), float64(15))
+}
+
+func Test_ParticipantSessionIsRecordedOnce(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomLabel = telemetry.RoomLabelFull
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "SessionRoom"}
+	sessions := func() uint64 {
+		if metric := findMetric(t, "livekit_participant_session_duration_seconds", map[string]string{"room": room.Name}); metric != nil {
+			return metric.GetHistogram().GetSampleCount()
+		}
+		return 0
+	}
+
+	// leaving without a worker records nothing
+	fixture.sut.ParticipantLeft(context.Background(), room, &livekit.ParticipantInfo{Sid: "unknown"}, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	fixture.flush()
+	require.Zero(t, sessions())
+
+	participantInfo := &livekit.ParticipantInfo{Sid: "part1"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	fixture.flush()
+	require.Equal(t, uint64(1), sessions())
+}
+
+func Test_ParticipantSessionsOfRestartedRoomAreKept(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomLabel = telemetry.RoomLabelFull
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RM_first", Name: "RestartedRoom"}
+	sessions := func() uint64 {
+		if metric := findMetric(t, "livekit_participant_session_duration_seconds", map[string]string{"room": room.Name}); metric != nil {
+			return metric.GetHistogram().GetSampleCount()
+		}
+		return 0
+	}
+
+	participantInfo := &livekit.ParticipantInfo{Sid: "PA_first"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+	require.Equal(t, uint64(1), sessions())
+
+	// a room with the same name starts before the sessions of the first are deleted
+	second := &livekit.Room{Sid: "RM_second", Name: room.Name}
+	fixture.sut.RoomStarted(context.Background(), second)
+	fixture.sut.ParticipantJoined(context.Background(), second, &livekit.ParticipantInfo{Sid: "PA_second"}, nil, nil, true)
+	flushEvents(fixture.sut)
+	clock.Advance(time.Minute)
+	require.Equal(t, uint64(1), sessions())
+
+	// once it ends too, the sessions of both are deleted after being kept long enough
+	fixture.sut.ParticipantLeft(context.Background(), second, &livekit.ParticipantInfo{Sid: "PA_second"}, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	fixture.sut.RoomEnded(context.Background(), second)
+	flushEvents(fixture.sut)
+	require.Equal(t, uint64(2), sessions())
+	clock.Advance(time.Minute)
+	require.Zero(t, sessions())
+}
+
+func Test_TrackBytesAreRecordedAsDeltas(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+	track := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_AUDIO}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+
+	trackBytes := func(name string) float64 {
+		if metric := findMetric(t, name, map[string]string{"kind": livekit.TrackType_AUDIO.String()}); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	publishedBefore := trackBytes("livekit_track_published_bytes_total")
+	subscribedBefore := trackBytes("livekit_track_subscribed_bytes_total")
+
+	upstream := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, livekit.TrackID(track.Sid), livekit.TrackSource_MICROPHONE, track.Type)
+	downstream := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, partSID, "TR_2", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	fixture.sut.TrackStats(upstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000, PaddingBytes: 100}}})
+	fixture.sut.TrackStats(downstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 2000, RetransmitBytes: 200}}})
+	fixture.flush()
+	require.Equal(t, publishedBefore+1100, trackBytes("livekit_track_published_bytes_total"))
+	require.Equal(t, subscribedBefore+2200, trackBytes("livekit_track_subscribed_bytes_total"))
+
+	// bytes received just before unpublishing are still counted
+	fixture.sut.TrackStats(upstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 1, PrimaryBytes: 500}}})
+	fixture.sut.TrackUnpublished(context.Background(), partSID, "", track, true)
+	fixture.flush()
+	require.Equal(t, publishedBefore+1600, trackBytes("livekit_track_published_bytes_total"))
+	require.Equal(t, subscribedBefore+2200, trackBytes("livekit_track_subscribed_bytes_total"))
+}
+
+func Test_StatsAreSampledAtConfiguredInterval(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.StatsInterval = time.Minute
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+
+	stat := &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 33}}}
+	fixture.sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, ""), stat)
+
+	flushEvents(fixture.sut)
+
+	// sent by the sampling tick, without FlushStats
+	clock.Advance(conf.Analytics.StatsInterval)
+	require.Eventually(t, func() bool {
+		return fixture.analytics.SendStatsCallCount() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func Test_InvalidStatsIntervalFallsBackToDefault(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.StatsInterval = -time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+
+	stat := &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 33}}}
+	fixture.sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, ""), stat)
+
+	flushEvents(fixture.sut)
+
+	// sampled once the default interval has passed
+	clock.Advance(config.TelemetryStatsUpdateInterval - time.Millisecond)
+	flushEvents(fixture.sut)
+	require.Zero(t, fixture.analytics.SendStatsCallCount())
+	clock.Advance(time.Millisecond)
+	require.Eventually(t, func() bool {
+		return fixture.analytics.SendStatsCallCount() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func Test_GetParticipantStats(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID)}
+
+	_, ok := fixture.sut.GetParticipantStats(partSID)
+	require.False(t, ok)
+
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	stats, ok := fixture.sut.GetParticipantStats(partSID)
+	require.True(t, ok)
+	require.Equal(t, livekit.RoomName("RoomName"), stats.RoomName)
+	require.True(t, stats.SampledAt.IsZero())
+	require.Empty(t, stats.Tracks)
+
+	upstream := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, "TR_1", livekit.TrackSource_MICROPHONE, livekit.TrackType_AUDIO)
+	downstream := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, partSID, "TR_2", livekit.TrackSource_CAMERA, livekit.TrackType_VIDEO)
+	fixture.sut.TrackStats(upstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{
+		PrimaryPackets: 90, PacketsLost: 10, PrimaryBytes: 1000, Jitter: 5000, Rtt: 40,
+	}}})
+	fixture.sut.TrackStats(downstream, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{
+		PrimaryPackets: 100, PrimaryBytes: 50000, Rtt: 60,
+	}}})
+	// data doesn't have media stats worth reporting
+	fixture.sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, ""), &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 33}}})
+	fixture.flush()
+
+	stats, ok = fixture.sut.GetParticipantStats(partSID)
+	require.True(t, ok)
+	require.False(t, stats.SampledAt.IsZero())
+	require.Len(t, stats.Tracks, 2)
+
+	published := stats.Tracks[0]
+	require.Equal(t, livekit.TrackID("TR_1"), published.TrackID)
+	require.Equal(t, livekit.TrackType_AUDIO, published.TrackType)
+	require.Equal(t, livekit.StreamType_UPSTREAM, published.Direction)
+	require.InDelta(t, 0.1, published.PacketLoss, 0.0001)
+	require.Equal(t, 5*time.Millisecond, published.Jitter)
+	require.Equal(t, 40*time.Millisecond, published.RTT)
+	require.Greater(t, published.Bitrate, float64(0))
+
+	subscribed := stats.Tracks[1]
+	require.Equal(t, livekit.TrackID("TR_2"), subscribed.TrackID)
+	require.Equal(t, livekit.StreamType_DOWNSTREAM, subscribed.Direction)
+	require.Zero(t, subscribed.PacketLoss)
+	require.Greater(t, subscribed.Bitrate, published.Bitrate)
+
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	flushEvents(fixture.sut)
+	_, ok = fixture.sut.GetParticipantStats(partSID)
+	require.False(t, ok)
+}
+
+// trackStalls returns the number of stalls of tracks of kind in direction counted so far
+func trackStalls(t *testing.T, kind string, direction string) float64 {
+	return findMetric(t, "livekit_track_stalls_total", map[string]string{"kind": kind, "direction": direction}).GetCounter().GetValue()
+}
+
+func Test_StalledTrackIsReported(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.TrackStallIntervals = 2
+	conf.WebHook.TrackStallEvents = true
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	track := &livekit.TrackInfo{Sid: "TR_stall", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, false)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+	before := trackStalls(t, "VIDEO", "publish")
+
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	media := &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}}
+	fixture.sut.TrackStats(key, media)
+	fixture.flush()
+
+	// one interval without media is not a stall yet
+	fixture.sut.FlushStats()
+	flushEvents(fixture.sut)
+	require.Equal(t, before, trackStalls(t, "VIDEO", "publish"))
+
+	fixture.sut.FlushStats()
+	flushEvents(fixture.sut)
+	require.Equal(t, before+1, trackStalls(t, "VIDEO", "publish"))
+
+	// still stalled, not reported again
+	fixture.sut.FlushStats()
+	flushEvents(fixture.sut)
+	require.Equal(t, before+1, trackStalls(t, "VIDEO", "publish"))
+
+	fixture.sut.TrackStats(key, media)
+	fixture.flush()
+
+	fixture.drain(t)
+	stalled := findWebhookEvents(fixture, telemetry.EventTrackStalled)
+	require.Len(t, stalled, 1)
+	require.Equal(t, track.Sid, stalled[0].Track.GetSid())
+	require.Equal(t, string(partSID), stalled[0].Participant.GetSid())
+	require.Len(t, findWebhookEvents(fixture, telemetry.EventTrackResumed), 1)
+}
+
+func Test_StalledSubscribedTrackIsReported(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.TrackStallIntervals = 1
+	conf.WebHook.TrackStallEvents = true
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	publisher := &livekit.ParticipantInfo{Sid: "PA_publisher"}
+	subscriber := &livekit.ParticipantInfo{Sid: "PA_subscriber"}
+	track := &livekit.TrackInfo{Sid: "TR_frozen", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, publisher, nil, nil, false)
+	fixture.sut.ParticipantJoined(context.Background(), room, subscriber, nil, nil, false)
+	fixture.sut.TrackPublished(context.Background(), livekit.ParticipantID(publisher.Sid), "", track)
+	fixture.sut.TrackSubscribed(context.Background(), livekit.ParticipantID(subscriber.Sid), track, publisher, false)
+	before := trackStalls(t, "VIDEO", "subscribe")
+
+	published := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, livekit.ParticipantID(publisher.Sid), livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	subscribed := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, livekit.ParticipantID(subscriber.Sid), livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	media := func() *livekit.AnalyticsStat {
+		return &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}}
+	}
+	fixture.sut.TrackStats(published, media())
+	fixture.sut.TrackStats(subscribed, media())
+	fixture.flush()
+
+	// media keeps flowing on the published track, but no longer to the subscriber
+	fixture.sut.TrackStats(published, media())
+	fixture.flush()
+	flushEvents(fixture.sut)
+	require.Equal(t, before+1, trackStalls(t, "VIDEO", "subscribe"))
+
+	fixture.sut.TrackStats(published, media())
+	fixture.sut.TrackStats(subscribed, media())
+	fixture.flush()
+
+	fixture.drain(t)
+	stalled := findWebhookEvents(fixture, telemetry.EventSubscribedTrackStalled)
+	require.Len(t, stalled, 1)
+	require.Equal(t, track.Sid, stalled[0].Track.GetSid())
+	require.Equal(t, subscriber.Sid, stalled[0].Participant.GetSid())
+	require.Empty(t, findWebhookEvents(fixture, telemetry.EventTrackStalled))
+	require.Len(t, findWebhookEvents(fixture, telemetry.EventSubscribedTrackResumed), 1)
+}
+
+func Test_SubscribedTrackDoesNotStallWithPublisher(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.TrackStallIntervals = 1
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	publisher := &livekit.ParticipantInfo{Sid: "PA_publisher"}
+	subscriber := &livekit.ParticipantInfo{Sid: "PA_subscriber"}
+	track := &livekit.TrackInfo{Sid: "TR_muted_source", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, publisher, nil, nil, false)
+	fixture.sut.ParticipantJoined(context.Background(), room, subscriber, nil, nil, false)
+	fixture.sut.TrackPublished(context.Background(), livekit.ParticipantID(publisher.Sid), "", track)
+	fixture.sut.TrackSubscribed(context.Background(), livekit.ParticipantID(subscriber.Sid), track, publisher, false)
+	before := trackStalls(t, "VIDEO", "subscribe")
+
+	published := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, livekit.ParticipantID(publisher.Sid), livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	subscribed := telemetry.StatsKeyForTrack(livekit.StreamType_DOWNSTREAM, livekit.ParticipantID(subscriber.Sid), livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	fixture.sut.TrackStats(published, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}})
+	fixture.sut.TrackStats(subscribed, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}})
+	fixture.flush()
+
+	// the publisher muted, there is nothing to forward
+	fixture.sut.TrackMuted(context.Background(), livekit.ParticipantID(publisher.Sid), track, telemetry.TrackMuteSourcePublisher)
+	fixture.flush()
+	fixture.sut.FlushStats()
+	flushEvents(fixture.sut)
+
+	require.Equal(t, before, trackStalls(t, "VIDEO", "subscribe"))
+}
+
+func Test_MutedTrackDoesNotStall(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.TrackStallIntervals = 1
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	track := &livekit.TrackInfo{Sid: "TR_muted", Type: livekit.TrackType_AUDIO}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, false)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+	before := trackStalls(t, "AUDIO", "publish")
+
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, livekit.TrackID(track.Sid), livekit.TrackSource_MICROPHONE, track.Type)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryPackets: 10, PrimaryBytes: 1000}}})
+	fixture.sut.TrackMuted(context.Background(), partSID, track, telemetry.TrackMuteSourcePublisher)
+	fixture.flush()
+	fixture.sut.FlushStats()
+	flushEvents(fixture.sut)
+
+	require.Equal(t, before, trackStalls(t, "AUDIO", "publish"))
+}
+
+func createFixtureWithClock(conf *config.Config, clock telemetry.Clock) *telemetryServiceFixture {
+	fixture := &telemetryServiceFixture{
+		analytics: &telemetryfakes.FakeAnalyticsService{},
+		notifier:  &telemetryfakes.FakeWebhookNotifier{},
+	}
+	fixture.sut = telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{fixture.notifier}, fixture.analytics, telemetry.WithClock(clock))
+	return fixture
+}
+
+func Test_TrackFirstPacketLatencyIsRecorded(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.TrackFirstPacketTimeout = 10 * time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	firstPackets := func() (uint64, float64) {
+		histogram := findMetric(t, "livekit_track_first_packet_seconds", map[string]string{"kind": "VIDEO"}).GetHistogram()
+		return histogram.GetSampleCount(), histogram.GetSampleSum()
+	}
+	beforeCount, beforeSum := firstPackets()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	track := &livekit.TrackInfo{Sid: "TR_first", Type: livekit.TrackType_VIDEO}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", track)
+	flushEvents(fixture.sut)
+	publishedAt := clock.Now()
+
+	// the latency is taken from the time the first packet was received, not the time stats are collected
+	clock.Advance(2 * time.Second)
+	fixture.sut.TrackFirstPacket(context.Background(), partSID, livekit.TrackID(track.Sid), publishedAt.Add(1500*time.Millisecond))
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, livekit.TrackID(track.Sid), livekit.TrackSource_CAMERA, track.Type)
+	fixture.sut.TrackStats(key, &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{Ssrc: 1, PrimaryPackets: 10, PrimaryBytes: 1000}}})
+	flushEvents(fixture.sut)
+	count, sum := firstPackets()
+	require.Equal(t, beforeCount+1, count)
+	require.InDelta(t, beforeSum+1.5, sum, 0.001)
+
+	// recorded once
+	fixture.sut.TrackFirstPacket(context.Background(), partSID, livekit.TrackID(track.Sid), clock.Now())
+	flushEvents(fixture.sut)
+	count, _ = firstPackets()
+	require.Equal(t, beforeCount+1, count)
+
+	// the track is active, it isn't reported as never active
+	neverActive := findMetric(t, "livekit_track_never_active_total", map[string]string{"kind": track.Type.String()}).GetCounter().GetValue()
+	clock.Advance(conf.Analytics.TrackFirstPacketTimeout)
+	fixture.sut.FlushStats()
+	require.Equal(t, neverActive, findMetric(t, "livekit_track_never_active_total", map[string]string{"kind": track.Type.String()}).GetCounter().GetValue())
+}
+
+func Test_TrackNeverActiveIsReported(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.TrackFirstPacketTimeout = 10 * time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	neverActive := func() float64 {
+		return findMetric(t, "livekit_track_never_active_total", map[string]string{"kind": "AUDIO"}).GetCounter().GetValue()
+	}
+	before := neverActive()
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	dead := &livekit.TrackInfo{Sid: "TR_dead", Type: livekit.TrackType_AUDIO}
+	muted := &livekit.TrackInfo{Sid: "TR_muted", Type: livekit.TrackType_AUDIO, Muted: true}
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", dead)
+	fixture.sut.TrackPublished(context.Background(), partSID, "", muted)
+	flushEvents(fixture.sut)
+
+	// not timed out yet
+	clock.Advance(conf.Analytics.TrackFirstPacketTimeout - time.Second)
+	fixture.sut.FlushStats()
+	require.Equal(t, before, neverActive())
+
+	// muted tracks aren't expected to send anything
+	clock.Advance(time.Second)
+	fixture.sut.FlushStats()
+	require.Equal(t, before+1, neverActive())
+
+	// reported once
+	fixture.sut.FlushStats()
+	require.Equal(t, before+1, neverActive())
+}
@@ -22,15 +22,70 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 )
 
+// trackLoss accumulates packet loss of a published track across flushes
+type trackLoss struct {
+	trackType livekit.TrackType
+	packets   uint64
+	lost      uint64
+	// spatial layers received in the last interval
+	layers int
+	// stall detection, see updateStallsLocked
+	muted    bool
+	hadMedia bool
+	idle     int
+	stalled  bool
+	// first packet detection, see updateFirstPacketsLocked. waitingSince is reset when the track is unmuted
+	publishedAt  time.Time
+	waitingSince time.Time
+	firstPackets map[uint32]time.Time
+	neverActive  bool
+	// whether the latency of the first packet has been recorded, see SetTrackFirstPacket
+	firstPacketRecorded bool
+}
+
+// subscribedTrack is a track the participant subscribes to, for stall detection, see updateSubscribedStallsLocked
+type subscribedTrack struct {
+	trackType   livekit.TrackType
+	publisherID livekit.ParticipantID
+	hadMedia    bool
+	idle        int
+	stalled     bool
+}
+
+// trackStall is a published or subscribed track that stalled or resumed in the last interval
+type trackStall struct {
+	trackID   livekit.TrackID
+	trackType livekit.TrackType
+	// StreamType_UPSTREAM for a published track, StreamType_DOWNSTREAM for a subscribed one
+	direction livekit.StreamType
+	stalled   bool
+}
+
+// trafficTotals accumulates media sent or received by a participant between room stats rollups
+type trafficTotals struct {
+	bytes   uint64
+	packets uint64
+}
+
+func (t *trafficTotals) add(stat *livekit.AnalyticsStat) {
+	for _, stream := range stat.Streams {
+		t.bytes += stream.PrimaryBytes + stream.RetransmitBytes + stream.PaddingBytes
+		t.packets += uint64(stream.PrimaryPackets) + uint64(stream.RetransmitPackets) + uint64(stream.PaddingPackets)
+	}
+}
+
 // StatsWorker handles participant stats
 type StatsWorker struct {
 	ctx                 context.Context
 	t                   TelemetryService
+	clock               Clock
 	roomID              livekit.RoomID
 	roomName            livekit.RoomName
 	participantID       livekit.ParticipantID
@@ -40,12 +95,88 @@ type StatsWorker struct {
 	lock             sync.RWMutex
 	outgoingPerTrack map[livekit.TrackID][]*livekit.AnalyticsStat
 	incomingPerTrack map[livekit.TrackID][]*livekit.AnalyticsStat
-	closedAt         time.Time
+	// types of the tracks stats were received for in the current interval
+	trackTypes      map[livekit.TrackID]livekit.TrackType
+	publishedTracks map[livekit.TrackID]*trackLoss
+	quality         qualityTracker
+	joinedAt        time.Time
+	lastActivity    time.Time
+	closedAt        time.Time
+
+	// media since the last room stats rollup
+	roomPublished  trafficTotals
+	roomSubscribed trafficTotals
+
+	// called once, the first time stats show the participant sending or receiving media
+	onMediaActive func(s *StatsWorker, activeAt time.Time)
+	mediaActive   bool
+
+	// called when media stops flowing on a published or subscribed track for stallIntervals flushes, and when it
+	// flows again
+	stallIntervals   int
+	onTrackStall     func(s *StatsWorker, stall trackStall)
+	subscribedTracks map[livekit.TrackID]*subscribedTrack
+	// whether media flows on a track published by another participant, a subscribed track only stalls while it does
+	isPublishedTrackLive func(publisherID livekit.ParticipantID, trackID livekit.TrackID) bool
+
+	// called once for a published track that receives no media within firstPacketTimeout of being published
+	firstPacketTimeout time.Duration
+	onTrackNeverActive func(s *StatsWorker, trackID livekit.TrackID, trackType livekit.TrackType)
+
+	// audio levels since the last summary, see TelemetryService.ParticipantAudioLevel
+	audio audioActivity
+
+	// quality scores of the tracks the participant subscribes to, see QoSScore
+	qosWeights    config.QoSScoreConfig
+	subscribedQoS map[livekit.TrackID]*trackQoS
+
+	// bitrates of the tracks in the latest sample, smoothed over previous ones, see smoothBitrate
+	bitrateAlpha     float64
+	smoothedBitrates map[trackDirection]float64
+
+	// how the room is labelled in metrics, taken when the worker is created so that rooms labelled by name are
+	// only admitted then
+	roomLabels roomLabeler
+	roomLabel  string
+
+	// codecs of the tracks the participant publishes and subscribes to, see SetTrackCodecs
+	trackCodecs map[trackDirection]*trackCodecs
+
+	// latest estimate of the participant's available downlink in bps, see TelemetryService.BandwidthEstimate
+	hasBandwidthEstimate bool
+
+	// latest sample, see ParticipantStats
+	sampledAt     time.Time
+	sampledTracks []ParticipantTrackStats
+
+	// timestamp of the last analytics event sent about the participant
+	lastEventAt time.Time
+
+	// joined is set when the worker was created by the participant joining, rather than for a migration.
+	// connectRecorded once the time it took to connect has been taken
+	joined          bool
+	connectRecorded bool
+	// the client the participant joined from, nil when it did not join through this worker
+	clientInfo *livekit.ClientInfo
+
+	// when each subscription the participant asked for and that hasn't been subscribed yet was requested
+	subscribeRequests map[livekit.TrackID]time.Time
+
+	// unpublishes whose events are held back in case the track is published again, oldest first
+	heldUnpublishes []*heldUnpublish
+}
+
+// heldUnpublish is an unpublished track whose events are sent once the churn window has passed
+type heldUnpublish struct {
+	trackID livekit.TrackID
+	timer   Timer
+	send    func()
 }
 
 func newStatsWorker(
 	ctx context.Context,
 	t TelemetryService,
+	clock Clock,
 	roomID livekit.RoomID,
 	roomName livekit.RoomName,
 	participantID livekit.ParticipantID,
@@ -54,33 +185,306 @@ func newStatsWorker(
 	s := &StatsWorker{
 		ctx:                 ctx,
 		t:                   t,
+		clock:               clock,
 		roomID:              roomID,
 		roomName:            roomName,
 		participantID:       participantID,
 		participantIdentity: identity,
 		outgoingPerTrack:    make(map[livekit.TrackID][]*livekit.AnalyticsStat),
 		incomingPerTrack:    make(map[livekit.TrackID][]*livekit.AnalyticsStat),
+		trackTypes:          make(map[livekit.TrackID]livekit.TrackType),
+		publishedTracks:     make(map[livekit.TrackID]*trackLoss),
+		subscribedQoS:       make(map[livekit.TrackID]*trackQoS),
+		subscribedTracks:    make(map[livekit.TrackID]*subscribedTrack),
+		smoothedBitrates:    make(map[trackDirection]float64),
+		trackCodecs:         make(map[trackDirection]*trackCodecs),
 	}
+	s.joinedAt = s.clock.Now()
+	s.lastActivity = s.joinedAt
 	return s
 }
 
-func (s *StatsWorker) OnTrackStat(trackID livekit.TrackID, direction livekit.StreamType, stat *livekit.AnalyticsStat) {
+func (s *StatsWorker) OnTrackStat(key StatsKey, stat *livekit.AnalyticsStat) {
 	s.lock.Lock()
-	if direction == livekit.StreamType_DOWNSTREAM {
-		s.outgoingPerTrack[trackID] = append(s.outgoingPerTrack[trackID], stat)
+	s.lastActivity = s.clock.Now()
+	// stats that trickle in after the participant left don't count, the session is over
+	becameActive := !s.mediaActive && s.closedAt.IsZero() && hasMedia(stat)
+	if becameActive {
+		s.mediaActive = true
+	}
+	if key.streamType == livekit.StreamType_DOWNSTREAM {
+		s.outgoingPerTrack[key.trackID] = append(s.outgoingPerTrack[key.trackID], stat)
 	} else {
-		s.incomingPerTrack[trackID] = append(s.incomingPerTrack[trackID], stat)
+		s.incomingPerTrack[key.trackID] = append(s.incomingPerTrack[key.trackID], stat)
+		if track, ok := s.publishedTracks[key.trackID]; ok {
+			s.updateFirstPacketsLocked(key.trackID, track, stat)
+		}
+	}
+	if key.track {
+		s.trackTypes[key.trackID] = key.trackType
+		if key.streamType == livekit.StreamType_DOWNSTREAM {
+			s.roomSubscribed.add(stat)
+		} else {
+			s.roomPublished.add(stat)
+		}
 	}
+	activeAt := s.lastActivity
 	s.lock.Unlock()
+
+	if becameActive && s.onMediaActive != nil {
+		s.onMediaActive(s, activeAt)
+	}
+}
+
+func hasMedia(stat *livekit.AnalyticsStat) bool {
+	for _, stream := range stat.Streams {
+		if stream.PrimaryBytes > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTrack starts recording packet loss and active layers of a track published by the participant
+func (s *StatsWorker) AddTrack(trackID livekit.TrackID, trackType livekit.TrackType) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.publishedTracks[trackID]; ok || !s.closedAt.IsZero() {
+		return
+	}
+	now := s.clock.Now()
+	s.publishedTracks[trackID] = &trackLoss{
+		trackType:    trackType,
+		publishedAt:  now,
+		waitingSince: now,
+		firstPackets: make(map[uint32]time.Time),
+	}
+	prometheus.AddPacketLossTrack(trackType.String(), s.roomLabel)
+}
+
+// SetTrackMuted records whether a published track is muted, muted tracks don't stall
+func (s *StatsWorker) SetTrackMuted(trackID livekit.TrackID, muted bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if track, ok := s.publishedTracks[trackID]; ok {
+		if track.muted && !muted {
+			track.waitingSince = s.clock.Now()
+		}
+		track.muted = muted
+		track.idle = 0
+	}
+}
+
+// SetTrackFirstPacket records the time from the publish of a track to its first packet, received at firstPacketAt.
+// only the first call for a track is recorded
+func (s *StatsWorker) SetTrackFirstPacket(trackID livekit.TrackID, firstPacketAt time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	track, ok := s.publishedTracks[trackID]
+	if !ok || track.firstPacketRecorded {
+		return
+	}
+	track.firstPacketRecorded = true
+
+	// the first packet can be received before the publish is processed
+	latency := firstPacketAt.Sub(track.publishedAt)
+	if latency < 0 {
+		latency = 0
+	}
+	prometheus.RecordTrackFirstPacket(track.trackType.String(), latency)
+}
+
+// RemoveTrack stops recording packet loss and active layers of an unpublished track
+func (s *StatsWorker) RemoveTrack(trackID livekit.TrackID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.removeTrackLocked(trackID)
+}
+
+func (s *StatsWorker) removeTrackLocked(trackID livekit.TrackID) {
+	loss, ok := s.publishedTracks[trackID]
+	if !ok {
+		return
+	}
+	delete(s.publishedTracks, trackID)
+	prometheus.SubPacketLossTrack(loss.trackType.String(), s.roomLabel)
+	prometheus.RecordTrackActiveLayers(loss.trackType.String(), loss.layers, 0)
+	logger.Debugw("track packet loss",
+		"pID", s.participantID,
+		"trackID", trackID,
+		"packets", loss.packets,
+		"lost", loss.lost,
+	)
+}
+
+// AddSubscribedTrack starts detecting stalls of a track the participant subscribed to, published by publisherID
+func (s *StatsWorker) AddSubscribedTrack(trackID livekit.TrackID, trackType livekit.TrackType, publisherID livekit.ParticipantID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.subscribedTracks[trackID]; !ok {
+		s.subscribedTracks[trackID] = &subscribedTrack{trackType: trackType, publisherID: publisherID}
+	}
+}
+
+// RemoveSubscribedTrack stops scoring a track the participant unsubscribed from
+func (s *StatsWorker) RemoveSubscribedTrack(trackID livekit.TrackID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.removeQoSLocked(trackID)
+	delete(s.subscribedTracks, trackID)
+}
+
+// isTrackLive returns true if a track published by the participant received media in the last interval and isn't muted
+func (s *StatsWorker) isTrackLive(trackID livekit.TrackID) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	track, ok := s.publishedTracks[trackID]
+	return ok && track.hadMedia && !track.muted && track.idle == 0
+}
+
+// holdUnpublish remembers an unpublish until it is released or taken back by a republish
+func (s *StatsWorker) holdUnpublish(held *heldUnpublish) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.heldUnpublishes = append(s.heldUnpublishes, held)
+}
+
+// releaseUnpublish forgets held, returns false if it had already been taken
+func (s *StatsWorker) releaseUnpublish(held *heldUnpublish) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, h := range s.heldUnpublishes {
+		if h == held {
+			s.heldUnpublishes = append(s.heldUnpublishes[:i], s.heldUnpublishes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// takeUnpublish forgets the unpublish held for trackID and returns it, if there is one
+func (s *StatsWorker) takeUnpublish(trackID livekit.TrackID) (*heldUnpublish, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, h := range s.heldUnpublishes {
+		if h.trackID == trackID {
+			s.heldUnpublishes = append(s.heldUnpublishes[:i], s.heldUnpublishes[i+1:]...)
+			return h, true
+		}
+	}
+	return nil, false
+}
+
+// takeUnpublishes forgets every held unpublish and returns them, oldest first
+func (s *StatsWorker) takeUnpublishes() []*heldUnpublish {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	held := s.heldUnpublishes
+	s.heldUnpublishes = nil
+	return held
 }
 
 func (s *StatsWorker) ParticipantID() livekit.ParticipantID {
 	return s.participantID
 }
 
+// orderEventTime returns ts if it is later than the participant's previous event, otherwise a microsecond after it,
+// so that the participant's events can be ordered by timestamp
+func (s *StatsWorker) orderEventTime(ts time.Time) time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !ts.After(s.lastEventAt) {
+		ts = s.lastEventAt.Add(time.Microsecond)
+	}
+	s.lastEventAt = ts
+	return ts
+}
+
 func (s *StatsWorker) SetConnected() {
 	s.lock.Lock()
 	s.isConnected = true
+	s.lastActivity = s.clock.Now()
+	s.lock.Unlock()
+}
+
+func (s *StatsWorker) setJoined(clientInfo *livekit.ClientInfo) {
+	s.lock.Lock()
+	s.joined = true
+	s.clientInfo = clientInfo
+	s.lock.Unlock()
+}
+
+func (s *StatsWorker) getClientInfo() *livekit.ClientInfo {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.clientInfo
+}
+
+// takeConnectLatency returns the time from the participant joining to connectedAt, false when it did not join
+// through this worker or the latency was already taken
+func (s *StatsWorker) takeConnectLatency(connectedAt time.Time) (time.Duration, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.joined || s.connectRecorded {
+		return 0, false
+	}
+	s.connectRecorded = true
+	return connectedAt.Sub(s.joinedAt), true
+}
+
+// subscribeRequested notes when the participant asked to subscribe to trackID. requests that were not subscribed
+// within subscribeLatencyTimeout, usually because the subscription failed, are dropped
+func (s *StatsWorker) subscribeRequested(trackID livekit.TrackID, requestedAt time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, at := range s.subscribeRequests {
+		if requestedAt.Sub(at) > subscribeLatencyTimeout {
+			delete(s.subscribeRequests, id)
+		}
+	}
+	if s.subscribeRequests == nil {
+		s.subscribeRequests = make(map[livekit.TrackID]time.Time)
+	}
+	s.subscribeRequests[trackID] = requestedAt
+}
+
+// takeSubscribeLatency returns the time from the participant asking to subscribe to trackID to subscribedAt,
+// false when the request wasn't seen, has expired or the latency was already taken
+func (s *StatsWorker) takeSubscribeLatency(trackID livekit.TrackID, subscribedAt time.Time) (time.Duration, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	requestedAt, ok := s.subscribeRequests[trackID]
+	if !ok {
+		return 0, false
+	}
+	delete(s.subscribeRequests, trackID)
+
+	latency := subscribedAt.Sub(requestedAt)
+	if latency > subscribeLatencyTimeout {
+		return 0, false
+	}
+	return latency, true
+}
+
+// clearSubscribeRequest drops the pending request for trackID, when subscribing to it failed
+func (s *StatsWorker) clearSubscribeRequest(trackID livekit.TrackID) {
+	s.lock.Lock()
+	delete(s.subscribeRequests, trackID)
 	s.lock.Unlock()
 }
 
@@ -92,7 +496,10 @@ func (s *StatsWorker) IsConnected() bool {
 }
 
 func (s *StatsWorker) Flush() {
-	ts := timestamppb.Now()
+	now := s.clock.Now()
+	ts := timestamppb.New(now)
+	// taken without the lock held, the publishers' workers have their own
+	live := s.livePublishedTracks()
 
 	s.lock.Lock()
 	stats := make([]*livekit.AnalyticsStat, 0, len(s.incomingPerTrack)+len(s.outgoingPerTrack))
@@ -102,23 +509,414 @@ func (s *StatsWorker) Flush() {
 
 	outgoingPerTrack := s.outgoingPerTrack
 	s.outgoingPerTrack = make(map[livekit.TrackID][]*livekit.AnalyticsStat)
+
+	trackTypes := s.trackTypes
+	s.trackTypes = make(map[livekit.TrackID]livekit.TrackType)
+
+	intervalStart := s.sampledAt
+	if intervalStart.IsZero() {
+		intervalStart = s.joinedAt
+	}
+	s.sampledAt = now
+	s.updateActiveLayersLocked(incomingPerTrack)
+	stalls := s.updateStallsLocked(incomingPerTrack)
+	stalls = append(stalls, s.updateSubscribedStallsLocked(outgoingPerTrack, live)...)
+	neverActive := s.neverActiveTracksLocked(now)
 	s.lock.Unlock()
 
+	if s.onTrackStall != nil {
+		for _, stall := range stalls {
+			s.onTrackStall(s, stall)
+		}
+	}
+	if s.onTrackNeverActive != nil {
+		for trackID, trackType := range neverActive {
+			s.onTrackNeverActive(s, trackID, trackType)
+		}
+	}
+
 	stats = s.collectStats(ts, livekit.StreamType_UPSTREAM, incomingPerTrack, stats)
 	stats = s.collectStats(ts, livekit.StreamType_DOWNSTREAM, outgoingPerTrack, stats)
 	if len(stats) > 0 {
 		s.t.SendStats(s.ctx, stats)
 	}
+	// sent by codec, recorded by track
+	stats = mergeCodecStats(stats)
+
+	tracks := sampleTracks(stats, trackTypes, now.Sub(intervalStart))
+	s.lock.Lock()
+	s.smoothBitratesLocked(tracks)
+	s.sampledTracks = tracks
+	s.lock.Unlock()
+
+	recordNetworkStats(stats, trackTypes)
+	recordTrackBytes(stats, trackTypes)
+	s.updatePacketLoss(stats)
+	recordTrackFeedback(stats, trackTypes)
+	s.updateQuality(stats)
+	s.updateQoS(stats, trackTypes)
+}
+
+// recordNetworkStats records the jitter and RTT of each media track over the interval
+func recordNetworkStats(stats []*livekit.AnalyticsStat, trackTypes map[livekit.TrackID]livekit.TrackType) {
+	for _, stat := range stats {
+		trackType, ok := trackTypes[livekit.TrackID(stat.TrackId)]
+		if !ok || trackType == livekit.TrackType_DATA {
+			continue
+		}
+		direction := "publish"
+		if stat.Kind == livekit.StreamType_DOWNSTREAM {
+			direction = "subscribe"
+		}
+		for _, stream := range stat.Streams {
+			// jitter is reported in microseconds
+			prometheus.RecordIntervalNetworkStats(trackType.String(), direction, float64(stream.Jitter)/1000, float64(stream.Rtt))
+		}
+	}
+}
+
+// recordTrackBytes records the bytes of each media track over the interval. stats are per interval,
+// so the counters get deltas; stats still pending for a track that is unpublished are part of the next flush
+func recordTrackBytes(stats []*livekit.AnalyticsStat, trackTypes map[livekit.TrackID]livekit.TrackType) {
+	published := make(map[livekit.TrackType]uint64)
+	subscribed := make(map[livekit.TrackType]uint64)
+	for _, stat := range stats {
+		trackType, ok := trackTypes[livekit.TrackID(stat.TrackId)]
+		if !ok || trackType == livekit.TrackType_DATA {
+			continue
+		}
+		var bytes uint64
+		for _, stream := range stat.Streams {
+			bytes += stream.PrimaryBytes + stream.RetransmitBytes + stream.PaddingBytes
+		}
+		if stat.Kind == livekit.StreamType_DOWNSTREAM {
+			subscribed[trackType] += bytes
+		} else {
+			published[trackType] += bytes
+		}
+	}
+
+	for _, trackType := range []livekit.TrackType{livekit.TrackType_AUDIO, livekit.TrackType_VIDEO} {
+		prometheus.RecordTrackBytes(trackType.String(), published[trackType], subscribed[trackType])
+	}
+}
+
+// updateActiveLayersLocked records the number of layers each published track was received with over the interval,
+// a track with no stats in the interval has none
+func (s *StatsWorker) updateActiveLayersLocked(incomingPerTrack map[livekit.TrackID][]*livekit.AnalyticsStat) {
+	for trackID, track := range s.publishedTracks {
+		layers := activeLayers(incomingPerTrack[trackID])
+		prometheus.RecordTrackActiveLayers(track.trackType.String(), track.layers, layers)
+		track.layers = layers
+	}
+}
+
+// updateStallsLocked returns the published tracks that stalled or resumed over the interval. a track stalls once
+// it has received no media for stallIntervals intervals in a row after having received some, unless it is muted
+func (s *StatsWorker) updateStallsLocked(incomingPerTrack map[livekit.TrackID][]*livekit.AnalyticsStat) []trackStall {
+	if s.stallIntervals <= 0 {
+		return nil
+	}
+
+	var stalls []trackStall
+	for trackID, track := range s.publishedTracks {
+		received := false
+		for _, stat := range incomingPerTrack[trackID] {
+			if hasMedia(stat) {
+				received = true
+				break
+			}
+		}
+
+		switch {
+		case received:
+			track.hadMedia = true
+			track.idle = 0
+			if track.stalled {
+				track.stalled = false
+				stalls = append(stalls, trackStall{trackID: trackID, trackType: track.trackType, direction: livekit.StreamType_UPSTREAM})
+			}
+		case track.hadMedia && !track.muted && !track.stalled:
+			track.idle++
+			if track.idle >= s.stallIntervals {
+				track.stalled = true
+				stalls = append(stalls, trackStall{
+					trackID:   trackID,
+					trackType: track.trackType,
+					direction: livekit.StreamType_UPSTREAM,
+					stalled:   true,
+				})
+			}
+		}
+	}
+	return stalls
+}
+
+// livePublishedTracks returns the tracks the participant subscribes to whose publisher is receiving media for them,
+// nil when stalls aren't detected
+func (s *StatsWorker) livePublishedTracks() map[livekit.TrackID]bool {
+	if s.stallIntervals <= 0 || s.isPublishedTrackLive == nil {
+		return nil
+	}
+
+	s.lock.RLock()
+	publishers := make(map[livekit.TrackID]livekit.ParticipantID, len(s.subscribedTracks))
+	for trackID, track := range s.subscribedTracks {
+		publishers[trackID] = track.publisherID
+	}
+	s.lock.RUnlock()
+
+	live := make(map[livekit.TrackID]bool, len(publishers))
+	for trackID, publisherID := range publishers {
+		live[trackID] = s.isPublishedTrackLive(publisherID, trackID)
+	}
+	return live
+}
+
+// updateSubscribedStallsLocked returns the subscribed tracks that stalled or resumed over the interval. a track
+// stalls once the participant has been sent no media for stallIntervals intervals in a row after having been sent
+// some, while media flowed on the published track. tracks published on other nodes, or muted, don't stall
+func (s *StatsWorker) updateSubscribedStallsLocked(
+	outgoingPerTrack map[livekit.TrackID][]*livekit.AnalyticsStat,
+	live map[livekit.TrackID]bool,
+) []trackStall {
+	if s.stallIntervals <= 0 {
+		return nil
+	}
+
+	var stalls []trackStall
+	for trackID, track := range s.subscribedTracks {
+		received := false
+		for _, stat := range outgoingPerTrack[trackID] {
+			if hasMedia(stat) {
+				received = true
+				break
+			}
+		}
+
+		switch {
+		case received:
+			track.hadMedia = true
+			track.idle = 0
+			if track.stalled {
+				track.stalled = false
+				stalls = append(stalls, trackStall{trackID: trackID, trackType: track.trackType, direction: livekit.StreamType_DOWNSTREAM})
+			}
+		case track.hadMedia && live[trackID] && !track.stalled:
+			track.idle++
+			if track.idle >= s.stallIntervals {
+				track.stalled = true
+				stalls = append(stalls, trackStall{
+					trackID:   trackID,
+					trackType: track.trackType,
+					direction: livekit.StreamType_DOWNSTREAM,
+					stalled:   true,
+				})
+			}
+		case !track.stalled:
+			// nothing to forward
+			track.idle = 0
+		}
+	}
+	return stalls
+}
+
+// updateFirstPacketsLocked records when media was first seen on each ssrc of a published track, as stats are received
+// from its buffers, at the resolution of the stats interval. the latency of the first packet is recorded from the
+// time the buffers saw it instead, see SetTrackFirstPacket
+func (s *StatsWorker) updateFirstPacketsLocked(trackID livekit.TrackID, track *trackLoss, stat *livekit.AnalyticsStat) {
+	now := s.clock.Now()
+	for _, stream := range stat.Streams {
+		if stream.PrimaryPackets == 0 {
+			continue
+		}
+		if _, ok := track.firstPackets[stream.Ssrc]; ok {
+			continue
+		}
+
+		track.firstPackets[stream.Ssrc] = now
+		logger.Debugw("first packet received",
+			"pID", s.participantID,
+			"trackID", trackID,
+			"ssrc", stream.Ssrc,
+			"sincePublish", now.Sub(track.publishedAt),
+		)
+	}
+}
+
+// neverActiveTracksLocked returns the published tracks that haven't received any media within firstPacketTimeout
+// of being published, or unmuted. each track is returned once
+func (s *StatsWorker) neverActiveTracksLocked(now time.Time) map[livekit.TrackID]livekit.TrackType {
+	if s.firstPacketTimeout <= 0 {
+		return nil
+	}
+
+	var neverActive map[livekit.TrackID]livekit.TrackType
+	for trackID, track := range s.publishedTracks {
+		if len(track.firstPackets) > 0 || track.muted || track.neverActive || now.Sub(track.waitingSince) < s.firstPacketTimeout {
+			continue
+		}
+		track.neverActive = true
+		if neverActive == nil {
+			neverActive = make(map[livekit.TrackID]livekit.TrackType)
+		}
+		neverActive[trackID] = track.trackType
+	}
+	return neverActive
+}
+
+// activeLayers counts the layers media was received on, from the stats before they are coalesced.
+// simulcast layers are each sent on their own ssrc, while a single ssrc can carry several layers with SVC
+func activeLayers(stats []*livekit.AnalyticsStat) int {
+	ssrcs := make(map[uint32]struct{})
+	layers := make(map[int32]struct{})
+	for _, stat := range stats {
+		for _, stream := range stat.Streams {
+			if stream.PrimaryPackets == 0 {
+				continue
+			}
+			ssrcs[stream.Ssrc] = struct{}{}
+			for _, layer := range stream.VideoLayers {
+				if layer.Packets > 0 {
+					layers[layer.Layer] = struct{}{}
+				}
+			}
+		}
+	}
+	if len(layers) > len(ssrcs) {
+		return len(layers)
+	}
+	return len(ssrcs)
+}
+
+// updatePacketLoss records the loss of published tracks since the last flush
+func (s *StatsWorker) updatePacketLoss(stats []*livekit.AnalyticsStat) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// recorded with the lock held, so a track being removed can't leave a series behind
+	for _, stat := range stats {
+		if stat.Kind != livekit.StreamType_UPSTREAM {
+			continue
+		}
+		loss, ok := s.publishedTracks[livekit.TrackID(stat.TrackId)]
+		if !ok {
+			continue
+		}
+
+		var packets, lost uint32
+		for _, stream := range stat.Streams {
+			packets += stream.PrimaryPackets + stream.PaddingPackets
+			lost += stream.PacketsLost
+		}
+		loss.packets += uint64(packets)
+		loss.lost += uint64(lost)
+		prometheus.RecordTrackPacketLoss(loss.trackType.String(), s.roomLabel, lost, packets)
+	}
+}
+
+// recordTrackFeedback records the NACKs, PLIs and FIRs of the media tracks over the interval, by kind. upstream
+// feedback is sent to the publisher, downstream feedback received from subscribers
+func recordTrackFeedback(stats []*livekit.AnalyticsStat, trackTypes map[livekit.TrackID]livekit.TrackType) {
+	for _, stat := range stats {
+		trackType, ok := trackTypes[livekit.TrackID(stat.TrackId)]
+		if !ok || trackType == livekit.TrackType_DATA {
+			continue
+		}
+
+		var nacks, plis, firs uint32
+		for _, stream := range stat.Streams {
+			nacks += stream.Nacks
+			plis += stream.Plis
+			firs += stream.Firs
+		}
+		direction := prometheus.Incoming
+		if stat.Kind == livekit.StreamType_DOWNSTREAM {
+			direction = prometheus.Outgoing
+		}
+		prometheus.RecordTrackFeedback(trackType.String(), direction, nacks, plis, firs)
+	}
+}
+
+func (s *StatsWorker) updateQuality(stats []*livekit.AnalyticsStat) {
+	quality, ok := connectionQuality(stats)
+	if !ok {
+		return
+	}
+
+	s.lock.Lock()
+	// recorded with the lock held, so a worker being closed can't leave the participant counted
+	if !s.closedAt.IsZero() {
+		s.lock.Unlock()
+		return
+	}
+	prev, hadQuality := s.quality.quality, s.quality.hasQuality
+	changed := s.quality.update(quality)
+	if changed {
+		if hadQuality {
+			prometheus.SubConnectionQuality(prev.String())
+		}
+		prometheus.AddConnectionQuality(quality.String())
+	}
+	s.lock.Unlock()
+	if !changed {
+		return
+	}
+
+	s.t.ConnectionQualityChanged(s.ctx, s.participantID, quality)
 }
 
 func (s *StatsWorker) Close() {
 	s.Flush()
 
 	s.lock.Lock()
-	s.closedAt = time.Now()
+	s.closedAt = s.clock.Now()
+	for trackID := range s.publishedTracks {
+		s.removeTrackLocked(trackID)
+	}
+	for trackID := range s.subscribedQoS {
+		s.removeQoSLocked(trackID)
+	}
+	if s.quality.hasQuality {
+		s.quality.hasQuality = false
+		prometheus.SubConnectionQuality(s.quality.quality.String())
+	}
+	if s.audio.speaking {
+		s.audio.speaking = false
+		prometheus.SubParticipantSpeaking()
+	}
+	if s.hasBandwidthEstimate {
+		s.hasBandwidthEstimate = false
+		prometheus.DeleteBandwidthEstimate(string(s.participantID))
+	}
 	s.lock.Unlock()
 }
 
+// takeRoomTraffic returns the media published and subscribed since it was last called, and whether the
+// participant is still in the room and publishing
+func (s *StatsWorker) takeRoomTraffic() (published trafficTotals, subscribed trafficTotals, active bool, publishing bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	published, subscribed = s.roomPublished, s.roomSubscribed
+	s.roomPublished, s.roomSubscribed = trafficTotals{}, trafficTotals{}
+	active = s.closedAt.IsZero()
+	return published, subscribed, active, active && len(s.publishedTracks) > 0
+}
+
+// JoinedAt returns when the worker was created for the participant joining
+func (s *StatsWorker) JoinedAt() time.Time {
+	return s.joinedAt
+}
+
+// LastActivity returns when the worker last received stats or was marked connected
+func (s *StatsWorker) LastActivity() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.lastActivity
+}
+
 func (s *StatsWorker) ClosedAt() time.Time {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -133,18 +931,31 @@ func (s *StatsWorker) collectStats(
 	stats []*livekit.AnalyticsStat,
 ) []*livekit.AnalyticsStat {
 	for trackID, analyticsStats := range perTrack {
-		coalesced := coalesce(analyticsStats)
-		if coalesced == nil {
-			continue
+		// the stats of a track are split by codec as they are received, see splitByCodec
+		var mimes []string
+		perMime := make(map[string][]*livekit.AnalyticsStat)
+		for _, stat := range analyticsStats {
+			if _, ok := perMime[stat.Mime]; !ok {
+				mimes = append(mimes, stat.Mime)
+			}
+			perMime[stat.Mime] = append(perMime[stat.Mime], stat)
 		}
 
-		coalesced.TimeStamp = ts
-		coalesced.TrackId = string(trackID)
-		coalesced.Kind = streamType
-		coalesced.RoomId = string(s.roomID)
-		coalesced.ParticipantId = string(s.participantID)
-		coalesced.RoomName = string(s.roomName)
-		stats = append(stats, coalesced)
+		for _, mime := range mimes {
+			coalesced := coalesce(perMime[mime])
+			if coalesced == nil {
+				continue
+			}
+
+			coalesced.TimeStamp = ts
+			coalesced.TrackId = string(trackID)
+			coalesced.Kind = streamType
+			coalesced.RoomId = string(s.roomID)
+			coalesced.ParticipantId = string(s.participantID)
+			coalesced.RoomName = string(s.roomName)
+			coalesced.Mime = mime
+			stats = append(stats, coalesced)
+		}
 	}
 	return stats
 }
@@ -0,0 +1,114 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package telemetryfakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+type FakeDeadLetterSink struct {
+	StoreStub        func(context.Context, *telemetry.DeadLetter) error
+	storeMutex       sync.RWMutex
+	storeArgsForCall []struct {
+		arg1 context.Context
+		arg2 *telemetry.DeadLetter
+	}
+	storeReturns struct {
+		result1 error
+	}
+	storeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeDeadLetterSink) Store(arg1 context.Context, arg2 *telemetry.DeadLetter) error {
+	fake.storeMutex.Lock()
+	ret, specificReturn := fake.storeReturnsOnCall[len(fake.storeArgsForCall)]
+	fake.storeArgsForCall = append(fake.storeArgsForCall, struct {
+		arg1 context.Context
+		arg2 *telemetry.DeadLetter
+	}{arg1, arg2})
+	stub := fake.StoreStub
+	fakeReturns := fake.storeReturns
+	fake.recordInvocation("Store", []interface{}{arg1, arg2})
+	fake.storeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeDeadLetterSink) StoreCallCount() int {
+	fake.storeMutex.RLock()
+	defer fake.storeMutex.RUnlock()
+	return len(fake.storeArgsForCall)
+}
+
+func (fake *FakeDeadLetterSink) StoreCalls(stub func(context.Context, *telemetry.DeadLetter) error) {
+	fake.storeMutex.Lock()
+	defer fake.storeMutex.Unlock()
+	fake.StoreStub = stub
+}
+
+func (fake *FakeDeadLetterSink) StoreArgsForCall(i int) (context.Context, *telemetry.DeadLetter) {
+	fake.storeMutex.RLock()
+	defer fake.storeMutex.RUnlock()
+	argsForCall := fake.storeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDeadLetterSink) StoreReturns(result1 error) {
+	fake.storeMutex.Lock()
+	defer fake.storeMutex.Unlock()
+	fake.StoreStub = nil
+	fake.storeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDeadLetterSink) StoreReturnsOnCall(i int, result1 error) {
+	fake.storeMutex.Lock()
+	defer fake.storeMutex.Unlock()
+	fake.StoreStub = nil
+	if fake.storeReturnsOnCall == nil {
+		fake.storeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDeadLetterSink) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.storeMutex.RLock()
+	defer fake.storeMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeDeadLetterSink) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ telemetry.DeadLetterSink = new(FakeDeadLetterSink)
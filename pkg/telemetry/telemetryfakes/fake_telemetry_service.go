@@ -4,18 +4,54 @@ package telemetryfakes
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/livekit"
 )
 
 type FakeTelemetryService struct {
+	ActiveSpeakerChangedStub        func(context.Context, *livekit.Room, []*livekit.SpeakerInfo)
+	activeSpeakerChangedMutex       sync.RWMutex
+	activeSpeakerChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 []*livekit.SpeakerInfo
+	}
+	BandwidthEstimateStub        func(context.Context, livekit.ParticipantID, int64)
+	bandwidthEstimateMutex       sync.RWMutex
+	bandwidthEstimateArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 int64
+	}
+	ConnectionQualityChangedStub        func(context.Context, livekit.ParticipantID, livekit.ConnectionQuality)
+	connectionQualityChangedMutex       sync.RWMutex
+	connectionQualityChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.ConnectionQuality
+	}
+	DataPacketForwardedStub        func(context.Context, livekit.ParticipantID, livekit.DataPacket_Kind, int)
+	dataPacketForwardedMutex       sync.RWMutex
+	dataPacketForwardedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.DataPacket_Kind
+		arg4 int
+	}
 	EgressEndedStub        func(context.Context, *livekit.EgressInfo)
 	egressEndedMutex       sync.RWMutex
 	egressEndedArgsForCall []struct {
 		arg1 context.Context
 		arg2 *livekit.EgressInfo
 	}
+	EgressFailedStub        func(context.Context, *livekit.EgressInfo)
+	egressFailedMutex       sync.RWMutex
+	egressFailedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.EgressInfo
+	}
 	EgressStartedStub        func(context.Context, *livekit.EgressInfo)
 	egressStartedMutex       sync.RWMutex
 	egressStartedArgsForCall []struct {
@@ -28,10 +64,38 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 *livekit.EgressInfo
 	}
+	FlushAnalyticsStub        func(context.Context) error
+	flushAnalyticsMutex       sync.RWMutex
+	flushAnalyticsArgsForCall []struct {
+		arg1 context.Context
+	}
+	flushAnalyticsReturns struct {
+		result1 error
+	}
+	flushAnalyticsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	FlushEventsStub        func()
+	flushEventsMutex       sync.RWMutex
+	flushEventsArgsForCall []struct {
+	}
 	FlushStatsStub        func()
 	flushStatsMutex       sync.RWMutex
 	flushStatsArgsForCall []struct {
 	}
+	GetParticipantStatsStub        func(livekit.ParticipantID) (*telemetry.ParticipantStats, bool)
+	getParticipantStatsMutex       sync.RWMutex
+	getParticipantStatsArgsForCall []struct {
+		arg1 livekit.ParticipantID
+	}
+	getParticipantStatsReturns struct {
+		result1 *telemetry.ParticipantStats
+		result2 bool
+	}
+	getParticipantStatsReturnsOnCall map[int]struct {
+		result1 *telemetry.ParticipantStats
+		result2 bool
+	}
 	IngressCreatedStub        func(context.Context, *livekit.IngressInfo)
 	ingressCreatedMutex       sync.RWMutex
 	ingressCreatedArgsForCall []struct {
@@ -44,11 +108,12 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 *livekit.IngressInfo
 	}
-	IngressEndedStub        func(context.Context, *livekit.IngressInfo)
+	IngressEndedStub        func(context.Context, *livekit.IngressInfo, error)
 	ingressEndedMutex       sync.RWMutex
 	ingressEndedArgsForCall []struct {
 		arg1 context.Context
 		arg2 *livekit.IngressInfo
+		arg3 error
 	}
 	IngressStartedStub        func(context.Context, *livekit.IngressInfo)
 	ingressStartedMutex       sync.RWMutex
@@ -83,6 +148,36 @@ type FakeTelemetryService struct {
 		arg4 *livekit.AnalyticsClientMeta
 		arg5 bool
 	}
+	ParticipantAttributesChangedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantInfo)
+	participantAttributesChangedMutex       sync.RWMutex
+	participantAttributesChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 *livekit.ParticipantInfo
+	}
+	ParticipantAudioLevelStub        func(livekit.ParticipantID, float64, bool)
+	participantAudioLevelMutex       sync.RWMutex
+	participantAudioLevelArgsForCall []struct {
+		arg1 livekit.ParticipantID
+		arg2 float64
+		arg3 bool
+	}
+	ParticipantConnectedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo)
+	participantConnectedMutex       sync.RWMutex
+	participantConnectedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}
+	ParticipantDuplicateIdentityStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantInfo)
+	participantDuplicateIdentityMutex       sync.RWMutex
+	participantDuplicateIdentityArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 *livekit.ParticipantInfo
+	}
 	ParticipantJoinedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ClientInfo, *livekit.AnalyticsClientMeta, bool)
 	participantJoinedMutex       sync.RWMutex
 	participantJoinedArgsForCall []struct {
@@ -93,13 +188,22 @@ type FakeTelemetryService struct {
 		arg5 *livekit.AnalyticsClientMeta
 		arg6 bool
 	}
-	ParticipantLeftStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, bool)
+	ParticipantLeftStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.DisconnectReason, bool)
 	participantLeftMutex       sync.RWMutex
 	participantLeftArgsForCall []struct {
 		arg1 context.Context
 		arg2 *livekit.Room
 		arg3 *livekit.ParticipantInfo
-		arg4 bool
+		arg4 livekit.DisconnectReason
+		arg5 bool
+	}
+	ParticipantPermissionsChangedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantPermission)
+	participantPermissionsChangedMutex       sync.RWMutex
+	participantPermissionsChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 *livekit.ParticipantPermission
 	}
 	ParticipantResumedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.NodeID, livekit.ReconnectReason)
 	participantResumedMutex       sync.RWMutex
@@ -110,12 +214,54 @@ type FakeTelemetryService struct {
 		arg4 livekit.NodeID
 		arg5 livekit.ReconnectReason
 	}
+	ParticipantRoleChangedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.ParticipantInfo_Kind, livekit.ParticipantInfo_Kind)
+	participantRoleChangedMutex       sync.RWMutex
+	participantRoleChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 livekit.ParticipantInfo_Kind
+		arg5 livekit.ParticipantInfo_Kind
+	}
+	ReplayDeadLettersStub        func(context.Context) (telemetry.DeadLetterReplayResult, error)
+	replayDeadLettersMutex       sync.RWMutex
+	replayDeadLettersArgsForCall []struct {
+		arg1 context.Context
+	}
+	replayDeadLettersReturns struct {
+		result1 telemetry.DeadLetterReplayResult
+		result2 error
+	}
+	replayDeadLettersReturnsOnCall map[int]struct {
+		result1 telemetry.DeadLetterReplayResult
+		result2 error
+	}
+	RoomDeletedStub        func(context.Context, *livekit.Room, string)
+	roomDeletedMutex       sync.RWMutex
+	roomDeletedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 string
+	}
 	RoomEndedStub        func(context.Context, *livekit.Room)
 	roomEndedMutex       sync.RWMutex
 	roomEndedArgsForCall []struct {
 		arg1 context.Context
 		arg2 *livekit.Room
 	}
+	RoomMetadataChangedStub        func(context.Context, *livekit.Room, string)
+	roomMetadataChangedMutex       sync.RWMutex
+	roomMetadataChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 string
+	}
+	RoomReservedStub        func(context.Context, *livekit.Room)
+	roomReservedMutex       sync.RWMutex
+	roomReservedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+	}
 	RoomStartedStub        func(context.Context, *livekit.Room)
 	roomStartedMutex       sync.RWMutex
 	roomStartedArgsForCall []struct {
@@ -140,6 +286,78 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 []*livekit.AnalyticsStat
 	}
+	ShutdownStub        func(context.Context) error
+	shutdownMutex       sync.RWMutex
+	shutdownArgsForCall []struct {
+		arg1 context.Context
+	}
+	shutdownReturns struct {
+		result1 error
+	}
+	shutdownReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SimulcastLayerChangedStub        func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality, string)
+	simulcastLayerChangedMutex       sync.RWMutex
+	simulcastLayerChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.VideoQuality
+		arg5 string
+	}
+	SnapshotStub        func() telemetry.Snapshot
+	snapshotMutex       sync.RWMutex
+	snapshotArgsForCall []struct {
+	}
+	snapshotReturns struct {
+		result1 telemetry.Snapshot
+	}
+	snapshotReturnsOnCall map[int]struct {
+		result1 telemetry.Snapshot
+	}
+	SubscribeStub        func(telemetry.EventListener) func()
+	subscribeMutex       sync.RWMutex
+	subscribeArgsForCall []struct {
+		arg1 telemetry.EventListener
+	}
+	subscribeReturns struct {
+		result1 func()
+	}
+	subscribeReturnsOnCall map[int]struct {
+		result1 func()
+	}
+	SubscriptionPermissionChangedStub        func(context.Context, livekit.ParticipantID, *livekit.SubscriptionPermission)
+	subscriptionPermissionChangedMutex       sync.RWMutex
+	subscriptionPermissionChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 *livekit.SubscriptionPermission
+	}
+	TrackFirstPacketStub        func(context.Context, livekit.ParticipantID, livekit.TrackID, time.Time)
+	trackFirstPacketMutex       sync.RWMutex
+	trackFirstPacketArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 time.Time
+	}
+	TrackLayerPausedStub        func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality)
+	trackLayerPausedMutex       sync.RWMutex
+	trackLayerPausedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.VideoQuality
+	}
+	TrackLayerResumedStub        func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality)
+	trackLayerResumedMutex       sync.RWMutex
+	trackLayerResumedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.VideoQuality
+	}
 	TrackMaxSubscribedVideoQualityStub        func(context.Context, livekit.ParticipantID, *livekit.TrackInfo, string, livekit.VideoQuality)
 	trackMaxSubscribedVideoQualityMutex       sync.RWMutex
 	trackMaxSubscribedVideoQualityArgsForCall []struct {
@@ -149,12 +367,21 @@ type FakeTelemetryService struct {
 		arg4 string
 		arg5 livekit.VideoQuality
 	}
-	TrackMutedStub        func(context.Context, livekit.ParticipantID, *livekit.TrackInfo)
+	TrackMutedStub        func(context.Context, livekit.ParticipantID, *livekit.TrackInfo, string)
 	trackMutedMutex       sync.RWMutex
 	trackMutedArgsForCall []struct {
 		arg1 context.Context
 		arg2 livekit.ParticipantID
 		arg3 *livekit.TrackInfo
+		arg4 string
+	}
+	TrackPublishFailedStub        func(context.Context, livekit.ParticipantID, *livekit.AddTrackRequest, string)
+	trackPublishFailedMutex       sync.RWMutex
+	trackPublishFailedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 *livekit.AddTrackRequest
+		arg4 string
 	}
 	TrackPublishRTPStatsStub        func(context.Context, livekit.ParticipantID, livekit.TrackID, string, int, *livekit.RTPStats)
 	trackPublishRTPStatsMutex       sync.RWMutex
@@ -229,12 +456,21 @@ type FakeTelemetryService struct {
 		arg4 *livekit.ParticipantInfo
 		arg5 bool
 	}
-	TrackUnmutedStub        func(context.Context, livekit.ParticipantID, *livekit.TrackInfo)
+	TrackSubscriptionFailedStub        func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.SubscriptionError)
+	trackSubscriptionFailedMutex       sync.RWMutex
+	trackSubscriptionFailedArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.SubscriptionError
+	}
+	TrackUnmutedStub        func(context.Context, livekit.ParticipantID, *livekit.TrackInfo, string)
 	trackUnmutedMutex       sync.RWMutex
 	trackUnmutedArgsForCall []struct {
 		arg1 context.Context
 		arg2 livekit.ParticipantID
 		arg3 *livekit.TrackInfo
+		arg4 string
 	}
 	TrackUnpublishedStub        func(context.Context, livekit.ParticipantID, livekit.ParticipantIdentity, *livekit.TrackInfo, bool)
 	trackUnpublishedMutex       sync.RWMutex
@@ -253,10 +489,167 @@ type FakeTelemetryService struct {
 		arg3 *livekit.TrackInfo
 		arg4 bool
 	}
+	TranscriptionEndedStub        func(context.Context, *livekit.Room, livekit.TrackID, time.Duration)
+	transcriptionEndedMutex       sync.RWMutex
+	transcriptionEndedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 livekit.TrackID
+		arg4 time.Duration
+	}
+	TranscriptionStartedStub        func(context.Context, *livekit.Room, livekit.TrackID)
+	transcriptionStartedMutex       sync.RWMutex
+	transcriptionStartedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 livekit.TrackID
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeTelemetryService) ActiveSpeakerChanged(arg1 context.Context, arg2 *livekit.Room, arg3 []*livekit.SpeakerInfo) {
+	var arg3Copy []*livekit.SpeakerInfo
+	if arg3 != nil {
+		arg3Copy = make([]*livekit.SpeakerInfo, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.activeSpeakerChangedMutex.Lock()
+	fake.activeSpeakerChangedArgsForCall = append(fake.activeSpeakerChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 []*livekit.SpeakerInfo
+	}{arg1, arg2, arg3Copy})
+	stub := fake.ActiveSpeakerChangedStub
+	fake.recordInvocation("ActiveSpeakerChanged", []interface{}{arg1, arg2, arg3Copy})
+	fake.activeSpeakerChangedMutex.Unlock()
+	if stub != nil {
+		fake.ActiveSpeakerChangedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ActiveSpeakerChangedCallCount() int {
+	fake.activeSpeakerChangedMutex.RLock()
+	defer fake.activeSpeakerChangedMutex.RUnlock()
+	return len(fake.activeSpeakerChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ActiveSpeakerChangedCalls(stub func(context.Context, *livekit.Room, []*livekit.SpeakerInfo)) {
+	fake.activeSpeakerChangedMutex.Lock()
+	defer fake.activeSpeakerChangedMutex.Unlock()
+	fake.ActiveSpeakerChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) ActiveSpeakerChangedArgsForCall(i int) (context.Context, *livekit.Room, []*livekit.SpeakerInfo) {
+	fake.activeSpeakerChangedMutex.RLock()
+	defer fake.activeSpeakerChangedMutex.RUnlock()
+	argsForCall := fake.activeSpeakerChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) BandwidthEstimate(arg1 context.Context, arg2 livekit.ParticipantID, arg3 int64) {
+	fake.bandwidthEstimateMutex.Lock()
+	fake.bandwidthEstimateArgsForCall = append(fake.bandwidthEstimateArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 int64
+	}{arg1, arg2, arg3})
+	stub := fake.BandwidthEstimateStub
+	fake.recordInvocation("BandwidthEstimate", []interface{}{arg1, arg2, arg3})
+	fake.bandwidthEstimateMutex.Unlock()
+	if stub != nil {
+		fake.BandwidthEstimateStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) BandwidthEstimateCallCount() int {
+	fake.bandwidthEstimateMutex.RLock()
+	defer fake.bandwidthEstimateMutex.RUnlock()
+	return len(fake.bandwidthEstimateArgsForCall)
+}
+
+func (fake *FakeTelemetryService) BandwidthEstimateCalls(stub func(context.Context, livekit.ParticipantID, int64)) {
+	fake.bandwidthEstimateMutex.Lock()
+	defer fake.bandwidthEstimateMutex.Unlock()
+	fake.BandwidthEstimateStub = stub
+}
+
+func (fake *FakeTelemetryService) BandwidthEstimateArgsForCall(i int) (context.Context, livekit.ParticipantID, int64) {
+	fake.bandwidthEstimateMutex.RLock()
+	defer fake.bandwidthEstimateMutex.RUnlock()
+	argsForCall := fake.bandwidthEstimateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) ConnectionQualityChanged(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.ConnectionQuality) {
+	fake.connectionQualityChangedMutex.Lock()
+	fake.connectionQualityChangedArgsForCall = append(fake.connectionQualityChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.ConnectionQuality
+	}{arg1, arg2, arg3})
+	stub := fake.ConnectionQualityChangedStub
+	fake.recordInvocation("ConnectionQualityChanged", []interface{}{arg1, arg2, arg3})
+	fake.connectionQualityChangedMutex.Unlock()
+	if stub != nil {
+		fake.ConnectionQualityChangedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ConnectionQualityChangedCallCount() int {
+	fake.connectionQualityChangedMutex.RLock()
+	defer fake.connectionQualityChangedMutex.RUnlock()
+	return len(fake.connectionQualityChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ConnectionQualityChangedCalls(stub func(context.Context, livekit.ParticipantID, livekit.ConnectionQuality)) {
+	fake.connectionQualityChangedMutex.Lock()
+	defer fake.connectionQualityChangedMutex.Unlock()
+	fake.ConnectionQualityChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) ConnectionQualityChangedArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.ConnectionQuality) {
+	fake.connectionQualityChangedMutex.RLock()
+	defer fake.connectionQualityChangedMutex.RUnlock()
+	argsForCall := fake.connectionQualityChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) DataPacketForwarded(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.DataPacket_Kind, arg4 int) {
+	fake.dataPacketForwardedMutex.Lock()
+	fake.dataPacketForwardedArgsForCall = append(fake.dataPacketForwardedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.DataPacket_Kind
+		arg4 int
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.DataPacketForwardedStub
+	fake.recordInvocation("DataPacketForwarded", []interface{}{arg1, arg2, arg3, arg4})
+	fake.dataPacketForwardedMutex.Unlock()
+	if stub != nil {
+		fake.DataPacketForwardedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) DataPacketForwardedCallCount() int {
+	fake.dataPacketForwardedMutex.RLock()
+	defer fake.dataPacketForwardedMutex.RUnlock()
+	return len(fake.dataPacketForwardedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) DataPacketForwardedCalls(stub func(context.Context, livekit.ParticipantID, livekit.DataPacket_Kind, int)) {
+	fake.dataPacketForwardedMutex.Lock()
+	defer fake.dataPacketForwardedMutex.Unlock()
+	fake.DataPacketForwardedStub = stub
+}
+
+func (fake *FakeTelemetryService) DataPacketForwardedArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.DataPacket_Kind, int) {
+	fake.dataPacketForwardedMutex.RLock()
+	defer fake.dataPacketForwardedMutex.RUnlock()
+	argsForCall := fake.dataPacketForwardedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
 func (fake *FakeTelemetryService) EgressEnded(arg1 context.Context, arg2 *livekit.EgressInfo) {
 	fake.egressEndedMutex.Lock()
 	fake.egressEndedArgsForCall = append(fake.egressEndedArgsForCall, struct {
@@ -290,6 +683,39 @@ func (fake *FakeTelemetryService) EgressEndedArgsForCall(i int) (context.Context
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) EgressFailed(arg1 context.Context, arg2 *livekit.EgressInfo) {
+	fake.egressFailedMutex.Lock()
+	fake.egressFailedArgsForCall = append(fake.egressFailedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.EgressInfo
+	}{arg1, arg2})
+	stub := fake.EgressFailedStub
+	fake.recordInvocation("EgressFailed", []interface{}{arg1, arg2})
+	fake.egressFailedMutex.Unlock()
+	if stub != nil {
+		fake.EgressFailedStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeTelemetryService) EgressFailedCallCount() int {
+	fake.egressFailedMutex.RLock()
+	defer fake.egressFailedMutex.RUnlock()
+	return len(fake.egressFailedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) EgressFailedCalls(stub func(context.Context, *livekit.EgressInfo)) {
+	fake.egressFailedMutex.Lock()
+	defer fake.egressFailedMutex.Unlock()
+	fake.EgressFailedStub = stub
+}
+
+func (fake *FakeTelemetryService) EgressFailedArgsForCall(i int) (context.Context, *livekit.EgressInfo) {
+	fake.egressFailedMutex.RLock()
+	defer fake.egressFailedMutex.RUnlock()
+	argsForCall := fake.egressFailedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeTelemetryService) EgressStarted(arg1 context.Context, arg2 *livekit.EgressInfo) {
 	fake.egressStartedMutex.Lock()
 	fake.egressStartedArgsForCall = append(fake.egressStartedArgsForCall, struct {
@@ -356,6 +782,91 @@ func (fake *FakeTelemetryService) EgressUpdatedArgsForCall(i int) (context.Conte
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) FlushAnalytics(arg1 context.Context) error {
+	fake.flushAnalyticsMutex.Lock()
+	ret, specificReturn := fake.flushAnalyticsReturnsOnCall[len(fake.flushAnalyticsArgsForCall)]
+	fake.flushAnalyticsArgsForCall = append(fake.flushAnalyticsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.FlushAnalyticsStub
+	fakeReturns := fake.flushAnalyticsReturns
+	fake.recordInvocation("FlushAnalytics", []interface{}{arg1})
+	fake.flushAnalyticsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeTelemetryService) FlushAnalyticsCallCount() int {
+	fake.flushAnalyticsMutex.RLock()
+	defer fake.flushAnalyticsMutex.RUnlock()
+	return len(fake.flushAnalyticsArgsForCall)
+}
+
+func (fake *FakeTelemetryService) FlushAnalyticsCalls(stub func(context.Context) error) {
+	fake.flushAnalyticsMutex.Lock()
+	defer fake.flushAnalyticsMutex.Unlock()
+	fake.FlushAnalyticsStub = stub
+}
+
+func (fake *FakeTelemetryService) FlushAnalyticsArgsForCall(i int) context.Context {
+	fake.flushAnalyticsMutex.RLock()
+	defer fake.flushAnalyticsMutex.RUnlock()
+	argsForCall := fake.flushAnalyticsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeTelemetryService) FlushAnalyticsReturns(result1 error) {
+	fake.flushAnalyticsMutex.Lock()
+	defer fake.flushAnalyticsMutex.Unlock()
+	fake.FlushAnalyticsStub = nil
+	fake.flushAnalyticsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) FlushAnalyticsReturnsOnCall(i int, result1 error) {
+	fake.flushAnalyticsMutex.Lock()
+	defer fake.flushAnalyticsMutex.Unlock()
+	fake.FlushAnalyticsStub = nil
+	if fake.flushAnalyticsReturnsOnCall == nil {
+		fake.flushAnalyticsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.flushAnalyticsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) FlushEvents() {
+	fake.flushEventsMutex.Lock()
+	fake.flushEventsArgsForCall = append(fake.flushEventsArgsForCall, struct {
+	}{})
+	stub := fake.FlushEventsStub
+	fake.recordInvocation("FlushEvents", []interface{}{})
+	fake.flushEventsMutex.Unlock()
+	if stub != nil {
+		fake.FlushEventsStub()
+	}
+}
+
+func (fake *FakeTelemetryService) FlushEventsCallCount() int {
+	fake.flushEventsMutex.RLock()
+	defer fake.flushEventsMutex.RUnlock()
+	return len(fake.flushEventsArgsForCall)
+}
+
+func (fake *FakeTelemetryService) FlushEventsCalls(stub func()) {
+	fake.flushEventsMutex.Lock()
+	defer fake.flushEventsMutex.Unlock()
+	fake.FlushEventsStub = stub
+}
+
 func (fake *FakeTelemetryService) FlushStats() {
 	fake.flushStatsMutex.Lock()
 	fake.flushStatsArgsForCall = append(fake.flushStatsArgsForCall, struct {
@@ -380,6 +891,70 @@ func (fake *FakeTelemetryService) FlushStatsCalls(stub func()) {
 	fake.FlushStatsStub = stub
 }
 
+func (fake *FakeTelemetryService) GetParticipantStats(arg1 livekit.ParticipantID) (*telemetry.ParticipantStats, bool) {
+	fake.getParticipantStatsMutex.Lock()
+	ret, specificReturn := fake.getParticipantStatsReturnsOnCall[len(fake.getParticipantStatsArgsForCall)]
+	fake.getParticipantStatsArgsForCall = append(fake.getParticipantStatsArgsForCall, struct {
+		arg1 livekit.ParticipantID
+	}{arg1})
+	stub := fake.GetParticipantStatsStub
+	fakeReturns := fake.getParticipantStatsReturns
+	fake.recordInvocation("GetParticipantStats", []interface{}{arg1})
+	fake.getParticipantStatsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeTelemetryService) GetParticipantStatsCallCount() int {
+	fake.getParticipantStatsMutex.RLock()
+	defer fake.getParticipantStatsMutex.RUnlock()
+	return len(fake.getParticipantStatsArgsForCall)
+}
+
+func (fake *FakeTelemetryService) GetParticipantStatsCalls(stub func(livekit.ParticipantID) (*telemetry.ParticipantStats, bool)) {
+	fake.getParticipantStatsMutex.Lock()
+	defer fake.getParticipantStatsMutex.Unlock()
+	fake.GetParticipantStatsStub = stub
+}
+
+func (fake *FakeTelemetryService) GetParticipantStatsArgsForCall(i int) livekit.ParticipantID {
+	fake.getParticipantStatsMutex.RLock()
+	defer fake.getParticipantStatsMutex.RUnlock()
+	argsForCall := fake.getParticipantStatsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeTelemetryService) GetParticipantStatsReturns(result1 *telemetry.ParticipantStats, result2 bool) {
+	fake.getParticipantStatsMutex.Lock()
+	defer fake.getParticipantStatsMutex.Unlock()
+	fake.GetParticipantStatsStub = nil
+	fake.getParticipantStatsReturns = struct {
+		result1 *telemetry.ParticipantStats
+		result2 bool
+	}{result1, result2}
+}
+
+func (fake *FakeTelemetryService) GetParticipantStatsReturnsOnCall(i int, result1 *telemetry.ParticipantStats, result2 bool) {
+	fake.getParticipantStatsMutex.Lock()
+	defer fake.getParticipantStatsMutex.Unlock()
+	fake.GetParticipantStatsStub = nil
+	if fake.getParticipantStatsReturnsOnCall == nil {
+		fake.getParticipantStatsReturnsOnCall = make(map[int]struct {
+			result1 *telemetry.ParticipantStats
+			result2 bool
+		})
+	}
+	fake.getParticipantStatsReturnsOnCall[i] = struct {
+		result1 *telemetry.ParticipantStats
+		result2 bool
+	}{result1, result2}
+}
+
 func (fake *FakeTelemetryService) IngressCreated(arg1 context.Context, arg2 *livekit.IngressInfo) {
 	fake.ingressCreatedMutex.Lock()
 	fake.ingressCreatedArgsForCall = append(fake.ingressCreatedArgsForCall, struct {
@@ -446,17 +1021,18 @@ func (fake *FakeTelemetryService) IngressDeletedArgsForCall(i int) (context.Cont
 	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeTelemetryService) IngressEnded(arg1 context.Context, arg2 *livekit.IngressInfo) {
+func (fake *FakeTelemetryService) IngressEnded(arg1 context.Context, arg2 *livekit.IngressInfo, arg3 error) {
 	fake.ingressEndedMutex.Lock()
 	fake.ingressEndedArgsForCall = append(fake.ingressEndedArgsForCall, struct {
 		arg1 context.Context
 		arg2 *livekit.IngressInfo
-	}{arg1, arg2})
+		arg3 error
+	}{arg1, arg2, arg3})
 	stub := fake.IngressEndedStub
-	fake.recordInvocation("IngressEnded", []interface{}{arg1, arg2})
+	fake.recordInvocation("IngressEnded", []interface{}{arg1, arg2, arg3})
 	fake.ingressEndedMutex.Unlock()
 	if stub != nil {
-		fake.IngressEndedStub(arg1, arg2)
+		fake.IngressEndedStub(arg1, arg2, arg3)
 	}
 }
 
@@ -466,17 +1042,17 @@ func (fake *FakeTelemetryService) IngressEndedCallCount() int {
 	return len(fake.ingressEndedArgsForCall)
 }
 
-func (fake *FakeTelemetryService) IngressEndedCalls(stub func(context.Context, *livekit.IngressInfo)) {
+func (fake *FakeTelemetryService) IngressEndedCalls(stub func(context.Context, *livekit.IngressInfo, error)) {
 	fake.ingressEndedMutex.Lock()
 	defer fake.ingressEndedMutex.Unlock()
 	fake.IngressEndedStub = stub
 }
 
-func (fake *FakeTelemetryService) IngressEndedArgsForCall(i int) (context.Context, *livekit.IngressInfo) {
+func (fake *FakeTelemetryService) IngressEndedArgsForCall(i int) (context.Context, *livekit.IngressInfo, error) {
 	fake.ingressEndedMutex.RLock()
 	defer fake.ingressEndedMutex.RUnlock()
 	argsForCall := fake.ingressEndedArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeTelemetryService) IngressStarted(arg1 context.Context, arg2 *livekit.IngressInfo) {
@@ -647,6 +1223,144 @@ func (fake *FakeTelemetryService) ParticipantActiveArgsForCall(i int) (context.C
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
+func (fake *FakeTelemetryService) ParticipantAttributesChanged(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 *livekit.ParticipantInfo) {
+	fake.participantAttributesChangedMutex.Lock()
+	fake.participantAttributesChangedArgsForCall = append(fake.participantAttributesChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 *livekit.ParticipantInfo
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ParticipantAttributesChangedStub
+	fake.recordInvocation("ParticipantAttributesChanged", []interface{}{arg1, arg2, arg3, arg4})
+	fake.participantAttributesChangedMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantAttributesChangedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantAttributesChangedCallCount() int {
+	fake.participantAttributesChangedMutex.RLock()
+	defer fake.participantAttributesChangedMutex.RUnlock()
+	return len(fake.participantAttributesChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantAttributesChangedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantInfo)) {
+	fake.participantAttributesChangedMutex.Lock()
+	defer fake.participantAttributesChangedMutex.Unlock()
+	fake.ParticipantAttributesChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantAttributesChangedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantInfo) {
+	fake.participantAttributesChangedMutex.RLock()
+	defer fake.participantAttributesChangedMutex.RUnlock()
+	argsForCall := fake.participantAttributesChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeTelemetryService) ParticipantAudioLevel(arg1 livekit.ParticipantID, arg2 float64, arg3 bool) {
+	fake.participantAudioLevelMutex.Lock()
+	fake.participantAudioLevelArgsForCall = append(fake.participantAudioLevelArgsForCall, struct {
+		arg1 livekit.ParticipantID
+		arg2 float64
+		arg3 bool
+	}{arg1, arg2, arg3})
+	stub := fake.ParticipantAudioLevelStub
+	fake.recordInvocation("ParticipantAudioLevel", []interface{}{arg1, arg2, arg3})
+	fake.participantAudioLevelMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantAudioLevelStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantAudioLevelCallCount() int {
+	fake.participantAudioLevelMutex.RLock()
+	defer fake.participantAudioLevelMutex.RUnlock()
+	return len(fake.participantAudioLevelArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantAudioLevelCalls(stub func(livekit.ParticipantID, float64, bool)) {
+	fake.participantAudioLevelMutex.Lock()
+	defer fake.participantAudioLevelMutex.Unlock()
+	fake.ParticipantAudioLevelStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantAudioLevelArgsForCall(i int) (livekit.ParticipantID, float64, bool) {
+	fake.participantAudioLevelMutex.RLock()
+	defer fake.participantAudioLevelMutex.RUnlock()
+	argsForCall := fake.participantAudioLevelArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) ParticipantConnected(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo) {
+	fake.participantConnectedMutex.Lock()
+	fake.participantConnectedArgsForCall = append(fake.participantConnectedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}{arg1, arg2, arg3})
+	stub := fake.ParticipantConnectedStub
+	fake.recordInvocation("ParticipantConnected", []interface{}{arg1, arg2, arg3})
+	fake.participantConnectedMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantConnectedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantConnectedCallCount() int {
+	fake.participantConnectedMutex.RLock()
+	defer fake.participantConnectedMutex.RUnlock()
+	return len(fake.participantConnectedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantConnectedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo)) {
+	fake.participantConnectedMutex.Lock()
+	defer fake.participantConnectedMutex.Unlock()
+	fake.ParticipantConnectedStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantConnectedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo) {
+	fake.participantConnectedMutex.RLock()
+	defer fake.participantConnectedMutex.RUnlock()
+	argsForCall := fake.participantConnectedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) ParticipantDuplicateIdentity(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 *livekit.ParticipantInfo) {
+	fake.participantDuplicateIdentityMutex.Lock()
+	fake.participantDuplicateIdentityArgsForCall = append(fake.participantDuplicateIdentityArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 *livekit.ParticipantInfo
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ParticipantDuplicateIdentityStub
+	fake.recordInvocation("ParticipantDuplicateIdentity", []interface{}{arg1, arg2, arg3, arg4})
+	fake.participantDuplicateIdentityMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantDuplicateIdentityStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantDuplicateIdentityCallCount() int {
+	fake.participantDuplicateIdentityMutex.RLock()
+	defer fake.participantDuplicateIdentityMutex.RUnlock()
+	return len(fake.participantDuplicateIdentityArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantDuplicateIdentityCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantInfo)) {
+	fake.participantDuplicateIdentityMutex.Lock()
+	defer fake.participantDuplicateIdentityMutex.Unlock()
+	fake.ParticipantDuplicateIdentityStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantDuplicateIdentityArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantInfo) {
+	fake.participantDuplicateIdentityMutex.RLock()
+	defer fake.participantDuplicateIdentityMutex.RUnlock()
+	argsForCall := fake.participantDuplicateIdentityArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
 func (fake *FakeTelemetryService) ParticipantJoined(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 *livekit.ClientInfo, arg5 *livekit.AnalyticsClientMeta, arg6 bool) {
 	fake.participantJoinedMutex.Lock()
 	fake.participantJoinedArgsForCall = append(fake.participantJoinedArgsForCall, struct {
@@ -666,6 +1380,8 @@ func (fake *FakeTelemetryService) ParticipantJoined(arg1 context.Context, arg2 *
 }
 
 func (fake *FakeTelemetryService) ParticipantJoinedCallCount() int {
+	fake.participantDuplicateIdentityMutex.RLock()
+	defer fake.participantDuplicateIdentityMutex.RUnlock()
 	fake.participantJoinedMutex.RLock()
 	defer fake.participantJoinedMutex.RUnlock()
 	return len(fake.participantJoinedArgsForCall)
@@ -684,19 +1400,20 @@ func (fake *FakeTelemetryService) ParticipantJoinedArgsForCall(i int) (context.C
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
 }
 
-func (fake *FakeTelemetryService) ParticipantLeft(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 bool) {
+func (fake *FakeTelemetryService) ParticipantLeft(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 livekit.DisconnectReason, arg5 bool) {
 	fake.participantLeftMutex.Lock()
 	fake.participantLeftArgsForCall = append(fake.participantLeftArgsForCall, struct {
 		arg1 context.Context
 		arg2 *livekit.Room
 		arg3 *livekit.ParticipantInfo
-		arg4 bool
-	}{arg1, arg2, arg3, arg4})
+		arg4 livekit.DisconnectReason
+		arg5 bool
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.ParticipantLeftStub
-	fake.recordInvocation("ParticipantLeft", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("ParticipantLeft", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.participantLeftMutex.Unlock()
 	if stub != nil {
-		fake.ParticipantLeftStub(arg1, arg2, arg3, arg4)
+		fake.ParticipantLeftStub(arg1, arg2, arg3, arg4, arg5)
 	}
 }
 
@@ -706,53 +1423,222 @@ func (fake *FakeTelemetryService) ParticipantLeftCallCount() int {
 	return len(fake.participantLeftArgsForCall)
 }
 
-func (fake *FakeTelemetryService) ParticipantLeftCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, bool)) {
+func (fake *FakeTelemetryService) ParticipantLeftCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.DisconnectReason, bool)) {
 	fake.participantLeftMutex.Lock()
 	defer fake.participantLeftMutex.Unlock()
 	fake.ParticipantLeftStub = stub
 }
 
-func (fake *FakeTelemetryService) ParticipantLeftArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, bool) {
-	fake.participantLeftMutex.RLock()
-	defer fake.participantLeftMutex.RUnlock()
-	argsForCall := fake.participantLeftArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+func (fake *FakeTelemetryService) ParticipantLeftArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.DisconnectReason, bool) {
+	fake.participantLeftMutex.RLock()
+	defer fake.participantLeftMutex.RUnlock()
+	argsForCall := fake.participantLeftArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeTelemetryService) ParticipantPermissionsChanged(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 *livekit.ParticipantPermission) {
+	fake.participantPermissionsChangedMutex.Lock()
+	fake.participantPermissionsChangedArgsForCall = append(fake.participantPermissionsChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 *livekit.ParticipantPermission
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ParticipantPermissionsChangedStub
+	fake.recordInvocation("ParticipantPermissionsChanged", []interface{}{arg1, arg2, arg3, arg4})
+	fake.participantPermissionsChangedMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantPermissionsChangedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantPermissionsChangedCallCount() int {
+	fake.participantPermissionsChangedMutex.RLock()
+	defer fake.participantPermissionsChangedMutex.RUnlock()
+	return len(fake.participantPermissionsChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantPermissionsChangedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantPermission)) {
+	fake.participantPermissionsChangedMutex.Lock()
+	defer fake.participantPermissionsChangedMutex.Unlock()
+	fake.ParticipantPermissionsChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantPermissionsChangedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.ParticipantPermission) {
+	fake.participantPermissionsChangedMutex.RLock()
+	defer fake.participantPermissionsChangedMutex.RUnlock()
+	argsForCall := fake.participantPermissionsChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeTelemetryService) ParticipantResumed(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 livekit.NodeID, arg5 livekit.ReconnectReason) {
+	fake.participantResumedMutex.Lock()
+	fake.participantResumedArgsForCall = append(fake.participantResumedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 livekit.NodeID
+		arg5 livekit.ReconnectReason
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.ParticipantResumedStub
+	fake.recordInvocation("ParticipantResumed", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.participantResumedMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantResumedStub(arg1, arg2, arg3, arg4, arg5)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantResumedCallCount() int {
+	fake.participantResumedMutex.RLock()
+	defer fake.participantResumedMutex.RUnlock()
+	return len(fake.participantResumedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantResumedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.NodeID, livekit.ReconnectReason)) {
+	fake.participantResumedMutex.Lock()
+	defer fake.participantResumedMutex.Unlock()
+	fake.ParticipantResumedStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantResumedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.NodeID, livekit.ReconnectReason) {
+	fake.participantResumedMutex.RLock()
+	defer fake.participantResumedMutex.RUnlock()
+	argsForCall := fake.participantResumedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeTelemetryService) ParticipantRoleChanged(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 livekit.ParticipantInfo_Kind, arg5 livekit.ParticipantInfo_Kind) {
+	fake.participantRoleChangedMutex.Lock()
+	fake.participantRoleChangedArgsForCall = append(fake.participantRoleChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 livekit.ParticipantInfo_Kind
+		arg5 livekit.ParticipantInfo_Kind
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.ParticipantRoleChangedStub
+	fake.recordInvocation("ParticipantRoleChanged", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.participantRoleChangedMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantRoleChangedStub(arg1, arg2, arg3, arg4, arg5)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantRoleChangedCallCount() int {
+	fake.participantRoleChangedMutex.RLock()
+	defer fake.participantRoleChangedMutex.RUnlock()
+	return len(fake.participantRoleChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantRoleChangedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.ParticipantInfo_Kind, livekit.ParticipantInfo_Kind)) {
+	fake.participantRoleChangedMutex.Lock()
+	defer fake.participantRoleChangedMutex.Unlock()
+	fake.ParticipantRoleChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantRoleChangedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.ParticipantInfo_Kind, livekit.ParticipantInfo_Kind) {
+	fake.participantRoleChangedMutex.RLock()
+	defer fake.participantRoleChangedMutex.RUnlock()
+	argsForCall := fake.participantRoleChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeTelemetryService) ReplayDeadLetters(arg1 context.Context) (telemetry.DeadLetterReplayResult, error) {
+	fake.replayDeadLettersMutex.Lock()
+	ret, specificReturn := fake.replayDeadLettersReturnsOnCall[len(fake.replayDeadLettersArgsForCall)]
+	fake.replayDeadLettersArgsForCall = append(fake.replayDeadLettersArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.ReplayDeadLettersStub
+	fakeReturns := fake.replayDeadLettersReturns
+	fake.recordInvocation("ReplayDeadLetters", []interface{}{arg1})
+	fake.replayDeadLettersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeTelemetryService) ReplayDeadLettersCallCount() int {
+	fake.replayDeadLettersMutex.RLock()
+	defer fake.replayDeadLettersMutex.RUnlock()
+	return len(fake.replayDeadLettersArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ReplayDeadLettersCalls(stub func(context.Context) (telemetry.DeadLetterReplayResult, error)) {
+	fake.replayDeadLettersMutex.Lock()
+	defer fake.replayDeadLettersMutex.Unlock()
+	fake.ReplayDeadLettersStub = stub
+}
+
+func (fake *FakeTelemetryService) ReplayDeadLettersArgsForCall(i int) context.Context {
+	fake.replayDeadLettersMutex.RLock()
+	defer fake.replayDeadLettersMutex.RUnlock()
+	argsForCall := fake.replayDeadLettersArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeTelemetryService) ReplayDeadLettersReturns(result1 telemetry.DeadLetterReplayResult, result2 error) {
+	fake.replayDeadLettersMutex.Lock()
+	defer fake.replayDeadLettersMutex.Unlock()
+	fake.ReplayDeadLettersStub = nil
+	fake.replayDeadLettersReturns = struct {
+		result1 telemetry.DeadLetterReplayResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeTelemetryService) ReplayDeadLettersReturnsOnCall(i int, result1 telemetry.DeadLetterReplayResult, result2 error) {
+	fake.replayDeadLettersMutex.Lock()
+	defer fake.replayDeadLettersMutex.Unlock()
+	fake.ReplayDeadLettersStub = nil
+	if fake.replayDeadLettersReturnsOnCall == nil {
+		fake.replayDeadLettersReturnsOnCall = make(map[int]struct {
+			result1 telemetry.DeadLetterReplayResult
+			result2 error
+		})
+	}
+	fake.replayDeadLettersReturnsOnCall[i] = struct {
+		result1 telemetry.DeadLetterReplayResult
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeTelemetryService) ParticipantResumed(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 livekit.NodeID, arg5 livekit.ReconnectReason) {
-	fake.participantResumedMutex.Lock()
-	fake.participantResumedArgsForCall = append(fake.participantResumedArgsForCall, struct {
+func (fake *FakeTelemetryService) RoomDeleted(arg1 context.Context, arg2 *livekit.Room, arg3 string) {
+	fake.roomDeletedMutex.Lock()
+	fake.roomDeletedArgsForCall = append(fake.roomDeletedArgsForCall, struct {
 		arg1 context.Context
 		arg2 *livekit.Room
-		arg3 *livekit.ParticipantInfo
-		arg4 livekit.NodeID
-		arg5 livekit.ReconnectReason
-	}{arg1, arg2, arg3, arg4, arg5})
-	stub := fake.ParticipantResumedStub
-	fake.recordInvocation("ParticipantResumed", []interface{}{arg1, arg2, arg3, arg4, arg5})
-	fake.participantResumedMutex.Unlock()
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RoomDeletedStub
+	fake.recordInvocation("RoomDeleted", []interface{}{arg1, arg2, arg3})
+	fake.roomDeletedMutex.Unlock()
 	if stub != nil {
-		fake.ParticipantResumedStub(arg1, arg2, arg3, arg4, arg5)
+		fake.RoomDeletedStub(arg1, arg2, arg3)
 	}
 }
 
-func (fake *FakeTelemetryService) ParticipantResumedCallCount() int {
-	fake.participantResumedMutex.RLock()
-	defer fake.participantResumedMutex.RUnlock()
-	return len(fake.participantResumedArgsForCall)
+func (fake *FakeTelemetryService) RoomDeletedCallCount() int {
+	fake.roomDeletedMutex.RLock()
+	defer fake.roomDeletedMutex.RUnlock()
+	return len(fake.roomDeletedArgsForCall)
 }
 
-func (fake *FakeTelemetryService) ParticipantResumedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.NodeID, livekit.ReconnectReason)) {
-	fake.participantResumedMutex.Lock()
-	defer fake.participantResumedMutex.Unlock()
-	fake.ParticipantResumedStub = stub
+func (fake *FakeTelemetryService) RoomDeletedCalls(stub func(context.Context, *livekit.Room, string)) {
+	fake.roomDeletedMutex.Lock()
+	defer fake.roomDeletedMutex.Unlock()
+	fake.RoomDeletedStub = stub
 }
 
-func (fake *FakeTelemetryService) ParticipantResumedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.NodeID, livekit.ReconnectReason) {
-	fake.participantResumedMutex.RLock()
-	defer fake.participantResumedMutex.RUnlock()
-	argsForCall := fake.participantResumedArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+func (fake *FakeTelemetryService) RoomDeletedArgsForCall(i int) (context.Context, *livekit.Room, string) {
+	fake.roomDeletedMutex.RLock()
+	defer fake.roomDeletedMutex.RUnlock()
+	argsForCall := fake.roomDeletedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeTelemetryService) RoomEnded(arg1 context.Context, arg2 *livekit.Room) {
@@ -788,6 +1674,79 @@ func (fake *FakeTelemetryService) RoomEndedArgsForCall(i int) (context.Context,
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) RoomMetadataChanged(arg1 context.Context, arg2 *livekit.Room, arg3 string) {
+	fake.roomMetadataChangedMutex.Lock()
+	fake.roomMetadataChangedArgsForCall = append(fake.roomMetadataChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RoomMetadataChangedStub
+	fake.recordInvocation("RoomMetadataChanged", []interface{}{arg1, arg2, arg3})
+	fake.roomMetadataChangedMutex.Unlock()
+	if stub != nil {
+		fake.RoomMetadataChangedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) RoomMetadataChangedCallCount() int {
+	fake.roomMetadataChangedMutex.RLock()
+	defer fake.roomMetadataChangedMutex.RUnlock()
+	return len(fake.roomMetadataChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) RoomMetadataChangedCalls(stub func(context.Context, *livekit.Room, string)) {
+	fake.roomMetadataChangedMutex.Lock()
+	defer fake.roomMetadataChangedMutex.Unlock()
+	fake.RoomMetadataChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) RoomMetadataChangedArgsForCall(i int) (context.Context, *livekit.Room, string) {
+	fake.roomMetadataChangedMutex.RLock()
+	defer fake.roomMetadataChangedMutex.RUnlock()
+	argsForCall := fake.roomMetadataChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) RoomReserved(arg1 context.Context, arg2 *livekit.Room) {
+	fake.roomReservedMutex.Lock()
+	fake.roomReservedArgsForCall = append(fake.roomReservedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+	}{arg1, arg2})
+	stub := fake.RoomReservedStub
+	fake.recordInvocation("RoomReserved", []interface{}{arg1, arg2})
+	fake.roomReservedMutex.Unlock()
+	if stub != nil {
+		fake.RoomReservedStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeTelemetryService) RoomReservedCallCount() int {
+	fake.roomDeletedMutex.RLock()
+	defer fake.roomDeletedMutex.RUnlock()
+	fake.roomEndedMutex.RLock()
+	defer fake.roomEndedMutex.RUnlock()
+	fake.roomMetadataChangedMutex.RLock()
+	defer fake.roomMetadataChangedMutex.RUnlock()
+	fake.roomReservedMutex.RLock()
+	defer fake.roomReservedMutex.RUnlock()
+	return len(fake.roomReservedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) RoomReservedCalls(stub func(context.Context, *livekit.Room)) {
+	fake.roomReservedMutex.Lock()
+	defer fake.roomReservedMutex.Unlock()
+	fake.RoomReservedStub = stub
+}
+
+func (fake *FakeTelemetryService) RoomReservedArgsForCall(i int) (context.Context, *livekit.Room) {
+	fake.roomReservedMutex.RLock()
+	defer fake.roomReservedMutex.RUnlock()
+	argsForCall := fake.roomReservedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeTelemetryService) RoomStarted(arg1 context.Context, arg2 *livekit.Room) {
 	fake.roomStartedMutex.Lock()
 	fake.roomStartedArgsForCall = append(fake.roomStartedArgsForCall, struct {
@@ -925,6 +1884,358 @@ func (fake *FakeTelemetryService) SendStatsArgsForCall(i int) (context.Context,
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) Shutdown(arg1 context.Context) error {
+	fake.shutdownMutex.Lock()
+	ret, specificReturn := fake.shutdownReturnsOnCall[len(fake.shutdownArgsForCall)]
+	fake.shutdownArgsForCall = append(fake.shutdownArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.ShutdownStub
+	fakeReturns := fake.shutdownReturns
+	fake.recordInvocation("Shutdown", []interface{}{arg1})
+	fake.shutdownMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeTelemetryService) ShutdownCallCount() int {
+	fake.shutdownMutex.RLock()
+	defer fake.shutdownMutex.RUnlock()
+	return len(fake.shutdownArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ShutdownCalls(stub func(context.Context) error) {
+	fake.shutdownMutex.Lock()
+	defer fake.shutdownMutex.Unlock()
+	fake.ShutdownStub = stub
+}
+
+func (fake *FakeTelemetryService) ShutdownArgsForCall(i int) context.Context {
+	fake.shutdownMutex.RLock()
+	defer fake.shutdownMutex.RUnlock()
+	argsForCall := fake.shutdownArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeTelemetryService) ShutdownReturns(result1 error) {
+	fake.shutdownMutex.Lock()
+	defer fake.shutdownMutex.Unlock()
+	fake.ShutdownStub = nil
+	fake.shutdownReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) ShutdownReturnsOnCall(i int, result1 error) {
+	fake.shutdownMutex.Lock()
+	defer fake.shutdownMutex.Unlock()
+	fake.ShutdownStub = nil
+	if fake.shutdownReturnsOnCall == nil {
+		fake.shutdownReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.shutdownReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) SimulcastLayerChanged(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.TrackID, arg4 livekit.VideoQuality, arg5 string) {
+	fake.simulcastLayerChangedMutex.Lock()
+	fake.simulcastLayerChangedArgsForCall = append(fake.simulcastLayerChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.VideoQuality
+		arg5 string
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.SimulcastLayerChangedStub
+	fake.recordInvocation("SimulcastLayerChanged", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.simulcastLayerChangedMutex.Unlock()
+	if stub != nil {
+		fake.SimulcastLayerChangedStub(arg1, arg2, arg3, arg4, arg5)
+	}
+}
+
+func (fake *FakeTelemetryService) SimulcastLayerChangedCallCount() int {
+	fake.simulcastLayerChangedMutex.RLock()
+	defer fake.simulcastLayerChangedMutex.RUnlock()
+	return len(fake.simulcastLayerChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) SimulcastLayerChangedCalls(stub func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality, string)) {
+	fake.simulcastLayerChangedMutex.Lock()
+	defer fake.simulcastLayerChangedMutex.Unlock()
+	fake.SimulcastLayerChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) SimulcastLayerChangedArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality, string) {
+	fake.simulcastLayerChangedMutex.RLock()
+	defer fake.simulcastLayerChangedMutex.RUnlock()
+	argsForCall := fake.simulcastLayerChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeTelemetryService) Snapshot() telemetry.Snapshot {
+	fake.snapshotMutex.Lock()
+	ret, specificReturn := fake.snapshotReturnsOnCall[len(fake.snapshotArgsForCall)]
+	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct {
+	}{})
+	stub := fake.SnapshotStub
+	fakeReturns := fake.snapshotReturns
+	fake.recordInvocation("Snapshot", []interface{}{})
+	fake.snapshotMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeTelemetryService) SnapshotCallCount() int {
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	return len(fake.snapshotArgsForCall)
+}
+
+func (fake *FakeTelemetryService) SnapshotCalls(stub func() telemetry.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = stub
+}
+
+func (fake *FakeTelemetryService) SnapshotReturns(result1 telemetry.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	fake.snapshotReturns = struct {
+		result1 telemetry.Snapshot
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) SnapshotReturnsOnCall(i int, result1 telemetry.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	if fake.snapshotReturnsOnCall == nil {
+		fake.snapshotReturnsOnCall = make(map[int]struct {
+			result1 telemetry.Snapshot
+		})
+	}
+	fake.snapshotReturnsOnCall[i] = struct {
+		result1 telemetry.Snapshot
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) Subscribe(arg1 telemetry.EventListener) func() {
+	fake.subscribeMutex.Lock()
+	ret, specificReturn := fake.subscribeReturnsOnCall[len(fake.subscribeArgsForCall)]
+	fake.subscribeArgsForCall = append(fake.subscribeArgsForCall, struct {
+		arg1 telemetry.EventListener
+	}{arg1})
+	stub := fake.SubscribeStub
+	fakeReturns := fake.subscribeReturns
+	fake.recordInvocation("Subscribe", []interface{}{arg1})
+	fake.subscribeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeTelemetryService) SubscribeCallCount() int {
+	fake.subscribeMutex.RLock()
+	defer fake.subscribeMutex.RUnlock()
+	return len(fake.subscribeArgsForCall)
+}
+
+func (fake *FakeTelemetryService) SubscribeCalls(stub func(telemetry.EventListener) func()) {
+	fake.subscribeMutex.Lock()
+	defer fake.subscribeMutex.Unlock()
+	fake.SubscribeStub = stub
+}
+
+func (fake *FakeTelemetryService) SubscribeArgsForCall(i int) telemetry.EventListener {
+	fake.subscribeMutex.RLock()
+	defer fake.subscribeMutex.RUnlock()
+	argsForCall := fake.subscribeArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeTelemetryService) SubscribeReturns(result1 func()) {
+	fake.subscribeMutex.Lock()
+	defer fake.subscribeMutex.Unlock()
+	fake.SubscribeStub = nil
+	fake.subscribeReturns = struct {
+		result1 func()
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) SubscribeReturnsOnCall(i int, result1 func()) {
+	fake.subscribeMutex.Lock()
+	defer fake.subscribeMutex.Unlock()
+	fake.SubscribeStub = nil
+	if fake.subscribeReturnsOnCall == nil {
+		fake.subscribeReturnsOnCall = make(map[int]struct {
+			result1 func()
+		})
+	}
+	fake.subscribeReturnsOnCall[i] = struct {
+		result1 func()
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) SubscriptionPermissionChanged(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.SubscriptionPermission) {
+	fake.subscriptionPermissionChangedMutex.Lock()
+	fake.subscriptionPermissionChangedArgsForCall = append(fake.subscriptionPermissionChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 *livekit.SubscriptionPermission
+	}{arg1, arg2, arg3})
+	stub := fake.SubscriptionPermissionChangedStub
+	fake.recordInvocation("SubscriptionPermissionChanged", []interface{}{arg1, arg2, arg3})
+	fake.subscriptionPermissionChangedMutex.Unlock()
+	if stub != nil {
+		fake.SubscriptionPermissionChangedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) SubscriptionPermissionChangedCallCount() int {
+	fake.subscriptionPermissionChangedMutex.RLock()
+	defer fake.subscriptionPermissionChangedMutex.RUnlock()
+	return len(fake.subscriptionPermissionChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) SubscriptionPermissionChangedCalls(stub func(context.Context, livekit.ParticipantID, *livekit.SubscriptionPermission)) {
+	fake.subscriptionPermissionChangedMutex.Lock()
+	defer fake.subscriptionPermissionChangedMutex.Unlock()
+	fake.SubscriptionPermissionChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) SubscriptionPermissionChangedArgsForCall(i int) (context.Context, livekit.ParticipantID, *livekit.SubscriptionPermission) {
+	fake.subscriptionPermissionChangedMutex.RLock()
+	defer fake.subscriptionPermissionChangedMutex.RUnlock()
+	argsForCall := fake.subscriptionPermissionChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) TrackFirstPacket(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.TrackID, arg4 time.Time) {
+	fake.trackFirstPacketMutex.Lock()
+	fake.trackFirstPacketArgsForCall = append(fake.trackFirstPacketArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 time.Time
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.TrackFirstPacketStub
+	fake.recordInvocation("TrackFirstPacket", []interface{}{arg1, arg2, arg3, arg4})
+	fake.trackFirstPacketMutex.Unlock()
+	if stub != nil {
+		fake.TrackFirstPacketStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) TrackFirstPacketCallCount() int {
+	fake.trackFirstPacketMutex.RLock()
+	defer fake.trackFirstPacketMutex.RUnlock()
+	return len(fake.trackFirstPacketArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TrackFirstPacketCalls(stub func(context.Context, livekit.ParticipantID, livekit.TrackID, time.Time)) {
+	fake.trackFirstPacketMutex.Lock()
+	defer fake.trackFirstPacketMutex.Unlock()
+	fake.TrackFirstPacketStub = stub
+}
+
+func (fake *FakeTelemetryService) TrackFirstPacketArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.TrackID, time.Time) {
+	fake.trackFirstPacketMutex.RLock()
+	defer fake.trackFirstPacketMutex.RUnlock()
+	argsForCall := fake.trackFirstPacketArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeTelemetryService) TrackLayerPaused(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.TrackID, arg4 livekit.VideoQuality) {
+	fake.trackLayerPausedMutex.Lock()
+	fake.trackLayerPausedArgsForCall = append(fake.trackLayerPausedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.VideoQuality
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.TrackLayerPausedStub
+	fake.recordInvocation("TrackLayerPaused", []interface{}{arg1, arg2, arg3, arg4})
+	fake.trackLayerPausedMutex.Unlock()
+	if stub != nil {
+		fake.TrackLayerPausedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) TrackLayerPausedCallCount() int {
+	fake.trackFirstPacketMutex.RLock()
+	defer fake.trackFirstPacketMutex.RUnlock()
+	fake.trackLayerPausedMutex.RLock()
+	defer fake.trackLayerPausedMutex.RUnlock()
+	return len(fake.trackLayerPausedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TrackLayerPausedCalls(stub func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality)) {
+	fake.trackLayerPausedMutex.Lock()
+	defer fake.trackLayerPausedMutex.Unlock()
+	fake.TrackLayerPausedStub = stub
+}
+
+func (fake *FakeTelemetryService) TrackLayerPausedArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality) {
+	fake.trackLayerPausedMutex.RLock()
+	defer fake.trackLayerPausedMutex.RUnlock()
+	argsForCall := fake.trackLayerPausedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeTelemetryService) TrackLayerResumed(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.TrackID, arg4 livekit.VideoQuality) {
+	fake.trackLayerResumedMutex.Lock()
+	fake.trackLayerResumedArgsForCall = append(fake.trackLayerResumedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.VideoQuality
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.TrackLayerResumedStub
+	fake.recordInvocation("TrackLayerResumed", []interface{}{arg1, arg2, arg3, arg4})
+	fake.trackLayerResumedMutex.Unlock()
+	if stub != nil {
+		fake.TrackLayerResumedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) TrackLayerResumedCallCount() int {
+	fake.trackLayerResumedMutex.RLock()
+	defer fake.trackLayerResumedMutex.RUnlock()
+	return len(fake.trackLayerResumedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TrackLayerResumedCalls(stub func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality)) {
+	fake.trackLayerResumedMutex.Lock()
+	defer fake.trackLayerResumedMutex.Unlock()
+	fake.TrackLayerResumedStub = stub
+}
+
+func (fake *FakeTelemetryService) TrackLayerResumedArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.TrackID, livekit.VideoQuality) {
+	fake.trackLayerResumedMutex.RLock()
+	defer fake.trackLayerResumedMutex.RUnlock()
+	argsForCall := fake.trackLayerResumedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
 func (fake *FakeTelemetryService) TrackMaxSubscribedVideoQuality(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.TrackInfo, arg4 string, arg5 livekit.VideoQuality) {
 	fake.trackMaxSubscribedVideoQualityMutex.Lock()
 	fake.trackMaxSubscribedVideoQualityArgsForCall = append(fake.trackMaxSubscribedVideoQualityArgsForCall, struct {
@@ -961,18 +2272,19 @@ func (fake *FakeTelemetryService) TrackMaxSubscribedVideoQualityArgsForCall(i in
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
-func (fake *FakeTelemetryService) TrackMuted(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.TrackInfo) {
+func (fake *FakeTelemetryService) TrackMuted(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.TrackInfo, arg4 string) {
 	fake.trackMutedMutex.Lock()
 	fake.trackMutedArgsForCall = append(fake.trackMutedArgsForCall, struct {
 		arg1 context.Context
 		arg2 livekit.ParticipantID
 		arg3 *livekit.TrackInfo
-	}{arg1, arg2, arg3})
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.TrackMutedStub
-	fake.recordInvocation("TrackMuted", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("TrackMuted", []interface{}{arg1, arg2, arg3, arg4})
 	fake.trackMutedMutex.Unlock()
 	if stub != nil {
-		fake.TrackMutedStub(arg1, arg2, arg3)
+		fake.TrackMutedStub(arg1, arg2, arg3, arg4)
 	}
 }
 
@@ -982,17 +2294,52 @@ func (fake *FakeTelemetryService) TrackMutedCallCount() int {
 	return len(fake.trackMutedArgsForCall)
 }
 
-func (fake *FakeTelemetryService) TrackMutedCalls(stub func(context.Context, livekit.ParticipantID, *livekit.TrackInfo)) {
+func (fake *FakeTelemetryService) TrackMutedCalls(stub func(context.Context, livekit.ParticipantID, *livekit.TrackInfo, string)) {
 	fake.trackMutedMutex.Lock()
 	defer fake.trackMutedMutex.Unlock()
 	fake.TrackMutedStub = stub
 }
 
-func (fake *FakeTelemetryService) TrackMutedArgsForCall(i int) (context.Context, livekit.ParticipantID, *livekit.TrackInfo) {
+func (fake *FakeTelemetryService) TrackMutedArgsForCall(i int) (context.Context, livekit.ParticipantID, *livekit.TrackInfo, string) {
 	fake.trackMutedMutex.RLock()
 	defer fake.trackMutedMutex.RUnlock()
 	argsForCall := fake.trackMutedArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeTelemetryService) TrackPublishFailed(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.AddTrackRequest, arg4 string) {
+	fake.trackPublishFailedMutex.Lock()
+	fake.trackPublishFailedArgsForCall = append(fake.trackPublishFailedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 *livekit.AddTrackRequest
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.TrackPublishFailedStub
+	fake.recordInvocation("TrackPublishFailed", []interface{}{arg1, arg2, arg3, arg4})
+	fake.trackPublishFailedMutex.Unlock()
+	if stub != nil {
+		fake.TrackPublishFailedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) TrackPublishFailedCallCount() int {
+	fake.trackPublishFailedMutex.RLock()
+	defer fake.trackPublishFailedMutex.RUnlock()
+	return len(fake.trackPublishFailedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TrackPublishFailedCalls(stub func(context.Context, livekit.ParticipantID, *livekit.AddTrackRequest, string)) {
+	fake.trackPublishFailedMutex.Lock()
+	defer fake.trackPublishFailedMutex.Unlock()
+	fake.TrackPublishFailedStub = stub
+}
+
+func (fake *FakeTelemetryService) TrackPublishFailedArgsForCall(i int) (context.Context, livekit.ParticipantID, *livekit.AddTrackRequest, string) {
+	fake.trackPublishFailedMutex.RLock()
+	defer fake.trackPublishFailedMutex.RUnlock()
+	argsForCall := fake.trackPublishFailedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeTelemetryService) TrackPublishRTPStats(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.TrackID, arg4 string, arg5 int, arg6 *livekit.RTPStats) {
@@ -1311,18 +2658,54 @@ func (fake *FakeTelemetryService) TrackSubscribedArgsForCall(i int) (context.Con
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
-func (fake *FakeTelemetryService) TrackUnmuted(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.TrackInfo) {
+func (fake *FakeTelemetryService) TrackSubscriptionFailed(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.TrackID, arg4 livekit.SubscriptionError) {
+	fake.trackSubscriptionFailedMutex.Lock()
+	fake.trackSubscriptionFailedArgsForCall = append(fake.trackSubscriptionFailedArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.TrackID
+		arg4 livekit.SubscriptionError
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.TrackSubscriptionFailedStub
+	fake.recordInvocation("TrackSubscriptionFailed", []interface{}{arg1, arg2, arg3, arg4})
+	fake.trackSubscriptionFailedMutex.Unlock()
+	if stub != nil {
+		fake.TrackSubscriptionFailedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) TrackSubscriptionFailedCallCount() int {
+	fake.trackSubscriptionFailedMutex.RLock()
+	defer fake.trackSubscriptionFailedMutex.RUnlock()
+	return len(fake.trackSubscriptionFailedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TrackSubscriptionFailedCalls(stub func(context.Context, livekit.ParticipantID, livekit.TrackID, livekit.SubscriptionError)) {
+	fake.trackSubscriptionFailedMutex.Lock()
+	defer fake.trackSubscriptionFailedMutex.Unlock()
+	fake.TrackSubscriptionFailedStub = stub
+}
+
+func (fake *FakeTelemetryService) TrackSubscriptionFailedArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.TrackID, livekit.SubscriptionError) {
+	fake.trackSubscriptionFailedMutex.RLock()
+	defer fake.trackSubscriptionFailedMutex.RUnlock()
+	argsForCall := fake.trackSubscriptionFailedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeTelemetryService) TrackUnmuted(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.TrackInfo, arg4 string) {
 	fake.trackUnmutedMutex.Lock()
 	fake.trackUnmutedArgsForCall = append(fake.trackUnmutedArgsForCall, struct {
 		arg1 context.Context
 		arg2 livekit.ParticipantID
 		arg3 *livekit.TrackInfo
-	}{arg1, arg2, arg3})
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.TrackUnmutedStub
-	fake.recordInvocation("TrackUnmuted", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("TrackUnmuted", []interface{}{arg1, arg2, arg3, arg4})
 	fake.trackUnmutedMutex.Unlock()
 	if stub != nil {
-		fake.TrackUnmutedStub(arg1, arg2, arg3)
+		fake.TrackUnmutedStub(arg1, arg2, arg3, arg4)
 	}
 }
 
@@ -1332,17 +2715,17 @@ func (fake *FakeTelemetryService) TrackUnmutedCallCount() int {
 	return len(fake.trackUnmutedArgsForCall)
 }
 
-func (fake *FakeTelemetryService) TrackUnmutedCalls(stub func(context.Context, livekit.ParticipantID, *livekit.TrackInfo)) {
+func (fake *FakeTelemetryService) TrackUnmutedCalls(stub func(context.Context, livekit.ParticipantID, *livekit.TrackInfo, string)) {
 	fake.trackUnmutedMutex.Lock()
 	defer fake.trackUnmutedMutex.Unlock()
 	fake.TrackUnmutedStub = stub
 }
 
-func (fake *FakeTelemetryService) TrackUnmutedArgsForCall(i int) (context.Context, livekit.ParticipantID, *livekit.TrackInfo) {
+func (fake *FakeTelemetryService) TrackUnmutedArgsForCall(i int) (context.Context, livekit.ParticipantID, *livekit.TrackInfo, string) {
 	fake.trackUnmutedMutex.RLock()
 	defer fake.trackUnmutedMutex.RUnlock()
 	argsForCall := fake.trackUnmutedArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeTelemetryService) TrackUnpublished(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.ParticipantIdentity, arg4 *livekit.TrackInfo, arg5 bool) {
@@ -1416,17 +2799,102 @@ func (fake *FakeTelemetryService) TrackUnsubscribedArgsForCall(i int) (context.C
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
+func (fake *FakeTelemetryService) TranscriptionEnded(arg1 context.Context, arg2 *livekit.Room, arg3 livekit.TrackID, arg4 time.Duration) {
+	fake.transcriptionEndedMutex.Lock()
+	fake.transcriptionEndedArgsForCall = append(fake.transcriptionEndedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 livekit.TrackID
+		arg4 time.Duration
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.TranscriptionEndedStub
+	fake.recordInvocation("TranscriptionEnded", []interface{}{arg1, arg2, arg3, arg4})
+	fake.transcriptionEndedMutex.Unlock()
+	if stub != nil {
+		fake.TranscriptionEndedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) TranscriptionEndedCallCount() int {
+	fake.transcriptionEndedMutex.RLock()
+	defer fake.transcriptionEndedMutex.RUnlock()
+	return len(fake.transcriptionEndedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TranscriptionEndedCalls(stub func(context.Context, *livekit.Room, livekit.TrackID, time.Duration)) {
+	fake.transcriptionEndedMutex.Lock()
+	defer fake.transcriptionEndedMutex.Unlock()
+	fake.TranscriptionEndedStub = stub
+}
+
+func (fake *FakeTelemetryService) TranscriptionEndedArgsForCall(i int) (context.Context, *livekit.Room, livekit.TrackID, time.Duration) {
+	fake.transcriptionEndedMutex.RLock()
+	defer fake.transcriptionEndedMutex.RUnlock()
+	argsForCall := fake.transcriptionEndedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeTelemetryService) TranscriptionStarted(arg1 context.Context, arg2 *livekit.Room, arg3 livekit.TrackID) {
+	fake.transcriptionStartedMutex.Lock()
+	fake.transcriptionStartedArgsForCall = append(fake.transcriptionStartedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 livekit.TrackID
+	}{arg1, arg2, arg3})
+	stub := fake.TranscriptionStartedStub
+	fake.recordInvocation("TranscriptionStarted", []interface{}{arg1, arg2, arg3})
+	fake.transcriptionStartedMutex.Unlock()
+	if stub != nil {
+		fake.TranscriptionStartedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) TranscriptionStartedCallCount() int {
+	fake.transcriptionStartedMutex.RLock()
+	defer fake.transcriptionStartedMutex.RUnlock()
+	return len(fake.transcriptionStartedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TranscriptionStartedCalls(stub func(context.Context, *livekit.Room, livekit.TrackID)) {
+	fake.transcriptionStartedMutex.Lock()
+	defer fake.transcriptionStartedMutex.Unlock()
+	fake.TranscriptionStartedStub = stub
+}
+
+func (fake *FakeTelemetryService) TranscriptionStartedArgsForCall(i int) (context.Context, *livekit.Room, livekit.TrackID) {
+	fake.transcriptionStartedMutex.RLock()
+	defer fake.transcriptionStartedMutex.RUnlock()
+	argsForCall := fake.transcriptionStartedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
 func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.activeSpeakerChangedMutex.RLock()
+	defer fake.activeSpeakerChangedMutex.RUnlock()
+	fake.bandwidthEstimateMutex.RLock()
+	defer fake.bandwidthEstimateMutex.RUnlock()
+	fake.connectionQualityChangedMutex.RLock()
+	defer fake.connectionQualityChangedMutex.RUnlock()
+	fake.dataPacketForwardedMutex.RLock()
+	defer fake.dataPacketForwardedMutex.RUnlock()
 	fake.egressEndedMutex.RLock()
 	defer fake.egressEndedMutex.RUnlock()
+	fake.egressFailedMutex.RLock()
+	defer fake.egressFailedMutex.RUnlock()
 	fake.egressStartedMutex.RLock()
 	defer fake.egressStartedMutex.RUnlock()
 	fake.egressUpdatedMutex.RLock()
 	defer fake.egressUpdatedMutex.RUnlock()
+	fake.flushAnalyticsMutex.RLock()
+	defer fake.flushAnalyticsMutex.RUnlock()
+	fake.flushEventsMutex.RLock()
+	defer fake.flushEventsMutex.RUnlock()
 	fake.flushStatsMutex.RLock()
 	defer fake.flushStatsMutex.RUnlock()
+	fake.getParticipantStatsMutex.RLock()
+	defer fake.getParticipantStatsMutex.RUnlock()
 	fake.ingressCreatedMutex.RLock()
 	defer fake.ingressCreatedMutex.RUnlock()
 	fake.ingressDeletedMutex.RLock()
@@ -1443,14 +2911,26 @@ func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	defer fake.notifyEventMutex.RUnlock()
 	fake.participantActiveMutex.RLock()
 	defer fake.participantActiveMutex.RUnlock()
+	fake.participantAttributesChangedMutex.RLock()
+	defer fake.participantAttributesChangedMutex.RUnlock()
+	fake.participantAudioLevelMutex.RLock()
+	defer fake.participantAudioLevelMutex.RUnlock()
+	fake.participantConnectedMutex.RLock()
+	defer fake.participantConnectedMutex.RUnlock()
 	fake.participantJoinedMutex.RLock()
 	defer fake.participantJoinedMutex.RUnlock()
 	fake.participantLeftMutex.RLock()
 	defer fake.participantLeftMutex.RUnlock()
+	fake.participantPermissionsChangedMutex.RLock()
+	defer fake.participantPermissionsChangedMutex.RUnlock()
 	fake.participantResumedMutex.RLock()
 	defer fake.participantResumedMutex.RUnlock()
-	fake.roomEndedMutex.RLock()
-	defer fake.roomEndedMutex.RUnlock()
+	fake.participantRoleChangedMutex.RLock()
+	defer fake.participantRoleChangedMutex.RUnlock()
+	fake.replayDeadLettersMutex.RLock()
+	defer fake.replayDeadLettersMutex.RUnlock()
+	fake.roomReservedMutex.RLock()
+	defer fake.roomReservedMutex.RUnlock()
 	fake.roomStartedMutex.RLock()
 	defer fake.roomStartedMutex.RUnlock()
 	fake.sendEventMutex.RLock()
@@ -1459,10 +2939,26 @@ func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	defer fake.sendNodeRoomStatesMutex.RUnlock()
 	fake.sendStatsMutex.RLock()
 	defer fake.sendStatsMutex.RUnlock()
+	fake.shutdownMutex.RLock()
+	defer fake.shutdownMutex.RUnlock()
+	fake.simulcastLayerChangedMutex.RLock()
+	defer fake.simulcastLayerChangedMutex.RUnlock()
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	fake.subscribeMutex.RLock()
+	defer fake.subscribeMutex.RUnlock()
+	fake.subscriptionPermissionChangedMutex.RLock()
+	defer fake.subscriptionPermissionChangedMutex.RUnlock()
+	fake.trackLayerPausedMutex.RLock()
+	defer fake.trackLayerPausedMutex.RUnlock()
+	fake.trackLayerResumedMutex.RLock()
+	defer fake.trackLayerResumedMutex.RUnlock()
 	fake.trackMaxSubscribedVideoQualityMutex.RLock()
 	defer fake.trackMaxSubscribedVideoQualityMutex.RUnlock()
 	fake.trackMutedMutex.RLock()
 	defer fake.trackMutedMutex.RUnlock()
+	fake.trackPublishFailedMutex.RLock()
+	defer fake.trackPublishFailedMutex.RUnlock()
 	fake.trackPublishRTPStatsMutex.RLock()
 	defer fake.trackPublishRTPStatsMutex.RUnlock()
 	fake.trackPublishRequestedMutex.RLock()
@@ -1481,12 +2977,18 @@ func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	defer fake.trackSubscribeRequestedMutex.RUnlock()
 	fake.trackSubscribedMutex.RLock()
 	defer fake.trackSubscribedMutex.RUnlock()
+	fake.trackSubscriptionFailedMutex.RLock()
+	defer fake.trackSubscriptionFailedMutex.RUnlock()
 	fake.trackUnmutedMutex.RLock()
 	defer fake.trackUnmutedMutex.RUnlock()
 	fake.trackUnpublishedMutex.RLock()
 	defer fake.trackUnpublishedMutex.RUnlock()
 	fake.trackUnsubscribedMutex.RLock()
 	defer fake.trackUnsubscribedMutex.RUnlock()
+	fake.transcriptionEndedMutex.RLock()
+	defer fake.transcriptionEndedMutex.RUnlock()
+	fake.transcriptionStartedMutex.RLock()
+	defer fake.transcriptionStartedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
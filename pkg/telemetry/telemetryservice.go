@@ -16,38 +16,73 @@ package telemetry
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
+
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
-	"github.com/livekit/protocol/webhook"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . TelemetryService
 type TelemetryService interface {
-	// TrackStats is called periodically for each track in both directions (published/subscribed)
+	// TrackStats is called periodically for each track in both directions (published/subscribed). the stats of a
+	// track are sent for each codec of its media, with Mime set to the codec's mime type or unknown
 	TrackStats(key StatsKey, stat *livekit.AnalyticsStat)
 
 	// events
+	// RoomReserved - the room was created through the API, ahead of anyone joining it
+	RoomReserved(ctx context.Context, room *livekit.Room)
+	// RoomStarted - the first participant joined the room, whether or not it was created ahead of time
 	RoomStarted(ctx context.Context, room *livekit.Room)
 	RoomEnded(ctx context.Context, room *livekit.Room)
+	// RoomDeleted - the room was deleted through the API by deletedBy, the identity of the caller's token when known.
+	// sent in addition to RoomEnded, participants still in the room are considered gone
+	RoomDeleted(ctx context.Context, room *livekit.Room, deletedBy string)
 	// ParticipantJoined - a participant establishes signal connection to a room
 	ParticipantJoined(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, clientInfo *livekit.ClientInfo, clientMeta *livekit.AnalyticsClientMeta, shouldSendEvent bool)
 	// ParticipantActive - a participant establishes media connection
 	ParticipantActive(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, clientMeta *livekit.AnalyticsClientMeta, isMigration bool)
+	// ParticipantConnected - the participant's peer connection is established, the time since ParticipantJoined is
+	// recorded. nothing is recorded for participants that did not join on this node
+	ParticipantConnected(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo)
 	// ParticipantResumed - there has been an ICE restart or connection resume attempt, and we've received their signal connection
 	ParticipantResumed(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, nodeID livekit.NodeID, reason livekit.ReconnectReason)
-	// ParticipantLeft - the participant leaves the room, only sent if ParticipantActive has been called before
-	ParticipantLeft(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, shouldSendEvent bool)
+	// ParticipantDuplicateIdentity - participant is joining with the identity of evicted, which is already in the
+	// room and is removed to make way for it. sent before either has left or joined
+	ParticipantDuplicateIdentity(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, evicted *livekit.ParticipantInfo)
+	// ParticipantAttributesChanged - the participant's name or metadata has changed from prev, nothing is sent
+	// otherwise. the names of the changed fields are delivered along with the webhook, see WebHookConfig.EventDetails
+	ParticipantAttributesChanged(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, prev *livekit.ParticipantInfo)
+	// ParticipantPermissionsChanged - the participant's permissions have changed from prev, nothing is sent otherwise.
+	// prev and the names of the changed fields are delivered along with the webhook, see WebHookConfig.EventDetails
+	ParticipantPermissionsChanged(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, prev *livekit.ParticipantPermission)
+	// ParticipantRoleChanged - the participant's kind has changed from one to another during its session, e.g. an
+	// agent promoted to a standard participant, nothing is sent otherwise. the session's stats carry on
+	ParticipantRoleChanged(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, from livekit.ParticipantInfo_Kind, to livekit.ParticipantInfo_Kind)
+	// ParticipantLeft - the participant leaves the room, only sent if ParticipantActive has been called before.
+	// reason is UNKNOWN_REASON when it isn't known, see WebhookDetails.DisconnectReason
+	ParticipantLeft(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, reason livekit.DisconnectReason, shouldSendEvent bool)
 	// TrackPublishRequested - a publication attempt has been received
 	TrackPublishRequested(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo)
+	// TrackPublishFailed - a publication attempt has been rejected, reason is one of the TrackPublishFailReason values
+	TrackPublishFailed(ctx context.Context, participantID livekit.ParticipantID, req *livekit.AddTrackRequest, reason string)
 	// TrackPublished - a publication attempt has been successful
 	TrackPublished(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo)
-	// TrackUnpublished - a participant unpublished a track
+	// TrackFirstPacket - the first packet of a published track was received at firstPacketAt, the time from the
+	// publish is recorded
+	TrackFirstPacket(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, firstPacketAt time.Time)
+	// TrackUnpublished - a participant unpublished a track. with a track churn window, the events are held back
+	// for the window and neither they nor those of the next TrackPublished are sent if the track is republished in it
 	TrackUnpublished(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo, shouldSendEvent bool)
-	// TrackSubscribeRequested - a participant requested to subscribe to a track
+	// TrackSubscribeRequested - a participant requested to subscribe to a track, the time until TrackSubscribed
+	// is recorded
 	TrackSubscribeRequested(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo)
 	// TrackSubscribed - a participant subscribed to a track successfully
 	TrackSubscribed(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo, publisher *livekit.ParticipantInfo, shouldSendEvent bool)
@@ -55,54 +90,580 @@ type TelemetryService interface {
 	TrackUnsubscribed(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo, shouldSendEvent bool)
 	// TrackSubscribeFailed - failure to subscribe to a track
 	TrackSubscribeFailed(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, err error, isUserError bool)
-	// TrackMuted - the publisher has muted the Track
-	TrackMuted(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo)
-	// TrackUnmuted - the publisher has muted the Track
-	TrackUnmuted(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo)
+	// TrackSubscriptionFailed - the subscriber has been told it could not subscribe to a track
+	TrackSubscriptionFailed(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, reason livekit.SubscriptionError)
+	// SubscriptionPermissionChanged - the participant has changed who may subscribe to its tracks, the analytics
+	// event carries the full new set of permissions
+	SubscriptionPermissionChanged(ctx context.Context, participantID livekit.ParticipantID, permissions *livekit.SubscriptionPermission)
+	// TrackMuted - the Track has been muted, source is one of the TrackMuteSource values. the source labels
+	// livekit_track_mute_counter and is sent with the webhook, see WebhookDetails.MuteSource. the analytics event
+	// has no field for it
+	TrackMuted(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo, source string)
+	// TrackUnmuted - the Track has been unmuted, source is one of the TrackMuteSource values
+	TrackUnmuted(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo, source string)
 	// TrackPublishedUpdate - track metadata has been updated
 	TrackPublishedUpdate(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo)
 	// TrackMaxSubscribedVideoQuality - publisher is notified of the max quality subscribers desire
 	TrackMaxSubscribedVideoQuality(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo, mime string, maxQuality livekit.VideoQuality)
+	// SimulcastLayerChanged - the simulcast layer forwarded to a subscriber has changed, reason is one of the
+	// SimulcastLayerChangeReason values or empty when not known. only a sample of these is logged
+	SimulcastLayerChanged(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, layer livekit.VideoQuality, reason string)
+	// TrackLayerPaused - a layer of a track the participant subscribes to is no longer forwarded because of the
+	// bandwidth available to the participant, TrackLayerResumed once it is forwarded again
+	TrackLayerPaused(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, layer livekit.VideoQuality)
+	TrackLayerResumed(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, layer livekit.VideoQuality)
+	// ParticipantAudioLevel - the current audio level of a participant publishing a microphone, and whether it is
+	// speaking. called a few times a second, summaries are sent every AnalyticsConfig.AudioLevelInterval
+	ParticipantAudioLevel(participantID livekit.ParticipantID, level float64, active bool)
+	// DataPacketForwarded - a data packet of bytes has been sent to the participant. packets are counted by kind and
+	// recorded every stats interval
+	DataPacketForwarded(ctx context.Context, participantID livekit.ParticipantID, kind livekit.DataPacket_Kind, bytes int)
+	// BandwidthEstimate - the estimate of the participant's available downlink, in bps, has changed. called every
+	// time congestion control commits an estimate, the latest one is kept until the participant leaves
+	BandwidthEstimate(ctx context.Context, participantID livekit.ParticipantID, bps int64)
+	// ConnectionQualityChanged - the participant's connection quality, as computed from its stats, has changed
+	ConnectionQualityChanged(ctx context.Context, participantID livekit.ParticipantID, quality livekit.ConnectionQuality)
 	TrackPublishRTPStats(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, mimeType string, layer int, stats *livekit.RTPStats)
 	TrackSubscribeRTPStats(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, mimeType string, stats *livekit.RTPStats)
 	EgressStarted(ctx context.Context, info *livekit.EgressInfo)
 	EgressUpdated(ctx context.Context, info *livekit.EgressInfo)
+	// EgressEnded - egress has finished without failing
 	EgressEnded(ctx context.Context, info *livekit.EgressInfo)
+	// EgressFailed - egress has ended with an error, reported instead of EgressEnded
+	EgressFailed(ctx context.Context, info *livekit.EgressInfo)
+	// TranscriptionStarted - a speech-to-text session has started on a track
+	TranscriptionStarted(ctx context.Context, room *livekit.Room, trackID livekit.TrackID)
+	// TranscriptionEnded - a speech-to-text session on a track has ended after running for duration
+	TranscriptionEnded(ctx context.Context, room *livekit.Room, trackID livekit.TrackID, duration time.Duration)
 	IngressCreated(ctx context.Context, info *livekit.IngressInfo)
 	IngressDeleted(ctx context.Context, info *livekit.IngressInfo)
 	IngressStarted(ctx context.Context, info *livekit.IngressInfo)
 	IngressUpdated(ctx context.Context, info *livekit.IngressInfo)
-	IngressEnded(ctx context.Context, info *livekit.IngressInfo)
+	// IngressEnded - ingress stopped, err is set when it ended because of a failure
+	IngressEnded(ctx context.Context, info *livekit.IngressInfo, err error)
 	LocalRoomState(ctx context.Context, info *livekit.AnalyticsNodeRooms)
+	// RoomMetadataChanged - room metadata has been updated. room carries the new metadata, nothing is sent
+	// when it matches prevMetadata, which is delivered along with the webhook, see WebHookConfig.EventDetails
+	RoomMetadataChanged(ctx context.Context, room *livekit.Room, prevMetadata string)
+	// ActiveSpeakerChanged - the ordered list of active speakers in a room has changed
+	ActiveSpeakerChanged(ctx context.Context, room *livekit.Room, speakers []*livekit.SpeakerInfo)
 
 	// helpers
 	AnalyticsService
 	NotifyEvent(ctx context.Context, event *livekit.WebhookEvent)
+	// Subscribe calls listener with every event sent to webhooks, after filtering and deduplication, until the
+	// returned function is called. listeners are called one event at a time on a worker of their own, a slow
+	// listener delays other listeners but not webhook delivery. panics are recovered and logged
+	Subscribe(listener EventListener) (unsubscribe func())
+	// ReplayDeadLetters delivers the webhook events kept by a ReplayableDeadLetterSink again, removing those that
+	// are delivered, and returns how many were delivered and how many failed again
+	ReplayDeadLetters(ctx context.Context) (DeadLetterReplayResult, error)
+	// GetParticipantStats returns the latest stats sampled for a participant in a room on this node,
+	// false when there is no such participant
+	GetParticipantStats(participantID livekit.ParticipantID) (*ParticipantStats, bool)
+	// Snapshot returns the rooms with participants on this node, with participant and published track counts
+	Snapshot() Snapshot
 	FlushStats()
+	// FlushEvents sends buffered analytics events without waiting for them to be sent
+	FlushEvents()
+	// FlushAnalytics sends buffered analytics events and waits until they, and the analytics queued before them,
+	// have been handed to the analytics sink, returning the ctx error when ctx is done first
+	FlushAnalytics(ctx context.Context) error
+	// Shutdown stops sending webhooks for new events and waits, until ctx is done, for queued deliveries to finish.
+	// stats workers are closed and buffered and queued analytics are sent even when ctx is done first
+	Shutdown(ctx context.Context) error
 }
 
 const (
 	workerCleanupWait  = 3 * time.Minute
 	jobQueueBufferSize = 10000
+	// per endpoint
+	defaultWebhookWorkers   = 10
+	defaultWebhookQueueSize = 1000
+
+	webhookMaxRetryDelay          = time.Minute
+	defaultWebhookDeliveryTimeout = 10 * time.Second
+	defaultAnalyticsBatchInterval = time.Second
+	defaultAnalyticsRetryInterval = 5 * time.Second
+
+	// subscriptions not made within this long of being requested are not counted in the subscribe latency
+	subscribeLatencyTimeout = time.Minute
+	// how long the session durations of a room are kept after it ends, for the sessions that ended with it to be
+	// scraped
+	participantSessionRetention = time.Minute
 )
 
+type pendingSessionDelete struct {
+	timer Timer
+}
+
 type telemetryService struct {
 	AnalyticsService
 
-	notifier webhook.QueuedNotifier
-	jobsChan chan func()
+	webhookEndpoints []*webhookEndpoint
+	eventListeners   *eventListeners
+	webhookLock      sync.RWMutex
+	webhookClosed    bool
+	webhookPending   atomic.Int32
+	deadLetterSink   DeadLetterSink
+	analyticsSink    AnalyticsSink
+	tracer           trace.Tracer
+	jobsChan         chan func()
+
+	// deliveries and their retries, and events held back past the call that raised them, run on this context rather
+	// than the caller's, cancelled when Shutdown abandons them
+	webhookCtx    context.Context
+	webhookCancel context.CancelFunc
+
+	// held while ReplayDeadLetters runs
+	deadLetterReplayLock     sync.Mutex
+	deadLetterReplayInterval time.Duration
+
+	// region of this node, set on participant analytics events
+	region string
+	// set on every analytics event, see WithNodeID
+	nodeID livekit.NodeID
+
+	clock Clock
+
+	workerIdleTimeout time.Duration
+	statsInterval     time.Duration
+	roomStatsInterval time.Duration
+	roomStatsAt       time.Time
+	// workers of participants still in a room this long after it ended are closed, 0 to close them right away
+	roomEndDrainTimeout time.Duration
+
+	webhookMaxRetries     int
+	webhookRetryBaseDelay time.Duration
+	webhookTimeout        time.Duration
+	// per endpoint, see WebHookConfig.Workers and QueueSize
+	webhookWorkers       int
+	webhookQueueSize     int
+	webhookOrderByRoom   bool
+	webhookIncludeEvents map[string]struct{}
+	webhookExcludeEvents map[string]struct{}
+	webhookDedup         *webhookDedup
+	trackChurnWindow     time.Duration
+	notifierMiddlewares  []NotifierMiddleware
+	webhookTransport     http.RoundTripper
+	webhookRouter        WebhookRouter
+	webhookRedactions    []*webhookRedaction
+	// per endpoint circuit breakers, see WebHookConfig.BreakerFailures
+	webhookBreakerFailures int
+	webhookBreakerCooldown time.Duration
+	webhookBreakerPolicy   string
+	// every delivery attempt is recorded with auditLogger through webhookAuditor when set, see WithAuditLogger
+	auditLogger    AuditLogger
+	webhookAuditor *webhookAuditor
+
+	// media not flowing for this many stats intervals is reported as a stall, 0 to not detect stalls
+	trackStallIntervals int
+	trackStallWebhook   bool
+
+	// participant_joined events are delivered along with the client the participant joined from, see WebhookEnvelope
+	webhookClientInfo bool
+	// see WebHookConfig.EventDetails
+	webhookEventDetails bool
+	// published tracks that receive no media for this long are reported as never active, 0 to not report them
+	trackFirstPacketTimeout time.Duration
+
+	activeSpeakerDebounce time.Duration
+	activeSpeakerWebhook  bool
+	speakerLock           sync.Mutex
+	activeSpeakers        map[livekit.RoomID]*activeSpeakers
+
+	eventBatcher   AnalyticsBatchService
+	eventBatchSize int
+	eventInterval  time.Duration
+	eventLock      sync.Mutex
+	eventFlushLock sync.Mutex
+	pendingEvents  []*livekit.AnalyticsEvent
+	analyticsQueue *analyticsQueue
+	fileSink       *FileEventSink
+	eventStream    *EventStreamSink
+	// applied to events before they are published to eventStream
+	eventStreamRedactions []*webhookRedaction
+
+	// failed analytics events are kept here until they can be sent again
+	analyticsBuffer        *AnalyticsBuffer
+	analyticsRetrier       AnalyticsRetryService
+	analyticsRetryInterval time.Duration
+	analyticsRetryLock     sync.Mutex
+	analyticsRetryAt       time.Time
+
+	// see WithAnalyticsSinks
+	analyticsFailoverThreshold     int
+	analyticsFailoverRetryInterval time.Duration
+
+	// fraction of simulcast layer changes logged
+	simulcastLayerSampleRate float64
+
+	// data packets forwarded since the last stats interval, by kind
+	reliableDataPackets dataPacketCounts
+	lossyDataPackets    dataPacketCounts
+
+	// how often audio level summaries are recorded, 0 when disabled, and the fraction of them logged
+	audioLevelInterval   time.Duration
+	audioLevelSampleRate float64
+
+	// weights of the quality scores of subscribed tracks
+	qosWeights config.QoSScoreConfig
+
+	// weight of the latest interval in the smoothed bitrates of tracks
+	bitrateSmoothingAlpha float64
 
-	lock    sync.RWMutex
-	workers map[livekit.ParticipantID]*StatsWorker
+	// how rooms are labelled in metrics labelled by room
+	roomLabels roomLabeler
+
+	// room labels with bitrate series, per-room series are dropped above roomBitrateMaxRooms labels, 0 for no
+	// limit. only used on the run goroutine
+	roomBitrateMaxRooms int
+	roomBitrateRooms    map[string]struct{}
+
+	// tracks with a transcription session in progress. only used on the run goroutine
+	transcriptions map[livekit.TrackID]struct{}
+
+	// session durations of rooms that ended waiting to be deleted, by room label
+	sessionDeleteLock sync.Mutex
+	sessionDeletes    map[string]*pendingSessionDelete
+
+	// joins and leaves of each room, nil when not reporting rooms exceeding a rate of them
+	joinLeaveRates *joinLeaveRates
+
+	// optional fields of larger analytics events are dropped, 0 for no limit
+	maxEventSize int
+	// run on every analytics event, in order
+	eventEnrichers []EventEnricher
+
+	// fraction of analytics events of each type and of webhook events of each event sent
+	analyticsSampleRates eventSampleRates
+	webhookSampleRates   eventSampleRates
+
+	workers [workerShardCount]workerShard
+}
+
+type TelemetryServiceOpts func(t *telemetryService)
+
+// WithAnalyticsSink sends analytics events and stats to sink instead of the AnalyticsService.
+// node room states are still sent through the AnalyticsService
+func WithAnalyticsSink(sink AnalyticsSink) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.analyticsSink = sink
+	}
+}
+
+// WithAnalyticsSinks sends analytics events and stats to the first of sinks, failing over to the next one once
+// sends keep failing, see AnalyticsConfig.FailoverThreshold. the primary is tried again every
+// AnalyticsConfig.FailoverRetryInterval. node room states are still sent through the AnalyticsService
+func WithAnalyticsSinks(sinks ...AnalyticsSink) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		switch len(sinks) {
+		case 0:
+		case 1:
+			t.analyticsSink = sinks[0]
+		default:
+			t.analyticsSink = newAnalyticsFailover(sinks, t.analyticsFailoverThreshold, t.analyticsFailoverRetryInterval)
+		}
+	}
+}
+
+// WithClock sets the clock the telemetry service goes by, the real one by default
+func WithClock(clock Clock) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.clock = clock
+	}
+}
+
+// WithNodeID sets the node of analytics events that don't have one to nodeID, so the node that sent an event
+// can be told apart from the others in a cluster. node ids are generated when the server starts, so they also
+// tell apart instances of the same node
+func WithNodeID(nodeID livekit.NodeID) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.nodeID = nodeID
+	}
 }
 
-func NewTelemetryService(notifier webhook.QueuedNotifier, analytics AnalyticsService) TelemetryService {
+// WithTracerProvider creates spans for webhook deliveries and analytics events from provider
+// instead of the global otel provider, which does nothing unless one has been installed
+func WithTracerProvider(provider trace.TracerProvider) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// WithDeadLetterSink hands webhook events that exhausted their retries to sink instead of dropping them
+func WithDeadLetterSink(sink DeadLetterSink) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.deadLetterSink = sink
+	}
+}
+
+// WithNotifierMiddleware wraps every webhook notifier in middlewares, applied to each delivery attempt in order
+func WithNotifierMiddleware(middlewares ...NotifierMiddleware) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.notifierMiddlewares = append(t.notifierMiddlewares, middlewares...)
+	}
+}
+
+// WithWebhookTransport sends the requests of URL notifiers over transport, to go through a proxy, pool connections
+// or time out dials differently. notifiers created with a transport of their own keep it
+func WithWebhookTransport(transport http.RoundTripper) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.webhookTransport = transport
+	}
+}
+
+// WithAuditLogger records every webhook delivery attempt, retries included, with auditLogger once the attempt
+// has finished. records are logged on a goroutine of their own and dropped, rather than holding up deliveries,
+// while auditLogger can't keep up
+func WithAuditLogger(auditLogger AuditLogger) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.auditLogger = auditLogger
+	}
+}
+
+// WithWebhookRouter delivers each webhook event only to the notifier router picks for its room,
+// instead of to every notifier. filtering and deduplication apply before routing
+func WithWebhookRouter(router WebhookRouter) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.webhookRouter = router
+	}
+}
+
+// WithFileEventSink also writes webhook events, after filtering and deduplication, and analytics events to sink.
+// the sink is closed on Shutdown
+func WithFileEventSink(sink *FileEventSink) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.fileSink = sink
+	}
+}
+
+// WithEventStreamSink also publishes webhook events, after filtering, deduplication and redaction, to the clients of sink.
+// its clients are disconnected on Shutdown
+func WithEventStreamSink(sink *EventStreamSink) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.eventStream = sink
+	}
+}
+
+// WithEventEnrichers runs enrichers on every analytics event before it is sent, after any added before
+func WithEventEnrichers(enrichers ...EventEnricher) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.eventEnrichers = append(t.eventEnrichers, enrichers...)
+	}
+}
+
+// WithAnalyticsBuffer keeps analytics events that fail to send in buffer, sending them again in order once the
+// analytics sink is back. only used when the sink implements AnalyticsRetryService. the buffer is closed on Shutdown
+func WithAnalyticsBuffer(buffer *AnalyticsBuffer) TelemetryServiceOpts {
+	return func(t *telemetryService) {
+		t.analyticsBuffer = buffer
+	}
+}
+
+func NewTelemetryService(
+	conf *config.Config,
+	notifiers []WebhookNotifier,
+	analytics AnalyticsService,
+	opts ...TelemetryServiceOpts,
+) TelemetryService {
 	t := &telemetryService{
 		AnalyticsService: analytics,
 
-		notifier: notifier,
-		jobsChan: make(chan func(), jobQueueBufferSize),
-		workers:  make(map[livekit.ParticipantID]*StatsWorker),
+		eventListeners: newEventListeners(),
+		deadLetterSink: noopDeadLetterSink{},
+		analyticsSink:  analytics,
+		tracer:         otel.Tracer(tracerName),
+		jobsChan:       make(chan func(), jobQueueBufferSize),
+
+		region: conf.Region,
+
+		workerIdleTimeout: conf.Analytics.WorkerIdleTimeout,
+		statsInterval:     conf.Analytics.StatsInterval,
+		roomStatsInterval: conf.Analytics.RoomStatsInterval,
+
+		roomEndDrainTimeout: conf.Analytics.RoomEndDrainTimeout,
+
+		webhookMaxRetries:     conf.WebHook.MaxRetries,
+		webhookRetryBaseDelay: conf.WebHook.RetryBaseDelay,
+		webhookTimeout:        conf.WebHook.DeliveryTimeout,
+		webhookWorkers:        conf.WebHook.Workers,
+		webhookQueueSize:      conf.WebHook.QueueSize,
+		webhookOrderByRoom:    conf.WebHook.OrderByRoom,
+		webhookIncludeEvents:  toEventSet(conf.WebHook.IncludeEvents),
+		webhookExcludeEvents:  toEventSet(conf.WebHook.ExcludeEvents),
+		trackChurnWindow:      conf.WebHook.TrackChurnWindow,
+		webhookRedactions:     newWebhookRedactions(conf.WebHook.Redactions),
+
+		webhookBreakerFailures: conf.WebHook.BreakerFailures,
+		webhookBreakerCooldown: conf.WebHook.BreakerCooldown,
+		webhookBreakerPolicy:   conf.WebHook.BreakerPolicy,
+
+		trackStallIntervals: conf.Analytics.TrackStallIntervals,
+		trackStallWebhook:   conf.WebHook.TrackStallEvents,
+
+		webhookClientInfo:   conf.WebHook.ParticipantClientInfo,
+		webhookEventDetails: conf.WebHook.EventDetails,
+
+		trackFirstPacketTimeout: conf.Analytics.TrackFirstPacketTimeout,
+
+		activeSpeakerDebounce: conf.Audio.ActiveSpeakerDebounce,
+		activeSpeakerWebhook:  conf.WebHook.ActiveSpeakerEvents,
+		activeSpeakers:        make(map[livekit.RoomID]*activeSpeakers),
+
+		eventBatchSize: conf.Analytics.BatchSize,
+		eventInterval:  conf.Analytics.BatchInterval,
+
+		simulcastLayerSampleRate: conf.Analytics.SimulcastLayerSampleRate,
+
+		analyticsRetryInterval: conf.Analytics.BufferRetryInterval,
+
+		analyticsFailoverThreshold:     conf.Analytics.FailoverThreshold,
+		analyticsFailoverRetryInterval: conf.Analytics.FailoverRetryInterval,
+
+		audioLevelInterval:   conf.Analytics.AudioLevelInterval,
+		audioLevelSampleRate: conf.Analytics.AudioLevelSampleRate,
+
+		qosWeights:            conf.Analytics.QoSScore,
+		bitrateSmoothingAlpha: conf.Analytics.BitrateSmoothingAlpha,
+
+		roomLabels: newRoomLabeler(conf.Analytics.RoomLabel, conf.Analytics.RoomLabelBuckets, conf.Analytics.RoomLabelMaxRooms),
+
+		roomBitrateMaxRooms: conf.Analytics.RoomBitrateMaxRooms,
+		roomBitrateRooms:    make(map[string]struct{}),
+		sessionDeletes:      make(map[string]*pendingSessionDelete),
+		transcriptions:      make(map[livekit.TrackID]struct{}),
+
+		maxEventSize: conf.Analytics.MaxEventSize,
+
+		analyticsSampleRates: newEventSampleRates("analytics", conf.Analytics.EventSampleRates),
+		webhookSampleRates:   newEventSampleRates("webhook", conf.WebHook.EventSampleRates),
+	}
+	if t.webhookTimeout <= 0 {
+		t.webhookTimeout = defaultWebhookDeliveryTimeout
+	}
+	if replayRate := conf.WebHook.DeadLetterReplayRate; replayRate > 0 {
+		t.deadLetterReplayInterval = time.Duration(float64(time.Second) / replayRate)
+	} else {
+		logger.Warnw("invalid webhook dead letter replay rate, using default", nil,
+			"deadLetterReplayRate", replayRate,
+			"default", defaultDeadLetterReplayRate,
+		)
+		t.deadLetterReplayInterval = time.Second / defaultDeadLetterReplayRate
+	}
+	if t.webhookWorkers <= 0 {
+		logger.Warnw("invalid webhook workers, using default", nil,
+			"workers", t.webhookWorkers,
+			"default", defaultWebhookWorkers,
+		)
+		t.webhookWorkers = defaultWebhookWorkers
+	}
+	if t.webhookQueueSize <= 0 {
+		logger.Warnw("invalid webhook queue size, using default", nil,
+			"queueSize", t.webhookQueueSize,
+			"default", defaultWebhookQueueSize,
+		)
+		t.webhookQueueSize = defaultWebhookQueueSize
+	}
+	if t.webhookBreakerFailures > 0 {
+		if t.webhookBreakerCooldown <= 0 {
+			logger.Warnw("invalid webhook breaker cooldown, using default", nil,
+				"breakerCooldown", t.webhookBreakerCooldown,
+				"default", defaultWebhookBreakerCooldown,
+			)
+			t.webhookBreakerCooldown = defaultWebhookBreakerCooldown
+		}
+		switch t.webhookBreakerPolicy {
+		case WebhookBreakerDeadLetter, WebhookBreakerDrop:
+		default:
+			logger.Warnw("invalid webhook breaker policy, using default", nil,
+				"breakerPolicy", t.webhookBreakerPolicy,
+				"default", WebhookBreakerDeadLetter,
+			)
+			t.webhookBreakerPolicy = WebhookBreakerDeadLetter
+		}
+	}
+	queueSize := conf.Analytics.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultAnalyticsQueueSize
+	}
+	queuePolicy := conf.Analytics.QueuePolicy
+	switch queuePolicy {
+	case AnalyticsQueueBlock, AnalyticsQueueDropOldest, AnalyticsQueueDropNewest:
+	default:
+		logger.Warnw("invalid analytics queue policy, using default", nil,
+			"queuePolicy", queuePolicy,
+			"default", AnalyticsQueueDropOldest,
+		)
+		queuePolicy = AnalyticsQueueDropOldest
+	}
+	if t.statsInterval <= 0 {
+		logger.Warnw("invalid analytics stats interval, using default", nil,
+			"statsInterval", t.statsInterval,
+			"default", config.TelemetryStatsUpdateInterval,
+		)
+		t.statsInterval = config.TelemetryStatsUpdateInterval
+	}
+	if conf.WebHook.DedupWindow > 0 {
+		t.webhookDedup = newWebhookDedup(conf.WebHook.DedupWindow)
+	}
+	if conf.Analytics.JoinLeaveThreshold > 0 {
+		window := conf.Analytics.JoinLeaveWindow
+		if window <= 0 {
+			logger.Warnw("invalid analytics join/leave window, using default", nil,
+				"joinLeaveWindow", window,
+				"default", defaultJoinLeaveWindow,
+			)
+			window = defaultJoinLeaveWindow
+		}
+		t.joinLeaveRates = newJoinLeaveRates(window, conf.Analytics.JoinLeaveThreshold)
+	}
+	for i := range t.workers {
+		t.workers[i] = newWorkerShard()
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	// options may set the clock after others that use it, so it is only handed out from here
+	if t.clock == nil {
+		t.clock = realClock{}
+	}
+	if failover, ok := t.analyticsSink.(*analyticsFailover); ok {
+		failover.clock = t.clock
+	}
+	t.roomStatsAt = t.clock.Now()
+	t.webhookCtx, t.webhookCancel = context.WithCancel(context.Background())
+	if t.auditLogger != nil {
+		t.webhookAuditor = newWebhookAuditor(t.auditLogger)
+	}
+	t.webhookEndpoints = t.newWebhookEndpoints(notifiers)
+	if len(t.webhookEndpoints) == 0 {
+		logger.Warnw("no webhook urls or endpoints configured, webhook events will not be sent", nil)
+	}
+	if batcher, ok := t.analyticsSink.(AnalyticsBatchService); ok && t.eventBatchSize > 1 {
+		t.eventBatcher = batcher
+		if t.eventInterval <= 0 {
+			t.eventInterval = defaultAnalyticsBatchInterval
+		}
+	}
+	if t.analyticsBuffer != nil {
+		if retrier, ok := t.analyticsSink.(AnalyticsRetryService); ok {
+			t.analyticsRetrier = retrier
+			if t.analyticsRetryInterval <= 0 {
+				t.analyticsRetryInterval = defaultAnalyticsRetryInterval
+			}
+		} else {
+			logger.Warnw("analytics sink does not report failed sends, analytics buffer will not be used", nil)
+		}
+	}
+	t.analyticsQueue = newAnalyticsQueue(queueSize, queuePolicy, t.sendAnalytics)
+	if t.fileSink != nil {
+		t.Subscribe(func(event *livekit.WebhookEvent) {
+			t.writeFileEvent(t.fileSink.WriteWebhookEvent(event))
+		})
+	}
+	if t.eventStream != nil {
+		t.eventStreamRedactions = t.webhookRedactionsFor(EventStreamEndpoint)
 	}
 
 	go t.run()
@@ -110,28 +671,173 @@ func NewTelemetryService(notifier webhook.QueuedNotifier, analytics AnalyticsSer
 	return t
 }
 
+func toEventSet(events []string) map[string]struct{} {
+	if len(events) == 0 {
+		return nil
+	}
+
+	set := make(map[string]struct{}, len(events))
+	for _, event := range events {
+		set[event] = struct{}{}
+	}
+	return set
+}
+
+// FlushStats sends the stats of every worker, waiting until they have been handed to the analytics sink
 func (t *telemetryService) FlushStats() {
-	t.lock.Lock()
-	defer t.lock.Unlock()
+	t.flushStats()
+	_ = t.analyticsQueue.flush(context.Background())
+}
 
-	for _, worker := range t.workers {
+// flushStats queues the stats of every worker without waiting for them to be sent, so a slow analytics sink
+// doesn't hold up the run goroutine
+func (t *telemetryService) flushStats() {
+	for _, worker := range t.allWorkers() {
 		worker.Flush()
 	}
+	t.flushDataPackets()
+}
+
+func (t *telemetryService) Shutdown(ctx context.Context) error {
+	// let events that are already queued go out first
+	done := make(chan struct{})
+	select {
+	case t.jobsChan <- func() {
+		for _, worker := range t.allWorkers() {
+			sendHeldUnpublishes(worker)
+		}
+		close(done)
+	}:
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	default:
+	}
+
+	defer t.webhookCancel()
+
+	t.webhookLock.Lock()
+	closed := t.webhookClosed
+	t.webhookClosed = true
+	t.webhookLock.Unlock()
+	if closed {
+		return nil
+	}
+
+	for _, worker := range t.allWorkers() {
+		worker.Close()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		for _, endpoint := range t.webhookEndpoints {
+			endpoint.pool.StopWait()
+		}
+		t.eventListeners.pool.StopWait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+		// abandoned deliveries may still be running otherwise
+		if t.webhookAuditor != nil {
+			t.webhookAuditor.close(ctx)
+		}
+	case <-ctx.Done():
+		dropped := t.webhookPending.Load()
+		logger.Warnw("telemetry shutdown timed out, abandoning webhook deliveries", ctx.Err(), "dropped", dropped)
+		err = fmt.Errorf("telemetry shutdown abandoned %d webhook deliveries: %w", dropped, ctx.Err())
+	}
+
+	t.flushEvents(analyticsFlushShutdown)
+	// stats and events already queued are sent even when ctx is done, anything sent later goes straight to the sink
+	t.analyticsQueue.close(context.Background())
+	if t.analyticsBuffer != nil {
+		// one last try for buffered events, those still not sent are kept on disk for the next run
+		t.sendBuffered(context.Background(), nil)
+		if closeErr := t.analyticsBuffer.Close(); closeErr != nil {
+			logger.Errorw("failed to close analytics buffer", closeErr)
+		}
+	}
+	if t.fileSink != nil {
+		if closeErr := t.fileSink.Close(); closeErr != nil {
+			logger.Errorw("failed to close event file", closeErr)
+		}
+	}
+	if t.eventStream != nil {
+		t.eventStream.Close()
+	}
+	return err
 }
 
 func (t *telemetryService) run() {
-	ticker := time.NewTicker(config.TelemetryStatsUpdateInterval)
+	ticker := t.clock.NewTicker(t.statsInterval)
 	defer ticker.Stop()
 
-	cleanupTicker := time.NewTicker(time.Minute)
+	// check often enough that idle workers are not kept much longer than the idle timeout
+	cleanupInterval := time.Minute
+	if t.workerIdleTimeout > 0 && t.workerIdleTimeout/2 < cleanupInterval {
+		cleanupInterval = t.workerIdleTimeout / 2
+	}
+	cleanupTicker := t.clock.NewTicker(cleanupInterval)
 	defer cleanupTicker.Stop()
 
+	// only fires when room stats are enabled
+	var roomStatsTickerC <-chan time.Time
+	if t.roomStatsInterval > 0 {
+		roomStatsTicker := t.clock.NewTicker(t.roomStatsInterval)
+		defer roomStatsTicker.Stop()
+		roomStatsTickerC = roomStatsTicker.C()
+	}
+
+	// only fires when events are batched
+	var eventTickerC <-chan time.Time
+	if t.eventBatcher != nil {
+		eventTicker := t.clock.NewTicker(t.eventInterval)
+		defer eventTicker.Stop()
+		eventTickerC = eventTicker.C()
+	}
+
+	// only fires when audio levels are summarized
+	var audioLevelTickerC <-chan time.Time
+	if t.audioLevelInterval > 0 {
+		audioLevelTicker := t.clock.NewTicker(t.audioLevelInterval)
+		defer audioLevelTicker.Stop()
+		audioLevelTickerC = audioLevelTicker.C()
+	}
+
+	// only fires when failed analytics events are buffered
+	var replayTickerC <-chan time.Time
+	if t.analyticsRetrier != nil {
+		replayTicker := t.clock.NewTicker(t.analyticsRetryInterval)
+		defer replayTicker.Stop()
+		replayTickerC = replayTicker.C()
+	}
+
 	for {
 		select {
-		case <-ticker.C:
-			t.FlushStats()
-		case <-cleanupTicker.C:
+		case <-ticker.C():
+			t.flushStats()
+			t.updateRoomBitrates()
+		case <-cleanupTicker.C():
 			t.cleanupWorkers()
+			if t.webhookDedup != nil {
+				t.webhookDedup.prune(t.clock.Now())
+			}
+			if t.joinLeaveRates != nil {
+				t.joinLeaveRates.prune(t.clock.Now())
+			}
+		case <-roomStatsTickerC:
+			t.flushRoomStats(context.Background(), "")
+		case <-eventTickerC:
+			t.flushEvents(analyticsFlushInterval)
+		case <-audioLevelTickerC:
+			t.flushAudioLevels()
+		case <-replayTickerC:
+			// events sent in the meantime also trigger a replay, this covers a quiet node
+			t.queueAnalytics(&analyticsItem{ctx: context.Background(), replay: true})
 		case op := <-t.jobsChan:
 			op()
 		}
@@ -147,48 +853,6 @@ func (t *telemetryService) enqueue(op func()) {
 	}
 }
 
-func (t *telemetryService) getWorker(participantID livekit.ParticipantID) (worker *StatsWorker, ok bool) {
-	t.lock.RLock()
-	defer t.lock.RUnlock()
-
-	worker, ok = t.workers[participantID]
-	return
-}
-
-func (t *telemetryService) createWorker(ctx context.Context,
-	roomID livekit.RoomID,
-	roomName livekit.RoomName,
-	participantID livekit.ParticipantID,
-	participantIdentity livekit.ParticipantIdentity,
-) *StatsWorker {
-	worker := newStatsWorker(
-		ctx,
-		t,
-		roomID,
-		roomName,
-		participantID,
-		participantIdentity,
-	)
-
-	t.lock.Lock()
-	t.workers[participantID] = worker
-	t.lock.Unlock()
-	return worker
-}
-
-func (t *telemetryService) cleanupWorkers() {
-	t.lock.Lock()
-	defer t.lock.Unlock()
-
-	for participantID, worker := range t.workers {
-		closedAt := worker.ClosedAt()
-		if !closedAt.IsZero() && time.Since(closedAt) > workerCleanupWait {
-			logger.Debugw("reaping analytics worker for participant", "pID", participantID)
-			delete(t.workers, participantID)
-		}
-	}
-}
-
 func (t *telemetryService) LocalRoomState(ctx context.Context, info *livekit.AnalyticsNodeRooms) {
 	t.enqueue(func() {
 		t.SendNodeRoomStates(ctx, info)
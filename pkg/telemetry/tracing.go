@@ -0,0 +1,83 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const tracerName = "github.com/livekit/livekit-server/pkg/telemetry"
+
+const (
+	attrEventType     = attribute.Key("livekit.event.type")
+	attrEventID       = attribute.Key("livekit.event.id")
+	attrEndpoint      = attribute.Key("livekit.webhook.endpoint")
+	attrRoomID        = attribute.Key("livekit.room.sid")
+	attrParticipantID = attribute.Key("livekit.participant.sid")
+	attrHTTPStatus    = attribute.Key("http.status_code")
+)
+
+// detachContext returns a context that keeps the trace span of ctx but not its cancellation, for work that goes on
+// after the caller returns
+func (t *telemetryService) detachContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(t.webhookCtx, trace.SpanFromContext(ctx))
+}
+
+// startWebhookSpan starts the span covering the delivery of event to one notifier, including time spent queued and retries
+func (t *telemetryService) startWebhookSpan(ctx context.Context, endpoint string, event *livekit.WebhookEvent) (context.Context, trace.Span) {
+	ctx, span := t.tracer.Start(ctx, "telemetry.NotifyEvent", trace.WithSpanKind(trace.SpanKindClient))
+	if span.IsRecording() {
+		span.SetAttributes(
+			attrEndpoint.String(endpoint),
+			attrEventType.String(event.Event),
+			attrEventID.String(event.Id),
+			attrRoomID.String(event.Room.GetSid()),
+			attrParticipantID.String(event.Participant.GetSid()),
+		)
+	}
+	return ctx, span
+}
+
+func (t *telemetryService) startAnalyticsSpan(ctx context.Context, event *livekit.AnalyticsEvent) (context.Context, trace.Span) {
+	ctx, span := t.tracer.Start(ctx, "telemetry.SendEvent")
+	if span.IsRecording() {
+		span.SetAttributes(
+			attrEventType.String(event.Type.String()),
+			attrRoomID.String(event.RoomId),
+			attrParticipantID.String(event.ParticipantId),
+		)
+	}
+	return ctx, span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordHTTPStatus sets the response status of a webhook request on the span in ctx, if any
+func recordHTTPStatus(ctx context.Context, status int) {
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.SetAttributes(attrHTTPStatus.Int(status))
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func Test_NotifyEvent_RecordsSpan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 0
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:       server.URL,
+		APIKey:    "key",
+		APISecret: "secret",
+	})
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithTracerProvider(provider),
+	)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "ParticipantJoined")
+	sut.NotifyEvent(ctx, &livekit.WebhookEvent{
+		Event:       webhook.EventParticipantJoined,
+		Room:        &livekit.Room{Sid: "RoomSid"},
+		Participant: &livekit.ParticipantInfo{Sid: "PartSid"},
+	})
+	parent.End()
+	require.NoError(t, sut.Shutdown(context.Background()))
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "telemetry.NotifyEvent" {
+			span = s
+		}
+	}
+	require.NotNil(t, span)
+
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	require.Equal(t, codes.Error, span.Status().Code)
+	attrs := spanAttributes(span)
+	require.Equal(t, webhook.EventParticipantJoined, attrs["livekit.event.type"].AsString())
+	require.Equal(t, "RoomSid", attrs["livekit.room.sid"].AsString())
+	require.Equal(t, "PartSid", attrs["livekit.participant.sid"].AsString())
+	require.EqualValues(t, http.StatusBadGateway, attrs["http.status_code"].AsInt64())
+	require.Len(t, span.Events(), 1)
+}
+
+func Test_SendEvent_RecordsSpan(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{}, telemetry.WithTracerProvider(provider))
+
+	sut.SendEvent(context.Background(), &livekit.AnalyticsEvent{
+		Type:          livekit.AnalyticsEventType_PARTICIPANT_JOINED,
+		RoomId:        "RoomSid",
+		ParticipantId: "PartSid",
+	})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "telemetry.SendEvent", spans[0].Name())
+	attrs := spanAttributes(spans[0])
+	require.Equal(t, livekit.AnalyticsEventType_PARTICIPANT_JOINED.String(), attrs["livekit.event.type"].AsString())
+	require.Equal(t, "RoomSid", attrs["livekit.room.sid"].AsString())
+	require.Equal(t, "PartSid", attrs["livekit.participant.sid"].AsString())
+}
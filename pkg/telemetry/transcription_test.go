@@ -0,0 +1,83 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_TranscriptionLifecycle(t *testing.T) {
+	fixture := createFixture()
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	before := transcriptionsActive(t)
+	sessions := transcriptionSessions(t)
+
+	fixture.sut.TranscriptionStarted(context.Background(), room, "TR_1")
+	flushEvents(fixture.sut)
+	require.Equal(t, before+1, transcriptionsActive(t))
+
+	fixture.sut.TranscriptionEnded(context.Background(), room, "TR_1", 90*time.Second)
+	fixture.drain(t)
+	require.Equal(t, before, transcriptionsActive(t))
+	require.Equal(t, sessions+1, transcriptionSessions(t))
+
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventTranscriptionStarted, event.Event)
+	require.Equal(t, room, event.Room)
+	require.Equal(t, "TR_1", event.Track.Sid)
+	_, event = fixture.notifier.NotifyArgsForCall(1)
+	require.Equal(t, telemetry.EventTranscriptionEnded, event.Event)
+	require.Equal(t, "TR_1", event.Track.Sid)
+
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+}
+
+func Test_TranscriptionsActiveIsNotUnbalanced(t *testing.T) {
+	fixture := createFixture()
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	before := transcriptionsActive(t)
+
+	// ended without having started
+	fixture.sut.TranscriptionEnded(context.Background(), room, "TR_1", time.Second)
+	// started twice, ended twice
+	fixture.sut.TranscriptionStarted(context.Background(), room, "TR_2")
+	fixture.sut.TranscriptionStarted(context.Background(), room, "TR_2")
+	flushEvents(fixture.sut)
+	require.Equal(t, before+1, transcriptionsActive(t))
+
+	fixture.sut.TranscriptionEnded(context.Background(), room, "TR_2", time.Second)
+	fixture.sut.TranscriptionEnded(context.Background(), room, "TR_2", time.Second)
+	fixture.drain(t)
+	require.Equal(t, before, transcriptionsActive(t))
+	require.Equal(t, 5, fixture.notifier.NotifyCallCount())
+}
+
+func transcriptionsActive(t *testing.T) float64 {
+	return findMetric(t, "livekit_transcription_active", nil).GetGauge().GetValue()
+}
+
+func transcriptionSessions(t *testing.T) uint64 {
+	return findMetric(t, "livekit_transcription_duration_seconds", nil).GetHistogram().GetSampleCount()
+}
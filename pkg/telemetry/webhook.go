@@ -0,0 +1,551 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	webhookAuthHeader = "Authorization"
+
+	WebhookEventIDHeader   = "X-Livekit-Event-Id"
+	WebhookSignatureHeader = "X-Livekit-Signature"
+)
+
+// how URLNotifier encodes the events it sends
+const (
+	WebhookEncodingJSON     = "json"
+	WebhookEncodingProtobuf = "protobuf"
+	// the JSON encoded event as the single field of a form, for receivers that only take forms
+	WebhookEncodingForm = "form"
+)
+
+// content types of the request bodies sent by URLNotifier, by encoding
+const (
+	// use a custom mime type to ensure signature is checked prior to parsing
+	WebhookContentTypeJSON     = "application/webhook+json"
+	WebhookContentTypeProtobuf = "application/protobuf"
+	WebhookContentTypeForm     = "application/x-www-form-urlencoded"
+)
+
+const defaultWebhookFormField = "payload"
+
+// webhook events emitted by this server in addition to the ones defined in protocol
+const (
+	// the room was created through the API, ahead of anyone joining. room_started follows once someone joins
+	EventRoomReserved = "room_reserved"
+
+	EventTrackMuted   = "track_muted"
+	EventTrackUnmuted = "track_unmuted"
+
+	EventActiveSpeakerChanged = "active_speaker_changed"
+	EventRoomMetadataChanged  = "room_metadata_changed"
+
+	EventParticipantAttributesChanged = "participant_attributes_changed"
+	EventEgressFailed                 = "egress_failed"
+
+	EventTranscriptionStarted = "transcription_started"
+	EventTranscriptionEnded   = "transcription_ended"
+
+	// sent once per session, the first time the participant sends or receives media
+	EventParticipantMediaActive = "participant_media_active"
+
+	// media stopped flowing on a published track that isn't muted, and started again. see WebHookConfig.TrackStallEvents
+	EventTrackStalled = "track_stalled"
+	EventTrackResumed = "track_resumed"
+
+	// media stopped being sent to a subscriber while it flowed on the published track, and started again.
+	// Participant is the subscriber. see WebHookConfig.TrackStallEvents
+	EventSubscribedTrackStalled = "subscribed_track_stalled"
+	EventSubscribedTrackResumed = "subscribed_track_resumed"
+
+	// the participant's permissions changed, Participant carries the new ones
+	EventParticipantUpdated = "participant_updated"
+
+	// the room was deleted through the API. room_finished follows once the room has closed
+	EventRoomDeleted = "room_deleted"
+
+	// the participant's kind changed during its session, Participant carries the new one
+	EventParticipantRoleChanged = "participant_role_changed"
+)
+
+var (
+	ErrWebhookSignatureMissing = errors.New("webhook signature header could not be found")
+	ErrWebhookSignatureInvalid = errors.New("webhook signature does not match payload")
+	ErrWebhookPayloadTooLarge  = errors.New("decompressed webhook payload is too large")
+
+	errWebhookDropped   = errors.New("webhook dropped, telemetry is shutting down")
+	errWebhookQueueFull = errors.New("webhook dropped, endpoint queue is full")
+	// the endpoint's circuit breaker is open
+	errWebhookShortCircuited = errors.New("webhook not delivered, endpoint circuit breaker is open")
+)
+
+// WebhookStatusError is returned by URLNotifier when the endpoint responds with a non-2xx status
+type WebhookStatusError struct {
+	StatusCode int
+	// how long the endpoint asked to wait before retrying, from the Retry-After header of a 429 response. 0 when not given
+	RetryAfter time.Duration
+}
+
+func (e *WebhookStatusError) Error() string {
+	return fmt.Sprintf("webhook endpoint returned status %d", e.StatusCode)
+}
+
+// WebhookNotifier delivers a single webhook event, returning once the endpoint has accepted or rejected it
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . WebhookNotifier
+type WebhookNotifier interface {
+	Notify(ctx context.Context, event *livekit.WebhookEvent) error
+}
+
+// NamedWebhookNotifier is implemented by notifiers that can name the endpoint they deliver to.
+// the name labels webhook metrics and logs, other notifiers are named after their position
+type NamedWebhookNotifier interface {
+	WebhookNotifier
+	Name() string
+}
+
+// DeadLetter is a webhook event that could not be delivered to one endpoint. an event failing on several
+// endpoints is dead-lettered once for each
+type DeadLetter struct {
+	// the endpoint the event was not delivered to, as named in metrics and logs, see NamedWebhookNotifier
+	Endpoint string
+	// the event including its Id and CreatedAt, before the endpoint's redactions, which are applied again when it is
+	// replayed. sinks that must not keep the redacted fields should drop them themselves
+	Event *livekit.WebhookEvent
+	// the details delivered along with the event, see WebhookDetails. before redactions, as Event
+	Details *WebhookDetails
+}
+
+// DeadLetterSink receives webhook events that could not be delivered once retries were exhausted,
+// so they can be persisted and replayed later
+//
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . DeadLetterSink
+type DeadLetterSink interface {
+	Store(ctx context.Context, letter *DeadLetter) error
+}
+
+type noopDeadLetterSink struct{}
+
+func (noopDeadLetterSink) Store(_ context.Context, _ *DeadLetter) error {
+	return nil
+}
+
+type URLNotifierParams struct {
+	// identifies the endpoint in metrics and logs, defaults to the host of URL
+	Name      string
+	URL       string
+	APIKey    string
+	APISecret string
+	// when set, requests carry an HMAC-SHA256 signature of the event id and body
+	SigningKey string
+	// added to every request, {room_name} and {event} in values are replaced with those of the event.
+	// headers the notifier sets itself can't be overridden and are ignored
+	Headers map[string]string
+	// gzip bodies larger than CompressThreshold bytes, 1KB when not set.
+	// the auth token and signatures are of the compressed body, as sent, see DecompressWebhookPayload
+	Compress          bool
+	CompressThreshold int
+	// WebhookEncodingJSON, WebhookEncodingProtobuf or WebhookEncodingForm, JSON when not set.
+	// signatures are of the body sent, whichever the encoding, the whole form with WebhookEncodingForm
+	Encoding string
+	// the form field the event is sent in with WebhookEncodingForm, payload when not set
+	FormField string
+	// version of the payload sent, see WebhookAPIVersionV1. signatures cover the version. v1 when not set
+	APIVersion string
+	// send events in a WebhookEnvelope, along with the details they have no field for, see
+	// WebHookConfig.ParticipantClientInfo and WebHookConfig.EventDetails
+	Envelope bool
+	// client certificate and CAs to connect with, may be shared by notifiers. the default TLS configuration when not set
+	TLS *WebhookTLS
+	// requests are sent over, to go through a proxy, pool connections or time out dials differently. may be shared
+	// by notifiers. NewWebhookTransport when not set. TLS is ignored when set, the transport configures its own
+	Transport http.RoundTripper
+}
+
+const defaultWebhookCompressThreshold = 1024
+
+// headers set by URLNotifier that receivers rely on to authenticate and parse the request
+var reservedWebhookHeaders = map[string]struct{}{
+	webhookAuthHeader:      {},
+	"Content-Type":         {},
+	"Content-Length":       {},
+	"Content-Encoding":     {},
+	"Host":                 {},
+	WebhookEventIDHeader:   {},
+	WebhookSignatureHeader: {},
+}
+
+// URLNotifier is a WebhookNotifier that sends a signed POST request to a webhook URL.
+// It does not retry on its own, retries are handled by the caller
+type URLNotifier struct {
+	params  URLNotifierParams
+	headers map[string]string
+	client  *http.Client
+	// Retry-After dates are relative to it
+	clock Clock
+}
+
+func NewURLNotifier(params URLNotifierParams) *URLNotifier {
+	headers := make(map[string]string, len(params.Headers))
+	for k, v := range params.Headers {
+		k = http.CanonicalHeaderKey(k)
+		if _, ok := reservedWebhookHeaders[k]; ok {
+			logger.Warnw("ignoring reserved webhook header", nil, "header", k, "url", params.URL)
+			continue
+		}
+		headers[k] = v
+	}
+	if params.CompressThreshold <= 0 {
+		params.CompressThreshold = defaultWebhookCompressThreshold
+	}
+	switch params.Encoding {
+	case WebhookEncodingJSON, WebhookEncodingProtobuf:
+	case WebhookEncodingForm:
+		if params.FormField == "" {
+			params.FormField = defaultWebhookFormField
+		}
+	case "":
+		params.Encoding = WebhookEncodingJSON
+	default:
+		logger.Warnw("invalid webhook encoding, using default", nil,
+			"encoding", params.Encoding,
+			"default", WebhookEncodingJSON,
+			"url", params.URL,
+		)
+		params.Encoding = WebhookEncodingJSON
+	}
+	if params.APIVersion == "" {
+		params.APIVersion = WebhookAPIVersionV1
+	} else if !validWebhookAPIVersion(params.APIVersion) {
+		logger.Warnw("invalid webhook api version, using default", nil,
+			"apiVersion", params.APIVersion,
+			"default", WebhookAPIVersionV1,
+			"url", params.URL,
+		)
+		params.APIVersion = WebhookAPIVersionV1
+	}
+	n := &URLNotifier{
+		params:  params,
+		headers: headers,
+		clock:   realClock{},
+	}
+	switch {
+	case params.Transport != nil:
+		if params.TLS != nil {
+			logger.Warnw("ignoring webhook TLS settings, the notifier has a transport of its own", nil, "url", params.URL)
+		}
+		n.client = &http.Client{Transport: params.Transport}
+	case params.TLS != nil:
+		n.client = &http.Client{Transport: params.TLS.transport(n.Name())}
+	default:
+		n.client = &http.Client{Transport: NewWebhookTransport()}
+	}
+	return n
+}
+
+// useTransport sends requests over transport unless the notifier was given one of its own. must be called before
+// the notifier is used
+func (n *URLNotifier) useTransport(transport http.RoundTripper) {
+	if n.params.Transport != nil {
+		return
+	}
+	if n.params.TLS != nil {
+		logger.Warnw("ignoring webhook TLS settings, the telemetry service has a transport for webhooks", nil, "url", n.params.URL)
+	}
+	n.client = &http.Client{Transport: transport}
+}
+
+// useClock makes the notifier go by clock, see WithClock. must be called before the notifier is used
+func (n *URLNotifier) useClock(clock Clock) {
+	n.clock = clock
+}
+
+func (n *URLNotifier) Name() string {
+	if n.params.Name != "" {
+		return n.params.Name
+	}
+	if u, err := url.Parse(n.params.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return n.params.URL
+}
+
+func (n *URLNotifier) Notify(ctx context.Context, event *livekit.WebhookEvent) error {
+	encoded, contentType, err := n.encode(event, webhookDetails(ctx))
+	if err != nil {
+		return err
+	}
+
+	body := encoded
+	compressed := n.params.Compress && len(encoded) > n.params.CompressThreshold
+	if compressed {
+		if body, err = gzipBody(encoded); err != nil {
+			return err
+		}
+	}
+
+	// sign payload, as sent
+	sum := sha256.Sum256(body)
+	b64 := base64.StdEncoding.EncodeToString(sum[:])
+
+	at := auth.NewAccessToken(n.params.APIKey, n.params.APISecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(b64)
+	token, err := at.ToJWT()
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, n.params.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if len(n.headers) > 0 {
+		expand := strings.NewReplacer(
+			"{room_name}", headerValue(event.Room.GetName()),
+			"{event}", headerValue(event.Event),
+		)
+		for k, v := range n.headers {
+			r.Header.Set(k, expand.Replace(v))
+		}
+	}
+	r.Header.Set(webhookAuthHeader, token)
+	r.Header.Set("content-type", contentType)
+	if compressed {
+		r.Header.Set("Content-Encoding", "gzip")
+	}
+	if n.params.SigningKey != "" {
+		r.Header.Set(WebhookEventIDHeader, event.Id)
+		r.Header.Set(WebhookSignatureHeader, SignWebhookPayload(n.params.SigningKey, event.Id, body))
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	res, err := n.client.Do(r)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	recordHTTPStatus(ctx, res.StatusCode)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		statusErr := &WebhookStatusError{StatusCode: res.StatusCode}
+		if res.StatusCode == http.StatusTooManyRequests {
+			statusErr.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"), n.clock.Now())
+		}
+		return statusErr
+	}
+	return nil
+}
+
+func (n *URLNotifier) encode(event *livekit.WebhookEvent, details *WebhookDetails) ([]byte, string, error) {
+	var (
+		encoded     []byte
+		contentType string
+		err         error
+	)
+	if n.params.Encoding == WebhookEncodingProtobuf {
+		encoded, err = proto.Marshal(event)
+		contentType = WebhookContentTypeProtobuf
+	} else {
+		encoded, err = protojson.Marshal(event)
+		contentType = WebhookContentTypeJSON
+	}
+	if err == nil && n.params.Envelope {
+		encoded, err = encodeWebhookEnvelope(encoded, details, n.params.Encoding)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	encoded = versionWebhookPayload(encoded, n.params.Encoding, n.params.APIVersion)
+
+	if n.params.Encoding == WebhookEncodingForm {
+		form := url.Values{n.params.FormField: {string(encoded)}}
+		return []byte(form.Encode()), WebhookContentTypeForm, nil
+	}
+	return encoded, contentType, nil
+}
+
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// payloads decompress to at most this many bytes, larger ones are rejected
+const maxWebhookPayloadSize = 16 << 20
+
+// DecompressWebhookPayload returns the decompressed payload of a webhook request sent with contentEncoding, e.g.
+// the body returned by webhook.Receive, which verifies the auth token against the body as sent. payloads that
+// aren't compressed are returned as they are
+func DecompressWebhookPayload(contentEncoding string, data []byte) ([]byte, error) {
+	if !strings.EqualFold(contentEncoding, "gzip") {
+		return data, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return readWebhookPayload(zr)
+}
+
+// DecompressWebhookRequest replaces the body of a webhook request sent gzipped with the decompressed body, so that
+// it can be parsed like any other. the auth token and signature are of the compressed body, use
+// DecompressWebhookPayload to check them first. requests that aren't compressed are left as they are
+func DecompressWebhookRequest(r *http.Request) error {
+	if !strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return err
+	}
+	data, err := readWebhookPayload(zr)
+	_ = r.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.ContentLength = int64(len(data))
+	r.Header.Del("Content-Encoding")
+	r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return nil
+}
+
+// readWebhookPayload reads a decompressed payload, up to maxWebhookPayloadSize bytes
+func readWebhookPayload(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxWebhookPayloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxWebhookPayloadSize {
+		return nil, ErrWebhookPayloadTooLarge
+	}
+	return data, nil
+}
+
+// headerValue drops control characters, which are not allowed in header values, from a value substituted into one
+func headerValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// parseRetryAfter parses a Retry-After header given either as a number of seconds or as an HTTP date,
+// returning 0 if it is missing, malformed or already in the past
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// SignWebhookPayload returns the hex encoded HMAC-SHA256 of the event id and body, joined by a "."
+func SignWebhookPayload(signingKey string, eventID string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(eventID))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reads the body of a signed webhook request and checks it against the signature header,
+// returning it decompressed if needed. closes body after reading
+func VerifyWebhookSignature(r *http.Request, signingKey string) ([]byte, error) {
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := r.Header.Get(WebhookSignatureHeader)
+	if signature == "" {
+		return nil, ErrWebhookSignatureMissing
+	}
+
+	expected := SignWebhookPayload(signingKey, r.Header.Get(WebhookEventIDHeader), data)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, ErrWebhookSignatureInvalid
+	}
+	return DecompressWebhookPayload(r.Header.Get("Content-Encoding"), data)
+}
+
+const (
+	webhookDialTimeout = 10 * time.Second
+	// deliveries to an endpoint run on a number of workers, connections are kept for as many of them
+	webhookMaxIdleConnsPerHost = 10
+)
+
+// NewWebhookTransport returns the transport URL notifiers send requests over unless given another, a clone of
+// http.DefaultTransport, so taking proxies from the environment, keeping a connection idle for each of an endpoint's
+// default number of workers and giving up on dials sooner. it can be changed and passed to URLNotifierParams or
+// WithWebhookTransport
+func NewWebhookTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   webhookDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConnsPerHost = webhookMaxIdleConnsPerHost
+	return transport
+}
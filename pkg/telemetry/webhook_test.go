@@ -0,0 +1,1186 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+// advanceRetries waits for the given number of failed deliveries to be waiting to be retried, then advances the
+// clock by d
+func advanceRetries(t *testing.T, clock *fakeClock, waiting int, d time.Duration) {
+	require.Eventually(t, func() bool {
+		return clock.pending() == waiting
+	}, time.Second, time.Millisecond)
+	clock.Advance(d)
+	require.Zero(t, clock.pending())
+}
+
+func Test_NotifyEvent_RetriesUntilSuccess(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 3
+	conf.WebHook.RetryBaseDelay = time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	fixture.notifier.NotifyReturnsOnCall(0, errors.New("bad gateway"))
+	fixture.notifier.NotifyReturnsOnCall(1, errors.New("bad gateway"))
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	advanceRetries(t, clock, 1, time.Minute)
+	advanceRetries(t, clock, 1, time.Minute)
+
+	fixture.drain(t)
+	require.Equal(t, 3, fixture.notifier.NotifyCallCount())
+}
+
+func Test_NotifyEvent_StopsAfterMaxRetries(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 2
+	conf.WebHook.RetryBaseDelay = time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	fixture.notifier.NotifyReturns(errors.New("bad gateway"))
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	advanceRetries(t, clock, 1, time.Minute)
+	advanceRetries(t, clock, 1, time.Minute)
+
+	fixture.drain(t)
+	require.Equal(t, 3, fixture.notifier.NotifyCallCount())
+}
+
+func Test_NotifyEvent_ContextCancelDoesNotAbortRetries(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 3
+	conf.WebHook.RetryBaseDelay = time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	fixture.notifier.NotifyReturnsOnCall(0, errors.New("bad gateway"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fixture.sut.NotifyEvent(ctx, &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	cancel()
+	advanceRetries(t, clock, 1, time.Minute)
+
+	fixture.drain(t)
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+}
+
+func Test_NotifyEvent_ShutdownAbortsRetries(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 3
+	conf.WebHook.RetryBaseDelay = time.Hour
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	fixture.notifier.NotifyReturns(errors.New("bad gateway"))
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	require.Eventually(t, func() bool {
+		return clock.pending() == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Error(t, fixture.sut.Shutdown(ctx))
+	// the retry is stopped rather than attempted
+	require.Eventually(t, func() bool {
+		return clock.pending() == 0
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+}
+
+func Test_NotifyEvent_DeadLettersAfterMaxRetries(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 1
+	conf.WebHook.RetryBaseDelay = time.Second
+
+	clock := newFakeClock()
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyReturns(errors.New("bad gateway"))
+	sink := &telemetryfakes.FakeDeadLetterSink{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+		telemetry.WithClock(clock),
+	)
+
+	event := &livekit.WebhookEvent{Event: webhook.EventParticipantLeft}
+	sut.NotifyEvent(context.Background(), event)
+	advanceRetries(t, clock, 1, time.Minute)
+
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, 1, sink.StoreCallCount())
+	require.Equal(t, 2, notifier.NotifyCallCount())
+
+	_, letter := sink.StoreArgsForCall(0)
+	require.Equal(t, "notifier_0", letter.Endpoint)
+	require.Same(t, event, letter.Event)
+	require.NotEmpty(t, letter.Event.Id)
+}
+
+func Test_NotifyEvent_DeadLettersWhenQueueFull(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.Workers = 1
+	conf.WebHook.QueueSize = 1
+
+	release := make(chan struct{})
+	defer close(release)
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyStub = func(ctx context.Context, _ *livekit.WebhookEvent) error {
+		<-release
+		return nil
+	}
+	sink := &telemetryfakes.FakeDeadLetterSink{}
+	labels := map[string]string{"endpoint": "notifier_0"}
+	before := findMetric(t, "livekit_webhook_queue_full", labels).GetCounter().GetValue()
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+	)
+	require.Equal(t, float64(1), findMetric(t, "livekit_webhook_queue_capacity", labels).GetGauge().GetValue())
+
+	// the only worker is held by the first delivery and the second fills the queue
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	require.Eventually(t, func() bool {
+		return notifier.NotifyCallCount() == 1
+	}, time.Second, 10*time.Millisecond)
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+
+	event := &livekit.WebhookEvent{Event: webhook.EventRoomFinished}
+	sut.NotifyEvent(context.Background(), event)
+
+	require.Equal(t, 1, sink.StoreCallCount())
+	_, letter := sink.StoreArgsForCall(0)
+	require.Same(t, event, letter.Event)
+	require.Equal(t, float64(1), findMetric(t, "livekit_webhook_queue_full", labels).GetCounter().GetValue()-before)
+	require.Equal(t, 1, notifier.NotifyCallCount())
+}
+
+func Test_URLNotifier_SignsPayload(t *testing.T) {
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	received := make(chan *livekit.WebhookEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, err := webhook.ReceiveWebhookEvent(r, provider)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:       server.URL,
+		APIKey:    "key",
+		APISecret: "secret",
+	})
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Id:    "EV_test",
+	}))
+
+	event := <-received
+	require.Equal(t, webhook.EventRoomStarted, event.Event)
+	require.Equal(t, "EV_test", event.Id)
+}
+
+func Test_URLNotifier_ErrorOnFailureStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:       server.URL,
+		APIKey:    "key",
+		APISecret: "secret",
+	})
+	require.Error(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted}))
+}
+
+func Test_URLNotifier_HMACSignature(t *testing.T) {
+	verified := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := telemetry.VerifyWebhookSignature(r, "signing-key")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "EV_signed", r.Header.Get(telemetry.WebhookEventIDHeader))
+		verified <- data
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:        server.URL,
+		APIKey:     "key",
+		APISecret:  "secret",
+		SigningKey: "signing-key",
+	})
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Id:    "EV_signed",
+	}))
+	require.NotEmpty(t, <-verified)
+
+	wrongKey := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:        server.URL,
+		APIKey:     "key",
+		APISecret:  "secret",
+		SigningKey: "other-key",
+	})
+	require.Error(t, wrongKey.Notify(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Id:    "EV_signed",
+	}))
+}
+
+func Test_SignWebhookPayload_Deterministic(t *testing.T) {
+	body := []byte(`{"event":"room_started"}`)
+	require.Equal(t,
+		telemetry.SignWebhookPayload("key", "EV_1", body),
+		telemetry.SignWebhookPayload("key", "EV_1", body),
+	)
+	require.NotEqual(t,
+		telemetry.SignWebhookPayload("key", "EV_1", body),
+		telemetry.SignWebhookPayload("key", "EV_2", body),
+	)
+}
+
+func Test_NotifyEvent_Filtering(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.IncludeEvents = []string{webhook.EventRoomStarted, webhook.EventRoomFinished}
+	conf.WebHook.ExcludeEvents = []string{webhook.EventRoomFinished}
+	fixture := createFixtureWithConfig(conf)
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventParticipantJoined})
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, webhook.EventRoomStarted, event.Event)
+}
+
+func Test_NotifyEvent_CountsEventsWithoutEndpoints(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	sut := telemetry.NewTelemetryService(conf, nil, &telemetryfakes.FakeAnalyticsService{})
+	unconfigured := func() float64 {
+		labels := map[string]string{"event": webhook.EventRoomStarted}
+		if metric := findMetric(t, "livekit_webhook_unconfigured_total", labels); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	before := unconfigured()
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+
+	require.Equal(t, before+2, unconfigured())
+}
+
+func Test_NotifyEvent_DeliveryTimeout(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 0
+	conf.WebHook.DeliveryTimeout = 50 * time.Millisecond
+	fixture := createFixtureWithConfig(conf)
+	fixture.notifier.NotifyCalls(func(ctx context.Context, _ *livekit.WebhookEvent) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// a deadline on the incoming context should not apply to delivery
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	fixture.sut.NotifyEvent(ctx, &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+
+	require.Eventually(t, func() bool {
+		return fixture.notifier.NotifyCallCount() == 1
+	}, time.Second, 5*time.Millisecond)
+	deliveryCtx, _ := fixture.notifier.NotifyArgsForCall(0)
+	<-deliveryCtx.Done()
+	require.ErrorIs(t, deliveryCtx.Err(), context.DeadlineExceeded)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func Test_TrackMuted_NotifiesWebhook(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.IncludeEvents = []string{telemetry.EventTrackMuted, telemetry.EventTrackUnmuted}
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	fixture.sut.ParticipantJoined(context.Background(), room, &livekit.ParticipantInfo{Sid: string(partSID)}, nil, nil, true)
+
+	mutes := func(state string, source string) float64 {
+		labels := map[string]string{"kind": "AUDIO", "state": state, "source": source}
+		return findMetric(t, "livekit_track_mute_counter", labels).GetCounter().GetValue()
+	}
+	mutedBefore, unmutedBefore := mutes("muted", telemetry.TrackMuteSourceAdmin), mutes("unmuted", telemetry.TrackMuteSourcePublisher)
+
+	track := &livekit.TrackInfo{Sid: "trackID", Type: livekit.TrackType_AUDIO}
+	fixture.sut.TrackMuted(context.Background(), partSID, track, telemetry.TrackMuteSourceAdmin)
+	fixture.sut.TrackUnmuted(context.Background(), partSID, track, telemetry.TrackMuteSourcePublisher)
+
+	fixture.drain(t)
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+
+	events := map[string]*livekit.WebhookEvent{}
+	for i := 0; i < 2; i++ {
+		_, event := fixture.notifier.NotifyArgsForCall(i)
+		events[event.Event] = event
+	}
+	for _, name := range []string{telemetry.EventTrackMuted, telemetry.EventTrackUnmuted} {
+		event := events[name]
+		require.NotNil(t, event, name)
+		require.Equal(t, room.Sid, event.Room.Sid)
+		require.Equal(t, string(partSID), event.Participant.Sid)
+		require.Equal(t, track.Sid, event.Track.Sid)
+	}
+
+	require.Equal(t, 3, fixture.analytics.SendEventCallCount())
+	_, ev := fixture.analytics.SendEventArgsForCall(1)
+	require.Equal(t, livekit.AnalyticsEventType_TRACK_MUTED, ev.Type)
+	require.Equal(t, room.Sid, ev.RoomId)
+
+	// the source is counted
+	require.Equal(t, mutedBefore+1, mutes("muted", telemetry.TrackMuteSourceAdmin))
+	require.Equal(t, unmutedBefore+1, mutes("unmuted", telemetry.TrackMuteSourcePublisher))
+}
+
+func Test_RoomMetadataChanged(t *testing.T) {
+	fixture := createFixture()
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName", Metadata: "new"}
+
+	// unchanged metadata is not sent
+	fixture.sut.RoomMetadataChanged(context.Background(), room, "new")
+	fixture.sut.RoomMetadataChanged(context.Background(), room, "old")
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventRoomMetadataChanged, event.Event)
+	require.Equal(t, "new", event.Room.Metadata)
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+}
+
+func Test_ParticipantAttributesChanged(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.IncludeEvents = []string{telemetry.EventParticipantAttributesChanged}
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	prev := &livekit.ParticipantInfo{Sid: "part1", Identity: "alice", Name: "Alice", Metadata: "m1"}
+	fixture.sut.ParticipantJoined(context.Background(), room, prev, nil, nil, true)
+
+	// no relevant change
+	fixture.sut.ParticipantAttributesChanged(context.Background(), room, &livekit.ParticipantInfo{
+		Sid: "part1", Identity: "alice", Name: "Alice", Metadata: "m1", Version: 2,
+	}, prev)
+	fixture.sut.ParticipantAttributesChanged(context.Background(), room, &livekit.ParticipantInfo{
+		Sid: "part1", Identity: "alice", Name: "Alice B", Metadata: "m1",
+	}, prev)
+
+	fixture.drain(t)
+	require.Equal(t, 1, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventParticipantAttributesChanged, event.Event)
+	require.Equal(t, "Alice B", event.Participant.Name)
+}
+
+func Test_ParticipantPermissionsChanged(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.IncludeEvents = []string{telemetry.EventParticipantUpdated}
+	fixture := createFixtureWithConfig(conf)
+
+	permissionChanges := func(permission string) float64 {
+		if metric := findMetric(t, "livekit_participant_permission_changes_total", map[string]string{"permission": permission}); metric != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return 0
+	}
+	canPublishBefore, hiddenBefore, canSubscribeBefore := permissionChanges("can_publish"), permissionChanges("hidden"), permissionChanges("can_subscribe")
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	prev := &livekit.ParticipantPermission{CanSubscribe: true, CanPublish: true, CanPublishData: true}
+
+	// no change
+	fixture.sut.ParticipantPermissionsChanged(context.Background(), room, &livekit.ParticipantInfo{
+		Sid:        "part1",
+		Permission: &livekit.ParticipantPermission{CanSubscribe: true, CanPublish: true, CanPublishData: true},
+	}, prev)
+	fixture.sut.ParticipantPermissionsChanged(context.Background(), room, &livekit.ParticipantInfo{
+		Sid:        "part1",
+		Permission: &livekit.ParticipantPermission{CanSubscribe: true, CanPublishData: true, Hidden: true},
+	}, prev)
+	flushEvents(fixture.sut)
+
+	// each changed permission is counted, analytics has no event type for it
+	require.Equal(t, canPublishBefore+1, permissionChanges("can_publish"))
+	require.Equal(t, hiddenBefore+1, permissionChanges("hidden"))
+	require.Zero(t, fixture.analytics.SendEventCallCount())
+
+	// permissions set for the first time
+	fixture.sut.ParticipantPermissionsChanged(context.Background(), room, &livekit.ParticipantInfo{
+		Sid:        "part1",
+		Permission: &livekit.ParticipantPermission{CanSubscribe: true},
+	}, nil)
+
+	fixture.drain(t)
+	require.Equal(t, canSubscribeBefore+1, permissionChanges("can_subscribe"))
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+	_, event := fixture.notifier.NotifyArgsForCall(0)
+	require.Equal(t, telemetry.EventParticipantUpdated, event.Event)
+	require.False(t, event.Participant.Permission.CanPublish)
+}
+
+func Test_NotifyEvent_SuppressesDuplicates(t *testing.T) {
+	fixture := createFixture()
+	room := &livekit.Room{Sid: "RoomSid"}
+	participant := &livekit.ParticipantInfo{Sid: "PartSid"}
+
+	for i := 0; i < 2; i++ {
+		fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event:       webhook.EventParticipantJoined,
+			Room:        room,
+			Participant: participant,
+		})
+	}
+	// a different participant is not a duplicate
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+		Event:       webhook.EventParticipantJoined,
+		Room:        room,
+		Participant: &livekit.ParticipantInfo{Sid: "OtherSid"},
+	})
+	// state changes are never suppressed
+	for _, event := range []string{telemetry.EventTrackMuted, telemetry.EventTrackUnmuted, telemetry.EventTrackMuted} {
+		fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event:       event,
+			Room:        room,
+			Participant: participant,
+			Track:       &livekit.TrackInfo{Sid: "TrackSid"},
+		})
+	}
+
+	fixture.drain(t)
+	require.Equal(t, 5, fixture.notifier.NotifyCallCount())
+
+	ids := make(map[string]struct{})
+	for i := 0; i < fixture.notifier.NotifyCallCount(); i++ {
+		_, event := fixture.notifier.NotifyArgsForCall(i)
+		ids[event.Id] = struct{}{}
+	}
+	require.Len(t, ids, 5)
+}
+
+func Test_NotifyEvent_DedupsIngressSessions(t *testing.T) {
+	fixture := createFixture()
+	ingress := func(resourceID string) *livekit.IngressInfo {
+		return &livekit.IngressInfo{IngressId: "IN_1", State: &livekit.IngressState{ResourceId: resourceID}}
+	}
+
+	for _, info := range []*livekit.IngressInfo{
+		ingress("RS_1"),
+		// the same session
+		ingress("RS_1"),
+		// another session of the same ingress
+		ingress("RS_2"),
+		// sessions without a resource can't be told apart
+		ingress(""),
+		ingress(""),
+	} {
+		fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event:       webhook.EventIngressStarted,
+			IngressInfo: info,
+		})
+	}
+
+	fixture.drain(t)
+	require.Equal(t, 4, fixture.notifier.NotifyCallCount())
+}
+
+func Test_NotifyEvent_DedupDisabled(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	fixture := createFixtureWithConfig(conf)
+
+	for i := 0; i < 2; i++ {
+		fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event:       webhook.EventParticipantJoined,
+			Room:        &livekit.Room{Sid: "RoomSid"},
+			Participant: &livekit.ParticipantInfo{Sid: "PartSid"},
+		})
+	}
+
+	fixture.drain(t)
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+}
+
+func Test_NotifyEvent_EndpointsAreIndependent(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+
+	// hangs every delivery until the test ends, occupying all of its workers
+	release := make(chan struct{})
+	defer close(release)
+	stuck := &telemetryfakes.FakeWebhookNotifier{}
+	stuck.NotifyStub = func(ctx context.Context, _ *livekit.WebhookEvent) error {
+		<-release
+		return nil
+	}
+	healthy := &telemetryfakes.FakeWebhookNotifier{}
+	sut := telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{stuck, healthy}, &telemetryfakes.FakeAnalyticsService{})
+
+	for i := 0; i < 50; i++ {
+		sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	}
+
+	require.Eventually(t, func() bool {
+		return healthy.NotifyCallCount() == 50
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_NotifyEvent_OrdersByRoom(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.OrderByRoom = true
+
+	var (
+		lock      sync.Mutex
+		delivered []string
+	)
+	release := make(chan struct{})
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyStub = func(_ context.Context, event *livekit.WebhookEvent) error {
+		// the first event of RM_A is slow, on its own it would be delivered after the next one
+		if event.Room.Sid == "RM_A" && event.Event == webhook.EventRoomStarted {
+			<-release
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		delivered = append(delivered, event.Room.Sid+" "+event.Event)
+		return nil
+	}
+	sut := telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{notifier}, &telemetryfakes.FakeAnalyticsService{})
+	deliveries := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), delivered...)
+	}
+
+	roomA := &livekit.Room{Sid: "RM_A"}
+	roomB := &livekit.Room{Sid: "RM_B"}
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted, Room: roomA})
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventParticipantJoined, Room: roomA})
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted, Room: roomB})
+
+	// other rooms are not held up
+	require.Eventually(t, func() bool {
+		return len(deliveries()) == 1
+	}, time.Second, time.Millisecond)
+	require.Equal(t, []string{"RM_B room_started"}, deliveries())
+
+	close(release)
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, []string{"RM_B room_started", "RM_A room_started", "RM_A participant_joined"}, deliveries())
+}
+
+func Test_NotifyEvent_RoutesByRoom(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+
+	tenantA := &telemetryfakes.FakeWebhookNotifier{}
+	tenantB := &telemetryfakes.FakeWebhookNotifier{}
+	unknown := telemetry.WebhookNotifierFunc(func(context.Context, *livekit.WebhookEvent) error { return nil })
+	router := func(room *livekit.Room) telemetry.WebhookNotifier {
+		switch {
+		case strings.HasPrefix(room.GetMetadata(), "tenant-a"):
+			return tenantA
+		case strings.HasPrefix(room.GetMetadata(), "tenant-b"):
+			return tenantB
+		case strings.HasPrefix(room.GetMetadata(), "unknown"):
+			return unknown
+		}
+		return nil
+	}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{tenantA, tenantB},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithWebhookRouter(router),
+	)
+
+	for _, metadata := range []string{"tenant-a:1", "tenant-b:1", "tenant-a:2", "unknown", "other"} {
+		sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Room:  &livekit.Room{Sid: "RoomSid", Metadata: metadata},
+		})
+	}
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventIngressStarted})
+
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, 2, tenantA.NotifyCallCount())
+	require.Equal(t, 1, tenantB.NotifyCallCount())
+	for i := 0; i < tenantA.NotifyCallCount(); i++ {
+		_, event := tenantA.NotifyArgsForCall(i)
+		require.True(t, strings.HasPrefix(event.Room.Metadata, "tenant-a"))
+	}
+}
+
+func Test_URLNotifier_Name(t *testing.T) {
+	require.Equal(t, "billing", telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		Name: "billing",
+		URL:  "https://billing.example.com/hook",
+	}).Name())
+	require.Equal(t, "moderation.example.com:8443", telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL: "https://moderation.example.com:8443/hook",
+	}).Name())
+}
+
+func Test_URLNotifier_RetryAfter(t *testing.T) {
+	retryAfter := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", retryAfter)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:       server.URL,
+		APIKey:    "key",
+		APISecret: "secret",
+	})
+	notify := func() *telemetry.WebhookStatusError {
+		var statusErr *telemetry.WebhookStatusError
+		require.ErrorAs(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted}), &statusErr)
+		require.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		return statusErr
+	}
+
+	retryAfter = "2"
+	require.Equal(t, 2*time.Second, notify().RetryAfter)
+
+	retryAfter = time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	delay := notify().RetryAfter
+	require.Greater(t, delay, 80*time.Second)
+	require.LessOrEqual(t, delay, 90*time.Second)
+
+	retryAfter = "soon"
+	require.Zero(t, notify().RetryAfter)
+}
+
+func Test_NotifyEvent_HonorsRetryAfter(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 1
+	conf.WebHook.RetryBaseDelay = time.Millisecond
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	fixture.notifier.NotifyReturnsOnCall(0, &telemetry.WebhookStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 20 * time.Second})
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	require.Eventually(t, func() bool {
+		return clock.pending() == 1
+	}, time.Second, time.Millisecond)
+
+	// the backoff alone would have retried by now
+	clock.Advance(20*time.Second - time.Millisecond)
+	require.Equal(t, 1, clock.pending())
+	advanceRetries(t, clock, 1, time.Millisecond)
+
+	fixture.drain(t)
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+}
+
+func Test_NotifyEvent_IgnoresRetryAfterAboveMax(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 1
+	conf.WebHook.RetryBaseDelay = time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	fixture.notifier.NotifyReturnsOnCall(0, &telemetry.WebhookStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour})
+
+	fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+
+	// falls back to exponential backoff instead of waiting an hour
+	advanceRetries(t, clock, 1, time.Minute)
+
+	fixture.drain(t)
+	require.Equal(t, 2, fixture.notifier.NotifyCallCount())
+}
+
+func Test_URLNotifier_CustomHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:       server.URL,
+		APIKey:    "key",
+		APISecret: "secret",
+		Headers: map[string]string{
+			"x-api-key":    "gateway-key",
+			"X-Room":       "tenant/{room_name}/{event}",
+			"Content-Type": "text/plain",
+		},
+	})
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Room:  &livekit.Room{Name: "my\r\nroom"},
+	}))
+
+	require.Equal(t, "gateway-key", header.Get("X-Api-Key"))
+	require.Equal(t, "tenant/myroom/room_started", header.Get("X-Room"))
+	// reserved headers can't be overridden
+	require.Equal(t, "application/webhook+json", header.Get("Content-Type"))
+	require.NotEmpty(t, header.Get("Authorization"))
+}
+
+func Test_NotifyEvent_RecordsDeliveryLatency(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 1
+	conf.WebHook.RetryBaseDelay = time.Second
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+	fixture.notifier.NotifyCalls(func(_ context.Context, event *livekit.WebhookEvent) error {
+		switch event.Room.GetSid() {
+		case "failed":
+			return errors.New("failed")
+		case "timed_out":
+			return context.DeadlineExceeded
+		}
+		return nil
+	})
+
+	deliveries := func(outcome string) uint64 {
+		labels := map[string]string{"event": webhook.EventRoomStarted, "outcome": outcome}
+		if metric := findMetric(t, "livekit_webhook_delivery_latency_seconds", labels); metric != nil {
+			return metric.GetHistogram().GetSampleCount()
+		}
+		return 0
+	}
+	successBefore, failureBefore, timeoutBefore := deliveries("success"), deliveries("failure"), deliveries("timeout")
+
+	for _, sid := range []string{"delivered", "failed", "timed_out"} {
+		fixture.sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Room:  &livekit.Room{Sid: sid},
+		})
+	}
+	advanceRetries(t, clock, 2, time.Minute)
+	require.NoError(t, fixture.sut.Shutdown(context.Background()))
+
+	// a delivery is recorded once, however many attempts it took
+	require.Equal(t, successBefore+1, deliveries("success"))
+	require.Equal(t, failureBefore+1, deliveries("failure"))
+	require.Equal(t, timeoutBefore+1, deliveries("timeout"))
+}
+
+func Test_URLNotifier_CompressesLargePayloads(t *testing.T) {
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	type received struct {
+		encoding string
+		event    *livekit.WebhookEvent
+		signed   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		// the signature and auth token cover the body as sent
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		signed, err := telemetry.VerifyWebhookSignature(r, "signing-key")
+		require.NoError(t, err)
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		data, err := webhook.Receive(r, provider)
+		require.NoError(t, err)
+		data, err = telemetry.DecompressWebhookPayload(encoding, data)
+		require.NoError(t, err)
+		require.Equal(t, signed, data)
+		event := &livekit.WebhookEvent{}
+		require.NoError(t, protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, event))
+
+		requests <- received{encoding: encoding, event: event, signed: signed}
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:        server.URL,
+		APIKey:     "key",
+		APISecret:  "secret",
+		SigningKey: "signing-key",
+		Compress:   true,
+	})
+
+	// below the threshold, sent as is
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted, Id: "EV_small"}))
+	small := <-requests
+	require.Empty(t, small.encoding)
+	require.Equal(t, "EV_small", small.event.Id)
+
+	metadata := strings.Repeat("participant ", 200)
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomFinished,
+		Id:    "EV_large",
+		Room:  &livekit.Room{Metadata: metadata},
+	}))
+	large := <-requests
+	require.Equal(t, "gzip", large.encoding)
+	require.Equal(t, "EV_large", large.event.Id)
+	require.Equal(t, metadata, large.event.Room.Metadata)
+	require.Contains(t, string(large.signed), "EV_large")
+}
+
+func Test_DecompressWebhookPayload_IsBounded(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(make([]byte, 17<<20))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	_, err = telemetry.DecompressWebhookPayload("gzip", buf.Bytes())
+	require.ErrorIs(t, err, telemetry.ErrWebhookPayloadTooLarge)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(buf.Bytes()))
+	r.Header.Set("Content-Encoding", "gzip")
+	require.ErrorIs(t, telemetry.DecompressWebhookRequest(r), telemetry.ErrWebhookPayloadTooLarge)
+}
+
+func Test_URLNotifier_EncodesProtobuf(t *testing.T) {
+	type received struct {
+		contentType string
+		event       *livekit.WebhookEvent
+		tokenSha    string
+		bodySha     string
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := auth.ParseAPIToken(r.Header.Get("Authorization"))
+		require.NoError(t, err)
+		grants, err := claims.Verify("secret")
+		require.NoError(t, err)
+
+		// the signature covers the protobuf body that was sent
+		data, err := telemetry.VerifyWebhookSignature(r, "signing-key")
+		require.NoError(t, err)
+		sum := sha256.Sum256(data)
+
+		event := &livekit.WebhookEvent{}
+		require.NoError(t, proto.Unmarshal(data, event))
+		requests <- received{
+			contentType: r.Header.Get("Content-Type"),
+			event:       event,
+			tokenSha:    grants.Sha256,
+			bodySha:     base64.StdEncoding.EncodeToString(sum[:]),
+		}
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:        server.URL,
+		APIKey:     "key",
+		APISecret:  "secret",
+		SigningKey: "signing-key",
+		Encoding:   telemetry.WebhookEncodingProtobuf,
+	})
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{
+		Event: webhook.EventRoomStarted,
+		Id:    "EV_proto",
+		Room:  &livekit.Room{Name: "RoomName"},
+	}))
+
+	req := <-requests
+	require.Equal(t, telemetry.WebhookContentTypeProtobuf, req.contentType)
+	require.Equal(t, "EV_proto", req.event.Id)
+	require.Equal(t, "RoomName", req.event.Room.GetName())
+	require.Equal(t, req.bodySha, req.tokenSha)
+}
+
+func Test_URLNotifier_EncodesForm(t *testing.T) {
+	type received struct {
+		contentType string
+		form        url.Values
+		tokenSha    string
+		bodySha     string
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := auth.ParseAPIToken(r.Header.Get("Authorization"))
+		require.NoError(t, err)
+		grants, err := claims.Verify("secret")
+		require.NoError(t, err)
+
+		// the signature covers the whole form that was sent
+		data, err := telemetry.VerifyWebhookSignature(r, "signing-key")
+		require.NoError(t, err)
+		sum := sha256.Sum256(data)
+
+		form, err := url.ParseQuery(string(data))
+		require.NoError(t, err)
+		requests <- received{
+			contentType: r.Header.Get("Content-Type"),
+			form:        form,
+			tokenSha:    grants.Sha256,
+			bodySha:     base64.StdEncoding.EncodeToString(sum[:]),
+		}
+	}))
+	defer server.Close()
+
+	notify := func(formField string) received {
+		notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+			URL:        server.URL,
+			APIKey:     "key",
+			APISecret:  "secret",
+			SigningKey: "signing-key",
+			Encoding:   telemetry.WebhookEncodingForm,
+			FormField:  formField,
+		})
+		require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{
+			Event: webhook.EventRoomStarted,
+			Id:    "EV_form",
+			Room:  &livekit.Room{Name: "Room & Name"},
+		}))
+		return <-requests
+	}
+
+	req := notify("data")
+	require.Equal(t, telemetry.WebhookContentTypeForm, req.contentType)
+	require.Equal(t, req.bodySha, req.tokenSha)
+	require.Len(t, req.form, 1)
+	event := &livekit.WebhookEvent{}
+	require.NoError(t, protojson.Unmarshal([]byte(req.form.Get("data")), event))
+	require.Equal(t, "EV_form", event.Id)
+	require.Equal(t, "Room & Name", event.Room.GetName())
+
+	req = notify("")
+	require.NotEmpty(t, req.form.Get("payload"))
+}
+
+func Test_URLNotifier_APIVersion(t *testing.T) {
+	type received struct {
+		version string
+		event   *livekit.WebhookEvent
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the version is covered by the signature
+		data, err := telemetry.VerifyWebhookSignature(r, "signing-key")
+		require.NoError(t, err)
+		version, err := telemetry.WebhookPayloadAPIVersion(data, r.Header.Get("Content-Type"))
+		require.NoError(t, err)
+
+		event := &livekit.WebhookEvent{}
+		if r.Header.Get("Content-Type") == telemetry.WebhookContentTypeProtobuf {
+			require.NoError(t, proto.Unmarshal(data, event))
+		} else {
+			require.NoError(t, protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, event))
+		}
+		requests <- received{version: version, event: event}
+	}))
+	defer server.Close()
+
+	for _, encoding := range []string{telemetry.WebhookEncodingJSON, telemetry.WebhookEncodingProtobuf} {
+		for _, tc := range []struct {
+			apiVersion string
+			expected   string
+		}{
+			{apiVersion: "", expected: telemetry.WebhookAPIVersionV1},
+			{apiVersion: telemetry.WebhookAPIVersionV1, expected: telemetry.WebhookAPIVersionV1},
+			{apiVersion: telemetry.WebhookAPIVersionV2, expected: telemetry.WebhookAPIVersionV2},
+			{apiVersion: "v9", expected: telemetry.WebhookAPIVersionV1},
+		} {
+			t.Run(encoding+"/"+tc.apiVersion, func(t *testing.T) {
+				notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+					URL:        server.URL,
+					APIKey:     "key",
+					APISecret:  "secret",
+					SigningKey: "signing-key",
+					Encoding:   encoding,
+					APIVersion: tc.apiVersion,
+				})
+				require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{
+					Event: webhook.EventRoomStarted,
+					Id:    "EV_versioned",
+					Room:  &livekit.Room{Name: "RoomName"},
+				}))
+
+				req := <-requests
+				require.Equal(t, tc.expected, req.version)
+				require.Equal(t, "EV_versioned", req.event.Id)
+				require.Equal(t, "RoomName", req.event.Room.GetName())
+			})
+		}
+	}
+}
+
+func Test_URLNotifier_APIVersionV1IsUnversioned(t *testing.T) {
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		// receivers that predate versioning still accept it
+		_, err = webhook.ReceiveWebhookEvent(r, provider)
+		require.NoError(t, err)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:        server.URL,
+		APIKey:     "key",
+		APISecret:  "secret",
+		APIVersion: telemetry.WebhookAPIVersionV1,
+	})
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted}))
+	require.NotContains(t, string(<-bodies), "apiVersion")
+}
+
+func Test_NotifyEvent_RedactsFields(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.Redactions = []config.WebHookRedactionConfig{
+		{Fields: []string{"participant.metadata", "unknown.field"}},
+		{Fields: []string{"participant.name"}, Events: []string{webhook.EventParticipantJoined}, Endpoints: []string{"notifier_1"}, Hash: true},
+	}
+	first := &telemetryfakes.FakeWebhookNotifier{}
+	second := &telemetryfakes.FakeWebhookNotifier{}
+	sut := telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{first, second}, &telemetryfakes.FakeAnalyticsService{})
+	listened := make(chan *livekit.WebhookEvent, 2)
+	sut.Subscribe(func(event *livekit.WebhookEvent) {
+		listened <- event
+	})
+
+	participant := &livekit.ParticipantInfo{Sid: "PA_redacted", Name: "Jane Doe", Metadata: `{"email":"jane@example.com"}`}
+	joined := &livekit.WebhookEvent{Event: webhook.EventParticipantJoined, Participant: participant}
+	sut.NotifyEvent(context.Background(), joined)
+	// the name is only redacted from participant_joined
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventParticipantLeft, Participant: participant})
+	require.NoError(t, sut.Shutdown(context.Background()))
+
+	require.Equal(t, 2, first.NotifyCallCount())
+	require.Equal(t, 2, second.NotifyCallCount())
+	_, event := first.NotifyArgsForCall(0)
+	require.Empty(t, event.Participant.Metadata)
+	require.Equal(t, "Jane Doe", event.Participant.Name)
+	_, event = second.NotifyArgsForCall(0)
+	require.Empty(t, event.Participant.Metadata)
+	hash := sha256.Sum256([]byte("Jane Doe"))
+	require.Equal(t, hex.EncodeToString(hash[:]), event.Participant.Name)
+	require.Equal(t, participant.Sid, event.Participant.Sid)
+
+	// the event itself, and what listeners see, is left as it is
+	require.Equal(t, `{"email":"jane@example.com"}`, joined.Participant.Metadata)
+	require.Equal(t, "Jane Doe", joined.Participant.Name)
+	require.Equal(t, "Jane Doe", (<-listened).Participant.Name)
+
+	_, event = second.NotifyArgsForCall(1)
+	require.Empty(t, event.Participant.Metadata)
+	require.Equal(t, "Jane Doe", event.Participant.Name)
+}
+
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func Test_URLNotifier_Transport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:       server.URL,
+		APIKey:    "key",
+		APISecret: "secret",
+		Transport: transport,
+	})
+	require.NoError(t, notifier.Notify(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted}))
+	require.EqualValues(t, 1, transport.requests.Load())
+}
+
+func Test_NotifyEvent_WebhookTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	shared := &countingTransport{}
+	own := &countingTransport{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret"}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Transport: own}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithWebhookTransport(shared),
+	)
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.EqualValues(t, 1, shared.requests.Load())
+	require.EqualValues(t, 1, own.requests.Load())
+}
+
+func Test_NewWebhookTransport(t *testing.T) {
+	transport := telemetry.NewWebhookTransport()
+	require.NotNil(t, transport.Proxy)
+	require.NotNil(t, transport.DialContext)
+	require.Greater(t, transport.MaxIdleConnsPerHost, http.DefaultMaxIdleConnsPerHost)
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// audit records waiting to be logged, beyond which they are dropped rather than holding up deliveries
+const webhookAuditQueueSize = 1000
+
+// WebhookAuditRecord describes a single attempt to deliver a webhook event to an endpoint
+type WebhookAuditRecord struct {
+	// when the attempt started, and how long it took
+	Time     time.Time
+	Duration time.Duration
+	EventID  string
+	Event    string
+	Endpoint string
+	// 1 for the first attempt, counting up with every retry
+	Attempt int
+	// success, failure or timeout
+	Outcome string
+	// the status the endpoint rejected the event with, 0 when it accepted it or no response was received
+	StatusCode int
+	// nil on success
+	Err error
+}
+
+// AuditLogger records every webhook delivery attempt, see WithAuditLogger
+type AuditLogger interface {
+	LogWebhookAttempt(record WebhookAuditRecord)
+}
+
+// AuditLoggerFunc adapts a function to an AuditLogger
+type AuditLoggerFunc func(record WebhookAuditRecord)
+
+func (f AuditLoggerFunc) LogWebhookAttempt(record WebhookAuditRecord) {
+	f(record)
+}
+
+type webhookAttemptKey struct{}
+
+// withWebhookAttempt returns ctx carrying the number of the delivery attempt, starting from 1
+func withWebhookAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, webhookAttemptKey{}, attempt)
+}
+
+// webhookAttempt returns the number of the delivery attempt in ctx, 1 when not set
+func webhookAttempt(ctx context.Context) int {
+	if attempt, ok := ctx.Value(webhookAttemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// webhookAuditor hands audit records to the audit logger on a goroutine of its own, so that a slow logger never
+// holds up deliveries. records are dropped, and counted, while its queue is full
+type webhookAuditor struct {
+	auditLogger AuditLogger
+	records     chan WebhookAuditRecord
+	done        chan struct{}
+
+	lock   sync.RWMutex
+	closed bool
+}
+
+func newWebhookAuditor(auditLogger AuditLogger) *webhookAuditor {
+	a := &webhookAuditor{
+		auditLogger: auditLogger,
+		records:     make(chan WebhookAuditRecord, webhookAuditQueueSize),
+		done:        make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *webhookAuditor) run() {
+	defer close(a.done)
+	for record := range a.records {
+		a.log(record)
+	}
+}
+
+func (a *webhookAuditor) log(record WebhookAuditRecord) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorw("webhook audit logger panicked", fmt.Errorf("%v", r), "endpoint", record.Endpoint, "event", record.Event)
+		}
+	}()
+
+	a.auditLogger.LogWebhookAttempt(record)
+}
+
+func (a *webhookAuditor) record(record WebhookAuditRecord) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if a.closed {
+		return
+	}
+
+	select {
+	case a.records <- record:
+	default:
+		prometheus.RecordWebhookAuditDropped(record.Endpoint)
+	}
+}
+
+// close logs the records still queued, until ctx is done. records after close are dropped
+func (a *webhookAuditor) close(ctx context.Context) {
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return
+	}
+	a.closed = true
+	close(a.records)
+	a.lock.Unlock()
+
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+		logger.Warnw("telemetry shutdown timed out, abandoning webhook audit records", ctx.Err(), "dropped", len(a.records))
+	}
+}
+
+// auditMiddleware hands a record of each delivery attempt to endpoint to the auditor, timed on clock
+func auditMiddleware(clock Clock, endpoint string, auditor *webhookAuditor) NotifierMiddleware {
+	return func(next WebhookNotifier) WebhookNotifier {
+		return WebhookNotifierFunc(func(ctx context.Context, event *livekit.WebhookEvent) error {
+			startedAt := clock.Now()
+			err := next.Notify(ctx, event)
+
+			record := WebhookAuditRecord{
+				Time:     startedAt,
+				Duration: clock.Now().Sub(startedAt),
+				EventID:  event.Id,
+				Event:    event.Event,
+				Endpoint: endpoint,
+				Attempt:  webhookAttempt(ctx),
+				Outcome:  webhookOutcome(err),
+				Err:      err,
+			}
+			var statusErr *WebhookStatusError
+			if errors.As(err, &statusErr) {
+				record.StatusCode = statusErr.StatusCode
+			}
+			auditor.record(record)
+			return err
+		})
+	}
+}
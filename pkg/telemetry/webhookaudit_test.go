@@ -0,0 +1,105 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func Test_NotifyEvent_AuditsEveryAttempt(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.MaxRetries = 1
+	conf.WebHook.RetryBaseDelay = time.Second
+
+	clock := newFakeClock()
+	notifier := namedNotifier{FakeWebhookNotifier: &telemetryfakes.FakeWebhookNotifier{}, name: "audited"}
+	notifier.NotifyReturnsOnCall(0, &telemetry.WebhookStatusError{StatusCode: http.StatusServiceUnavailable})
+	notifier.NotifyReturnsOnCall(1, nil)
+
+	var lock sync.Mutex
+	var records []telemetry.WebhookAuditRecord
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithAuditLogger(telemetry.AuditLoggerFunc(func(record telemetry.WebhookAuditRecord) {
+			lock.Lock()
+			defer lock.Unlock()
+			records = append(records, record)
+		})),
+		telemetry.WithClock(clock),
+	)
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	advanceRetries(t, clock, 1, time.Minute)
+	require.NoError(t, sut.Shutdown(context.Background()))
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, records, 2)
+	_, event := notifier.NotifyArgsForCall(0)
+	for i, record := range records {
+		require.Equal(t, event.Id, record.EventID)
+		require.Equal(t, webhook.EventRoomStarted, record.Event)
+		require.Equal(t, "audited", record.Endpoint)
+		require.Equal(t, i+1, record.Attempt)
+		require.False(t, record.Time.IsZero())
+	}
+	require.Equal(t, "failure", records[0].Outcome)
+	require.Equal(t, http.StatusServiceUnavailable, records[0].StatusCode)
+	require.Error(t, records[0].Err)
+	require.Equal(t, "success", records[1].Outcome)
+	require.Zero(t, records[1].StatusCode)
+	require.NoError(t, records[1].Err)
+}
+
+func Test_NotifyEvent_AuditDoesNotBlockDelivery(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.Workers = 1
+
+	release := make(chan struct{})
+	defer close(release)
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithAuditLogger(telemetry.AuditLoggerFunc(func(record telemetry.WebhookAuditRecord) {
+			<-release
+		})),
+	)
+
+	for i := 0; i < 5; i++ {
+		sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	}
+	require.Eventually(t, func() bool {
+		return notifier.NotifyCallCount() == 5
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// what happens to webhook events for an endpoint while its circuit breaker is open
+const (
+	// hand them to the dead letter sink, to be replayed once the endpoint is back
+	WebhookBreakerDeadLetter = "dead_letter"
+	// drop them
+	WebhookBreakerDrop = "drop"
+)
+
+const defaultWebhookBreakerCooldown = 30 * time.Second
+
+// states of a webhook circuit breaker, the values are reported in livekit_webhook_breaker_state
+const (
+	webhookBreakerClosed = iota
+	webhookBreakerOpen
+	webhookBreakerHalfOpen
+)
+
+// webhookBreaker stops deliveries to an endpoint that is down, so they don't tie up its workers with retries.
+// it opens after a number of deliveries in a row fail, once their retries are exhausted, and lets a single
+// delivery through to probe the endpoint once the cooldown has passed. the breaker closes when the probe succeeds
+// and opens for another cooldown when it fails
+type webhookBreaker struct {
+	endpoint string
+	failures int
+	cooldown time.Duration
+
+	lock        sync.Mutex
+	state       int
+	consecutive int
+	openedAt    time.Time
+}
+
+func newWebhookBreaker(endpoint string, failures int, cooldown time.Duration) *webhookBreaker {
+	b := &webhookBreaker{
+		endpoint: endpoint,
+		failures: failures,
+		cooldown: cooldown,
+	}
+	prometheus.RecordWebhookBreakerState(endpoint, webhookBreakerClosed)
+	return b
+}
+
+// allow returns true when a delivery may be attempted, false while the breaker is open or another delivery is
+// probing the endpoint. the result of an allowed delivery must be passed to record
+func (b *webhookBreaker) allow(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case webhookBreakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setStateLocked(webhookBreakerHalfOpen)
+		return true
+	case webhookBreakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the result of a delivery, after its retries
+func (b *webhookBreaker) record(err error, now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err == nil {
+		b.consecutive = 0
+		if b.state != webhookBreakerClosed {
+			logger.Infow("webhook endpoint recovered, closing circuit breaker", "endpoint", b.endpoint)
+			b.setStateLocked(webhookBreakerClosed)
+		}
+		return
+	}
+
+	b.consecutive++
+	if b.state == webhookBreakerHalfOpen || (b.state == webhookBreakerClosed && b.consecutive >= b.failures) {
+		logger.Warnw("webhook endpoint failing, opening circuit breaker", err,
+			"endpoint", b.endpoint,
+			"consecutiveFailures", b.consecutive,
+			"cooldown", b.cooldown,
+		)
+		b.openedAt = now
+		b.setStateLocked(webhookBreakerOpen)
+	}
+}
+
+func (b *webhookBreaker) setStateLocked(state int) {
+	b.state = state
+	prometheus.RecordWebhookBreakerState(b.endpoint, state)
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+// namedNotifier labels the metrics of a fake notifier, so they're not shared with other tests
+type namedNotifier struct {
+	*telemetryfakes.FakeWebhookNotifier
+	name string
+}
+
+func (n namedNotifier) Name() string {
+	return n.name
+}
+
+func Test_NotifyEvent_CircuitBreaker(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.MaxRetries = 0
+	conf.WebHook.Workers = 1
+	conf.WebHook.BreakerFailures = 2
+	conf.WebHook.BreakerCooldown = time.Minute
+
+	clock := newFakeClock()
+	notifier := namedNotifier{FakeWebhookNotifier: &telemetryfakes.FakeWebhookNotifier{}, name: "breaker"}
+	notifier.NotifyReturns(errors.New("connection refused"))
+	sink := &telemetryfakes.FakeDeadLetterSink{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+		telemetry.WithClock(clock),
+	)
+	labels := map[string]string{"endpoint": "breaker"}
+	breakerState := func() float64 {
+		return findMetric(t, "livekit_webhook_breaker_state", labels).GetGauge().GetValue()
+	}
+	shortCircuited := func() float64 {
+		return findMetric(t, "livekit_webhook_short_circuited_total", labels).GetCounter().GetValue()
+	}
+	require.Zero(t, breakerState())
+	before := shortCircuited()
+
+	// opens after two failures in a row, the third event is dead-lettered without an attempt
+	for i := 0; i < 3; i++ {
+		sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	}
+	require.Eventually(t, func() bool {
+		return sink.StoreCallCount() == 3
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 2, notifier.NotifyCallCount())
+	require.Equal(t, float64(1), breakerState())
+	require.Equal(t, before+1, shortCircuited())
+
+	// after the cooldown a delivery probes the endpoint, which has recovered
+	clock.Advance(conf.WebHook.BreakerCooldown)
+	notifier.NotifyReturns(nil)
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomFinished})
+
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, 4, notifier.NotifyCallCount())
+	require.Zero(t, breakerState())
+	require.Equal(t, 3, sink.StoreCallCount())
+}
+
+func Test_NotifyEvent_CircuitBreakerDrops(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.MaxRetries = 0
+	conf.WebHook.Workers = 1
+	conf.WebHook.BreakerFailures = 1
+	conf.WebHook.BreakerCooldown = time.Hour
+	conf.WebHook.BreakerPolicy = telemetry.WebhookBreakerDrop
+
+	notifier := namedNotifier{FakeWebhookNotifier: &telemetryfakes.FakeWebhookNotifier{}, name: "breaker_drop"}
+	notifier.NotifyReturns(errors.New("connection refused"))
+	sink := &telemetryfakes.FakeDeadLetterSink{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+	)
+
+	shortCircuited := func() float64 {
+		return findMetric(t, "livekit_webhook_short_circuited_total", map[string]string{"endpoint": "breaker_drop"}).GetCounter().GetValue()
+	}
+	before := shortCircuited()
+
+	for i := 0; i < 3; i++ {
+		sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	}
+	require.NoError(t, sut.Shutdown(context.Background()))
+	require.Equal(t, before+2, shortCircuited())
+	require.Equal(t, 1, notifier.NotifyCallCount())
+	// only the failed delivery is dead-lettered
+	require.Equal(t, 1, sink.StoreCallCount())
+}
+
+func Test_ReplayDeadLetters_CircuitBreaker(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 0
+	conf.WebHook.BreakerFailures = 1
+	conf.WebHook.BreakerCooldown = time.Hour
+
+	notifier := namedNotifier{FakeWebhookNotifier: &telemetryfakes.FakeWebhookNotifier{}, name: "breaker_replay"}
+	notifier.NotifyReturns(errors.New("connection refused"))
+	sink := &memoryDeadLetters{}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{notifier},
+		&telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithDeadLetterSink(sink),
+	)
+	shortCircuited := func() float64 {
+		return findMetric(t, "livekit_webhook_short_circuited_total", map[string]string{"endpoint": "breaker_replay"}).GetCounter().GetValue()
+	}
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	require.Eventually(t, func() bool {
+		return len(sink.stored()) == 1
+	}, time.Second, 10*time.Millisecond)
+	before := shortCircuited()
+
+	// the breaker opened on the failure, so the replay isn't attempted and the event is kept
+	result, err := sut.ReplayDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, telemetry.DeadLetterReplayResult{Failed: 1}, result)
+	require.Equal(t, 1, notifier.NotifyCallCount())
+	require.Equal(t, before+1, shortCircuited())
+	require.Len(t, sink.stored(), 1)
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+)
+
+// lifecycle events happen at most once for the room, participant, track, egress or ingress they are about,
+// so sending one again within the dedup window is a duplicate. events reporting a change of state are never deduplicated
+var dedupEvents = map[string]struct{}{
+	EventRoomReserved:              {},
+	webhook.EventRoomStarted:       {},
+	webhook.EventRoomFinished:      {},
+	webhook.EventParticipantJoined: {},
+	webhook.EventParticipantLeft:   {},
+	EventParticipantMediaActive:    {},
+	webhook.EventTrackPublished:    {},
+	webhook.EventTrackUnpublished:  {},
+	webhook.EventEgressStarted:     {},
+	webhook.EventEgressEnded:       {},
+	EventEgressFailed:              {},
+	webhook.EventIngressStarted:    {},
+	webhook.EventIngressEnded:      {},
+}
+
+type webhookDedupKey struct {
+	event         string
+	roomID        string
+	participantID string
+	trackID       string
+	egressID      string
+	ingressID     string
+	// ingresses are reused, each session has its own resource
+	ingressResourceID string
+}
+
+// webhookDedup remembers recently sent lifecycle events to suppress exact duplicates within a window
+type webhookDedup struct {
+	window time.Duration
+
+	lock sync.Mutex
+	seen map[webhookDedupKey]time.Time
+}
+
+func newWebhookDedup(window time.Duration) *webhookDedup {
+	return &webhookDedup{
+		window: window,
+		seen:   make(map[webhookDedupKey]time.Time),
+	}
+}
+
+// isDuplicate returns true if the same event was seen within the window, otherwise records it as seen at now
+func (d *webhookDedup) isDuplicate(event *livekit.WebhookEvent, now time.Time) bool {
+	if _, ok := dedupEvents[event.Event]; !ok {
+		return false
+	}
+	if event.IngressInfo != nil && event.IngressInfo.GetState().GetResourceId() == "" {
+		// the sessions of an ingress can't be told apart without their resource
+		return false
+	}
+
+	key := webhookDedupKey{
+		event:             event.Event,
+		roomID:            event.Room.GetSid(),
+		participantID:     event.Participant.GetSid(),
+		trackID:           event.Track.GetSid(),
+		egressID:          event.EgressInfo.GetEgressId(),
+		ingressID:         event.IngressInfo.GetIngressId(),
+		ingressResourceID: event.IngressInfo.GetState().GetResourceId(),
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if seenAt, ok := d.seen[key]; ok && now.Sub(seenAt) < d.window {
+		return true
+	}
+	d.seen[key] = now
+	return false
+}
+
+// prune forgets events that are outside the window
+func (d *webhookDedup) prune(now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for key, seenAt := range d.seen {
+		if now.Sub(seenAt) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+}
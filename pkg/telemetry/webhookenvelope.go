@@ -0,0 +1,329 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// livekit.WebhookEvent has no field for the client a participant joined from, see WebHookConfig.ParticipantClientInfo,
+// nor for the state an event changed, see WebHookConfig.EventDetails. notifiers that send either wrap every event in
+// an envelope, with the details alongside the event rather than in it:
+//
+//	message WebhookEnvelope {
+//	  WebhookEvent event = 1;
+//	  // set on participant_joined events only
+//	  ClientInfo client_info = 2;
+//	  // set on room_metadata_changed events only
+//	  string prev_room_metadata = 3;
+//	  // set on participant_attributes_changed and participant_updated events only
+//	  repeated string changed_fields = 4;
+//	  // set on participant_updated events only
+//	  ParticipantPermission prev_permission = 5;
+//	  // set on active_speaker_changed events only
+//	  repeated SpeakerInfo speakers = 6;
+//	  // set on participant_left events only
+//	  DisconnectReason disconnect_reason = 7;
+//	  // set on track_muted and track_unmuted events only, one of the TrackMuteSource values
+//	  string mute_source = 8;
+//	}
+//
+// JSON bodies are {"event": {...}, "clientInfo": {...}, "prevRoomMetadata": "...", "changedFields": [...],
+// "prevPermission": {...}, "speakers": [...], "disconnectReason": "...", "muteSource": "..."}, without the details
+// an event has none of. an API version other than v1 is added to the envelope, not the event, see
+// WebhookPayloadAPIVersion.
+// receivers read envelopes with ParseWebhookEnvelope
+const (
+	webhookEnvelopeEventField            protowire.Number = 1
+	webhookEnvelopeClientInfoField       protowire.Number = 2
+	webhookEnvelopePrevRoomMetadataField protowire.Number = 3
+	webhookEnvelopeChangedFieldsField    protowire.Number = 4
+	webhookEnvelopePrevPermissionField   protowire.Number = 5
+	webhookEnvelopeSpeakersField         protowire.Number = 6
+	webhookEnvelopeDisconnectReasonField protowire.Number = 7
+	webhookEnvelopeMuteSourceField       protowire.Number = 8
+)
+
+// WebhookDetails are delivered along with an event, in its WebhookEnvelope
+type WebhookDetails struct {
+	// the client of the participant of a participant_joined event, nil for other events
+	ClientInfo *livekit.ClientInfo
+	// the room metadata a room_metadata_changed event replaced, empty for other events
+	PrevRoomMetadata string
+	// the fields a participant_attributes_changed event changed, named as in livekit.ParticipantInfo, or those a
+	// participant_updated event changed, named as in livekit.ParticipantPermission. empty for other events
+	ChangedFields []string
+	// the permissions a participant_updated event replaced, nil for other events and when there were none
+	PrevPermission *livekit.ParticipantPermission
+	// the active speakers of an active_speaker_changed event with their audio levels, loudest first. empty for
+	// other events and when nobody is speaking
+	Speakers []*livekit.SpeakerInfo
+	// why the participant of a participant_left event left, UNKNOWN_REASON for other events
+	DisconnectReason livekit.DisconnectReason
+	// who muted or unmuted the track of a track_muted or track_unmuted event, one of the TrackMuteSource values.
+	// empty for other events
+	MuteSource string
+}
+
+// clone returns a copy of d sharing its fields, which are copied again before being redacted
+func (d *WebhookDetails) clone() *WebhookDetails {
+	c := *d
+	return &c
+}
+
+// WebhookEnvelope is a webhook payload sent by a notifier with URLNotifierParams.Envelope set
+type WebhookEnvelope struct {
+	Event *livekit.WebhookEvent
+	WebhookDetails
+}
+
+type webhookEnvelopeJSON struct {
+	Event            json.RawMessage   `json:"event"`
+	ClientInfo       json.RawMessage   `json:"clientInfo,omitempty"`
+	PrevRoomMetadata string            `json:"prevRoomMetadata,omitempty"`
+	ChangedFields    []string          `json:"changedFields,omitempty"`
+	PrevPermission   json.RawMessage   `json:"prevPermission,omitempty"`
+	Speakers         []json.RawMessage `json:"speakers,omitempty"`
+	DisconnectReason string            `json:"disconnectReason,omitempty"`
+	MuteSource       string            `json:"muteSource,omitempty"`
+}
+
+type webhookDetailsKey struct{}
+
+// withWebhookDetails returns ctx carrying the details delivered along with an event, as notifiers and their
+// middlewares are passed the event alone
+func withWebhookDetails(ctx context.Context, details *WebhookDetails) context.Context {
+	if details == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, webhookDetailsKey{}, details)
+}
+
+// webhookDetails returns the details delivered along with the event of ctx, nil when there are none
+func webhookDetails(ctx context.Context) *WebhookDetails {
+	details, _ := ctx.Value(webhookDetailsKey{}).(*WebhookDetails)
+	return details
+}
+
+// encodeWebhookEnvelope wraps an event, encoded with encoding, in an envelope with details
+func encodeWebhookEnvelope(encodedEvent []byte, details *WebhookDetails, encoding string) ([]byte, error) {
+	if details == nil {
+		details = &WebhookDetails{}
+	}
+
+	if encoding == WebhookEncodingProtobuf {
+		encoded := protowire.AppendTag(nil, webhookEnvelopeEventField, protowire.BytesType)
+		encoded = protowire.AppendBytes(encoded, encodedEvent)
+		if details.ClientInfo != nil {
+			encodedInfo, err := proto.Marshal(details.ClientInfo)
+			if err != nil {
+				return nil, err
+			}
+			encoded = protowire.AppendTag(encoded, webhookEnvelopeClientInfoField, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, encodedInfo)
+		}
+		if details.PrevRoomMetadata != "" {
+			encoded = protowire.AppendTag(encoded, webhookEnvelopePrevRoomMetadataField, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, details.PrevRoomMetadata)
+		}
+		for _, field := range details.ChangedFields {
+			encoded = protowire.AppendTag(encoded, webhookEnvelopeChangedFieldsField, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, field)
+		}
+		if details.PrevPermission != nil {
+			encodedPermission, err := proto.Marshal(details.PrevPermission)
+			if err != nil {
+				return nil, err
+			}
+			encoded = protowire.AppendTag(encoded, webhookEnvelopePrevPermissionField, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, encodedPermission)
+		}
+		for _, speaker := range details.Speakers {
+			encodedSpeaker, err := proto.Marshal(speaker)
+			if err != nil {
+				return nil, err
+			}
+			encoded = protowire.AppendTag(encoded, webhookEnvelopeSpeakersField, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, encodedSpeaker)
+		}
+		if details.DisconnectReason != livekit.DisconnectReason_UNKNOWN_REASON {
+			encoded = protowire.AppendTag(encoded, webhookEnvelopeDisconnectReasonField, protowire.VarintType)
+			encoded = protowire.AppendVarint(encoded, uint64(details.DisconnectReason))
+		}
+		if details.MuteSource != "" {
+			encoded = protowire.AppendTag(encoded, webhookEnvelopeMuteSourceField, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, details.MuteSource)
+		}
+		return encoded, nil
+	}
+
+	envelope := webhookEnvelopeJSON{
+		Event:            encodedEvent,
+		PrevRoomMetadata: details.PrevRoomMetadata,
+		ChangedFields:    details.ChangedFields,
+		MuteSource:       details.MuteSource,
+	}
+	if details.ClientInfo != nil {
+		encodedInfo, err := protojson.Marshal(details.ClientInfo)
+		if err != nil {
+			return nil, err
+		}
+		envelope.ClientInfo = encodedInfo
+	}
+	if details.PrevPermission != nil {
+		encodedPermission, err := protojson.Marshal(details.PrevPermission)
+		if err != nil {
+			return nil, err
+		}
+		envelope.PrevPermission = encodedPermission
+	}
+	for _, speaker := range details.Speakers {
+		encodedSpeaker, err := protojson.Marshal(speaker)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Speakers = append(envelope.Speakers, encodedSpeaker)
+	}
+	if details.DisconnectReason != livekit.DisconnectReason_UNKNOWN_REASON {
+		envelope.DisconnectReason = details.DisconnectReason.String()
+	}
+	return json.Marshal(envelope)
+}
+
+// ParseWebhookEnvelope parses the payload of a notifier sending envelopes, as sent with contentType, once its
+// signature has been verified. the payload of a form is the value of its field, sent as WebhookContentTypeJSON
+func ParseWebhookEnvelope(data []byte, contentType string) (*WebhookEnvelope, error) {
+	envelope := &WebhookEnvelope{Event: &livekit.WebhookEvent{}}
+	if contentType == WebhookContentTypeProtobuf {
+		for len(data) > 0 {
+			num, typ, n := protowire.ConsumeTag(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			if num == webhookEnvelopeDisconnectReasonField && typ == protowire.VarintType {
+				reason, n := protowire.ConsumeVarint(data)
+				if n < 0 {
+					return nil, protowire.ParseError(n)
+				}
+				data = data[n:]
+				envelope.DisconnectReason = livekit.DisconnectReason(reason)
+				continue
+			}
+			if typ != protowire.BytesType || !isWebhookEnvelopeField(num) {
+				// the API version and fields added later
+				n = protowire.ConsumeFieldValue(num, typ, data)
+				if n < 0 {
+					return nil, protowire.ParseError(n)
+				}
+				data = data[n:]
+				continue
+			}
+
+			encoded, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			switch num {
+			case webhookEnvelopeEventField:
+				if err := proto.Unmarshal(encoded, envelope.Event); err != nil {
+					return nil, err
+				}
+			case webhookEnvelopeClientInfoField:
+				envelope.ClientInfo = &livekit.ClientInfo{}
+				if err := proto.Unmarshal(encoded, envelope.ClientInfo); err != nil {
+					return nil, err
+				}
+			case webhookEnvelopePrevRoomMetadataField:
+				envelope.PrevRoomMetadata = string(encoded)
+			case webhookEnvelopeChangedFieldsField:
+				envelope.ChangedFields = append(envelope.ChangedFields, string(encoded))
+			case webhookEnvelopePrevPermissionField:
+				envelope.PrevPermission = &livekit.ParticipantPermission{}
+				if err := proto.Unmarshal(encoded, envelope.PrevPermission); err != nil {
+					return nil, err
+				}
+			case webhookEnvelopeSpeakersField:
+				speaker := &livekit.SpeakerInfo{}
+				if err := proto.Unmarshal(encoded, speaker); err != nil {
+					return nil, err
+				}
+				envelope.Speakers = append(envelope.Speakers, speaker)
+			case webhookEnvelopeMuteSourceField:
+				envelope.MuteSource = string(encoded)
+			}
+		}
+		return envelope, nil
+	}
+
+	var payload webhookEnvelopeJSON
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if len(payload.Event) != 0 {
+		if err := protojson.Unmarshal(payload.Event, envelope.Event); err != nil {
+			return nil, err
+		}
+	}
+	if len(payload.ClientInfo) != 0 {
+		envelope.ClientInfo = &livekit.ClientInfo{}
+		if err := protojson.Unmarshal(payload.ClientInfo, envelope.ClientInfo); err != nil {
+			return nil, err
+		}
+	}
+	if len(payload.PrevPermission) != 0 {
+		envelope.PrevPermission = &livekit.ParticipantPermission{}
+		if err := protojson.Unmarshal(payload.PrevPermission, envelope.PrevPermission); err != nil {
+			return nil, err
+		}
+	}
+	for _, encodedSpeaker := range payload.Speakers {
+		speaker := &livekit.SpeakerInfo{}
+		if err := protojson.Unmarshal(encodedSpeaker, speaker); err != nil {
+			return nil, err
+		}
+		envelope.Speakers = append(envelope.Speakers, speaker)
+	}
+	if payload.DisconnectReason != "" {
+		// unknown to this version of protocol when missing, as protojson would have it
+		envelope.DisconnectReason = livekit.DisconnectReason(livekit.DisconnectReason_value[payload.DisconnectReason])
+	}
+	envelope.PrevRoomMetadata = payload.PrevRoomMetadata
+	envelope.ChangedFields = payload.ChangedFields
+	envelope.MuteSource = payload.MuteSource
+	return envelope, nil
+}
+
+func isWebhookEnvelopeField(num protowire.Number) bool {
+	switch num {
+	case webhookEnvelopeEventField,
+		webhookEnvelopeClientInfoField,
+		webhookEnvelopePrevRoomMetadataField,
+		webhookEnvelopeChangedFieldsField,
+		webhookEnvelopePrevPermissionField,
+		webhookEnvelopeSpeakersField,
+		webhookEnvelopeMuteSourceField:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+type envelopeRequest struct {
+	contentType string
+	event       *livekit.WebhookEvent
+	details     telemetry.WebhookDetails
+	apiVersion  string
+}
+
+// newEnvelopeServer receives webhooks sent in a WebhookEnvelope when enveloped, plain events otherwise
+func newEnvelopeServer(t *testing.T, enveloped bool) (*httptest.Server, chan envelopeRequest) {
+	requests := make(chan envelopeRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		contentType := r.Header.Get("Content-Type")
+		req := envelopeRequest{contentType: contentType}
+		if enveloped {
+			envelope, err := telemetry.ParseWebhookEnvelope(data, contentType)
+			require.NoError(t, err)
+			req.event, req.details = envelope.Event, envelope.WebhookDetails
+			req.apiVersion, err = telemetry.WebhookPayloadAPIVersion(data, contentType)
+			require.NoError(t, err)
+		} else {
+			req.event = &livekit.WebhookEvent{}
+			require.NoError(t, protojson.Unmarshal(data, req.event))
+		}
+		requests <- req
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func joinWithClientInfo(sut telemetry.TelemetryService, clientInfo *livekit.ClientInfo) {
+	room := &livekit.Room{Sid: "RM_client", Name: "client"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_client", Identity: "client"}
+	sut.ParticipantJoined(context.Background(), room, participant, clientInfo, nil, true)
+	sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+}
+
+func Test_ParticipantJoined_WebhookCarriesClientInfo(t *testing.T) {
+	server, requests := newEnvelopeServer(t, true)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	conf.WebHook.ParticipantClientInfo = true
+	conf.WebHook.Redactions = []config.WebHookRedactionConfig{
+		{Fields: []string{"client_info.address"}},
+		{Fields: []string{"client_info.device_model"}, Hash: true},
+	}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Envelope: true}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:        server.URL,
+				APIKey:     "key",
+				APISecret:  "secret",
+				Encoding:   telemetry.WebhookEncodingProtobuf,
+				APIVersion: telemetry.WebhookAPIVersionV2,
+				Envelope:   true,
+			}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+	)
+
+	joinWithClientInfo(sut, &livekit.ClientInfo{
+		Sdk:         livekit.ClientInfo_JS,
+		Version:     "2.0.0",
+		Os:          "macOS",
+		DeviceModel: "MacBookPro18,1",
+		Browser:     "Chrome",
+		Address:     "203.0.113.7",
+		Network:     "wifi",
+	})
+
+	contentTypes := map[string]struct{}{}
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-requests:
+			contentTypes[req.contentType] = struct{}{}
+			if req.contentType == telemetry.WebhookContentTypeProtobuf {
+				require.Equal(t, telemetry.WebhookAPIVersionV2, req.apiVersion)
+			}
+			require.Equal(t, webhook.EventParticipantJoined, req.event.Event)
+			require.Equal(t, "PA_client", req.event.Participant.GetSid())
+			require.NotNil(t, req.details.ClientInfo)
+			require.Equal(t, livekit.ClientInfo_JS, req.details.ClientInfo.Sdk)
+			require.Equal(t, "2.0.0", req.details.ClientInfo.Version)
+			require.Equal(t, "macOS", req.details.ClientInfo.Os)
+			require.Equal(t, "Chrome", req.details.ClientInfo.Browser)
+			require.Equal(t, "wifi", req.details.ClientInfo.Network)
+			require.Empty(t, req.details.ClientInfo.Address)
+			require.Len(t, req.details.ClientInfo.DeviceModel, 64)
+		case <-time.After(time.Second):
+			require.Fail(t, "participant_joined not delivered")
+		}
+	}
+	require.Len(t, contentTypes, 2)
+}
+
+func Test_ParticipantJoined_WebhookOmitsClientInfoByDefault(t *testing.T) {
+	server, requests := newEnvelopeServer(t, false)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.DedupWindow = 0
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret"}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+	)
+
+	joinWithClientInfo(sut, &livekit.ClientInfo{Sdk: livekit.ClientInfo_JS, Address: "203.0.113.7"})
+
+	select {
+	case req := <-requests:
+		// a plain event, not an envelope
+		require.Equal(t, webhook.EventParticipantJoined, req.event.Event)
+		require.Equal(t, "PA_client", req.event.Participant.GetSid())
+	case <-time.After(time.Second):
+		require.Fail(t, "participant_joined not delivered")
+	}
+}
+
+func Test_RoomMetadataChanged_WebhookCarriesPrevMetadata(t *testing.T) {
+	server, requests := newEnvelopeServer(t, true)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.EventDetails = true
+	conf.WebHook.Redactions = []config.WebHookRedactionConfig{
+		{Fields: []string{"room.metadata"}, Endpoints: []string{"redacted"}, Hash: true},
+	}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Envelope: true}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				Name:      "redacted",
+				URL:       server.URL,
+				APIKey:    "key",
+				APISecret: "secret",
+				Encoding:  telemetry.WebhookEncodingProtobuf,
+				Envelope:  true,
+			}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+	)
+
+	room := &livekit.Room{Sid: "RM_metadata", Name: "metadata", Metadata: "new"}
+	sut.RoomMetadataChanged(context.Background(), room, "old")
+
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-requests:
+			require.Equal(t, telemetry.EventRoomMetadataChanged, req.event.Event)
+			if req.contentType == telemetry.WebhookContentTypeProtobuf {
+				// redacted as the metadata of the room
+				require.Len(t, req.event.Room.Metadata, 64)
+				require.Len(t, req.details.PrevRoomMetadata, 64)
+				require.NotEqual(t, req.event.Room.Metadata, req.details.PrevRoomMetadata)
+			} else {
+				require.Equal(t, "new", req.event.Room.Metadata)
+				require.Equal(t, "old", req.details.PrevRoomMetadata)
+			}
+			require.Nil(t, req.details.ClientInfo)
+		case <-time.After(time.Second):
+			require.Fail(t, "room_metadata_changed not delivered")
+		}
+	}
+}
+
+func Test_ParticipantAttributesChanged_WebhookCarriesChangedFields(t *testing.T) {
+	server, requests := newEnvelopeServer(t, true)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.EventDetails = true
+	conf.WebHook.IncludeEvents = []string{telemetry.EventParticipantAttributesChanged}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Envelope: true}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:       server.URL,
+				APIKey:    "key",
+				APISecret: "secret",
+				Encoding:  telemetry.WebhookEncodingProtobuf,
+				Envelope:  true,
+			}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+	)
+
+	room := &livekit.Room{Sid: "RM_attributes", Name: "attributes"}
+	prev := &livekit.ParticipantInfo{Sid: "PA_attributes", Identity: "alice", Name: "Alice", Metadata: "m1"}
+	sut.ParticipantAttributesChanged(context.Background(), room, &livekit.ParticipantInfo{
+		Sid: "PA_attributes", Identity: "alice", Name: "Alice B", Metadata: "m2",
+	}, prev)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-requests:
+			require.Equal(t, telemetry.EventParticipantAttributesChanged, req.event.Event)
+			require.Equal(t, []string{"name", "metadata"}, req.details.ChangedFields)
+		case <-time.After(time.Second):
+			require.Fail(t, "participant_attributes_changed not delivered")
+		}
+	}
+}
+
+func Test_ParticipantPermissionsChanged_WebhookCarriesPrevPermission(t *testing.T) {
+	server, requests := newEnvelopeServer(t, true)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.EventDetails = true
+	conf.WebHook.IncludeEvents = []string{telemetry.EventParticipantUpdated}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Envelope: true}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:       server.URL,
+				APIKey:    "key",
+				APISecret: "secret",
+				Encoding:  telemetry.WebhookEncodingProtobuf,
+				Envelope:  true,
+			}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+	)
+
+	room := &livekit.Room{Sid: "RM_permissions", Name: "permissions"}
+	prev := &livekit.ParticipantPermission{CanSubscribe: true, CanPublish: true}
+	sut.ParticipantPermissionsChanged(context.Background(), room, &livekit.ParticipantInfo{
+		Sid:        "PA_permissions",
+		Permission: &livekit.ParticipantPermission{CanSubscribe: true, Hidden: true},
+	}, prev)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-requests:
+			require.Equal(t, telemetry.EventParticipantUpdated, req.event.Event)
+			require.False(t, req.event.Participant.Permission.CanPublish)
+			require.Equal(t, []string{"can_publish", "hidden"}, req.details.ChangedFields)
+			require.NotNil(t, req.details.PrevPermission)
+			require.True(t, req.details.PrevPermission.CanPublish)
+			require.False(t, req.details.PrevPermission.Hidden)
+		case <-time.After(time.Second):
+			require.Fail(t, "participant_updated not delivered")
+		}
+	}
+}
+
+func Test_ParticipantLeft_WebhookCarriesDisconnectReason(t *testing.T) {
+	server, requests := newEnvelopeServer(t, true)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.EventDetails = true
+	conf.WebHook.IncludeEvents = []string{webhook.EventParticipantLeft}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Envelope: true}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:       server.URL,
+				APIKey:    "key",
+				APISecret: "secret",
+				Encoding:  telemetry.WebhookEncodingProtobuf,
+				Envelope:  true,
+			}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+	)
+
+	room := &livekit.Room{Sid: "RM_left", Name: "left"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_left", Identity: "left"}
+	sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+	sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_DUPLICATE_IDENTITY, true)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-requests:
+			require.Equal(t, webhook.EventParticipantLeft, req.event.Event)
+			require.Equal(t, livekit.DisconnectReason_DUPLICATE_IDENTITY, req.details.DisconnectReason)
+		case <-time.After(time.Second):
+			require.Fail(t, "participant_left not delivered")
+		}
+	}
+}
+
+func Test_TrackMuted_WebhookCarriesSourceAndIdentity(t *testing.T) {
+	server, requests := newEnvelopeServer(t, true)
+
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.EventDetails = true
+	conf.WebHook.IncludeEvents = []string{telemetry.EventTrackMuted}
+	sut := telemetry.NewTelemetryService(
+		conf,
+		[]telemetry.WebhookNotifier{
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{URL: server.URL, APIKey: "key", APISecret: "secret", Envelope: true}),
+			telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+				URL:       server.URL,
+				APIKey:    "key",
+				APISecret: "secret",
+				Encoding:  telemetry.WebhookEncodingProtobuf,
+				Envelope:  true,
+			}),
+		},
+		&telemetryfakes.FakeAnalyticsService{},
+	)
+
+	room := &livekit.Room{Sid: "RM_muted", Name: "muted"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_muted", Identity: "muted"}
+	track := &livekit.TrackInfo{Sid: "TR_muted", Type: livekit.TrackType_AUDIO}
+	sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	sut.TrackMuted(context.Background(), livekit.ParticipantID(participant.Sid), track, telemetry.TrackMuteSourceAdmin)
+	require.NoError(t, sut.Shutdown(context.Background()))
+
+	require.Len(t, requests, 2)
+	for i := 0; i < 2; i++ {
+		req := <-requests
+		require.Equal(t, telemetry.EventTrackMuted, req.event.Event)
+		require.Equal(t, participant.Sid, req.event.Participant.Sid)
+		require.Equal(t, participant.Identity, req.event.Participant.Identity)
+		require.Equal(t, telemetry.TrackMuteSourceAdmin, req.details.MuteSource)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// NotifierMiddleware wraps a notifier to add behavior around delivery, such as logging, metrics or headers
+type NotifierMiddleware func(next WebhookNotifier) WebhookNotifier
+
+// WebhookNotifierFunc adapts a function to a WebhookNotifier
+type WebhookNotifierFunc func(ctx context.Context, event *livekit.WebhookEvent) error
+
+func (f WebhookNotifierFunc) Notify(ctx context.Context, event *livekit.WebhookEvent) error {
+	return f(ctx, event)
+}
+
+// ChainNotifier wraps notifier in middlewares, the first of which sees deliveries first
+func ChainNotifier(notifier WebhookNotifier, middlewares ...NotifierMiddleware) WebhookNotifier {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		notifier = middlewares[i](notifier)
+	}
+	return notifier
+}
+
+// RetryMiddleware retries failed deliveries up to maxRetries times, until ctx is done, backing off exponentially
+// from baseDelay between attempts or waiting as long as the endpoint asked. returns the last delivery error on failure.
+// each attempt's context carries its number, starting from 1. the telemetry service delivers on a context of its
+// own, so retries outlive the context the event was generated with and stop only on shutdown
+func RetryMiddleware(endpoint string, maxRetries int, baseDelay time.Duration) NotifierMiddleware {
+	return retryMiddleware(realClock{}, endpoint, maxRetries, baseDelay)
+}
+
+// retryMiddleware is RetryMiddleware waiting out backoffs on clock
+func retryMiddleware(clock Clock, endpoint string, maxRetries int, baseDelay time.Duration) NotifierMiddleware {
+	return func(next WebhookNotifier) WebhookNotifier {
+		return WebhookNotifierFunc(func(ctx context.Context, event *livekit.WebhookEvent) error {
+			for attempt := 0; ; attempt++ {
+				err := next.Notify(withWebhookAttempt(ctx, attempt+1), event)
+				if err == nil {
+					return nil
+				}
+				if attempt >= maxRetries {
+					logger.Warnw("failed to notify webhook", err, "endpoint", endpoint, "event", event.Event, "attempts", attempt+1)
+					return err
+				}
+
+				timer := clock.NewTimer(webhookRetryAfter(err, baseDelay, attempt))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					logger.Warnw("failed to notify webhook, retries aborted", err, "endpoint", endpoint, "event", event.Event, "attempts", attempt+1)
+					return err
+				case <-timer.C():
+				}
+			}
+		})
+	}
+}
+
+// TimeoutMiddleware bounds each delivery attempt by timeout. the attempt gets its own context carrying only
+// the trace span and webhook details of the one it is given, so a deadline on the context the event was generated
+// with does not cut delivery short
+func TimeoutMiddleware(endpoint string, timeout time.Duration) NotifierMiddleware {
+	return func(next WebhookNotifier) WebhookNotifier {
+		return WebhookNotifierFunc(func(parent context.Context, event *livekit.WebhookEvent) error {
+			detached := withWebhookDetails(trace.ContextWithSpan(context.Background(), trace.SpanFromContext(parent)), webhookDetails(parent))
+			ctx, cancel := context.WithTimeout(detached, timeout)
+			defer cancel()
+
+			err := next.Notify(ctx, event)
+			if errors.Is(err, context.DeadlineExceeded) {
+				logger.Warnw("webhook delivery timed out", err, "endpoint", endpoint, "event", event.Event, "timeout", timeout)
+			}
+			return err
+		})
+	}
+}
+
+// MetricsMiddleware records the outcome of each delivery attempt to endpoint, and its error on the trace span
+func MetricsMiddleware(endpoint string) NotifierMiddleware {
+	return func(next WebhookNotifier) WebhookNotifier {
+		return WebhookNotifierFunc(func(ctx context.Context, event *livekit.WebhookEvent) error {
+			err := next.Notify(ctx, event)
+			prometheus.RecordWebhookAttempt(endpoint, err == nil)
+			if err != nil {
+				trace.SpanFromContext(ctx).RecordError(err)
+				var tlsErr *WebhookTLSError
+				switch {
+				case errors.Is(err, context.DeadlineExceeded):
+					prometheus.RecordWebhookTimeout(endpoint)
+				case errors.As(err, &tlsErr):
+					prometheus.RecordWebhookTLSError(endpoint)
+				}
+			}
+			return err
+		})
+	}
+}
+
+// webhookRetryAfter returns how long to wait before retrying a failed delivery: as long as the endpoint asked for,
+// unless that is longer than webhookMaxRetryDelay, otherwise the exponential backoff delay
+func webhookRetryAfter(err error, base time.Duration, attempt int) time.Duration {
+	var statusErr *WebhookStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 && statusErr.RetryAfter <= webhookMaxRetryDelay {
+		return statusErr.RetryAfter
+	}
+	return webhookRetryDelay(base, attempt)
+}
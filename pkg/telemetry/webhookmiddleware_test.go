@@ -0,0 +1,113 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func Test_ChainNotifier_AppliesMiddlewaresInOrder(t *testing.T) {
+	var calls []string
+	middleware := func(name string) telemetry.NotifierMiddleware {
+		return func(next telemetry.WebhookNotifier) telemetry.WebhookNotifier {
+			return telemetry.WebhookNotifierFunc(func(ctx context.Context, event *livekit.WebhookEvent) error {
+				calls = append(calls, name)
+				return next.Notify(ctx, event)
+			})
+		}
+	}
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+
+	err := telemetry.ChainNotifier(notifier, middleware("outer"), middleware("inner")).Notify(context.Background(), &livekit.WebhookEvent{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"outer", "inner"}, calls)
+	require.Equal(t, 1, notifier.NotifyCallCount())
+}
+
+func Test_RetryMiddleware(t *testing.T) {
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyReturnsOnCall(0, errors.New("failed"))
+	notifier.NotifyReturnsOnCall(1, errors.New("failed"))
+
+	retry := telemetry.RetryMiddleware("test", 2, time.Millisecond)
+	require.NoError(t, retry(notifier).Notify(context.Background(), &livekit.WebhookEvent{}))
+	require.Equal(t, 3, notifier.NotifyCallCount())
+
+	// gives up once retries are exhausted
+	notifier = &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyReturns(errors.New("failed"))
+	require.Error(t, retry(notifier).Notify(context.Background(), &livekit.WebhookEvent{}))
+	require.Equal(t, 3, notifier.NotifyCallCount())
+
+	// or once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	notifier = &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyReturns(errors.New("failed"))
+	require.Error(t, retry(notifier).Notify(ctx, &livekit.WebhookEvent{}))
+	require.Equal(t, 1, notifier.NotifyCallCount())
+}
+
+func Test_TimeoutMiddleware(t *testing.T) {
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyCalls(func(ctx context.Context, _ *livekit.WebhookEvent) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// a deadline on the caller's context is replaced by the timeout
+	parent, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := telemetry.TimeoutMiddleware("test", 100*time.Millisecond)(notifier).Notify(parent, &livekit.WebhookEvent{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func Test_WithNotifierMiddleware_WrapsEachAttempt(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.WebHook.MaxRetries = 1
+	conf.WebHook.RetryBaseDelay = time.Millisecond
+	notifier := &telemetryfakes.FakeWebhookNotifier{}
+	notifier.NotifyReturnsOnCall(0, errors.New("failed"))
+
+	var events []string
+	sut := telemetry.NewTelemetryService(conf, []telemetry.WebhookNotifier{notifier}, &telemetryfakes.FakeAnalyticsService{},
+		telemetry.WithNotifierMiddleware(func(next telemetry.WebhookNotifier) telemetry.WebhookNotifier {
+			return telemetry.WebhookNotifierFunc(func(ctx context.Context, event *livekit.WebhookEvent) error {
+				events = append(events, event.Event)
+				return next.Notify(ctx, event)
+			})
+		}),
+	)
+
+	sut.NotifyEvent(context.Background(), &livekit.WebhookEvent{Event: webhook.EventRoomStarted})
+	require.NoError(t, sut.Shutdown(context.Background()))
+
+	require.Equal(t, 2, notifier.NotifyCallCount())
+	require.Equal(t, []string{webhook.EventRoomStarted, webhook.EventRoomStarted}, events)
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"sync"
+
+	"github.com/gammazero/workerpool"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// roomQueues delivers an endpoint's events for a room one at a time, in the order they were submitted, while
+// events for different rooms are delivered in parallel on the endpoint's pool. see WebHookConfig.OrderByRoom
+type roomQueues struct {
+	lock sync.Mutex
+	// deliveries waiting for the one in flight for each room, rooms without a delivery in flight have no entry
+	rooms map[livekit.RoomID][]func()
+}
+
+func newRoomQueues() *roomQueues {
+	return &roomQueues{
+		rooms: make(map[livekit.RoomID][]func()),
+	}
+}
+
+// submit runs deliver on pool once the deliveries submitted before it for the room have finished
+func (q *roomQueues) submit(pool *workerpool.WorkerPool, roomID livekit.RoomID, deliver func()) {
+	q.lock.Lock()
+	if pending, ok := q.rooms[roomID]; ok {
+		q.rooms[roomID] = append(pending, deliver)
+		q.lock.Unlock()
+		return
+	}
+	q.rooms[roomID] = nil
+	q.lock.Unlock()
+
+	pool.Submit(func() {
+		q.drain(roomID, deliver)
+	})
+}
+
+// drain runs deliver, then the deliveries queued for the room behind it, on the same worker
+func (q *roomQueues) drain(roomID livekit.RoomID, deliver func()) {
+	for deliver != nil {
+		deliver()
+
+		q.lock.Lock()
+		if pending := q.rooms[roomID]; len(pending) != 0 {
+			deliver = pending[0]
+			pending[0] = nil
+			q.rooms[roomID] = pending[1:]
+		} else {
+			delete(q.rooms, roomID)
+			deliver = nil
+		}
+		q.lock.Unlock()
+	}
+}
+
+// webhookRoomID returns the room an event is about, empty for events that aren't about a room
+func webhookRoomID(event *livekit.WebhookEvent) livekit.RoomID {
+	if sid := event.Room.GetSid(); sid != "" {
+		return livekit.RoomID(sid)
+	}
+	return livekit.RoomID(event.EgressInfo.GetRoomId())
+}
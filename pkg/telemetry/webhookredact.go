@@ -0,0 +1,209 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// fields of webhook events that can be redacted, see WebHookConfig.Redactions. each replaces the value of its field
+// with the result of redact, and leaves events without the field alone
+var redactableWebhookFields = map[string]func(event *livekit.WebhookEvent, redact func(string) string){
+	"room.name": func(event *livekit.WebhookEvent, redact func(string) string) {
+		if event.Room != nil {
+			event.Room.Name = redact(event.Room.Name)
+		}
+	},
+	"room.metadata": func(event *livekit.WebhookEvent, redact func(string) string) {
+		if event.Room != nil {
+			event.Room.Metadata = redact(event.Room.Metadata)
+		}
+	},
+	"participant.identity": func(event *livekit.WebhookEvent, redact func(string) string) {
+		if event.Participant != nil {
+			event.Participant.Identity = redact(event.Participant.Identity)
+		}
+	},
+	"participant.name": func(event *livekit.WebhookEvent, redact func(string) string) {
+		if event.Participant != nil {
+			event.Participant.Name = redact(event.Participant.Name)
+		}
+	},
+	"participant.metadata": func(event *livekit.WebhookEvent, redact func(string) string) {
+		if event.Participant != nil {
+			event.Participant.Metadata = redact(event.Participant.Metadata)
+		}
+	},
+	"ingress.participant_identity": func(event *livekit.WebhookEvent, redact func(string) string) {
+		if event.IngressInfo != nil {
+			event.IngressInfo.ParticipantIdentity = redact(event.IngressInfo.ParticipantIdentity)
+		}
+	},
+	"ingress.participant_name": func(event *livekit.WebhookEvent, redact func(string) string) {
+		if event.IngressInfo != nil {
+			event.IngressInfo.ParticipantName = redact(event.IngressInfo.ParticipantName)
+		}
+	},
+}
+
+// fields of the client info delivered along with participant_joined events that can be redacted, see
+// WebHookConfig.ParticipantClientInfo
+var redactableClientInfoFields = map[string]func(info *livekit.ClientInfo, redact func(string) string){
+	"client_info.os": func(info *livekit.ClientInfo, redact func(string) string) {
+		info.Os = redact(info.Os)
+	},
+	"client_info.os_version": func(info *livekit.ClientInfo, redact func(string) string) {
+		info.OsVersion = redact(info.OsVersion)
+	},
+	"client_info.device_model": func(info *livekit.ClientInfo, redact func(string) string) {
+		info.DeviceModel = redact(info.DeviceModel)
+	},
+	"client_info.browser": func(info *livekit.ClientInfo, redact func(string) string) {
+		info.Browser = redact(info.Browser)
+	},
+	"client_info.browser_version": func(info *livekit.ClientInfo, redact func(string) string) {
+		info.BrowserVersion = redact(info.BrowserVersion)
+	},
+	"client_info.address": func(info *livekit.ClientInfo, redact func(string) string) {
+		info.Address = redact(info.Address)
+	},
+	"client_info.network": func(info *livekit.ClientInfo, redact func(string) string) {
+		info.Network = redact(info.Network)
+	},
+}
+
+// webhookRedaction is a parsed WebHookRedactionConfig
+type webhookRedaction struct {
+	fields           []func(event *livekit.WebhookEvent, redact func(string) string)
+	clientInfoFields []func(info *livekit.ClientInfo, redact func(string) string)
+	// nil to apply to every event or endpoint
+	events    map[string]struct{}
+	endpoints map[string]struct{}
+	hash      bool
+}
+
+func newWebhookRedactions(configs []config.WebHookRedactionConfig) []*webhookRedaction {
+	redactions := make([]*webhookRedaction, 0, len(configs))
+	for _, conf := range configs {
+		r := &webhookRedaction{
+			events:    toEventSet(conf.Events),
+			endpoints: toEventSet(conf.Endpoints),
+			hash:      conf.Hash,
+		}
+		for _, field := range conf.Fields {
+			if redactField, ok := redactableWebhookFields[field]; ok {
+				r.fields = append(r.fields, redactField)
+			} else if redactField, ok := redactableClientInfoFields[field]; ok {
+				r.clientInfoFields = append(r.clientInfoFields, redactField)
+			} else {
+				logger.Warnw("ignoring unknown webhook redaction field", nil, "field", field)
+			}
+		}
+		if len(r.fields) != 0 || len(r.clientInfoFields) != 0 {
+			redactions = append(redactions, r)
+		}
+	}
+	return redactions
+}
+
+// webhookRedactionsFor returns the redactions that apply to the endpoint named name
+func (t *telemetryService) webhookRedactionsFor(name string) []*webhookRedaction {
+	var redactions []*webhookRedaction
+	for _, r := range t.webhookRedactions {
+		if _, ok := r.endpoints[name]; ok || r.endpoints == nil {
+			redactions = append(redactions, r)
+		}
+	}
+	return redactions
+}
+
+// redact returns event and the details delivered along with it as they are delivered to the endpoint. when any of
+// its redactions apply, fields are redacted on copies, leaving event and details as they are for the other endpoints,
+// listeners and analytics
+func (e *webhookEndpoint) redact(event *livekit.WebhookEvent, details *WebhookDetails) (*livekit.WebhookEvent, *WebhookDetails) {
+	return redactWebhook(e.redactions, event, details)
+}
+
+func redactWebhook(
+	redactions []*webhookRedaction,
+	event *livekit.WebhookEvent,
+	details *WebhookDetails,
+) (*livekit.WebhookEvent, *WebhookDetails) {
+	redactedEvent, redactedDetails := event, details
+	for _, r := range redactions {
+		if _, ok := r.events[event.Event]; !ok && r.events != nil {
+			continue
+		}
+		redact := clearWebhookField
+		if r.hash {
+			redact = hashWebhookField
+		}
+		if len(r.fields) != 0 {
+			if redactedEvent == event {
+				redactedEvent = proto.Clone(event).(*livekit.WebhookEvent)
+			}
+			for _, redactField := range r.fields {
+				redactField(redactedEvent, redact)
+			}
+		}
+		if details == nil {
+			continue
+		}
+		if len(r.fields) != 0 && details.PrevRoomMetadata != "" {
+			if redactedDetails == details {
+				redactedDetails = details.clone()
+			}
+			// the previous state is redacted as the event's own
+			prev := &livekit.WebhookEvent{Room: &livekit.Room{Metadata: redactedDetails.PrevRoomMetadata}}
+			for _, redactField := range r.fields {
+				redactField(prev, redact)
+			}
+			redactedDetails.PrevRoomMetadata = prev.Room.Metadata
+		}
+		if len(r.clientInfoFields) != 0 && details.ClientInfo != nil {
+			if redactedDetails == details {
+				redactedDetails = details.clone()
+			}
+			if redactedDetails.ClientInfo == details.ClientInfo {
+				redactedDetails.ClientInfo = proto.Clone(details.ClientInfo).(*livekit.ClientInfo)
+			}
+			for _, redactField := range r.clientInfoFields {
+				redactField(redactedDetails.ClientInfo, redact)
+			}
+		}
+	}
+	return redactedEvent, redactedDetails
+}
+
+func clearWebhookField(string) string {
+	return ""
+}
+
+// hashWebhookField replaces a value with its hex encoded SHA-256, so receivers can still tell values apart
+func hashWebhookField(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"reflect"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+// WebhookRouter picks the notifier that receives webhook events about room, or nil to not send them.
+// the notifier must be one of those the TelemetryService was created with. room is nil for events
+// that are not about a room, such as ingress events
+type WebhookRouter func(room *livekit.Room) WebhookNotifier
+
+// routeWebhook returns the endpoints event is delivered to, all of them unless a router is set
+func (t *telemetryService) routeWebhook(event *livekit.WebhookEvent) []*webhookEndpoint {
+	if t.webhookRouter == nil {
+		return t.webhookEndpoints
+	}
+
+	notifier := t.webhookRouter(event.Room)
+	if notifier == nil {
+		return nil
+	}
+	for _, endpoint := range t.webhookEndpoints {
+		if isSameNotifier(endpoint.target, notifier) {
+			return []*webhookEndpoint{endpoint}
+		}
+	}
+	logger.Warnw("webhook router returned a notifier that is not configured", nil, "event", event.Event)
+	return nil
+}
+
+// isSameNotifier compares notifiers without panicking on types that can't be compared, such as WebhookNotifierFunc
+func isSameNotifier(a, b WebhookNotifier) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+var errWebhookTLSKeyPair = errors.New("webhook client certificate and key files must be configured together")
+
+type WebhookTLSParams struct {
+	// PEM encoded client certificate and its private key, presented to endpoints that ask for one
+	CertFile string
+	KeyFile  string
+	// PEM encoded CAs trusted to sign endpoint certificates, in addition to the system roots
+	CAFile string
+}
+
+// WebhookTLSError is returned by URLNotifier when the TLS handshake with the endpoint fails, e.g. because the
+// endpoint rejected the client certificate or its own certificate is not trusted
+type WebhookTLSError struct {
+	Err error
+}
+
+func (e *WebhookTLSError) Error() string {
+	return fmt.Sprintf("webhook TLS handshake failed: %v", e.Err)
+}
+
+func (e *WebhookTLSError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookTLS is the TLS configuration URLNotifier connects to endpoints with, see URLNotifierParams.TLS.
+// the files are checked whenever a connection is made and reloaded once they change, so certificates can be
+// rotated on disk. connections that are already open keep the certificate they were made with
+type WebhookTLS struct {
+	params WebhookTLSParams
+
+	lock     sync.Mutex
+	config   *tls.Config
+	modTimes []time.Time
+}
+
+func NewWebhookTLS(params WebhookTLSParams) (*WebhookTLS, error) {
+	if (params.CertFile == "") != (params.KeyFile == "") {
+		return nil, errWebhookTLSKeyPair
+	}
+	w := &WebhookTLS{params: params}
+	modTimes, err := w.stat()
+	if err != nil {
+		return nil, err
+	}
+	if w.config, err = w.load(); err != nil {
+		return nil, err
+	}
+	w.modTimes = modTimes
+	return w, nil
+}
+
+// current returns the configuration to connect with, reloading it first when the files have changed.
+// when the changed files can't be loaded, the previous configuration is kept until they change again
+func (w *WebhookTLS) current() *tls.Config {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	modTimes, err := w.stat()
+	if err != nil {
+		// files are replaced rather than written in place by most tools, try again on the next connection
+		logger.Debugw("could not check webhook TLS files", "error", err)
+		return w.config
+	}
+	if sameModTimes(modTimes, w.modTimes) {
+		return w.config
+	}
+	w.modTimes = modTimes
+
+	config, err := w.load()
+	if err != nil {
+		logger.Warnw("could not reload webhook TLS files, keeping previous certificates", err,
+			"certFile", w.params.CertFile,
+			"caFile", w.params.CAFile,
+		)
+		return w.config
+	}
+	logger.Infow("reloaded webhook TLS files", "certFile", w.params.CertFile, "caFile", w.params.CAFile)
+	w.config = config
+	return w.config
+}
+
+func (w *WebhookTLS) load() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if w.params.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(w.params.CertFile, w.params.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load webhook client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if w.params.CAFile != "" {
+		pem, err := os.ReadFile(w.params.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load webhook CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in webhook CA file %s", w.params.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+func (w *WebhookTLS) stat() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, path := range []string{w.params.CertFile, w.params.KeyFile, w.params.CAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+// transport returns a transport that makes TLS connections with the current configuration, returning
+// handshake failures as WebhookTLSError
+func (w *WebhookTLS) transport(endpoint string) *http.Transport {
+	transport := NewWebhookTransport()
+	dialer := &net.Dialer{
+		Timeout:   webhookDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		config := w.current().Clone()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			config.ServerName = host
+		} else {
+			config.ServerName = addr
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			// running out of time during the handshake is a timeout like any other
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			logger.Warnw("webhook TLS handshake failed", err, "endpoint", endpoint, "address", addr)
+			return nil, &WebhookTLSError{Err: err}
+		}
+		return tlsConn, nil
+	}
+	return transport
+}
+
+func sameModTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
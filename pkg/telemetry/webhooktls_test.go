@@ -0,0 +1,149 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+func Test_URLNotifier_MutualTLS(t *testing.T) {
+	clientNames := make(chan string, 3)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientNames <- r.TLS.PeerCertificates[0].Subject.CommonName
+		// close the connection so that every delivery makes a new one
+		w.Header().Set("Connection", "close")
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	params := telemetry.WebhookTLSParams{
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+	writeClientCertificate(t, params, "first", time.Now())
+	require.NoError(t, os.WriteFile(params.CAFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	webhookTLS, err := telemetry.NewWebhookTLS(params)
+	require.NoError(t, err)
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		URL:       server.URL,
+		APIKey:    "mykey",
+		APISecret: "mysecret",
+		TLS:       webhookTLS,
+	})
+	event := &livekit.WebhookEvent{Event: "room_started", Room: &livekit.Room{Name: "room"}}
+
+	require.NoError(t, notifier.Notify(context.Background(), event))
+	require.Equal(t, "first", <-clientNames)
+
+	// a rotated certificate is picked up by the next connection
+	writeClientCertificate(t, params, "second", time.Now().Add(time.Second))
+	require.NoError(t, notifier.Notify(context.Background(), event))
+	require.Equal(t, "second", <-clientNames)
+
+	// an invalid certificate is not loaded, the previous one is kept
+	require.NoError(t, os.WriteFile(params.CertFile, []byte("invalid"), 0600))
+	require.NoError(t, os.Chtimes(params.CertFile, time.Now().Add(2*time.Second), time.Now().Add(2*time.Second)))
+	require.NoError(t, notifier.Notify(context.Background(), event))
+	require.Equal(t, "second", <-clientNames)
+}
+
+func Test_URLNotifier_TLSHandshakeError(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	params := telemetry.WebhookTLSParams{
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+	}
+	writeClientCertificate(t, params, "client", time.Now())
+	webhookTLS, err := telemetry.NewWebhookTLS(params)
+	require.NoError(t, err)
+
+	// without the CA file, the server's certificate isn't trusted
+	notifier := telemetry.NewURLNotifier(telemetry.URLNotifierParams{
+		Name:      "mtls",
+		URL:       server.URL,
+		APIKey:    "mykey",
+		APISecret: "mysecret",
+		TLS:       webhookTLS,
+	})
+	labels := map[string]string{"endpoint": "mtls"}
+	err = telemetry.MetricsMiddleware("mtls")(notifier).Notify(context.Background(), &livekit.WebhookEvent{Event: "room_started"})
+	var tlsErr *telemetry.WebhookTLSError
+	require.True(t, errors.As(err, &tlsErr))
+	require.Equal(t, float64(1), findMetric(t, "livekit_webhook_tls_errors", labels).GetCounter().GetValue())
+}
+
+func Test_NewWebhookTLS_InvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := telemetry.NewWebhookTLS(telemetry.WebhookTLSParams{CertFile: filepath.Join(dir, "client.crt")})
+	require.Error(t, err)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+	_, err = telemetry.NewWebhookTLS(telemetry.WebhookTLSParams{CAFile: caFile})
+	require.Error(t, err)
+}
+
+// writeClientCertificate writes a self-signed certificate for commonName and its key to the files in params,
+// modified at modTime
+func writeClientCertificate(t *testing.T, params telemetry.WebhookTLSParams, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(params.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600))
+	require.NoError(t, os.WriteFile(params.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	require.NoError(t, os.Chtimes(params.CertFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(params.KeyFile, modTime, modTime))
+}
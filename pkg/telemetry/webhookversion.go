@@ -0,0 +1,101 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// versions of the webhook payload, so the payload can change without breaking receivers that expect an older one.
+// each endpoint is sent the version it is configured with, receivers can be moved over one at a time
+const (
+	// the event as it is, without a version. receivers that predate versioning only understand this one
+	WebhookAPIVersionV1 = "v1"
+	// the event with an apiVersion field alongside its own. in protobuf bodies that is field webhookAPIVersionField,
+	// which receivers parsing the body as a livekit.WebhookEvent skip
+	WebhookAPIVersionV2 = "v2"
+)
+
+// clear of the fields of livekit.WebhookEvent, present and future
+const webhookAPIVersionField protowire.Number = 1000
+
+const webhookAPIVersionJSONField = "apiVersion"
+
+func validWebhookAPIVersion(version string) bool {
+	return version == WebhookAPIVersionV1 || version == WebhookAPIVersionV2
+}
+
+// versionWebhookPayload adds version to an encoded event, the result is what is signed and sent
+func versionWebhookPayload(encoded []byte, encoding string, version string) []byte {
+	if version == WebhookAPIVersionV1 {
+		return encoded
+	}
+
+	if encoding == WebhookEncodingProtobuf {
+		encoded = protowire.AppendTag(encoded, webhookAPIVersionField, protowire.BytesType)
+		return protowire.AppendString(encoded, version)
+	}
+
+	// encoded is a JSON object, the version goes first
+	versioned := make([]byte, 0, len(encoded)+len(version)+len(webhookAPIVersionJSONField)+6)
+	versioned = append(versioned, `{"`+webhookAPIVersionJSONField+`":"`+version+`"`...)
+	rest := bytes.TrimSpace(encoded[1:])
+	if len(rest) > 0 && rest[0] != '}' {
+		versioned = append(versioned, ',')
+	}
+	return append(versioned, rest...)
+}
+
+// WebhookPayloadAPIVersion returns the version of a webhook payload, as sent with contentType, once its signature
+// has been verified. payloads without a version are WebhookAPIVersionV1. the payload of a form is the value of its
+// field, sent as WebhookContentTypeJSON
+func WebhookPayloadAPIVersion(data []byte, contentType string) (string, error) {
+	if contentType == WebhookContentTypeProtobuf {
+		for len(data) > 0 {
+			num, typ, n := protowire.ConsumeTag(data)
+			if n < 0 {
+				return "", protowire.ParseError(n)
+			}
+			data = data[n:]
+			if num == webhookAPIVersionField && typ == protowire.BytesType {
+				version, n := protowire.ConsumeString(data)
+				if n < 0 {
+					return "", protowire.ParseError(n)
+				}
+				return version, nil
+			}
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return "", protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+		return WebhookAPIVersionV1, nil
+	}
+
+	var payload struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return "", err
+	}
+	if payload.APIVersion == "" {
+		return WebhookAPIVersionV1, nil
+	}
+	return payload.APIVersion, nil
+}
@@ -0,0 +1,238 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+// workers are spread across shards by participant so unrelated participants don't contend on the same lock
+const workerShardCount = 32
+
+type workerShard struct {
+	lock    sync.RWMutex
+	workers map[livekit.ParticipantID]*StatsWorker
+	// rooms of removed workers, so events arriving after a worker is gone still carry the room
+	participantRooms map[livekit.ParticipantID]participantRoom
+}
+
+type participantRoom struct {
+	roomID   livekit.RoomID
+	roomName livekit.RoomName
+	// the worker was removed while the participant was connected, before its leave was reported
+	connected bool
+	expiresAt time.Time
+}
+
+func newWorkerShard() workerShard {
+	return workerShard{
+		workers:          make(map[livekit.ParticipantID]*StatsWorker),
+		participantRooms: make(map[livekit.ParticipantID]participantRoom),
+	}
+}
+
+func (t *telemetryService) shard(participantID livekit.ParticipantID) *workerShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(participantID))
+	return &t.workers[h.Sum32()%workerShardCount]
+}
+
+func (t *telemetryService) getWorker(participantID livekit.ParticipantID) (worker *StatsWorker, ok bool) {
+	shard := t.shard(participantID)
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+
+	worker, ok = shard.workers[participantID]
+	return
+}
+
+func (t *telemetryService) getParticipantRoom(participantID livekit.ParticipantID) (participantRoom, bool) {
+	shard := t.shard(participantID)
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+
+	cached, ok := shard.participantRooms[participantID]
+	return cached, ok
+}
+
+// takeConnected returns whether the worker of the participant was removed while it was connected, clearing it so
+// the leave is reported once
+func (t *telemetryService) takeConnected(participantID livekit.ParticipantID) bool {
+	shard := t.shard(participantID)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	cached, ok := shard.participantRooms[participantID]
+	if !ok || !cached.connected {
+		return false
+	}
+	cached.connected = false
+	shard.participantRooms[participantID] = cached
+	return true
+}
+
+// allWorkers returns a snapshot of all workers, taking each shard's lock in turn
+func (t *telemetryService) allWorkers() []*StatsWorker {
+	var workers []*StatsWorker
+	for i := range t.workers {
+		shard := &t.workers[i]
+		shard.lock.RLock()
+		for _, worker := range shard.workers {
+			workers = append(workers, worker)
+		}
+		shard.lock.RUnlock()
+	}
+	return workers
+}
+
+func (t *telemetryService) createWorker(ctx context.Context,
+	roomID livekit.RoomID,
+	roomName livekit.RoomName,
+	participantID livekit.ParticipantID,
+	participantIdentity livekit.ParticipantIdentity,
+) *StatsWorker {
+	worker := newStatsWorker(
+		ctx,
+		t,
+		t.clock,
+		roomID,
+		roomName,
+		participantID,
+		participantIdentity,
+	)
+	worker.onMediaActive = t.participantMediaActive
+	worker.stallIntervals = t.trackStallIntervals
+	worker.onTrackStall = t.trackStallChanged
+	worker.isPublishedTrackLive = t.isPublishedTrackLive
+	worker.firstPacketTimeout = t.trackFirstPacketTimeout
+	worker.onTrackNeverActive = t.trackNeverActive
+	worker.qosWeights = t.qosWeights
+	worker.bitrateAlpha = t.bitrateSmoothingAlpha
+	worker.roomLabels = t.roomLabels
+	worker.roomLabel = t.roomLabels.label(roomName)
+	// a room with the name of one that just ended started, its sessions are recorded under the same label
+	t.cancelParticipantSessionsDelete(worker.roomLabel)
+
+	shard := t.shard(participantID)
+	shard.lock.Lock()
+	if _, ok := shard.workers[participantID]; !ok {
+		prometheus.AddStatsWorker()
+	}
+	shard.workers[participantID] = worker
+	delete(shard.participantRooms, participantID)
+	shard.lock.Unlock()
+	return worker
+}
+
+// removeWorker must be called with the shard's lock held
+func (s *workerShard) removeWorker(participantID livekit.ParticipantID, worker *StatsWorker, now time.Time) {
+	delete(s.workers, participantID)
+	s.participantRooms[participantID] = participantRoom{
+		roomID:    worker.roomID,
+		roomName:  worker.roomName,
+		connected: worker.IsConnected() && worker.ClosedAt().IsZero(),
+		expiresAt: now.Add(workerCleanupWait),
+	}
+	prometheus.SubStatsWorker()
+}
+
+// cleanupWorkers removes workers some time after they were closed, and closes and removes workers of participants
+// that are not connected and have been idle for longer than the idle timeout, which happens when a participant goes
+// away without ParticipantLeft
+func (t *telemetryService) cleanupWorkers() {
+	var idle []*StatsWorker
+	for i := range t.workers {
+		idle = t.workers[i].cleanup(t.clock.Now(), t.workerIdleTimeout, idle)
+	}
+
+	for _, worker := range idle {
+		logger.Infow("reaping idle analytics worker for participant",
+			"pID", worker.ParticipantID(),
+			"lastActivity", worker.LastActivity(),
+		)
+		worker.Close()
+		// ParticipantLeft won't find the worker anymore, account for the participant here
+		prometheus.SubParticipant()
+	}
+}
+
+// reapRoomWorkers closes and removes the workers of participants of a room that are still open, for when the
+// room is deleted or ends without them leaving. their sessions end with the room
+func (t *telemetryService) reapRoomWorkers(room *livekit.Room) {
+	roomID := livekit.RoomID(room.Sid)
+	now := t.clock.Now()
+	var lingering []*StatsWorker
+	for i := range t.workers {
+		shard := &t.workers[i]
+		shard.lock.Lock()
+		for participantID, worker := range shard.workers {
+			if worker.roomID == roomID && worker.ClosedAt().IsZero() {
+				shard.removeWorker(participantID, worker, now)
+				lingering = append(lingering, worker)
+			}
+		}
+		shard.lock.Unlock()
+	}
+
+	for _, worker := range lingering {
+		logger.Infow("force-closing analytics worker of participant that did not leave room", "pID", worker.ParticipantID(), "roomID", roomID)
+		// a ParticipantLeft arriving later won't find the worker, so the session is recorded here
+		prometheus.RecordParticipantSession(worker.roomLabel, now.Sub(worker.JoinedAt()))
+		prometheus.RecordParticipantLeft(livekit.DisconnectReason_ROOM_DELETED.String())
+		t.recordJoinLeave(room)
+		sendHeldUnpublishes(worker)
+		worker.Close()
+		// ParticipantLeft won't find the worker anymore, account for the participant here
+		prometheus.SubParticipant()
+	}
+}
+
+// cleanup removes closed workers, and idle ones of participants that are not connected, from the shard, appending
+// idle ones to idle so they can be closed once the lock is released
+func (s *workerShard) cleanup(now time.Time, idleTimeout time.Duration, idle []*StatsWorker) []*StatsWorker {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for participantID, worker := range s.workers {
+		closedAt := worker.ClosedAt()
+		if !closedAt.IsZero() {
+			if now.Sub(closedAt) > workerCleanupWait {
+				logger.Debugw("reaping analytics worker for participant", "pID", participantID)
+				s.removeWorker(participantID, worker, now)
+			}
+			continue
+		}
+
+		if idleTimeout > 0 && !worker.IsConnected() && now.Sub(worker.LastActivity()) > idleTimeout {
+			s.removeWorker(participantID, worker, now)
+			idle = append(idle, worker)
+		}
+	}
+
+	for participantID, cached := range s.participantRooms {
+		if now.After(cached.expiresAt) {
+			delete(s.participantRooms, participantID)
+		}
+	}
+	return idle
+}
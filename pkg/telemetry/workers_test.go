@@ -0,0 +1,230 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// joins, publishes and leaves from many participants at once, all of which touch the workers map
+func Benchmark_ConcurrentParticipantLifecycle(b *testing.B) {
+	fixture := createFixture()
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	var counter atomic.Uint64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			partSID := livekit.ParticipantID(fmt.Sprintf("PA_%d", counter.Inc()))
+			participantInfo := &livekit.ParticipantInfo{Sid: string(partSID), Identity: string(partSID)}
+			fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+			fixture.sut.TrackPublished(context.Background(), partSID, livekit.ParticipantIdentity(participantInfo.Identity), &livekit.TrackInfo{
+				Sid:  utils.NewGuid(utils.TrackPrefix),
+				Type: livekit.TrackType_AUDIO,
+			})
+			fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, livekit.DisconnectReason_CLIENT_INITIATED, true)
+		}
+	})
+}
+
+func Test_RoomEnded_ClosesLingeringWorkers(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomEndDrainTimeout = 0
+	fixture := createFixtureWithConfig(conf)
+
+	ended := &livekit.Room{Sid: "RM_ended", Name: "ended"}
+	other := &livekit.Room{Sid: "RM_other", Name: "other"}
+	lingering := []*livekit.ParticipantInfo{{Sid: "PA_lingering_1"}, {Sid: "PA_lingering_2"}}
+	for _, participant := range lingering {
+		fixture.sut.ParticipantJoined(context.Background(), ended, participant, nil, nil, true)
+		fixture.sut.ParticipantActive(context.Background(), ended, participant, &livekit.AnalyticsClientMeta{}, false)
+	}
+	staying := &livekit.ParticipantInfo{Sid: "PA_staying"}
+	fixture.sut.ParticipantJoined(context.Background(), other, staying, nil, nil, true)
+
+	fixture.sut.RoomEnded(context.Background(), ended)
+	flushEvents(fixture.sut)
+
+	for _, participant := range lingering {
+		_, ok := fixture.sut.GetParticipantStats(livekit.ParticipantID(participant.Sid))
+		require.False(t, ok)
+	}
+	_, ok := fixture.sut.GetParticipantStats(livekit.ParticipantID(staying.Sid))
+	require.True(t, ok)
+}
+
+func Test_RoomEnded_RecordsSessionsOfLingeringParticipants(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomEndDrainTimeout = 0
+	conf.Analytics.RoomLabel = telemetry.RoomLabelFull
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RM_lingering", Name: "LingeringRoom"}
+	sessions := func() *dto.Histogram {
+		return findMetric(t, "livekit_participant_session_duration_seconds", map[string]string{"room": room.Name}).GetHistogram()
+	}
+	left := func() float64 {
+		return findMetric(t, "livekit_participant_left_total", map[string]string{"reason": livekit.DisconnectReason_ROOM_DELETED.String()}).GetCounter().GetValue()
+	}
+	before := left()
+
+	participant := &livekit.ParticipantInfo{Sid: "PA_lingering"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	fixture.sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+	flushEvents(fixture.sut)
+
+	clock.Advance(time.Minute)
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+	require.Equal(t, uint64(1), sessions().GetSampleCount())
+	require.Equal(t, time.Minute.Seconds(), sessions().GetSampleSum())
+	require.Equal(t, before+1, left())
+
+	// the leave arriving after the room ended doesn't count the participant again
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_ROOM_DELETED, true)
+	flushEvents(fixture.sut)
+	require.Equal(t, uint64(1), sessions().GetSampleCount())
+	require.Equal(t, before+1, left())
+}
+
+func Test_RoomEnded_WaitsForLeavesToDrain(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomEndDrainTimeout = 100 * time.Millisecond
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RM_draining", Name: "draining"}
+	leaving := &livekit.ParticipantInfo{Sid: "PA_leaving"}
+	lingering := &livekit.ParticipantInfo{Sid: "PA_lingering"}
+	for _, participant := range []*livekit.ParticipantInfo{leaving, lingering} {
+		fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+		fixture.sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+	}
+
+	fixture.sut.RoomEnded(context.Background(), room)
+	// a leave racing with the end of the room is reported as usual
+	fixture.sut.ParticipantLeft(context.Background(), room, leaving, livekit.DisconnectReason_ROOM_DELETED, true)
+	flushEvents(fixture.sut)
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_PARTICIPANT_LEFT), 1)
+
+	_, ok := fixture.sut.GetParticipantStats(livekit.ParticipantID(lingering.Sid))
+	require.True(t, ok)
+
+	clock.Advance(conf.Analytics.RoomEndDrainTimeout)
+	flushEvents(fixture.sut)
+	_, ok = fixture.sut.GetParticipantStats(livekit.ParticipantID(lingering.Sid))
+	require.False(t, ok)
+}
+
+func Test_IdleConnectedWorkerIsKept(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.WorkerIdleTimeout = time.Minute
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	room := &livekit.Room{Sid: "RM_idle", Name: "idle"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_idle"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	fixture.sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+	flushEvents(fixture.sut)
+
+	// a connected participant without media is idle, but still in the room
+	clock.Advance(2 * conf.Analytics.WorkerIdleTimeout)
+	flushEvents(fixture.sut)
+	_, ok := fixture.sut.GetParticipantStats(livekit.ParticipantID(participant.Sid))
+	require.True(t, ok)
+
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	flushEvents(fixture.sut)
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_PARTICIPANT_LEFT), 1)
+}
+
+func Test_ParticipantLeft_WithoutWorkerIsReported(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomEndDrainTimeout = 0
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RM_late", Name: "late"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_late"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	fixture.sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+
+	// the room ends and its workers are reaped before the leave arrives
+	fixture.sut.RoomEnded(context.Background(), room)
+	flushEvents(fixture.sut)
+	_, ok := fixture.sut.GetParticipantStats(livekit.ParticipantID(participant.Sid))
+	require.False(t, ok)
+
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_ROOM_DELETED, true)
+	flushEvents(fixture.sut)
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_PARTICIPANT_LEFT), 1)
+}
+
+func Test_ParticipantLeft_OfReapedIdleWorkerIsNotReported(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.WorkerIdleTimeout = time.Minute
+	clock := newFakeClock()
+	fixture := createFixtureWithClock(conf, clock)
+
+	// never connects, so it never gets participant_joined
+	room := &livekit.Room{Sid: "RM_idle", Name: "idle"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_never_connected"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	flushEvents(fixture.sut)
+
+	clock.Advance(2 * conf.Analytics.WorkerIdleTimeout)
+	require.Eventually(t, func() bool {
+		_, ok := fixture.sut.GetParticipantStats(livekit.ParticipantID(participant.Sid))
+		return !ok
+	}, time.Second, time.Millisecond)
+
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_CLIENT_INITIATED, true)
+	fixture.drain(t)
+	require.Empty(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_PARTICIPANT_LEFT))
+	require.Zero(t, fixture.notifier.NotifyCallCount())
+}
+
+func Test_ParticipantLeft_OfReapedConnectedWorkerIsReportedOnce(t *testing.T) {
+	conf, _ := config.NewConfig("", true, nil, nil)
+	conf.Analytics.RoomEndDrainTimeout = 0
+	fixture := createFixtureWithConfig(conf)
+
+	room := &livekit.Room{Sid: "RM_repeated", Name: "repeated"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_repeated"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participant, nil, nil, true)
+	fixture.sut.ParticipantActive(context.Background(), room, participant, &livekit.AnalyticsClientMeta{}, false)
+	fixture.sut.RoomEnded(context.Background(), room)
+
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_ROOM_DELETED, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participant, livekit.DisconnectReason_ROOM_DELETED, true)
+	fixture.drain(t)
+	require.Len(t, findAnalyticsEvents(fixture, livekit.AnalyticsEventType_PARTICIPANT_LEFT), 1)
+	require.Len(t, findWebhookEvents(fixture, webhook.EventParticipantLeft), 1)
+}